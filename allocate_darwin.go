@@ -0,0 +1,41 @@
+package mtpx
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	fPreallocate = 42
+	fAllocateAll = 0x4
+	fPeofPosMode = 3
+)
+
+// argument of the F_PREALLOCATE fcntl
+type fstore struct {
+	flags      uint32
+	posmode    int32
+	offset     int64
+	length     int64
+	bytesalloc int64
+}
+
+// allocate the disk blocks for [size] bytes of [f]
+func allocateLocalFile(f *os.File, size int64) error {
+	store := fstore{flags: fAllocateAll, posmode: fPeofPosMode, length: size}
+
+	_, _, errno := syscall.Syscall(syscall.SYS_FCNTL, f.Fd(), fPreallocate, uintptr(unsafe.Pointer(&store)))
+
+	switch errno {
+	case 0:
+		return nil
+
+	case syscall.ENOSPC:
+		return LocalSpaceError{error: errno}
+
+	// the file system can't preallocate
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,27 @@
+package mtpx
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// keeps the size of the file unchanged while its blocks are allocated
+const fallocKeepSize = 0x01
+
+// allocate the disk blocks for [size] bytes of [f]
+func allocateLocalFile(f *os.File, size int64) error {
+	err := syscall.Fallocate(int(f.Fd()), fallocKeepSize, 0, size)
+
+	switch {
+	case err == nil:
+		return nil
+
+	case errors.Is(err, syscall.ENOSPC):
+		return LocalSpaceError{error: err}
+
+	// the file system can't preallocate
+	default:
+		return nil
+	}
+}
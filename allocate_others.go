@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !windows
+// +build !linux,!darwin,!windows
+
+package mtpx
+
+import "os"
+
+// the files are not preallocated on this system
+func allocateLocalFile(f *os.File, size int64) error {
+	return nil
+}
@@ -0,0 +1,32 @@
+package mtpx
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var setFileInformationByHandle = syscall.NewLazyDLL("kernel32.dll").NewProc("SetFileInformationByHandle")
+
+const (
+	fileAllocationInfo = 5
+	errorDiskFull      = syscall.Errno(112)
+)
+
+// allocate the disk blocks for [size] bytes of [f]
+// unlike SetEndOfFile the size of the file is not changed
+func allocateLocalFile(f *os.File, size int64) error {
+	allocationSize := size
+
+	r, _, err := setFileInformationByHandle.Call(f.Fd(), fileAllocationInfo, uintptr(unsafe.Pointer(&allocationSize)), unsafe.Sizeof(allocationSize))
+	if r != 0 {
+		return nil
+	}
+
+	if err == errorDiskFull {
+		return LocalSpaceError{error: err}
+	}
+
+	// the file system can't preallocate
+	return nil
+}
@@ -0,0 +1,74 @@
+package mtpx
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// Create a middleware which records every mutating operation (op, paths/objectIds, size, result, timestamp) to [cb]
+// the operations which do not change the device (eg: [DownloadFiles]) are not recorded
+// if [cb] fails then the operation returns an [AuditLogError], even if the operation itself succeeded
+// eg: Initialize(Init{Middlewares: []Middleware{NewAuditMiddleware(AuditLogFile("/var/log/mtpx-audit.log"))}})
+func NewAuditMiddleware(cb AuditCb) Middleware {
+	return func(op *OperationInfo, next func() error) error {
+		if !op.Mutating {
+			return next()
+		}
+
+		startTime := time.Now()
+		err := next()
+
+		record := &AuditRecord{
+			Time:        startTime,
+			Operation:   op.Type,
+			StorageId:   op.StorageId,
+			FileProps:   op.FileProps,
+			Sources:     op.Sources,
+			Destination: op.Destination,
+			NewFileName: op.NewFileName,
+			Size:        op.Size,
+			Success:     err == nil,
+			Duration:    time.Since(startTime),
+		}
+		if err != nil {
+			record.Error = err.Error()
+		}
+
+		if cbErr := cb(record); cbErr != nil {
+			return AuditLogError{error: cbErr}
+		}
+
+		return err
+	}
+}
+
+// Append the audit records to the file at [filename] as JSON lines
+// the file is created if it does not exist and is never truncated
+func AuditLogFile(filename string) AuditCb {
+	var mu sync.Mutex
+
+	return func(record *AuditRecord) error {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, newLocalFileMode)
+		if err != nil {
+			return err
+		}
+
+		if _, err := f.Write(append(line, '\n')); err != nil {
+			f.Close()
+
+			return err
+		}
+
+		return f.Close()
+	}
+}
@@ -0,0 +1,72 @@
+package mtpx
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAuditMiddleware(t *testing.T) {
+	Convey("Test NewAuditMiddleware | AuditLogFile", t, func() {
+		dir, err := ioutil.TempDir("", "mtpx-audit")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		logFile := filepath.Join(dir, "audit.log")
+		mw := NewAuditMiddleware(AuditLogFile(logFile))
+
+		err = mw(&OperationInfo{Type: DeleteFileOp, Mutating: true, StorageId: 1, FileProps: []FileProp{{0, "/a.txt"}}}, func() error {
+			return nil
+		})
+		So(err, ShouldBeNil)
+
+		err = mw(&OperationInfo{Type: RenameFileOp, Mutating: true, StorageId: 1, FileProps: []FileProp{{5, ""}}, NewFileName: "b.txt"}, func() error {
+			return fmt.Errorf("rename failed")
+		})
+		So(err, ShouldBeError, "rename failed")
+
+		// not recorded
+		err = mw(&OperationInfo{Type: DownloadFilesOp, StorageId: 1}, func() error {
+			return nil
+		})
+		So(err, ShouldBeNil)
+
+		f, err := os.Open(logFile)
+		So(err, ShouldBeNil)
+		defer f.Close()
+
+		var records []AuditRecord
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var r AuditRecord
+			So(json.Unmarshal(scanner.Bytes(), &r), ShouldBeNil)
+
+			records = append(records, r)
+		}
+
+		So(len(records), ShouldEqual, 2)
+		So(records[0].Operation, ShouldEqual, DeleteFileOp)
+		So(records[0].FileProps[0].FullPath, ShouldEqual, "/a.txt")
+		So(records[0].Success, ShouldBeTrue)
+		So(records[1].Operation, ShouldEqual, RenameFileOp)
+		So(records[1].NewFileName, ShouldEqual, "b.txt")
+		So(records[1].Success, ShouldBeFalse)
+		So(records[1].Error, ShouldEqual, "rename failed")
+	})
+
+	Convey("Test NewAuditMiddleware | Should throw an error", t, func() {
+		mw := NewAuditMiddleware(func(record *AuditRecord) error {
+			return fmt.Errorf("disk full")
+		})
+
+		err := mw(&OperationInfo{Type: MakeDirectoryOp, Mutating: true}, func() error {
+			return nil
+		})
+		So(err, ShouldHaveSameTypeAs, AuditLogError{})
+	})
+}
@@ -0,0 +1,157 @@
+package mtpx
+
+import (
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/ganeshrvel/go-mtpx/devicepath"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Send a book to an e-reader
+// the book is placed in the books directory of the device profile (see [DeviceProfile.Books]) unless [opts.Destination] is set
+// and it is sent with the object format which the readers index the documents by
+// a book with the same title and size in the destination directory is treated as a duplicate and nothing is sent
+// another file of the same name is only replaced if [opts.Overwrite] is set, an [InvalidPathError] is returned otherwise
+// return:
+// [objectId]: objectId of the sent book, or of the existing one if it is a duplicate
+// [duplicate]: the book was already on the device
+func SendBook(dev *mtp.Device, localPath string, opts SendBookOptions) (objectId uint32, duplicate bool, err error) {
+	storageId := opts.StorageId
+	if storageId == 0 {
+		if storageId, err = firstWritableStorage(dev); err != nil {
+			return 0, false, err
+		}
+	}
+
+	destination := bookDestination(dev, opts.Destination)
+
+	op := &OperationInfo{Type: UploadFilesOp, Mutating: true, StorageId: storageId, Sources: []string{localPath}, Destination: destination}
+
+	err = runMiddlewares(dev, op, func() error {
+		objectId, duplicate, err = sendBook(dev, storageId, localPath, destination, opts.Overwrite, opts.ProgressCb)
+
+		return err
+	})
+
+	return objectId, duplicate, err
+}
+
+// helper function for [SendBook]
+func sendBook(dev *mtp.Device, storageId uint32, localPath, destination string, overwrite bool, progressCb SizeProgressCb) (objectId uint32, duplicate bool, err error) {
+	if err := checkStorageWritable(dev, storageId, false); err != nil {
+		return 0, false, err
+	}
+
+	stat, err := os.Stat(localPath)
+	if err != nil {
+		return 0, false, InvalidPathError{error: err}
+	}
+
+	if stat.IsDir() {
+		return 0, false, InvalidPathError{error: fmt.Errorf("a book should be a file: %s", localPath)}
+	}
+
+	parentId, err := makeNestedDirectory(dev, storageId, destination)
+	if err != nil {
+		return 0, false, err
+	}
+
+	filename := filepath.Base(localPath)
+
+	children, err := listDirectory(dev, storageId, parentId, destination)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if existing := findDuplicateBook(children, filename, stat.Size()); existing != nil {
+		return existing.ObjectId, true, nil
+	}
+
+	if !overwrite {
+		mode := PathMatching()
+		for _, child := range children {
+			if matchFilename(child.Name, filename, mode) != noFilenameMatch {
+				return 0, false, InvalidPathError{error: fmt.Errorf("an object named %s already exists in %s", filename, destination)}
+			}
+		}
+	}
+
+	fileBuf, err := os.Open(localPath)
+	if err != nil {
+		return 0, false, InvalidPathError{error: err}
+	}
+	defer fileBuf.Close()
+
+	size := stat.Size()
+
+	startTime := time.Now()
+
+	objectId, err = sendObject(dev, storageId, parentId, filename, objectFormat(filename), stat.ModTime(), fileBuf, size, overwrite,
+		func(total, sent int64, objectId uint32, err error) error {
+			if err != nil || progressCb == nil {
+				return err
+			}
+
+			return progressCb(total, sent, objectId, nil)
+		})
+	if err != nil {
+		recordTransferError(dev)
+
+		return objectId, false, err
+	}
+
+	recordTransferredBytes(dev, Upload, size, time.Since(startTime))
+	recordTransferredFile(dev, Upload)
+
+	return objectId, false, nil
+}
+
+// directory where the books are sent to
+// [destination] if set, otherwise the first books directory of the device profile or [defaultBooksPath]
+func bookDestination(dev *mtp.Device, destination string) string {
+	if destination != "" {
+		return devicepath.Clean(destination)
+	}
+
+	if p := FetchDeviceProfile(dev); p != nil && len(p.Books.Paths) > 0 {
+		return devicepath.Clean(p.Books.Paths[0])
+	}
+
+	return defaultBooksPath
+}
+
+// title of the book used to spot the duplicates
+// the extension, case, spacing and punctuation are ignored so that "The_Hobbit.epub" and "the hobbit.EPUB" match
+func bookTitle(filename string) string {
+	stem := strings.TrimSuffix(filename, filepath.Ext(filename))
+
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+
+		return -1
+	}, stem)
+}
+
+// find a file among [children] with the same title, extension and size as the book
+func findDuplicateBook(children []*FileInfo, filename string, size int64) *FileInfo {
+	title := bookTitle(filename)
+	ext := strings.ToLower(filepath.Ext(filename))
+
+	for _, child := range children {
+		if child.IsDir || child.Size != size {
+			continue
+		}
+
+		if strings.ToLower(filepath.Ext(child.Name)) == ext && bookTitle(child.Name) == title {
+			return child
+		}
+	}
+
+	return nil
+}
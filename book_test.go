@@ -0,0 +1,46 @@
+package mtpx
+
+import (
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestBookHelpers(t *testing.T) {
+	Convey("Test objectFormat", t, func() {
+		So(objectFormat("book.EPUB"), ShouldEqual, mtp.OFC_MTP_UndefinedDocument)
+		So(objectFormat("book.pdf"), ShouldEqual, mtp.OFC_MTP_UndefinedDocument)
+		So(objectFormat("notes.txt"), ShouldEqual, mtp.OFC_Text)
+		So(objectFormat("song.m4a"), ShouldEqual, mtp.OFC_MTP_M4A)
+		So(objectFormat("cover.jpg"), ShouldEqual, mtp.OFC_Undefined)
+	})
+
+	Convey("Test findDuplicateBook", t, func() {
+		So(bookTitle("The_Hobbit.epub"), ShouldEqual, "thehobbit")
+		So(bookTitle("the hobbit.EPUB"), ShouldEqual, "thehobbit")
+
+		children := []*FileInfo{
+			{ObjectId: 1, Name: "the hobbit.EPUB", Size: 100},
+			{ObjectId: 2, Name: "The Hobbit.pdf", Size: 100},
+			{ObjectId: 3, Name: "Dune.epub", Size: 300},
+			{ObjectId: 4, Name: "Dune", IsDir: true},
+		}
+
+		So(findDuplicateBook(children, "The_Hobbit.epub", 100).ObjectId, ShouldEqual, 1)
+		So(findDuplicateBook(children, "The_Hobbit.pdf", 100).ObjectId, ShouldEqual, 2)
+
+		// a different size is a different edition
+		So(findDuplicateBook(children, "Dune.epub", 301), ShouldBeNil)
+		So(findDuplicateBook(children, "Emma.epub", 100), ShouldBeNil)
+	})
+
+	Convey("Test bookDestination", t, func() {
+		defer disposeDeviceProfile(nil)
+
+		So(bookDestination(nil, ""), ShouldEqual, defaultBooksPath)
+		So(bookDestination(nil, "/ebooks/"), ShouldEqual, "/ebooks")
+
+		SetDeviceProfile(nil, matchDeviceProfile(0x1949, 0))
+		So(bookDestination(nil, ""), ShouldEqual, "/documents")
+	})
+}
@@ -0,0 +1,70 @@
+package mtpx
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// what happened to a file during a bulk transfer
+type transferOutcome int
+
+const (
+	transferOutcomeTransferred transferOutcome = iota
+	transferOutcomeSkipped
+	transferOutcomeFailed
+)
+
+func newTransferBreakdown() *TransferBreakdown {
+	return &TransferBreakdown{
+		ByExtension: map[string]*TransferCounts{},
+		ByCategory:  map[FileCategory]*TransferCounts{},
+	}
+}
+
+// account the file against its extension and category
+func (b *TransferBreakdown) record(name string, category FileCategory, size int64, outcome transferOutcome) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))
+
+	for _, counts := range []*TransferCounts{b.extensionCounts(ext), b.categoryCounts(category)} {
+		switch outcome {
+		case transferOutcomeTransferred:
+			counts.Transferred += 1
+			counts.TransferredSize += size
+
+		case transferOutcomeSkipped:
+			counts.Skipped += 1
+			counts.SkippedSize += size
+
+		case transferOutcomeFailed:
+			counts.Failed += 1
+			counts.FailedSize += size
+		}
+	}
+}
+
+func (b *TransferBreakdown) extensionCounts(ext string) *TransferCounts {
+	counts, ok := b.ByExtension[ext]
+	if !ok {
+		counts = &TransferCounts{}
+		b.ByExtension[ext] = counts
+	}
+
+	return counts
+}
+
+func (b *TransferBreakdown) categoryCounts(category FileCategory) *TransferCounts {
+	counts, ok := b.ByCategory[category]
+	if !ok {
+		counts = &TransferCounts{}
+		b.ByCategory[category] = counts
+	}
+
+	return counts
+}
@@ -0,0 +1,30 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestTransferBreakdown(t *testing.T) {
+	Convey("Test TransferBreakdown", t, func() {
+		b := newTransferBreakdown()
+
+		b.record("a.JPG", CategoryImage, 100, transferOutcomeTransferred)
+		b.record("b.jpg", CategoryImage, 50, transferOutcomeTransferred)
+		b.record("c.png", CategoryImage, 10, transferOutcomeFailed)
+		b.record("Thumbs.db", CategoryOther, 5, transferOutcomeSkipped)
+		b.record("README", CategoryOther, 1, transferOutcomeTransferred)
+
+		So(*b.ByExtension["jpg"], ShouldResemble, TransferCounts{Transferred: 2, TransferredSize: 150})
+		So(*b.ByExtension["png"], ShouldResemble, TransferCounts{Failed: 1, FailedSize: 10})
+		So(*b.ByExtension["db"], ShouldResemble, TransferCounts{Skipped: 1, SkippedSize: 5})
+		So(b.ByExtension[""].Transferred, ShouldEqual, 1)
+
+		So(*b.ByCategory[CategoryImage], ShouldResemble, TransferCounts{Transferred: 2, TransferredSize: 150, Failed: 1, FailedSize: 10})
+		So(*b.ByCategory[CategoryOther], ShouldResemble, TransferCounts{Transferred: 1, TransferredSize: 1, Skipped: 1, SkippedSize: 5})
+
+		// a transfer without a breakdown is left alone
+		var none *TransferBreakdown
+		So(func() { none.record("a.jpg", CategoryImage, 1, transferOutcomeTransferred) }, ShouldNotPanic)
+	})
+}
@@ -0,0 +1,140 @@
+package mtpx
+
+import (
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var transferChunkSize int64 = defaultTransferChunkSize
+
+// reusable buffers for the partial transfers, so that the large transfers don't churn the GC
+var transferBufferPool = sync.Pool{}
+
+// number of bytes requested from the device in a single partial transfer
+func TransferChunkSize() int64 {
+	return atomic.LoadInt64(&transferChunkSize)
+}
+
+// set the number of bytes requested from the device in a single partial transfer
+// larger chunks are faster on the USB3 devices, smaller chunks recover quicker from the flaky connections
+// the transfers start with this size and adapt it to the observed throughput of the device
+// the value is clamped between [minTransferChunkSize] and [maxTransferChunkSize]. if the value is 0 then [defaultTransferChunkSize] is used
+func SetTransferChunkSize(size int64) {
+	if size == 0 {
+		size = defaultTransferChunkSize
+	}
+
+	atomic.StoreInt64(&transferChunkSize, clampChunkSize(size))
+}
+
+// fetch a buffer of [size] bytes from the pool
+// return the buffer using [putTransferBuffer] once done
+func getTransferBuffer(size int64) *[]byte {
+	if v := transferBufferPool.Get(); v != nil {
+		buf := v.(*[]byte)
+
+		if int64(cap(*buf)) >= size {
+			*buf = (*buf)[:size]
+
+			return buf
+		}
+	}
+
+	buf := make([]byte, size)
+
+	return &buf
+}
+
+func putTransferBuffer(buf *[]byte) {
+	transferBufferPool.Put(buf)
+}
+
+// chunk sizes learned for the devices, the next transfer starts where the previous one left off
+var deviceChunkSizes = struct {
+	sync.Mutex
+	m map[*mtp.Device]int64
+}{m: map[*mtp.Device]int64{}}
+
+func getChunkSizer(dev *mtp.Device) *chunkSizer {
+	deviceChunkSizes.Lock()
+	defer deviceChunkSizes.Unlock()
+
+	size, ok := deviceChunkSizes.m[dev]
+	if !ok {
+		size = TransferChunkSize()
+	}
+
+	return &chunkSizer{size: size}
+}
+
+func putChunkSizer(dev *mtp.Device, sizer *chunkSizer) {
+	deviceChunkSizes.Lock()
+	defer deviceChunkSizes.Unlock()
+
+	deviceChunkSizes.m[dev] = sizer.size
+}
+
+// forget the chunk size learned for the device
+func resetChunkSizer(dev *mtp.Device) {
+	deviceChunkSizes.Lock()
+	defer deviceChunkSizes.Unlock()
+
+	delete(deviceChunkSizes.m, dev)
+}
+
+// grow the chunk size while the throughput keeps up and shrink it when the throughput drops
+// short chunks (the tail of a transfer) are too noisy to judge and are ignored
+func (s *chunkSizer) recordSuccess(n int64, elapsed time.Duration) {
+	s.failures = 0
+
+	if n < s.size || elapsed <= 0 {
+		return
+	}
+
+	rate := float64(n) / elapsed.Seconds()
+
+	switch {
+	case s.lastRate == 0 || rate >= s.lastRate*0.9:
+		s.size = clampChunkSize(s.size * 2)
+
+	case rate < s.lastRate*0.5:
+		s.size = clampChunkSize(s.size / 2)
+	}
+
+	s.lastRate = rate
+}
+
+// shrink the chunk size after a failed chunk
+// returns false if the transfer should be aborted
+func (s *chunkSizer) recordFailure() bool {
+	s.failures += 1
+	s.size = clampChunkSize(s.size / 2)
+	s.lastRate = 0
+
+	return s.failures <= maxChunkRetries
+}
+
+func clampChunkSize(size int64) int64 {
+	switch {
+	case size < minTransferChunkSize:
+		return minTransferChunkSize
+
+	case size > maxTransferChunkSize:
+		return maxTransferChunkSize
+	}
+
+	return size
+}
+
+func (bw *bufferWriter) Write(p []byte) (int, error) {
+	if len(p) > len(bw.buf)-bw.n {
+		return 0, io.ErrShortBuffer
+	}
+
+	bw.n += copy(bw.buf[bw.n:], p)
+
+	return len(p), nil
+}
@@ -0,0 +1,99 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestTransferBuffers(t *testing.T) {
+	Convey("Test SetTransferChunkSize", t, func() {
+		defer SetTransferChunkSize(0)
+
+		SetTransferChunkSize(4 * 1024 * 1024)
+		So(TransferChunkSize(), ShouldEqual, 4*1024*1024)
+
+		SetTransferChunkSize(1)
+		So(TransferChunkSize(), ShouldEqual, minTransferChunkSize)
+
+		SetTransferChunkSize(1024 * 1024 * 1024)
+		So(TransferChunkSize(), ShouldEqual, maxTransferChunkSize)
+
+		SetTransferChunkSize(0)
+		So(TransferChunkSize(), ShouldEqual, defaultTransferChunkSize)
+	})
+
+	Convey("Test getTransferBuffer | bufferWriter", t, func() {
+		buf := getTransferBuffer(8)
+		So(len(*buf), ShouldEqual, 8)
+
+		bw := &bufferWriter{buf: *buf}
+		n, err := bw.Write([]byte("abcd"))
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 4)
+
+		_, err = bw.Write([]byte("efghi"))
+		So(err, ShouldNotBeNil)
+		So(string(bw.buf[:bw.n]), ShouldEqual, "abcd")
+
+		putTransferBuffer(buf)
+
+		// a smaller request reuses a larger pooled buffer
+		buf = getTransferBuffer(4)
+		So(len(*buf), ShouldEqual, 4)
+		putTransferBuffer(buf)
+	})
+}
+
+func BenchmarkTransferBufferPool(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		buf := getTransferBuffer(defaultTransferChunkSize)
+		(*buf)[0] = byte(i)
+		putTransferBuffer(buf)
+	}
+}
+
+func BenchmarkTransferBufferAlloc(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		buf := make([]byte, defaultTransferChunkSize)
+		buf[0] = byte(i)
+	}
+}
+
+func TestChunkSizer(t *testing.T) {
+	Convey("Test chunkSizer", t, func() {
+		s := &chunkSizer{size: defaultTransferChunkSize}
+
+		// grows while the throughput keeps up
+		s.recordSuccess(defaultTransferChunkSize, 100*time.Millisecond)
+		So(s.size, ShouldEqual, 2*defaultTransferChunkSize)
+
+		s.recordSuccess(2*defaultTransferChunkSize, 200*time.Millisecond)
+		So(s.size, ShouldEqual, 4*defaultTransferChunkSize)
+
+		// shrinks when the throughput drops
+		s.recordSuccess(4*defaultTransferChunkSize, 2*time.Second)
+		So(s.size, ShouldEqual, 2*defaultTransferChunkSize)
+
+		// short chunks are ignored
+		s.recordSuccess(10, time.Second)
+		So(s.size, ShouldEqual, 2*defaultTransferChunkSize)
+
+		// shrinks on failures and gives up after [maxChunkRetries]
+		for i := 0; i < maxChunkRetries; i++ {
+			So(s.recordFailure(), ShouldBeTrue)
+		}
+		So(s.recordFailure(), ShouldBeFalse)
+		So(s.size, ShouldEqual, 128*1024)
+
+		// never drops below the minimum
+		for i := 0; i < 10; i++ {
+			s.recordFailure()
+		}
+		So(s.size, ShouldEqual, minTransferChunkSize)
+	})
+}
@@ -0,0 +1,106 @@
+package mtpx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/ganeshrvel/go-mtpx/devicepath"
+	"io"
+	"time"
+)
+
+// Download a file directly into the memory
+// meant for the small files (eg: config files, text files) which only need to be inspected
+// [maxBytes]: a [TooLargeError] is returned if the file is larger than [maxBytes]
+func ReadFileToBytes(dev *mtp.Device, objectId uint32, maxBytes int64) ([]byte, error) {
+	fi, err := GetObjectFromObjectId(dev, objectId, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if fi.IsDir {
+		return nil, InvalidPathError{error: fmt.Errorf("cannot read a directory: %d", objectId)}
+	}
+
+	if fi.Size > maxBytes {
+		return nil, TooLargeError{error: fmt.Errorf("the file is larger than %d bytes: %d", maxBytes, fi.Size)}
+	}
+
+	// the device could still send more than the reported size, hence the cap on the buffer
+	buf := &bufferWriter{buf: make([]byte, fi.Size)}
+	startTime := time.Now()
+
+	err = withTransferTimeout(dev, func() error {
+		return dev.GetObject(objectId, buf, mtp.EmptyProgressFunc)
+	})
+	if err != nil {
+		recordTransferError(dev)
+
+		if errors.Is(err, io.ErrShortBuffer) {
+			return nil, TooLargeError{error: fmt.Errorf("the device sent more than the reported size of the file: %d", fi.Size)}
+		}
+
+		return nil, FileTransferError{error: err}
+	}
+
+	recordTransferredBytes(dev, Download, int64(buf.n), time.Since(startTime))
+	recordTransferredFile(dev, Download)
+
+	return buf.buf[:buf.n], nil
+}
+
+// Upload a byte slice as a file
+// meant for the small generated files (eg: playlists, .nomedia markers, config files)
+// the parent directories are created if they do not exist and an existing file at [destPath] is overwritten
+// [modTime]: modification date of the new file
+// returns the objectId of the new file
+func WriteFileFromBytes(dev *mtp.Device, storageId uint32, destPath string, data []byte, modTime time.Time) (objectId uint32, err error) {
+	op := &OperationInfo{Type: WriteFileOp, Mutating: true, StorageId: storageId, FileProps: []FileProp{{0, destPath}}, Size: int64(len(data))}
+
+	err = runMiddlewares(dev, op, func() error {
+		objectId, err = writeFileFromBytes(dev, storageId, destPath, data, modTime)
+
+		return err
+	})
+
+	return objectId, err
+}
+
+// helper function for [WriteFileFromBytes]
+func writeFileFromBytes(dev *mtp.Device, storageId uint32, destPath string, data []byte, modTime time.Time) (objectId uint32, err error) {
+	if err := checkStorageWritable(dev, storageId, false); err != nil {
+		return 0, err
+	}
+
+	_destPath := devicepath.Clean(destPath)
+	parentPath, filename := devicepath.Split(_destPath)
+
+	if _destPath == devicepath.Separator {
+		return 0, InvalidPathError{error: fmt.Errorf("invalid file path: %s", destPath)}
+	}
+
+	parentId, err := makeNestedDirectory(dev, storageId, parentPath)
+	if err != nil {
+		return 0, err
+	}
+
+	size := int64(len(data))
+
+	startTime := time.Now()
+
+	objectId, err = sendObject(dev, storageId, parentId, filename, mtp.OFC_Undefined, modTime, bytes.NewReader(data), size, true,
+		func(total, sent int64, objectId uint32, err error) error {
+			return err
+		})
+	if err != nil {
+		recordTransferError(dev)
+
+		return objectId, err
+	}
+
+	recordTransferredBytes(dev, Upload, size, time.Since(startTime))
+	recordTransferredFile(dev, Upload)
+
+	return objectId, nil
+}
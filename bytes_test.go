@@ -0,0 +1,66 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"log"
+	"testing"
+	"time"
+)
+
+func TestReadFileToBytes(t *testing.T) {
+	dev, err := Initialize(Init{})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	storages, err := FetchStorages(dev)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	sid := storages[0].Sid
+
+	Convey("Read a small file | ReadFileToBytes", t, func() {
+		fi, err := GetObjectFromPath(dev, sid, "/mtp-test-files/mock_dir1/a.txt")
+		So(err, ShouldBeNil)
+
+		expected, err := ioutil.ReadFile(getTestMocksAsset("mock_dir1/a.txt"))
+		So(err, ShouldBeNil)
+
+		data, err := ReadFileToBytes(dev, fi.ObjectId, 1024*1024)
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, string(expected))
+	})
+
+	Convey("Read a file above the cap | ReadFileToBytes | Should throw an error", t, func() {
+		fi, err := GetObjectFromPath(dev, sid, "/mtp-test-files/4mb_txt_file")
+		So(err, ShouldBeNil)
+
+		data, err := ReadFileToBytes(dev, fi.ObjectId, 1024)
+		So(err, ShouldHaveSameTypeAs, TooLargeError{})
+		So(data, ShouldBeNil)
+	})
+
+	Convey("Write a file and read it back | WriteFileFromBytes", t, func() {
+		// test the file '/mtp-test-files/temp_dir/test-WriteFileFromBytes/playlist.m3u'
+		modTime := time.Date(2021, 1, 3, 10, 0, 0, 0, time.UTC)
+		objectId, err := WriteFileFromBytes(dev, sid, "/mtp-test-files/temp_dir/test-WriteFileFromBytes/playlist.m3u", []byte("#EXTM3U\n"), modTime)
+		So(err, ShouldBeNil)
+		So(objectId, ShouldBeGreaterThan, 0)
+
+		data, err := ReadFileToBytes(dev, objectId, 1024)
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, "#EXTM3U\n")
+
+		// overwrite the existing file
+		objectId, err = WriteFileFromBytes(dev, sid, "/mtp-test-files/temp_dir/test-WriteFileFromBytes/playlist.m3u", []byte{}, modTime)
+		So(err, ShouldBeNil)
+
+		fi, err := GetObjectFromObjectId(dev, objectId, "")
+		So(err, ShouldBeNil)
+		So(fi.Size, ShouldEqual, 0)
+	})
+
+	Dispose(dev)
+}
@@ -0,0 +1,518 @@
+package mtpx
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/ganeshrvel/go-mtpx/devicepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// List and cache the subtrees of [paths] so that the later lookups and walks are served from the memory
+// The metadata cache should be enabled for the device (see [Init.EnableCache])
+// [depth]: number of nested levels to cache below each path. 0 caches only the listing of the path itself, a negative value caches the whole subtree
+// paths which do not exist on the device are skipped
+// the device is busy until the prefetch is complete, so call this right after connecting and before handing the device over to the UI
+// eg: Prefetch(dev, sid, DefaultPrefetchPaths, 1)
+// return:
+// [totalCached]: total number of directory listings cached
+func Prefetch(dev *mtp.Device, storageId uint32, paths []string, depth int) (totalCached int64, err error) {
+	if getDeviceCache(dev) == nil {
+		return 0, CacheDisabledError{error: fmt.Errorf("metadata cache is not enabled for the device")}
+	}
+
+	for _, p := range paths {
+		fi, err := GetObjectFromPath(dev, storageId, p)
+		if err != nil {
+			switch err.(type) {
+			case InvalidPathError:
+				continue
+
+			default:
+				return totalCached, err
+			}
+		}
+
+		if !fi.IsDir {
+			continue
+		}
+
+		_totalCached, err := prefetchDirectory(dev, storageId, fi.ObjectId, fi.FullPath, depth)
+		totalCached += _totalCached
+
+		if err != nil {
+			return totalCached, err
+		}
+	}
+
+	return totalCached, nil
+}
+
+// helper function to cache the listing of a directory and its subdirectories up to [depth] levels
+func prefetchDirectory(dev *mtp.Device, storageId, objectId uint32, fullPath string, depth int) (totalCached int64, err error) {
+	children, err := listDirectory(dev, storageId, objectId, fullPath)
+	if err != nil {
+		return 0, err
+	}
+
+	totalCached = 1
+
+	if depth == 0 {
+		return totalCached, nil
+	}
+
+	for _, fi := range children {
+		if !fi.IsDir {
+			continue
+		}
+
+		_totalCached, err := prefetchDirectory(dev, storageId, fi.ObjectId, fi.FullPath, depth-1)
+		totalCached += _totalCached
+
+		if err != nil {
+			return totalCached, err
+		}
+	}
+
+	return totalCached, nil
+}
+
+var deviceCaches = struct {
+	sync.Mutex
+	m map[*mtp.Device]*objectCache
+}{m: map[*mtp.Device]*objectCache{}}
+
+// fetch the statistics of the metadata cache
+func FetchCacheStats(dev *mtp.Device) (*CacheStats, error) {
+	c := getDeviceCache(dev)
+	if c == nil {
+		return nil, CacheDisabledError{error: fmt.Errorf("metadata cache is not enabled for the device")}
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	stats := c.stats
+	stats.Entries = len(c.listings)
+
+	return &stats, nil
+}
+
+// Returns a copy of [ctx] under which the metadata cache is not read
+// pass it to the context variants of the operations (eg: [GetObjectFromPathContext], [WalkContext]) which need guaranteed
+// fresh data (eg: before a destructive sync). every listing is fetched from the device and written back to the cache
+// the operations which are not run under the returned context keep using the cache
+//
+//	fi, err := GetObjectFromPathContext(WithoutCache(context.Background()), dev, sid, "/DCIM/photo.jpg")
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheContextKey{}, true)
+}
+
+// returns true if an operation running on the device was started under [WithoutCache], see [runWithContext]
+func cacheBypassed(dev *mtp.Device) bool {
+	deviceContexts.Lock()
+	defer deviceContexts.Unlock()
+
+	for _, oc := range deviceContexts.m[dev] {
+		if oc.noCache {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Compare the cached objects against the live objects on the device and report the drift
+// [sampleSize]: maximum number of objects to compare, the most recently used listings are checked first. if the value is 0 then every cached object is compared
+// the listings which hold a drifted object are invalidated
+func VerifyCache(dev *mtp.Device, sampleSize int) (report *CacheDriftReport, err error) {
+	c := getDeviceCache(dev)
+	if c == nil {
+		return nil, CacheDisabledError{error: fmt.Errorf("metadata cache is not enabled for the device")}
+	}
+
+	report = &CacheDriftReport{}
+
+	op := &OperationInfo{Type: VerifyCacheOp}
+
+	err = runMiddlewares(dev, op, func() error {
+		for _, cached := range c.sample(sampleSize) {
+			if err := touchOperation(dev); err != nil {
+				return err
+			}
+
+			report.Checked += 1
+
+			live, err := GetObjectFromObjectId(dev, cached.fileInfo.ObjectId, cached.fileInfo.ParentPath)
+			if err != nil {
+				if !isInvalidObjectHandleError(err) {
+					return err
+				}
+
+				live = nil
+			}
+
+			reason := cacheDriftReason(&cached.fileInfo, live)
+			if reason == "" {
+				continue
+			}
+
+			report.Drifts = append(report.Drifts, CacheDrift{
+				StorageId: cached.key.storageId,
+				ObjectId:  cached.fileInfo.ObjectId,
+				Reason:    reason,
+				Cached:    &cached.fileInfo,
+				Live:      live,
+			})
+
+			c.invalidateListing(cached.key.storageId, cached.key.parentId)
+		}
+
+		return nil
+	})
+
+	return report, err
+}
+
+// Invalidate the cached listings affected by the [events]
+// use this to keep the metadata cache consistent when the changes are reported by a source other than this package (eg: the phone itself)
+// [Watch] does this automatically
+func InvalidateCacheForEvents(dev *mtp.Device, storageId uint32, events []WatchEvent) {
+	if c := getDeviceCache(dev); c != nil {
+		c.invalidateEvents(storageId, events, nil)
+	}
+}
+
+// Enable the metadata cache for a device which is already initialized, see [Init.EnableCache]
+// the listings of the directories are kept in memory and reused by the lookups, the walks and the path resolution
+// of all the later calls, until they are invalidated by the changes made through this package
+// the cache is left as is if it is enabled already
+func EnableCache(dev *mtp.Device, config CacheConfig) {
+	enableDeviceCache(dev, config)
+}
+
+// Disable the metadata cache of the device and drop the cached listings
+func DisableCache(dev *mtp.Device) {
+	disableDeviceCache(dev)
+}
+
+// enable the metadata cache for the device
+func enableDeviceCache(dev *mtp.Device, config CacheConfig) {
+	deviceCaches.Lock()
+	defer deviceCaches.Unlock()
+
+	if _, ok := deviceCaches.m[dev]; ok {
+		return
+	}
+
+	deviceCaches.m[dev] = newObjectCache(config)
+}
+
+// drop the metadata cache of the device
+func disableDeviceCache(dev *mtp.Device) {
+	deviceCaches.Lock()
+	defer deviceCaches.Unlock()
+
+	delete(deviceCaches.m, dev)
+}
+
+// returns the metadata cache of the device
+// returns nil if the cache is not enabled for the device
+func getDeviceCache(dev *mtp.Device) *objectCache {
+	deviceCaches.Lock()
+	defer deviceCaches.Unlock()
+
+	return deviceCaches.m[dev]
+}
+
+func newObjectCache(config CacheConfig) *objectCache {
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = defaultCacheMaxEntries
+	}
+
+	return &objectCache{
+		config:   config,
+		listings: map[objectCacheKey]*list.Element{},
+		lru:      list.New(),
+	}
+}
+
+// fetch the cached children of [parentId]
+// [parentPath] is used to build the [FullPath] of the returned objects
+func (c *objectCache) getListing(storageId, parentId uint32, parentPath string) ([]*FileInfo, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	entry, ok := c.lookup(storageId, parentId)
+	if !ok {
+		c.stats.Misses += 1
+
+		return nil, false
+	}
+
+	c.stats.Hits += 1
+
+	_parentPath := devicepath.Clean(parentPath)
+
+	// hand out copies so that the callers can't alter the cached entries
+	var children []*FileInfo
+	for _, fi := range entry.listing {
+		children = append(children, copyCachedObject(fi, _parentPath))
+	}
+
+	return children, true
+}
+
+// fetch the cached children of [parentId] which match [filename], see [matchingObjects]
+// only the objects sharing the [filenameKey] of [filename] are looked at, the rest of the listing isn't touched
+// the misses are left to be counted by [getListing], which the callers fall back to
+func (c *objectCache) getMatches(storageId, parentId uint32, filename string, mode PathMatchMode) ([]*FileInfo, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	entry, ok := c.lookup(storageId, parentId)
+	if !ok {
+		return nil, false
+	}
+
+	c.stats.Hits += 1
+
+	// the path of the parent isn't known here, see [GetObjectFromParentIdAndFilename]
+	parentPath := devicepath.Clean("")
+
+	var exact, loose []*FileInfo
+	for _, i := range entry.names[filenameKey(filename)] {
+		fi := entry.listing[i]
+
+		switch matchFilename(fi.Name, filename, mode) {
+		case exactFilenameMatch:
+			exact = append(exact, copyCachedObject(fi, parentPath))
+
+		case looseFilenameMatch:
+			loose = append(loose, copyCachedObject(fi, parentPath))
+		}
+	}
+
+	if len(exact) > 0 {
+		return exact, true
+	}
+
+	return loose, true
+}
+
+// fetch the cached listing of [parentId] and mark it as the most recently used
+// the expired listings are removed and treated as missing
+// the caller should hold the lock
+func (c *objectCache) lookup(storageId, parentId uint32) (*objectCacheEntry, bool) {
+	el, ok := c.listings[objectCacheKey{storageId, normalizeParentId(parentId)}]
+	if !ok {
+		return nil, false
+	}
+
+	entry := el.Value.(*objectCacheEntry)
+
+	if c.config.TTL > 0 && time.Since(entry.storedAt) > c.config.TTL {
+		c.removeElement(el)
+		c.stats.Evictions += 1
+
+		return nil, false
+	}
+
+	c.lru.MoveToFront(el)
+
+	return entry, true
+}
+
+func (c *objectCache) setListing(storageId, parentId uint32, children []*FileInfo) {
+	c.Lock()
+	defer c.Unlock()
+
+	key := objectCacheKey{storageId, normalizeParentId(parentId)}
+
+	entry := &objectCacheEntry{
+		key:      key,
+		listing:  make([]FileInfo, 0, len(children)),
+		storedAt: time.Now(),
+		names:    make(map[string][]int, len(children)),
+	}
+	for i, fi := range children {
+		entry.listing = append(entry.listing, *fi)
+		entry.size += fileInfoMemoryUsage(fi)
+
+		nameKey := filenameKey(fi.Name)
+		entry.names[nameKey] = append(entry.names[nameKey], i)
+	}
+
+	if el, ok := c.listings[key]; ok {
+		c.removeElement(el)
+	}
+
+	c.listings[key] = c.lru.PushFront(entry)
+	c.stats.Objects += len(entry.listing)
+	c.stats.MemoryUsage += entry.size
+
+	// evict the least recently used listings
+	for c.lru.Len() > c.config.MaxEntries {
+		c.removeElement(c.lru.Back())
+		c.stats.Evictions += 1
+	}
+}
+
+// remove the cached children of [parentId]
+func (c *objectCache) invalidateListing(storageId, parentId uint32) {
+	c.Lock()
+	defer c.Unlock()
+
+	if el, ok := c.listings[objectCacheKey{storageId, normalizeParentId(parentId)}]; ok {
+		c.removeElement(el)
+	}
+}
+
+// remove all the cached listings of the storage
+func (c *objectCache) invalidateStorage(storageId uint32) {
+	c.Lock()
+	defer c.Unlock()
+
+	for k, el := range c.listings {
+		if k.storageId == storageId {
+			c.removeElement(el)
+		}
+	}
+}
+
+// remove the listings which were stored before [before]
+// returns the number of listings removed
+func (c *objectCache) pruneBefore(before time.Time) int64 {
+	c.Lock()
+	defer c.Unlock()
+
+	var removed int64
+	for _, el := range c.listings {
+		if el.Value.(*objectCacheEntry).storedAt.Before(before) {
+			c.removeElement(el)
+			c.stats.Evictions += 1
+			removed += 1
+		}
+	}
+
+	return removed
+}
+
+// pick up to [sampleSize] cached objects, the most recently used listings first
+// if [sampleSize] is 0 then every cached object is returned
+func (c *objectCache) sample(sampleSize int) []cachedFileInfo {
+	c.Lock()
+	defer c.Unlock()
+
+	var result []cachedFileInfo
+	for el := c.lru.Front(); el != nil; el = el.Next() {
+		entry := el.Value.(*objectCacheEntry)
+
+		for _, fi := range entry.listing {
+			if sampleSize > 0 && len(result) >= sampleSize {
+				return result
+			}
+
+			result = append(result, cachedFileInfo{key: entry.key, fileInfo: fi})
+		}
+	}
+
+	return result
+}
+
+// remove the cached listings affected by the [events]
+// [prev] holds the previous state of the changed objects, if available, to catch the objects moved out of a directory
+func (c *objectCache) invalidateEvents(storageId uint32, events []WatchEvent, prev watchSnapshot) {
+	for _, e := range events {
+		if e.FileInfo != nil {
+			// a removed directory takes its whole subtree along, like [deleteFile] the storage is invalidated
+			// as the listings of the nested directories can't be told apart
+			if e.Type == ObjectRemoved && e.FileInfo.IsDir {
+				c.invalidateStorage(storageId)
+
+				return
+			}
+
+			// the listing of the parent directory holds the object
+			c.invalidateListing(storageId, e.FileInfo.ParentId)
+		}
+
+		if prevFi, ok := prev[e.ObjectId]; ok {
+			c.invalidateListing(storageId, prevFi.ParentId)
+		}
+	}
+}
+
+// the caller should hold the lock
+func (c *objectCache) removeElement(el *list.Element) {
+	entry := el.Value.(*objectCacheEntry)
+
+	c.lru.Remove(el)
+	delete(c.listings, entry.key)
+
+	c.stats.Objects -= len(entry.listing)
+	c.stats.MemoryUsage -= entry.size
+}
+
+// invalidate the cached listing of [parentId] if the metadata cache is enabled for the device
+func invalidateCachedListing(dev *mtp.Device, storageId, parentId uint32) {
+	if c := getDeviceCache(dev); c != nil {
+		c.invalidateListing(storageId, parentId)
+	}
+}
+
+// invalidate all the cached listings of the storage if the metadata cache is enabled for the device
+func invalidateCachedStorage(dev *mtp.Device, storageId uint32) {
+	if c := getDeviceCache(dev); c != nil {
+		c.invalidateStorage(storageId)
+	}
+}
+
+// copy of the cached object [fi] placed inside [parentPath]
+func copyCachedObject(fi FileInfo, parentPath string) *FileInfo {
+	fi.ParentPath = parentPath
+	fi.FullPath = devicepath.Join(parentPath, fi.Name)
+
+	return &fi
+}
+
+// key of the name index of the cached listings
+// the names which may match each other in any [PathMatchMode] share the key
+func filenameKey(name string) string {
+	return strings.ToLower(normalizeFilename(name))
+}
+
+// objects in the root directory report 0 as their parent
+func normalizeParentId(parentId uint32) uint32 {
+	if parentId == 0 {
+		return ParentObjectId
+	}
+
+	return parentId
+}
+
+// describe the difference between the [cached] and the [live] object
+// returns an empty string if they match
+func cacheDriftReason(cached, live *FileInfo) string {
+	switch {
+	case live == nil:
+		return "object no longer exists"
+
+	case cached.Name != live.Name:
+		return fmt.Sprintf("name changed from %s to %s", cached.Name, live.Name)
+
+	case cached.ParentId != live.ParentId:
+		return "object was moved"
+
+	case cached.Size != live.Size:
+		return fmt.Sprintf("size changed from %d to %d", cached.Size, live.Size)
+
+	case !cached.ModTime.Equal(live.ModTime):
+		return "modification date changed"
+	}
+
+	return ""
+}
@@ -0,0 +1,310 @@
+package mtpx
+
+import (
+	"context"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	. "github.com/smartystreets/goconvey/convey"
+	"log"
+	"testing"
+	"time"
+)
+
+func TestPrefetch(t *testing.T) {
+	dev, err := Initialize(Init{EnableCache: true})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	storages, err := FetchStorages(dev)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	sid := storages[0].Sid
+
+	Convey("Prefetch a directory | Prefetch", t, func() {
+		// test the directory '/mtp-test-files/mock_dir1'
+		totalCached, err := Prefetch(dev, sid, []string{"/mtp-test-files/mock_dir1", "/mtp-test-files/fake-dir"}, -1)
+
+		So(err, ShouldBeNil)
+		// mock_dir1, mock_dir1/1, mock_dir1/2, mock_dir1/3, mock_dir1/3/2
+		So(totalCached, ShouldEqual, 5)
+
+		stats, err := FetchCacheStats(dev)
+		So(err, ShouldBeNil)
+		So(stats.Entries, ShouldBeGreaterThanOrEqualTo, 5)
+
+		fi, err := GetObjectFromPath(dev, sid, "/mtp-test-files/mock_dir1/3/2/b.txt")
+		So(err, ShouldBeNil)
+		So(fi.Name, ShouldEqual, "b.txt")
+		So(fi.FullPath, ShouldEqual, "/mtp-test-files/mock_dir1/3/2/b.txt")
+
+		report, err := VerifyCache(dev, 0)
+		So(err, ShouldBeNil)
+		So(report.Checked, ShouldBeGreaterThan, 0)
+		So(len(report.Drifts), ShouldEqual, 0)
+	})
+
+	Convey("Bypass the cache | WithoutCache", t, func() {
+		hits := func() int64 {
+			stats, err := FetchCacheStats(dev)
+			So(err, ShouldBeNil)
+
+			return stats.Hits
+		}
+
+		before := hits()
+
+		fi, err := GetObjectFromPathContext(WithoutCache(context.Background()), dev, sid, "/mtp-test-files/mock_dir1/a.txt")
+		So(err, ShouldBeNil)
+		So(fi.Name, ShouldEqual, "a.txt")
+		So(hits(), ShouldEqual, before)
+	})
+
+	Convey("Cache is invalidated by MakeDirectory | Prefetch", t, func() {
+		// test the directory '/mtp-test-files/temp_dir/test-Prefetch'
+		_, err := Prefetch(dev, sid, []string{"/mtp-test-files/temp_dir"}, 0)
+		So(err, ShouldBeNil)
+
+		objectId, err := MakeDirectory(dev, sid, "/mtp-test-files/temp_dir/test-Prefetch")
+		So(err, ShouldBeNil)
+
+		fi, err := GetObjectFromPath(dev, sid, "/mtp-test-files/temp_dir/test-Prefetch")
+		So(err, ShouldBeNil)
+		So(fi.ObjectId, ShouldEqual, objectId)
+	})
+
+	Dispose(dev)
+
+	Convey("Prefetch without the cache | Prefetch | Should throw an error", t, func() {
+		dev, err := Initialize(Init{})
+		So(err, ShouldBeNil)
+
+		_, err = Prefetch(dev, sid, DefaultPrefetchPaths, 1)
+		So(err, ShouldHaveSameTypeAs, CacheDisabledError{})
+
+		Dispose(dev)
+	})
+}
+
+func TestObjectCache(t *testing.T) {
+	Convey("Test objectCache", t, func() {
+		c := newObjectCache(CacheConfig{})
+
+		c.setListing(1, ParentObjectId, []*FileInfo{
+			{ObjectId: 10, Name: "a.txt", FullPath: "/a.txt"},
+			{ObjectId: 11, Name: "dir", IsDir: true, FullPath: "/dir"},
+		})
+		c.setListing(1, 11, []*FileInfo{{ObjectId: 12, Name: "b.txt"}})
+		c.setListing(2, ParentObjectId, []*FileInfo{{ObjectId: 20, Name: "c.txt"}})
+
+		// root objects report 0 as their parent
+		children, ok := c.getListing(1, 0, "/")
+		So(ok, ShouldBeTrue)
+		So(len(children), ShouldEqual, 2)
+
+		children, ok = c.getListing(1, 11, "/dir")
+		So(ok, ShouldBeTrue)
+		So(children[0].FullPath, ShouldEqual, "/dir/b.txt")
+		So(children[0].ParentPath, ShouldEqual, "/dir")
+
+		// the cached entries can't be altered by the callers
+		children[0].Name = "altered"
+		children, _ = c.getListing(1, 11, "/dir")
+		So(children[0].Name, ShouldEqual, "b.txt")
+
+		c.invalidateListing(1, 11)
+		_, ok = c.getListing(1, 11, "/dir")
+		So(ok, ShouldBeFalse)
+
+		c.invalidateStorage(1)
+		_, ok = c.getListing(1, ParentObjectId, "/")
+		So(ok, ShouldBeFalse)
+
+		_, ok = c.getListing(2, ParentObjectId, "/")
+		So(ok, ShouldBeTrue)
+	})
+}
+
+func TestObjectCacheMatches(t *testing.T) {
+	Convey("Resolve the names using the index | objectCache.getMatches", t, func() {
+		c := newObjectCache(CacheConfig{})
+
+		c.setListing(1, ParentObjectId, []*FileInfo{
+			{ObjectId: 10, Name: "Photo.jpg"},
+			{ObjectId: 11, Name: "photo.jpg"},
+			{ObjectId: 12, Name: "notes. "},
+			{ObjectId: 13, Name: "other.txt"},
+		})
+
+		// the raw matches are preferred over the loose ones
+		matches, ok := c.getMatches(1, 0, "photo.jpg", PathMatchCaseInsensitive)
+		So(ok, ShouldBeTrue)
+		So(len(matches), ShouldEqual, 1)
+		So(matches[0].ObjectId, ShouldEqual, 11)
+
+		matches, _ = c.getMatches(1, ParentObjectId, "PHOTO.JPG", PathMatchCaseInsensitive)
+		So(len(matches), ShouldEqual, 2)
+
+		matches, _ = c.getMatches(1, ParentObjectId, "PHOTO.JPG", PathMatchExact)
+		So(matches, ShouldBeEmpty)
+
+		matches, _ = c.getMatches(1, ParentObjectId, "Notes", PathMatchNormalized)
+		So(len(matches), ShouldEqual, 1)
+		So(matches[0].ObjectId, ShouldEqual, 12)
+
+		matches, _ = c.getMatches(1, ParentObjectId, "Notes", PathMatchCaseInsensitive)
+		So(matches, ShouldBeEmpty)
+
+		// the cached entries can't be altered by the callers
+		matches, _ = c.getMatches(1, ParentObjectId, "other.txt", PathMatchExact)
+		matches[0].Name = "altered"
+		matches, _ = c.getMatches(1, ParentObjectId, "other.txt", PathMatchExact)
+		So(len(matches), ShouldEqual, 1)
+
+		// the missing listings are left to [getListing]
+		_, ok = c.getMatches(1, 13, "a.txt", PathMatchExact)
+		So(ok, ShouldBeFalse)
+		So(c.stats.Misses, ShouldEqual, 0)
+	})
+}
+
+func TestObjectCacheTunables(t *testing.T) {
+	Convey("Test objectCache | LRU eviction | statistics", t, func() {
+		c := newObjectCache(CacheConfig{MaxEntries: 2})
+
+		c.setListing(1, 10, []*FileInfo{{ObjectId: 100, Name: "a.txt"}})
+		c.setListing(1, 11, []*FileInfo{{ObjectId: 110, Name: "b.txt"}, {ObjectId: 111, Name: "c.txt"}})
+
+		// mark 10 as recently used so that 11 gets evicted
+		_, ok := c.getListing(1, 10, "/")
+		So(ok, ShouldBeTrue)
+
+		c.setListing(1, 12, []*FileInfo{{ObjectId: 120, Name: "d.txt"}})
+
+		_, ok = c.getListing(1, 11, "/")
+		So(ok, ShouldBeFalse)
+
+		_, ok = c.getListing(1, 12, "/")
+		So(ok, ShouldBeTrue)
+
+		So(c.stats.Hits, ShouldEqual, 2)
+		So(c.stats.Misses, ShouldEqual, 1)
+		So(c.stats.Evictions, ShouldEqual, 1)
+		So(c.lru.Len(), ShouldEqual, 2)
+		So(c.stats.Objects, ShouldEqual, 2)
+		So(c.stats.MemoryUsage, ShouldBeGreaterThan, 0)
+
+		c.invalidateStorage(1)
+		So(c.stats.Objects, ShouldEqual, 0)
+		So(c.stats.MemoryUsage, ShouldEqual, 0)
+	})
+
+	Convey("Test objectCache | TTL", t, func() {
+		c := newObjectCache(CacheConfig{TTL: time.Millisecond})
+
+		c.setListing(1, 10, []*FileInfo{{ObjectId: 100, Name: "a.txt"}})
+		time.Sleep(5 * time.Millisecond)
+
+		_, ok := c.getListing(1, 10, "/")
+		So(ok, ShouldBeFalse)
+		So(c.stats.Evictions, ShouldEqual, 1)
+		So(c.lru.Len(), ShouldEqual, 0)
+	})
+}
+
+func TestObjectCacheInvalidateEvents(t *testing.T) {
+	Convey("Test objectCache | invalidateEvents", t, func() {
+		c := newObjectCache(CacheConfig{})
+
+		c.setListing(1, ParentObjectId, []*FileInfo{{ObjectId: 10, Name: "dir1", IsDir: true}, {ObjectId: 11, Name: "dir2", IsDir: true}})
+		c.setListing(1, 10, []*FileInfo{{ObjectId: 100, Name: "a.txt", ParentId: 10}})
+		c.setListing(1, 11, []*FileInfo{{ObjectId: 110, Name: "b.txt", ParentId: 11}})
+		c.setListing(1, 12, []*FileInfo{{ObjectId: 120, Name: "c.txt", ParentId: 12}})
+
+		// a.txt was moved from dir1 to dir2
+		prev := watchSnapshot{100: &FileInfo{ObjectId: 100, Name: "a.txt", ParentId: 10}}
+		c.invalidateEvents(1, []WatchEvent{
+			{Type: ObjectChanged, ObjectId: 100, FileInfo: &FileInfo{ObjectId: 100, Name: "a.txt", ParentId: 11}},
+		}, prev)
+
+		_, ok := c.getListing(1, 10, "/dir1")
+		So(ok, ShouldBeFalse)
+		_, ok = c.getListing(1, 11, "/dir2")
+		So(ok, ShouldBeFalse)
+
+		// untouched listings are retained
+		_, ok = c.getListing(1, ParentObjectId, "/")
+		So(ok, ShouldBeTrue)
+		_, ok = c.getListing(1, 12, "/")
+		So(ok, ShouldBeTrue)
+
+		// a removed directory takes its subtree along
+		c.setListing(1, 13, []*FileInfo{{ObjectId: 130, Name: "d", IsDir: true, ParentId: 13}})
+		c.setListing(1, 130, []*FileInfo{{ObjectId: 1300, Name: "e.txt", ParentId: 130}})
+		c.setListing(2, 20, []*FileInfo{{ObjectId: 200, Name: "f.txt", ParentId: 20}})
+
+		c.invalidateEvents(1, []WatchEvent{
+			{Type: ObjectRemoved, ObjectId: 13, FileInfo: &FileInfo{ObjectId: 13, IsDir: true, ParentId: 12}},
+		}, nil)
+
+		_, ok = c.getListing(1, 12, "/")
+		So(ok, ShouldBeFalse)
+		_, ok = c.getListing(1, 13, "/")
+		So(ok, ShouldBeFalse)
+		_, ok = c.getListing(1, 130, "/")
+		So(ok, ShouldBeFalse)
+
+		// the other storages are left alone
+		_, ok = c.getListing(2, 20, "/")
+		So(ok, ShouldBeTrue)
+	})
+}
+
+func TestCacheDriftReason(t *testing.T) {
+	Convey("Test cacheDriftReason", t, func() {
+		modTime := time.Now()
+		cached := &FileInfo{ObjectId: 1, Name: "a.txt", Size: 10, ParentId: 2, ModTime: modTime}
+
+		So(cacheDriftReason(cached, &FileInfo{ObjectId: 1, Name: "a.txt", Size: 10, ParentId: 2, ModTime: modTime}), ShouldEqual, "")
+		So(cacheDriftReason(cached, nil), ShouldEqual, "object no longer exists")
+		So(cacheDriftReason(cached, &FileInfo{ObjectId: 1, Name: "b.txt", Size: 10, ParentId: 2, ModTime: modTime}), ShouldContainSubstring, "name changed")
+		So(cacheDriftReason(cached, &FileInfo{ObjectId: 1, Name: "a.txt", Size: 10, ParentId: 3, ModTime: modTime}), ShouldEqual, "object was moved")
+		So(cacheDriftReason(cached, &FileInfo{ObjectId: 1, Name: "a.txt", Size: 20, ParentId: 2, ModTime: modTime}), ShouldContainSubstring, "size changed")
+	})
+
+	Convey("Test objectCache | sample", t, func() {
+		c := newObjectCache(CacheConfig{})
+
+		c.setListing(1, 10, []*FileInfo{{ObjectId: 100}, {ObjectId: 101}})
+		c.setListing(1, 11, []*FileInfo{{ObjectId: 110}})
+
+		// the most recently used listing comes first
+		samples := c.sample(2)
+		So(len(samples), ShouldEqual, 2)
+		So(samples[0].fileInfo.ObjectId, ShouldEqual, 110)
+		So(samples[1].key.parentId, ShouldEqual, 10)
+
+		So(len(c.sample(0)), ShouldEqual, 3)
+	})
+
+	Convey("Test cacheBypassed | WithoutCache", t, func() {
+		var dev *mtp.Device
+
+		So(cacheBypassed(dev), ShouldBeFalse)
+
+		err := runWithContext(context.Background(), dev, func() error {
+			So(cacheBypassed(dev), ShouldBeFalse)
+
+			return runWithContext(WithoutCache(context.Background()), dev, func() error {
+				So(cacheBypassed(dev), ShouldBeTrue)
+
+				return nil
+			})
+		})
+		So(err, ShouldBeNil)
+
+		// the flag is dropped once the operation is complete
+		So(cacheBypassed(dev), ShouldBeFalse)
+	})
+}
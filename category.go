@@ -0,0 +1,167 @@
+package mtpx
+
+import (
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+var allowedSecondExtensions atomic.Value
+
+// current list of the second extensions which are kept as a part of the extension
+func AllowedSecondExtensions() []string {
+	var exts []string
+	for ext := range getAllowedSecondExtensions() {
+		exts = append(exts, ext)
+	}
+
+	sort.Strings(exts)
+
+	return exts
+}
+
+// set the second extensions which are kept as a part of the [FileInfo.Extension]
+// eg: with "tar" the extension of "a.tar.gz" is "tar.gz" instead of "gz"
+// the default is [defaultAllowedSecondExtensions]
+func SetAllowedSecondExtensions(exts ...string) {
+	m := allowedSecondExtMap{}
+	for _, ext := range exts {
+		m[ext] = ext
+	}
+
+	allowedSecondExtensions.Store(m)
+}
+
+func getAllowedSecondExtensions() allowedSecondExtMap {
+	if m, ok := allowedSecondExtensions.Load().(allowedSecondExtMap); ok {
+		return m
+	}
+
+	m := allowedSecondExtMap{}
+	for _, ext := range defaultAllowedSecondExtensions {
+		m[ext] = ext
+	}
+
+	return m
+}
+
+var formatCategories = map[uint16]FileCategory{
+	mtp.OFC_EXIF_JPEG:              CategoryImage,
+	mtp.OFC_TIFF_EP:                CategoryImage,
+	mtp.OFC_FlashPix:               CategoryImage,
+	mtp.OFC_BMP:                    CategoryImage,
+	mtp.OFC_CIFF:                   CategoryImage,
+	mtp.OFC_GIF:                    CategoryImage,
+	mtp.OFC_JFIF:                   CategoryImage,
+	mtp.OFC_PCD:                    CategoryImage,
+	mtp.OFC_PICT:                   CategoryImage,
+	mtp.OFC_PNG:                    CategoryImage,
+	mtp.OFC_TIFF:                   CategoryImage,
+	mtp.OFC_TIFF_IT:                CategoryImage,
+	mtp.OFC_JP2:                    CategoryImage,
+	mtp.OFC_JPX:                    CategoryImage,
+	mtp.OFC_DNG:                    CategoryImage,
+	mtp.OFC_CANON_CRW:              CategoryImage,
+	mtp.OFC_CANON_CRW3:             CategoryImage,
+	mtp.OFC_MTP_WindowsImageFormat: CategoryImage,
+
+	mtp.OFC_AVI:                CategoryVideo,
+	mtp.OFC_MPEG:               CategoryVideo,
+	mtp.OFC_ASF:                CategoryVideo,
+	mtp.OFC_CANON_MOV:          CategoryVideo,
+	mtp.OFC_MTP_UndefinedVideo: CategoryVideo,
+	mtp.OFC_MTP_WMV:            CategoryVideo,
+	mtp.OFC_MTP_MP4:            CategoryVideo,
+	mtp.OFC_MTP_MP2:            CategoryVideo,
+	mtp.OFC_MTP_3GP:            CategoryVideo,
+
+	mtp.OFC_AIFF:               CategoryAudio,
+	mtp.OFC_WAV:                CategoryAudio,
+	mtp.OFC_MP3:                CategoryAudio,
+	mtp.OFC_MTP_M4A:            CategoryAudio,
+	mtp.OFC_MTP_UndefinedAudio: CategoryAudio,
+	mtp.OFC_MTP_WMA:            CategoryAudio,
+	mtp.OFC_MTP_OGG:            CategoryAudio,
+	mtp.OFC_MTP_AAC:            CategoryAudio,
+	mtp.OFC_MTP_AudibleCodec:   CategoryAudio,
+	mtp.OFC_MTP_FLAC:           CategoryAudio,
+
+	mtp.OFC_Text:                            CategoryDocument,
+	mtp.OFC_HTML:                            CategoryDocument,
+	mtp.OFC_MTP_UndefinedDocument:           CategoryDocument,
+	mtp.OFC_MTP_AbstractDocument:            CategoryDocument,
+	mtp.OFC_MTP_XMLDocument:                 CategoryDocument,
+	mtp.OFC_MTP_MSWordDocument:              CategoryDocument,
+	mtp.OFC_MTP_MSExcelSpreadsheetXLS:       CategoryDocument,
+	mtp.OFC_MTP_MHTCompiledHTMLDocument:     CategoryDocument,
+	mtp.OFC_MTP_MSPowerpointPresentationPPT: CategoryDocument,
+}
+
+var extensionCategories = map[string]FileCategory{
+	"jpg": CategoryImage, "jpeg": CategoryImage, "png": CategoryImage, "gif": CategoryImage, "bmp": CategoryImage,
+	"webp": CategoryImage, "heic": CategoryImage, "heif": CategoryImage, "tif": CategoryImage, "tiff": CategoryImage,
+	"dng": CategoryImage, "raw": CategoryImage, "cr2": CategoryImage, "nef": CategoryImage, "arw": CategoryImage,
+	"svg": CategoryImage,
+
+	"mp4": CategoryVideo, "m4v": CategoryVideo, "mov": CategoryVideo, "avi": CategoryVideo, "mkv": CategoryVideo,
+	"webm": CategoryVideo, "3gp": CategoryVideo, "3g2": CategoryVideo, "wmv": CategoryVideo, "mpg": CategoryVideo,
+	"mpeg": CategoryVideo, "ts": CategoryVideo, "flv": CategoryVideo,
+
+	"mp3": CategoryAudio, "m4a": CategoryAudio, "aac": CategoryAudio, "flac": CategoryAudio, "wav": CategoryAudio,
+	"ogg": CategoryAudio, "oga": CategoryAudio, "opus": CategoryAudio, "wma": CategoryAudio, "amr": CategoryAudio,
+	"aiff": CategoryAudio, "mid": CategoryAudio, "midi": CategoryAudio,
+
+	"pdf": CategoryDocument, "txt": CategoryDocument, "md": CategoryDocument, "rtf": CategoryDocument,
+	"doc": CategoryDocument, "docx": CategoryDocument, "xls": CategoryDocument, "xlsx": CategoryDocument,
+	"ppt": CategoryDocument, "pptx": CategoryDocument, "odt": CategoryDocument, "ods": CategoryDocument,
+	"odp": CategoryDocument, "csv": CategoryDocument, "html": CategoryDocument, "htm": CategoryDocument,
+	"xml": CategoryDocument, "json": CategoryDocument, "epub": CategoryDocument,
+
+	"zip": CategoryArchive, "rar": CategoryArchive, "7z": CategoryArchive, "tar": CategoryArchive,
+	"gz": CategoryArchive, "tgz": CategoryArchive, "bz2": CategoryArchive, "xz": CategoryArchive,
+	"tar.gz": CategoryArchive, "tar.bz2": CategoryArchive, "tar.xz": CategoryArchive,
+
+	"apk": CategoryAPK, "apks": CategoryAPK, "xapk": CategoryAPK, "apkm": CategoryAPK,
+}
+
+// object format of the file [filename] sent to the device, picked by its extension
+// the files with an unknown extension are sent as [mtp.OFC_Undefined]
+func objectFormat(filename string) uint16 {
+	if format, ok := extensionObjectFormats[strings.ToLower(extension(filename, false))]; ok {
+		return format
+	}
+
+	return mtp.OFC_Undefined
+}
+
+// work out the kind of a file
+// the MTP format code reported by the device is preferred; the generic formats (eg: Undefined) fall back to the extension
+// directories have no category
+func fileCategory(objectFormat uint16, filename string, isDir bool) FileCategory {
+	if isDir {
+		return ""
+	}
+
+	if category, ok := formatCategories[objectFormat]; ok {
+		return category
+	}
+
+	ext := strings.ToLower(extension(filename, false))
+	if category, ok := extensionCategories[ext]; ok {
+		return category
+	}
+
+	// look up either part of a double extension, eg: "tar.zst" => "zst", "tar"
+	if i := strings.LastIndex(ext, "."); i > -1 {
+		if category, ok := extensionCategories[ext[i+1:]]; ok {
+			return category
+		}
+
+		if category, ok := extensionCategories[ext[:i]]; ok {
+			return category
+		}
+	}
+
+	return CategoryOther
+}
@@ -0,0 +1,32 @@
+package mtpx
+
+import (
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestFileCategory(t *testing.T) {
+	Convey("Test fileCategory", t, func() {
+		So(fileCategory(mtp.OFC_EXIF_JPEG, "IMG_0001", false), ShouldEqual, CategoryImage)
+		So(fileCategory(mtp.OFC_MTP_MP4, "clip.bin", false), ShouldEqual, CategoryVideo)
+		So(fileCategory(mtp.OFC_Undefined, "song.MP3", false), ShouldEqual, CategoryAudio)
+		So(fileCategory(mtp.OFC_Undefined, "report.pdf", false), ShouldEqual, CategoryDocument)
+		So(fileCategory(mtp.OFC_Undefined, "backup.tar.gz", false), ShouldEqual, CategoryArchive)
+		So(fileCategory(mtp.OFC_Undefined, "app-release.apk", false), ShouldEqual, CategoryAPK)
+		So(fileCategory(mtp.OFC_Undefined, "notes", false), ShouldEqual, CategoryOther)
+		So(fileCategory(mtp.OFC_Association, "DCIM", true), ShouldEqual, "")
+	})
+
+	Convey("Test SetAllowedSecondExtensions", t, func() {
+		So(AllowedSecondExtensions(), ShouldResemble, []string{"tar"})
+		So(extension("a.tar.gz", false), ShouldEqual, "tar.gz")
+
+		SetAllowedSecondExtensions("tar", "min")
+		defer SetAllowedSecondExtensions(defaultAllowedSecondExtensions...)
+
+		So(AllowedSecondExtensions(), ShouldResemble, []string{"min", "tar"})
+		So(extension("app.min.js", false), ShouldEqual, "min.js")
+		So(fileCategory(mtp.OFC_Undefined, "backup.tar.zst", false), ShouldEqual, CategoryArchive)
+	})
+}
@@ -0,0 +1,170 @@
+package mtpx
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Prune the state which this package keeps on the host so that it doesn't grow without a bound over the long-term use
+// the metadata caches of the connected devices drop the listings older than [policy.MaxAge]
+// the content cache drops the files which were not used for [policy.MaxAge] and then the least recently used files of
+// each device until they fit into [policy.MaxSize]
+// the devices which were not seen for [policy.DeviceMaxAge] are dropped from the device store along with their content cache
+// the content caches of the connected devices are never dropped as a whole
+// note: there is no thumbnail cache in this package, the thumbnails are read from the device every time
+func CleanCaches(policy CleanCachesPolicy) (*CleanCachesReport, error) {
+	report := &CleanCachesReport{}
+	now := time.Now()
+
+	if policy.MaxAge > 0 {
+		deviceCaches.Lock()
+		caches := make([]*objectCache, 0, len(deviceCaches.m))
+		for _, c := range deviceCaches.m {
+			caches = append(caches, c)
+		}
+		deviceCaches.Unlock()
+
+		for _, c := range caches {
+			report.RemovedListings += c.pruneBefore(now.Add(-policy.MaxAge))
+		}
+	}
+
+	if policy.DeviceMaxAge > 0 {
+		serials, err := forgetStaleDevices(policy.DeviceStorePath, now.Add(-policy.DeviceMaxAge))
+		if err != nil {
+			return report, err
+		}
+
+		report.RemovedDevices = serials
+	}
+
+	if err := cleanContentCaches(policy, now, report); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// remove the devices which were last connected before [before] from the device store
+// the store is left untouched if nothing is removed
+// returns the serial numbers of the removed devices
+func forgetStaleDevices(storePath string, before time.Time) ([]string, error) {
+	deviceStoreLock.Lock()
+	defer deviceStoreLock.Unlock()
+
+	c, err := readDeviceStore(storePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var serials []string
+	for serial, record := range c.Devices {
+		if record.LastConnected.Before(before) {
+			delete(c.Devices, serial)
+			serials = append(serials, serial)
+		}
+	}
+
+	if len(serials) < 1 {
+		return nil, nil
+	}
+
+	sort.Strings(serials)
+
+	return serials, writeDeviceStore(storePath, c)
+}
+
+// prune the content cache of every device found under the content cache directory, see [CleanCaches]
+func cleanContentCaches(policy CleanCachesPolicy, now time.Time, report *CleanCachesReport) error {
+	root := policy.ContentCacheDirectory
+	if root == "" {
+		path, err := DefaultContentCachePath()
+		if err != nil {
+			return err
+		}
+
+		root = path
+	}
+
+	dirs, err := ioutil.ReadDir(root)
+	if err != nil {
+		// nothing was cached yet
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return LocalFileError{error: err}
+	}
+
+	// the caches of the connected devices are pruned under their lock
+	deviceContentCaches.Lock()
+	active := map[string]*contentCache{}
+	for _, c := range deviceContentCaches.m {
+		active[filepath.Clean(c.dir)] = c
+	}
+	deviceContentCaches.Unlock()
+
+	var before time.Time
+	if policy.MaxAge > 0 {
+		before = now.Add(-policy.MaxAge)
+	}
+
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+
+		dir := filepath.Join(root, d.Name())
+		c := active[filepath.Clean(dir)]
+
+		// the modification time of the directory tells when the device was last connected, see [enableContentCache]
+		if c == nil && policy.DeviceMaxAge > 0 && d.ModTime().Before(now.Add(-policy.DeviceMaxAge)) {
+			entries, err := contentDirEntries(dir)
+			if err != nil {
+				return err
+			}
+
+			if err := os.RemoveAll(dir); err != nil {
+				return LocalFileError{error: err}
+			}
+
+			report.RemovedDeviceCaches += 1
+			report.RemovedFiles += int64(len(entries))
+			for _, e := range entries {
+				report.RemovedBytes += e.Size()
+			}
+
+			continue
+		}
+
+		if before.IsZero() && policy.MaxSize <= 0 {
+			continue
+		}
+
+		if c != nil {
+			c.Lock()
+		}
+
+		removedFiles, removedBytes, err := pruneContentDir(dir, before, policy.MaxSize)
+
+		if c != nil {
+			c.stats.Evictions += removedFiles
+			c.Unlock()
+		}
+
+		if err != nil {
+			return err
+		}
+
+		// removing the files touches the directory, keep the time when the device was last connected
+		_ = os.Chtimes(dir, d.ModTime(), d.ModTime())
+
+		report.RemovedFiles += removedFiles
+		report.RemovedBytes += removedBytes
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCleanCaches(t *testing.T) {
+	Convey("Test CleanCaches", t, func() {
+		baseDir := newTempMocksDir("test_CleanCaches", true)
+		storePath := filepath.Join(baseDir, "devices.json")
+		contentDir := filepath.Join(baseDir, "content")
+
+		past := time.Now().Add(-48 * time.Hour)
+
+		// a device which was not seen for two days and one which is seen now
+		So(SetDeviceAlias(storePath, "OLD", "old"), ShouldBeNil)
+		So(SetDeviceAlias(storePath, "NEW", "new"), ShouldBeNil)
+		So(updateDeviceStore(storePath, func(c *deviceStoreContainer) error {
+			old := c.Devices["OLD"]
+			old.LastConnected = past
+			c.Devices["OLD"] = old
+
+			recent := c.Devices["NEW"]
+			recent.LastConnected = time.Now()
+			c.Devices["NEW"] = recent
+
+			return nil
+		}), ShouldBeNil)
+
+		writeCached := func(dir, name, data string, modTime time.Time) {
+			So(os.MkdirAll(dir, os.ModePerm), ShouldBeNil)
+
+			filename := filepath.Join(dir, name)
+			So(ioutil.WriteFile(filename, []byte(data), newLocalFileMode), ShouldBeNil)
+			So(os.Chtimes(filename, modTime, modTime), ShouldBeNil)
+		}
+
+		oldDevice := filepath.Join(contentDir, "old")
+		writeCached(oldDevice, "a-3-1", "abc", past)
+		So(os.Chtimes(oldDevice, past, past), ShouldBeNil)
+
+		newDevice := filepath.Join(contentDir, "new")
+		writeCached(newDevice, "b-4-1", "abcd", past)
+		writeCached(newDevice, "c-5-1", "abcde", time.Now().Add(-time.Minute))
+		writeCached(newDevice, "d-6-1", "abcdef", time.Now())
+
+		c := newObjectCache(CacheConfig{})
+		c.setListing(1, ParentObjectId, []*FileInfo{{ObjectId: 10, Name: "a.txt"}})
+
+		deviceCaches.Lock()
+		deviceCaches.m[nil] = c
+		deviceCaches.Unlock()
+		defer disableDeviceCache(nil)
+
+		// nothing is dropped by the empty policy
+		report, err := CleanCaches(CleanCachesPolicy{ContentCacheDirectory: contentDir, DeviceStorePath: storePath})
+		So(err, ShouldBeNil)
+		So(report, ShouldResemble, &CleanCachesReport{})
+
+		report, err = CleanCaches(CleanCachesPolicy{
+			MaxAge:                24 * time.Hour,
+			MaxSize:               6,
+			DeviceMaxAge:          24 * time.Hour,
+			ContentCacheDirectory: contentDir,
+			DeviceStorePath:       storePath,
+		})
+		So(err, ShouldBeNil)
+		So(report.RemovedDevices, ShouldResemble, []string{"OLD"})
+		So(report.RemovedDeviceCaches, ShouldEqual, 1)
+		// a-3-1 along with its device, b-4-1 by its age and c-5-1 by the size
+		So(report.RemovedFiles, ShouldEqual, 3)
+		So(report.RemovedBytes, ShouldEqual, 12)
+		So(report.RemovedListings, ShouldEqual, 0)
+
+		So(isDirLocal(oldDevice), ShouldBeFalse)
+
+		entries, err := contentDirEntries(newDevice)
+		So(err, ShouldBeNil)
+		So(entries, ShouldHaveLength, 1)
+		So(entries[0].Name(), ShouldEqual, "d-6-1")
+
+		devices, err := ListKnownDevices(storePath)
+		So(err, ShouldBeNil)
+		So(devices, ShouldHaveLength, 1)
+		So(devices[0].Serial, ShouldEqual, "NEW")
+
+		// the listings older than [MaxAge] are dropped
+		time.Sleep(10 * time.Millisecond)
+		report, err = CleanCaches(CleanCachesPolicy{MaxAge: time.Millisecond, ContentCacheDirectory: contentDir, DeviceStorePath: storePath})
+		So(err, ShouldBeNil)
+		So(report.RemovedListings, ShouldEqual, 1)
+
+		_, ok := c.getListing(1, ParentObjectId, "/")
+		So(ok, ShouldBeFalse)
+	})
+}
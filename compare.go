@@ -0,0 +1,75 @@
+package mtpx
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"io"
+	"os"
+)
+
+// Compare a file on the device with a local file without downloading the whole object
+// both the files are streamed through sha256 chunk by chunk and the comparison stops at the first mismatching chunk
+// the sizes are compared first, so files of different sizes are never read
+// use this in the sync tools to skip the identical files
+// return:
+// [equal]: true if the contents of both the files match
+func CompareFile(dev *mtp.Device, objectId uint32, localPath string) (equal bool, err error) {
+	fi, err := GetObjectFromObjectId(dev, objectId, "")
+	if err != nil {
+		return false, err
+	}
+
+	if fi.IsDir {
+		return false, InvalidPathError{error: fmt.Errorf("cannot compare a directory: %d", objectId)}
+	}
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return false, LocalFileError{error: err}
+	}
+	defer f.Close()
+
+	lInfo, err := f.Stat()
+	if err != nil {
+		return false, LocalFileError{error: err}
+	}
+
+	if lInfo.IsDir() {
+		return false, InvalidPathError{error: fmt.Errorf("cannot compare a directory: %s", localPath)}
+	}
+
+	if lInfo.Size() != fi.Size {
+		return false, nil
+	}
+
+	maxChunkSize := TransferChunkSize()
+
+	remoteBuf := getTransferBuffer(maxChunkSize)
+	defer putTransferBuffer(remoteBuf)
+
+	localBuf := getTransferBuffer(maxChunkSize)
+	defer putTransferBuffer(localBuf)
+
+	for offset := int64(0); offset < fi.Size; offset += maxChunkSize {
+		chunkSize := fi.Size - offset
+		if chunkSize > maxChunkSize {
+			chunkSize = maxChunkSize
+		}
+
+		rw := &bufferWriter{buf: (*remoteBuf)[:chunkSize]}
+		if err := readPartialObject(dev, objectId, rw, offset, chunkSize); err != nil {
+			return false, err
+		}
+
+		if _, err := io.ReadFull(f, (*localBuf)[:chunkSize]); err != nil {
+			return false, LocalFileError{error: err}
+		}
+
+		if sha256.Sum256(rw.buf[:rw.n]) != sha256.Sum256((*localBuf)[:chunkSize]) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,49 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"log"
+	"testing"
+)
+
+func TestCompareFile(t *testing.T) {
+	dev, err := Initialize(Init{})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	storages, err := FetchStorages(dev)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	sid := storages[0].Sid
+
+	Convey("Compare identical files | CompareFile", t, func() {
+		fi, err := GetObjectFromPath(dev, sid, "/mtp-test-files/4mb_txt_file")
+		So(err, ShouldBeNil)
+
+		equal, err := CompareFile(dev, fi.ObjectId, getTestMocksAsset("4mb_txt_file"))
+		So(err, ShouldBeNil)
+		So(equal, ShouldBeTrue)
+	})
+
+	Convey("Compare different files | CompareFile", t, func() {
+		fi, err := GetObjectFromPath(dev, sid, "/mtp-test-files/4mb_txt_file")
+		So(err, ShouldBeNil)
+
+		equal, err := CompareFile(dev, fi.ObjectId, getTestMocksAsset("mock_dir1/a.txt"))
+		So(err, ShouldBeNil)
+		So(equal, ShouldBeFalse)
+	})
+
+	Convey("Compare with a missing local file | CompareFile | Should throw an error", t, func() {
+		fi, err := GetObjectFromPath(dev, sid, "/mtp-test-files/4mb_txt_file")
+		So(err, ShouldBeNil)
+
+		_, err = CompareFile(dev, fi.ObjectId, newTestMocksAsset("fake-file.txt"))
+		So(err, ShouldHaveSameTypeAs, LocalFileError{})
+	})
+
+	Dispose(dev)
+}
@@ -3,6 +3,7 @@ package mtpx
 import (
 	"github.com/ganeshrvel/go-mtpfs/mtp"
 	"os"
+	"time"
 )
 
 const PathSep = string(os.PathSeparator)
@@ -15,6 +16,262 @@ const newLocalDirectoryMode = 0755
 
 const disallowedFileName = ":*?\"<>|"
 
-var disallowedFiles = []string{".DS_Store", "[-----DS_Store.mtp.test----].txt"}
+// see [SetDisallowedFilesPolicy]
+var defaultDisallowedFiles = []string{".DS_Store", "Thumbs.db", "[-----DS_Store.mtp.test----].txt"}
 
-var allowedSecondExtensions allowedSecondExtMap = map[string]string{"tar": "tar"}
+// second extensions which are kept as a part of the extension, eg: "tar.gz". see [SetAllowedSecondExtensions]
+var defaultAllowedSecondExtensions = []string{"tar"}
+
+const deviceStoreDirName = "mtpx"
+
+const deviceStoreFileName = "devices.json"
+
+const newLocalFileMode = 0644
+
+const defaultWatchInterval = 2 * time.Second
+
+// time between two scans of the usb bus, see [WatchDevices]
+const deviceWatchInterval = 1 * time.Second
+
+// time between two snapshots of the storages taken by [SubscribeEvents]
+const eventPollInterval = 2 * time.Second
+
+const defaultCacheMaxEntries = 1024
+
+const contentCacheDirName = "content"
+
+// files which are still being copied into the content cache
+const contentCacheTmpSuffix = ".tmp"
+
+const defaultContentCacheMaxSize = 1024 * 1024 * 1024
+
+// directories which are usually browsed first on a phone
+var DefaultPrefetchPaths = []string{"/DCIM", "/Download"}
+
+const defaultFollowInterval = 1 * time.Second
+
+// default number of bytes requested from the device in a single partial read
+const defaultTransferChunkSize = 1024 * 1024
+
+// the downloaded blocks of this size which are all zeroes are left as holes in the local files
+const sparseBlockSize = 64 * 1024
+
+// number of the blocks (of [TransferChunkSize] bytes each) of a file kept in memory by an [ObjectReader]
+// a few blocks let the players jump between the index and the data of a video without reading them again
+const objectReaderCacheBlocks = 8
+
+// the writes of a [File] are kept in memory up to this size and in a local temp file beyond it, see [SetWriteSpillSize]
+const defaultWriteSpillSize = 16 * 1024 * 1024
+
+// the device-side copies of the files smaller than this finish too quickly to be worth reporting, see [SetDeviceSideProgressCb]
+const deviceSideProgressMinSize = 16 * 1024 * 1024
+
+// how often the estimated progress of a device-side copy is reported
+const deviceSideProgressInterval = 500 * time.Millisecond
+
+// copy rate (bytes/sec) assumed for the first device-side copy, until one has been measured
+const defaultDeviceSideCopyRate = 20 * 1024 * 1024
+
+const minTransferChunkSize = 64 * 1024
+
+const maxTransferChunkSize = 64 * 1024 * 1024
+
+// orphan objects are moved here by [FsckStorage]
+const lostAndFoundPath = "/lost+found"
+
+const deviceBusyRetryInterval = 1 * time.Second
+
+// error messages reported when another application holds the device
+var deviceBusyErrorPatterns = []string{"DeviceBusy", "SessionAlreadyOpened", "OperationNotSupported", "LIBUSB_ERROR_BUSY"}
+
+// error messages reported when a bulk transfer makes no progress, see [Init.TransferStallTimeout]
+var transferStallErrorPatterns = []string{"LIBUSB_ERROR_TIMEOUT", "LIBUSB_ERROR_PIPE", "LIBUSB_ERROR_IO"}
+
+// pause between dropping a stalled session and opening a new one
+const transferStallRecoveryDelay = 1 * time.Second
+
+// number of consecutive failed chunks tolerated before a partial transfer is aborted
+const maxChunkRetries = 3
+
+// number of listings fetched ahead of time by [WalkConcurrently] when none is specified
+const defaultWalkLookahead = 8
+
+// interval between the heartbeats of a running operation, see [HeartbeatConfig]
+const defaultHeartbeatInterval = 5 * time.Second
+
+// size of the temporary object written and read back by [Diagnostics]
+const defaultBenchmarkSize = 8 * 1024 * 1024
+
+// directory of the temp objects on each storage, see [CreateTempObject]
+const tempDirectoryPath = "/.mtpx-tmp"
+
+// the temp objects older than this are taken as left behind by a crashed run and deleted when the device is initialized
+// the younger ones may still be in use by another process
+const staleTempObjectAge = 24 * time.Hour
+
+// speeds reported by libusb (libusb_speed)
+var usbSpeeds = map[int]UsbSpeed{
+	1: UsbSpeedLow,
+	2: UsbSpeedFull,
+	3: UsbSpeedHigh,
+	4: UsbSpeedSuper,
+}
+
+// signalling rates of the usb speeds (in MB/s)
+var usbLinkSpeeds = map[UsbSpeed]float64{
+	UsbSpeedLow:   0.1875,
+	UsbSpeedFull:  1.5,
+	UsbSpeedHigh:  60,
+	UsbSpeedSuper: 625,
+}
+
+// chunks of a file which are read ahead of its hasher, see [HashFiles]
+const hashPipelineDepth = 4
+
+// time given to the cancelled operations to return before [Shutdown] closes the device anyway
+const shutdownCancelTimeout = 5 * time.Second
+
+// decoded payloads longer than this are truncated in the transcripts, see [StartTranscript]
+const transcriptPayloadLimit = 160
+
+// extensions of the camera RAW files, see [CopyPlanItem.Group]
+var rawExtensions = []string{
+	"3fr", "arw", "cr2", "cr3", "crw", "dng", "erf", "iiq", "kdc", "mef", "mos", "mrw", "nef", "nrw",
+	"orf", "pef", "raf", "raw", "rw2", "rwl", "sr2", "srf", "srw", "x3f",
+}
+
+// extensions of the files which a camera writes alongside the RAW file
+var rawCompanionExtensions = []string{"jpg", "jpeg", "heic", "heif"}
+
+// extensions of the metadata sidecars of the RAW files
+var rawSidecarExtensions = []string{"xmp"}
+
+// extensions of the still images of the motion photos, see [GroupMotionPhoto]
+var motionPhotoImageExtensions = []string{"jpg", "jpeg", "heic", "heif"}
+
+// extensions of the video clips of the motion photos
+var motionPhotoVideoExtensions = []string{"mp4", "mov"}
+
+// profiles of the devices which are not phones, see [RegisterDeviceProfile]
+var defaultDeviceProfiles = []*DeviceProfile{
+	{
+		Name:     "Garmin",
+		VendorId: 0x091e,
+		Tracks: ProfileContent{
+			Paths:      []string{"/GARMIN/Activity", "/GARMIN/GPX", "/GARMIN/Courses"},
+			Extensions: []string{"fit", "gpx", "tcx"},
+		},
+	},
+	{
+		Name:     "Kindle",
+		VendorId: 0x1949,
+		Books: ProfileContent{
+			Paths:      []string{"/documents"},
+			Extensions: []string{"azw", "azw3", "kfx", "mobi", "epub", "pdf", "txt"},
+		},
+	},
+	{
+		Name:     "Kobo",
+		VendorId: 0x2237,
+		Books: ProfileContent{
+			Paths:      []string{"/"},
+			Extensions: []string{"epub", "kepub", "pdf", "cbz", "cbr", "mobi", "txt"},
+		},
+	},
+	{
+		Name:     "Olympus voice recorder",
+		VendorId: 0x07b4,
+		Recordings: ProfileContent{
+			Paths:      []string{"/RECORDER", "/VOICE"},
+			Extensions: []string{"mp3", "wav", "wma", "dss", "ds2"},
+		},
+	},
+}
+
+// directory for the books on the devices without a profile, see [SendBook]
+const defaultBooksPath = "/Books"
+
+// object formats of the files sent to the device by their lowercase extension, see [objectFormat]
+// MTP has no dedicated formats for the ebooks, the readers index them as documents
+var extensionObjectFormats = map[string]uint16{
+	"epub":  mtp.OFC_MTP_UndefinedDocument,
+	"kepub": mtp.OFC_MTP_UndefinedDocument,
+	"pdf":   mtp.OFC_MTP_UndefinedDocument,
+	"mobi":  mtp.OFC_MTP_UndefinedDocument,
+	"azw":   mtp.OFC_MTP_UndefinedDocument,
+	"azw3":  mtp.OFC_MTP_UndefinedDocument,
+	"fb2":   mtp.OFC_MTP_XMLDocument,
+	"txt":   mtp.OFC_Text,
+	"htm":   mtp.OFC_HTML,
+	"html":  mtp.OFC_HTML,
+	"mp3":   mtp.OFC_MP3,
+	"wav":   mtp.OFC_WAV,
+	"ogg":   mtp.OFC_MTP_OGG,
+	"oga":   mtp.OFC_MTP_OGG,
+	"aac":   mtp.OFC_MTP_AAC,
+	"m4a":   mtp.OFC_MTP_M4A,
+	"flac":  mtp.OFC_MTP_FLAC,
+	"wma":   mtp.OFC_MTP_WMA,
+}
+
+// directories which the Android sound pickers list, see [InstallSound]
+var soundDirectories = map[SoundKind]string{
+	SoundRingtone:     "/Ringtones",
+	SoundNotification: "/Notifications",
+	SoundAlarm:        "/Alarms",
+}
+
+// columns of the flat reports written by [ExportTree]
+var exportColumns = []string{"path", "size", "mtime", "type", "objectId", "storage"}
+
+// content types of the media files served by [ServeObject], keyed by the lowercase extension without the dot
+var streamContentTypes = map[string]string{
+	"mp4":  "video/mp4",
+	"m4v":  "video/x-m4v",
+	"mov":  "video/quicktime",
+	"3gp":  "video/3gpp",
+	"mkv":  "video/x-matroska",
+	"webm": "video/webm",
+	"avi":  "video/x-msvideo",
+	"ts":   "video/mp2t",
+	"mp3":  "audio/mpeg",
+	"m4a":  "audio/mp4",
+	"aac":  "audio/aac",
+	"flac": "audio/flac",
+	"ogg":  "audio/ogg",
+	"oga":  "audio/ogg",
+	"opus": "audio/ogg",
+	"wav":  "audio/wav",
+	"amr":  "audio/amr",
+	"jpg":  "image/jpeg",
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+	"gif":  "image/gif",
+	"webp": "image/webp",
+	"heic": "image/heic",
+	"heif": "image/heif",
+}
+
+// folders advertised by the media server, see [MediaServerConfig.Folders]
+var DefaultMediaFolders = []string{"/DCIM", "/Pictures", "/Movies", "/Music"}
+
+// multicast address of the UPnP discovery
+const ssdpAddr = "239.255.255.250:1900"
+
+const defaultSsdpNotifyInterval = 30 * time.Second
+
+// seconds for which the players may remember the announcements of the media server
+const ssdpMaxAge = 1800
+
+// UPnP classes of the media files served by the media server, the other files are left out
+var mediaUpnpClasses = map[FileCategory]string{
+	CategoryImage: "object.item.imageItem.photo",
+	CategoryVideo: "object.item.videoItem",
+	CategoryAudio: "object.item.audioItem.musicTrack",
+}
+
+// see [SetKeepBothTemplate]
+const defaultKeepBothTemplate = "{name} ({n}){ext}"
+
+// layout of the {timestamp} placeholder of the keep-both names
+const keepBothTimestampLayout = "20060102-150405"
@@ -0,0 +1,309 @@
+package mtpx
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var deviceContentCaches = struct {
+	sync.Mutex
+	m map[*mtp.Device]*contentCache
+}{m: map[*mtp.Device]*contentCache{}}
+
+type contentCache struct {
+	sync.Mutex
+
+	config ContentCacheConfig
+
+	// directory holding the cached files of the device
+	dir string
+
+	stats ContentCacheStats
+}
+
+// returns the default location of the content cache
+// eg: ~/.cache/mtpx/content on linux
+func DefaultContentCachePath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", LocalFileError{error: err}
+	}
+
+	return filepath.Join(cacheDir, deviceStoreDirName, contentCacheDirName), nil
+}
+
+// fetch the statistics of the content cache
+func FetchContentCacheStats(dev *mtp.Device) (*ContentCacheStats, error) {
+	c := getContentCache(dev)
+	if c == nil {
+		return nil, CacheDisabledError{error: fmt.Errorf("content cache is not enabled for the device")}
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	entries, err := contentDirEntries(c.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := c.stats
+	stats.Entries = len(entries)
+	for _, e := range entries {
+		stats.Size += e.Size()
+	}
+
+	return &stats, nil
+}
+
+// enable the content cache for the device
+// the files of a device are kept in a directory of their own as the objects are identified by their persistent uid
+func enableContentCache(dev *mtp.Device, config ContentCacheConfig) error {
+	if config.Directory == "" {
+		path, err := DefaultContentCachePath()
+		if err != nil {
+			return err
+		}
+
+		config.Directory = path
+	}
+
+	if config.MaxSize <= 0 {
+		config.MaxSize = defaultContentCacheMaxSize
+	}
+
+	info, err := FetchDeviceInfo(dev)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(config.Directory, contentCacheDeviceDir(info))
+	if err := makeLocalDirectory(dir); err != nil {
+		return LocalFileError{error: err}
+	}
+
+	// the modification time of the directory tells when the device was last connected
+	now := time.Now()
+	_ = os.Chtimes(dir, now, now)
+
+	deviceContentCaches.Lock()
+	defer deviceContentCaches.Unlock()
+
+	deviceContentCaches.m[dev] = &contentCache{config: config, dir: dir}
+
+	return nil
+}
+
+// drop the content cache of the device, the cached files are left on the disk for the next session
+func disableContentCache(dev *mtp.Device) {
+	deviceContentCaches.Lock()
+	defer deviceContentCaches.Unlock()
+
+	delete(deviceContentCaches.m, dev)
+}
+
+// returns the content cache of the device
+// returns nil if the cache is not enabled for the device
+func getContentCache(dev *mtp.Device) *contentCache {
+	deviceContentCaches.Lock()
+	defer deviceContentCaches.Unlock()
+
+	return deviceContentCaches.m[dev]
+}
+
+// name of the directory of the device inside the content cache
+// the serial number is hashed as it may hold the characters which are not allowed in a file name
+func contentCacheDeviceDir(info *mtp.DeviceInfo) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s|%s|%s", info.Manufacturer, info.Model, info.SerialNumber)))
+
+	return fmt.Sprintf("%x", sum[:8])
+}
+
+// key of the content of the object in the content cache
+// a modified object changes its size or its modification date, the stale content is then never looked up again and ends up evicted
+// returns an empty string if the content cache is not enabled for the device or the device does not report the persistent uids
+func contentCacheKey(dev *mtp.Device, fi *FileInfo) string {
+	if fi.IsDir || getContentCache(dev) == nil {
+		return ""
+	}
+
+	uid := fi.PersistentUid
+	if uid == "" {
+		uid = persistentUid(dev, fi.ObjectId)
+	}
+
+	if uid == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("%s-%d-%d", uid, fi.Size, fi.ModTime.Unix())
+}
+
+// copy the cached content of [key] to [destination]
+// returns false if the content is not in the cache
+func fetchCachedContent(dev *mtp.Device, key, destination string) (bool, error) {
+	c := getContentCache(dev)
+	if c == nil || key == "" {
+		return false, nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	source := filepath.Join(c.dir, key)
+
+	size, err := copyLocalFile(source, destination)
+	if err != nil {
+		if os.IsNotExist(err) {
+			c.stats.Misses += 1
+
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	// the least recently used files are evicted first
+	now := time.Now()
+	_ = os.Chtimes(source, now, now)
+
+	c.stats.Hits += 1
+	c.stats.BytesSaved += size
+
+	return true, nil
+}
+
+// keep a copy of the downloaded file at [source] in the cache
+// the cache is best effort, hence the errors are ignored
+func storeCachedContent(dev *mtp.Device, key, source string, size int64) {
+	c := getContentCache(dev)
+	if c == nil || key == "" {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if size > c.config.MaxSize {
+		return
+	}
+
+	// copy to a temporary file first so that a crash won't leave a truncated file behind under the key
+	f, err := ioutil.TempFile(c.dir, fmt.Sprintf("%s.*%s", key, contentCacheTmpSuffix))
+	if err != nil {
+		return
+	}
+
+	tmpPath := f.Name()
+	_ = f.Close()
+
+	if _, err := copyLocalFile(source, tmpPath); err != nil {
+		_ = os.Remove(tmpPath)
+
+		return
+	}
+
+	if err := os.Rename(tmpPath, filepath.Join(c.dir, key)); err != nil {
+		_ = os.Remove(tmpPath)
+
+		return
+	}
+
+	c.stats.Stored += 1
+
+	c.evict()
+}
+
+// remove the least recently used files until the cache fits into [ContentCacheConfig.MaxSize]
+// the caller should hold the lock
+func (c *contentCache) evict() {
+	removedFiles, _, _ := pruneContentDir(c.dir, time.Time{}, c.config.MaxSize)
+	c.stats.Evictions += removedFiles
+}
+
+// remove the cached files in [dir] which were last used before [before] and then the least recently used ones
+// until the rest fits into [maxSize]. a zero [before] or [maxSize] skips the respective check
+func pruneContentDir(dir string, before time.Time, maxSize int64) (removedFiles, removedBytes int64, err error) {
+	entries, err := contentDirEntries(dir)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var total int64
+	for _, e := range entries {
+		total += e.Size()
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime().Before(entries[j].ModTime())
+	})
+
+	for _, e := range entries {
+		expired := !before.IsZero() && e.ModTime().Before(before)
+		oversized := maxSize > 0 && total > maxSize
+
+		if !expired && !oversized {
+			break
+		}
+
+		if err := os.Remove(filepath.Join(dir, e.Name())); err != nil {
+			continue
+		}
+
+		total -= e.Size()
+		removedFiles += 1
+		removedBytes += e.Size()
+	}
+
+	return removedFiles, removedBytes, nil
+}
+
+// cached files in [dir], the files which are still being written are left out
+func contentDirEntries(dir string) ([]os.FileInfo, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, LocalFileError{error: err}
+	}
+
+	var entries []os.FileInfo
+	for _, fi := range infos {
+		if fi.IsDir() || strings.HasSuffix(fi.Name(), contentCacheTmpSuffix) {
+			continue
+		}
+
+		entries = append(entries, fi)
+	}
+
+	return entries, nil
+}
+
+// copy the local file at [source] to [destination]
+// returns the number of bytes copied
+func copyLocalFile(source, destination string) (int64, error) {
+	src, err := os.Open(source)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(destination)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := io.Copy(dst, src)
+	if cErr := dst.Close(); err == nil {
+		err = cErr
+	}
+
+	return n, err
+}
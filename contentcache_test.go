@@ -0,0 +1,104 @@
+package mtpx
+
+import (
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestContentCache(t *testing.T) {
+	Convey("Test the content cache | contentCache", t, func() {
+		dir, err := ioutil.TempDir("", "mtpx-content-cache")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		localDir, err := ioutil.TempDir("", "mtpx-content-cache-local")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(localDir)
+
+		deviceContentCaches.Lock()
+		deviceContentCaches.m[nil] = &contentCache{config: ContentCacheConfig{Directory: dir, MaxSize: 10}, dir: dir}
+		deviceContentCaches.Unlock()
+		defer disableContentCache(nil)
+
+		source := filepath.Join(localDir, "downloaded.txt")
+		So(ioutil.WriteFile(source, []byte("123456"), newLocalFileMode), ShouldBeNil)
+		destination := filepath.Join(localDir, "served.txt")
+
+		// the files are not cached without a key
+		storeCachedContent(nil, "", source, 6)
+		stats, err := FetchContentCacheStats(nil)
+		So(err, ShouldBeNil)
+		So(stats.Stored, ShouldEqual, 0)
+
+		ok, err := fetchCachedContent(nil, "a-6-1", destination)
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeFalse)
+
+		storeCachedContent(nil, "a-6-1", source, 6)
+
+		ok, err = fetchCachedContent(nil, "a-6-1", destination)
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeTrue)
+
+		data, err := ioutil.ReadFile(destination)
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, "123456")
+
+		// make room for the new file by evicting the least recently used one
+		past := time.Now().Add(-time.Hour)
+		So(os.Chtimes(filepath.Join(dir, "a-6-1"), past, past), ShouldBeNil)
+		So(os.Remove(source), ShouldBeNil)
+		So(os.Remove(destination), ShouldBeNil)
+
+		So(ioutil.WriteFile(source, []byte("abcdef"), newLocalFileMode), ShouldBeNil)
+		storeCachedContent(nil, "b-6-1", source, 6)
+		So(os.Remove(source), ShouldBeNil)
+
+		ok, err = fetchCachedContent(nil, "a-6-1", destination)
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeFalse)
+
+		stats, err = FetchContentCacheStats(nil)
+		So(err, ShouldBeNil)
+		So(stats.Hits, ShouldEqual, 1)
+		So(stats.Misses, ShouldEqual, 2)
+		So(stats.Stored, ShouldEqual, 2)
+		So(stats.Evictions, ShouldEqual, 1)
+		So(stats.BytesSaved, ShouldEqual, 6)
+		So(stats.Entries, ShouldEqual, 1)
+		So(stats.Size, ShouldEqual, 6)
+
+		// the files larger than the cache are not kept
+		So(ioutil.WriteFile(source, []byte("0123456789abc"), newLocalFileMode), ShouldBeNil)
+		storeCachedContent(nil, "c-13-1", source, 13)
+		So(os.Remove(source), ShouldBeNil)
+
+		stats, err = FetchContentCacheStats(nil)
+		So(err, ShouldBeNil)
+		So(stats.Stored, ShouldEqual, 2)
+		So(stats.Entries, ShouldEqual, 1)
+	})
+
+	Convey("Test the disabled content cache | FetchContentCacheStats", t, func() {
+		_, err := FetchContentCacheStats(nil)
+		So(err, ShouldHaveSameTypeAs, CacheDisabledError{})
+
+		ok, err := fetchCachedContent(nil, "a-6-1", "")
+		So(err, ShouldBeNil)
+		So(ok, ShouldBeFalse)
+	})
+
+	Convey("Test the directory of a device | contentCacheDeviceDir", t, func() {
+		a := contentCacheDeviceDir(&mtp.DeviceInfo{Manufacturer: "Google", Model: "Pixel", SerialNumber: "1/2"})
+		b := contentCacheDeviceDir(&mtp.DeviceInfo{Manufacturer: "Google", Model: "Pixel", SerialNumber: "1/3"})
+
+		So(a, ShouldHaveLength, 16)
+		So(a, ShouldNotEqual, b)
+		So(a, ShouldEqual, contentCacheDeviceDir(&mtp.DeviceInfo{Manufacturer: "Google", Model: "Pixel", SerialNumber: "1/2"}))
+	})
+}
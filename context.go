@@ -0,0 +1,283 @@
+package mtpx
+
+import (
+	"context"
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"sync"
+)
+
+// the contexts of the operations running on the devices, see [runWithContext]
+var deviceContexts = struct {
+	sync.Mutex
+	m map[*mtp.Device][]*operationContext
+}{m: map[*mtp.Device][]*operationContext{}}
+
+// Walk under [ctx], so that a long walk can be cancelled or given a deadline. see [Walk] for the rest of the parameters
+// [ctx] is checked before the walk starts and before every request made to the device, once it is done the walk returns
+// an [OperationCancelledError]. a request which is already in flight is not cut short
+// [ctx] applies to the device while the walk runs: the operations run through [Interleave] in the meantime stop with it
+// eg: cancel a walk after a minute
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+//	defer cancel()
+//	_, _, _, err := WalkContext(ctx, dev, sid, "/DCIM", true, true, false, cb)
+func WalkContext(ctx context.Context, dev *mtp.Device, storageId uint32, fullPath string, recursive, skipDisallowedFiles,
+	skipHiddenFiles bool, cb WalkCb) (objectId uint32, totalFiles, totalDirectories int64, err error) {
+	err = runWithContext(ctx, dev, func() error {
+		objectId, totalFiles, totalDirectories, err = Walk(dev, storageId, fullPath, recursive, skipDisallowedFiles,
+			skipHiddenFiles, contextWalkCb(ctx, cb))
+
+		return err
+	})
+
+	return objectId, totalFiles, totalDirectories, err
+}
+
+// [UploadFiles] under [ctx], see [WalkContext]
+// the preprocessing, the directories made on the device and every chunk of the files are covered by [ctx]
+func UploadFilesContext(ctx context.Context, dev *mtp.Device, storageId uint32, sources []string, destination string,
+	preprocessFiles bool, preprocessCb LocalPreprocessCb, progressCb ProgressCb) (destinationObjectId uint32, bulkFilesSent int64, bulkSizeSent int64, err error) {
+	err = runWithContext(ctx, dev, func() error {
+		destinationObjectId, bulkFilesSent, bulkSizeSent, err = UploadFiles(dev, storageId, sources, destination,
+			preprocessFiles, preprocessCb, contextProgressCb(ctx, progressCb))
+
+		return err
+	})
+
+	return destinationObjectId, bulkFilesSent, bulkSizeSent, err
+}
+
+// [DownloadFiles] under [ctx], see [WalkContext]
+// the preprocessing, the listings of the device and every chunk of the files are covered by [ctx]
+func DownloadFilesContext(ctx context.Context, dev *mtp.Device, storageId uint32, sources []string, destination string,
+	preprocessFiles bool, preprocessCb MtpPreprocessCb, progressCb ProgressCb) (bulkFilesSent int64, bulkSizeSent int64, err error) {
+	err = runWithContext(ctx, dev, func() error {
+		bulkFilesSent, bulkSizeSent, err = DownloadFiles(dev, storageId, sources, destination, preprocessFiles,
+			preprocessCb, contextProgressCb(ctx, progressCb))
+
+		return err
+	})
+
+	return bulkFilesSent, bulkSizeSent, err
+}
+
+// [UploadFile] under [ctx], see [WalkContext]
+func UploadFileContext(ctx context.Context, dev *mtp.Device, storageId uint32, localPath, destinationParentPath string) (fi *FileInfo, err error) {
+	err = runWithContext(ctx, dev, func() error {
+		fi, err = UploadFile(dev, storageId, localPath, destinationParentPath)
+
+		return err
+	})
+
+	return fi, err
+}
+
+// [DownloadFile] under [ctx], see [WalkContext]
+func DownloadFileContext(ctx context.Context, dev *mtp.Device, storageId uint32, objectId uint32, fullPath,
+	localDestination string) (bytesTransferred int64, err error) {
+	err = runWithContext(ctx, dev, func() error {
+		bytesTransferred, err = DownloadFile(dev, storageId, objectId, fullPath, localDestination)
+
+		return err
+	})
+
+	return bytesTransferred, err
+}
+
+// [MakeDirectory] under [ctx], see [WalkContext]
+func MakeDirectoryContext(ctx context.Context, dev *mtp.Device, storageId uint32, fullPath string) (objectId uint32, err error) {
+	err = runWithContext(ctx, dev, func() error {
+		objectId, err = MakeDirectory(dev, storageId, fullPath)
+
+		return err
+	})
+
+	return objectId, err
+}
+
+// [DeleteFile] under [ctx], see [WalkContext]
+func DeleteFileContext(ctx context.Context, dev *mtp.Device, storageId uint32, fileProps []FileProp) error {
+	return DeleteFileWithOptionsContext(ctx, dev, storageId, fileProps, DeleteOptions{})
+}
+
+// [DeleteFileWithOptions] under [ctx], see [WalkContext]
+func DeleteFileWithOptionsContext(ctx context.Context, dev *mtp.Device, storageId uint32, fileProps []FileProp, opts DeleteOptions) error {
+	return runWithContext(ctx, dev, func() error {
+		return DeleteFileWithOptions(dev, storageId, fileProps, opts)
+	})
+}
+
+// [DeleteDirectoryContents] under [ctx], see [WalkContext]
+func DeleteDirectoryContentsContext(ctx context.Context, dev *mtp.Device, storageId uint32, fileProp FileProp, opts DeleteOptions,
+	filterCb FileFilterCb, progressCb DeleteProgressCb) (totalDeleted int64, err error) {
+	err = runWithContext(ctx, dev, func() error {
+		totalDeleted, err = DeleteDirectoryContents(dev, storageId, fileProp, opts, filterCb, progressCb)
+
+		return err
+	})
+
+	return totalDeleted, err
+}
+
+// [DeleteDirectoryRecursive] under [ctx], see [WalkContext]
+func DeleteDirectoryRecursiveContext(ctx context.Context, dev *mtp.Device, storageId uint32, fileProp FileProp, opts DeleteOptions,
+	progressCb DeleteProgressCb) (totalDeleted int64, err error) {
+	err = runWithContext(ctx, dev, func() error {
+		totalDeleted, err = DeleteDirectoryRecursive(dev, storageId, fileProp, opts, progressCb)
+
+		return err
+	})
+
+	return totalDeleted, err
+}
+
+// [RenameFile] under [ctx], see [WalkContext]
+func RenameFileContext(ctx context.Context, dev *mtp.Device, storageId uint32, fileProp FileProp, newFileName string) (objectId uint32, err error) {
+	err = runWithContext(ctx, dev, func() error {
+		objectId, err = RenameFile(dev, storageId, fileProp, newFileName)
+
+		return err
+	})
+
+	return objectId, err
+}
+
+// [MoveFile] under [ctx], see [WalkContext]
+func MoveFileContext(ctx context.Context, dev *mtp.Device, storageId uint32, objectId uint32, fullPath,
+	destinationParentPath string) (newObjectId uint32, err error) {
+	err = runWithContext(ctx, dev, func() error {
+		newObjectId, err = MoveFile(dev, storageId, objectId, fullPath, destinationParentPath)
+
+		return err
+	})
+
+	return newObjectId, err
+}
+
+// [MovePath] under [ctx], see [WalkContext]
+func MovePathContext(ctx context.Context, dev *mtp.Device, storageId uint32, sourcePath, destinationPath string) (objectId uint32, err error) {
+	err = runWithContext(ctx, dev, func() error {
+		objectId, err = MovePath(dev, storageId, sourcePath, destinationPath)
+
+		return err
+	})
+
+	return objectId, err
+}
+
+// [CopyFile] under [ctx], see [WalkContext]
+func CopyFileContext(ctx context.Context, dev *mtp.Device, storageId uint32, objectId uint32, fullPath,
+	destinationParentPath string) (newObjectId uint32, fi *FileInfo, err error) {
+	err = runWithContext(ctx, dev, func() error {
+		newObjectId, fi, err = CopyFile(dev, storageId, objectId, fullPath, destinationParentPath)
+
+		return err
+	})
+
+	return newObjectId, fi, err
+}
+
+// [FileExists] under [ctx], see [WalkContext]
+func FileExistsContext(ctx context.Context, dev *mtp.Device, storageId uint32, fileProps []FileProp) (fc []FileExistsContainer, err error) {
+	err = runWithContext(ctx, dev, func() error {
+		fc, err = FileExists(dev, storageId, fileProps)
+
+		return err
+	})
+
+	return fc, err
+}
+
+// [GetObjectFromPath] under [ctx], see [WalkContext]
+func GetObjectFromPathContext(ctx context.Context, dev *mtp.Device, storageId uint32, fullPath string) (fi *FileInfo, err error) {
+	err = runWithContext(ctx, dev, func() error {
+		fi, err = GetObjectFromPath(dev, storageId, fullPath)
+
+		return err
+	})
+
+	return fi, err
+}
+
+// run [fn] under [ctx]: once [ctx] is done the requests which [fn] makes to the device are refused with an
+// [OperationCancelledError], see [checkCancelled]
+func runWithContext(ctx context.Context, dev *mtp.Device, fn func() error) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	oc := &operationContext{ctx: ctx, noCache: ctx.Value(noCacheContextKey{}) != nil}
+
+	deviceContexts.Lock()
+	deviceContexts.m[dev] = append(deviceContexts.m[dev], oc)
+	deviceContexts.Unlock()
+
+	defer func() {
+		deviceContexts.Lock()
+		defer deviceContexts.Unlock()
+
+		contexts := deviceContexts.m[dev]
+		for i, c := range contexts {
+			if c == oc {
+				contexts = append(contexts[:i:i], contexts[i+1:]...)
+
+				break
+			}
+		}
+
+		if len(contexts) < 1 {
+			delete(deviceContexts.m, dev)
+		} else {
+			deviceContexts.m[dev] = contexts
+		}
+	}()
+
+	return fn()
+}
+
+// returns an [OperationCancelledError] if the context of an operation running on the device is done, see [runWithContext]
+func checkDeviceContexts(dev *mtp.Device) error {
+	deviceContexts.Lock()
+	contexts := deviceContexts.m[dev]
+	deviceContexts.Unlock()
+
+	for _, oc := range contexts {
+		if err := checkContext(oc.ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// returns an [OperationCancelledError] if [ctx] is done
+func checkContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return OperationCancelledError{error: fmt.Errorf("the operation was cancelled: %v", err)}
+	}
+
+	return nil
+}
+
+// [cb] which stops the walk once [ctx] is done
+func contextWalkCb(ctx context.Context, cb WalkCb) WalkCb {
+	return func(objectId uint32, fi *FileInfo, err error) error {
+		if err := checkContext(ctx); err != nil {
+			return err
+		}
+
+		return cb(objectId, fi, err)
+	}
+}
+
+// [cb] which stops the transfer once [ctx] is done
+func contextProgressCb(ctx context.Context, cb ProgressCb) ProgressCb {
+	return func(p *ProgressInfo, err error) error {
+		if err := checkContext(ctx); err != nil {
+			return err
+		}
+
+		return cb(p, err)
+	}
+}
@@ -0,0 +1,76 @@
+package mtpx
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	Convey("Test refusing the operations of a done context | WalkContext", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		called := false
+		_, _, _, err := WalkContext(ctx, nil, 0, "/", true, true, false, func(objectId uint32, fi *FileInfo, err error) error {
+			called = true
+
+			return nil
+		})
+		So(err, ShouldHaveSameTypeAs, OperationCancelledError{})
+		So(called, ShouldBeFalse)
+	})
+
+	Convey("Test cancelling the running operation | contextWalkCb | contextProgressCb", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var visited, reported int
+		walkCb := contextWalkCb(ctx, func(objectId uint32, fi *FileInfo, err error) error {
+			visited += 1
+
+			return nil
+		})
+		progressCb := contextProgressCb(ctx, func(p *ProgressInfo, err error) error {
+			reported += 1
+
+			return nil
+		})
+
+		So(walkCb(1, &FileInfo{}, nil), ShouldBeNil)
+		So(progressCb(&ProgressInfo{}, nil), ShouldBeNil)
+
+		cancel()
+
+		So(walkCb(2, &FileInfo{}, nil), ShouldHaveSameTypeAs, OperationCancelledError{})
+		So(progressCb(&ProgressInfo{}, nil), ShouldHaveSameTypeAs, OperationCancelledError{})
+		So(visited, ShouldEqual, 1)
+		So(reported, ShouldEqual, 1)
+
+		// the context of an operation doesn't cancel the other operations of the device
+		So(checkCancelled(nil), ShouldBeNil)
+	})
+	Convey("Test the context of a running operation | runWithContext | checkCancelled", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// the device requests of the operation are refused once the context is done
+		err := runWithContext(ctx, nil, func() error {
+			So(checkCancelled(nil), ShouldBeNil)
+
+			cancel()
+
+			return touchOperation(nil)
+		})
+		So(err, ShouldHaveSameTypeAs, OperationCancelledError{})
+
+		// the context is dropped along with the operation
+		So(checkCancelled(nil), ShouldBeNil)
+
+		_, err = MakeDirectoryContext(ctx, nil, 0, "/a")
+		So(err, ShouldHaveSameTypeAs, OperationCancelledError{})
+
+		err = DeleteFileContext(ctx, nil, 0, []FileProp{{0, "/a"}})
+		So(err, ShouldHaveSameTypeAs, OperationCancelledError{})
+	})
+}
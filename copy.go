@@ -0,0 +1,129 @@
+package mtpx
+
+import (
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/ganeshrvel/go-mtpx/devicepath"
+	"io/ioutil"
+	"os"
+)
+
+// Copy a file/directory into the directory [destinationParentPath] without downloading it first
+// the object is resolved using [objectId] if it is non zero, otherwise using [fullPath]
+// the destination directory is created if it does not exist
+// the device copies the object by itself if it supports the CopyObject operation. otherwise the object is read from
+// the device and sent back, staged in a local temp directory as the mtp session can't read and write at the same time
+// returns an [InvalidPathError] if an object with the same name exists in [destinationParentPath], unless the copy
+// is given a free name under [ConflictKeepBoth] (see [SetConflictPolicy]), or if a directory is copied into itself
+// return:
+// [newObjectId]: objectId of the copy
+// [fi]: information of the copy
+func CopyFile(dev *mtp.Device, storageId uint32, objectId uint32, fullPath, destinationParentPath string) (newObjectId uint32, fi *FileInfo, err error) {
+	op := &OperationInfo{Type: CopyFileOp, Mutating: true, StorageId: storageId, FileProps: []FileProp{{objectId, fullPath}}, Destination: destinationParentPath}
+
+	err = runMiddlewares(dev, op, func() error {
+		newObjectId, fi, err = copyFile(dev, storageId, objectId, fullPath, destinationParentPath)
+
+		return err
+	})
+
+	return newObjectId, fi, err
+}
+
+// helper function for [CopyFile]
+func copyFile(dev *mtp.Device, storageId uint32, objectId uint32, fullPath, destinationParentPath string) (uint32, *FileInfo, error) {
+	if err := checkStorageWritable(dev, storageId, false); err != nil {
+		return 0, nil, err
+	}
+
+	fi, err := GetObjectFromObjectIdOrPath(dev, storageId, FileProp{objectId, fullPath})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if fi.ObjectId == ParentObjectId {
+		return 0, nil, InvalidPathError{error: fmt.Errorf("the root directory cannot be copied")}
+	}
+
+	_destinationParentPath := devicepath.Clean(destinationParentPath)
+
+	ancestorId, exists, err := existingAncestor(dev, storageId, _destinationParentPath)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// the copy may take a free name rather than being refused, see [SetConflictPolicy]
+	name := fi.Name
+	if exists && FetchConflictPolicy(dev) == ConflictKeepBoth {
+		if name, err = KeepBothName(dev, storageId, ancestorId, fi.Name, fi.IsDir); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	if err := checkDestinationParent(dev, storageId, fi, name, ancestorId, exists, destinationParentPath, "copied"); err != nil {
+		return 0, nil, err
+	}
+
+	destParentId, err := makeNestedDirectory(dev, storageId, _destinationParentPath)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	newObjectId, err := copyFileOnDevice(dev, storageId, fi, destParentId, name)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	newFi, err := GetObjectFromObjectId(dev, newObjectId, _destinationParentPath)
+	if err != nil {
+		return newObjectId, nil, err
+	}
+
+	return newObjectId, newFi, nil
+}
+
+// copy [fi] into [destParentId] as [name] using the CopyObject operation if the device supports it, through the host otherwise
+// an existing object named [name] is never replaced
+func copyFileOnDevice(dev *mtp.Device, storageId uint32, fi *FileInfo, destParentId uint32, name string) (uint32, error) {
+	if supportsOperation(dev, mtp.OC_CopyObject) {
+		newObjectId, err := withDeviceSideProgress(dev, fi, func() (uint32, error) {
+			return copyObject(dev, fi.ObjectId, storageId, destParentId)
+		})
+		if err == nil {
+			invalidateCachedListing(dev, storageId, destParentId)
+
+			// the device copies the object under its own name
+			if name != fi.Name {
+				if _, err := renameFile(dev, storageId, FileProp{newObjectId, ""}, name); err != nil {
+					_ = deleteNestedFile(dev, storageId, []FileProp{{newObjectId, ""}}, DeleteOptions{})
+
+					return 0, err
+				}
+			}
+
+			return newObjectId, nil
+		}
+
+		// some devices advertise the operation but refuse it
+		if !isOperationNotSupported(err) {
+			return 0, err
+		}
+	}
+
+	tempDir, err := ioutil.TempDir("", "mtpx-copy")
+	if err != nil {
+		return 0, LocalFileError{error: err}
+	}
+	defer os.RemoveAll(tempDir)
+
+	newObjectId, err := copyObjectThroughHost(dev, storageId, fi, destParentId, name, tempDir)
+	if err != nil {
+		if newObjectId != 0 {
+			_ = deleteNestedFile(dev, storageId, []FileProp{{newObjectId, ""}}, DeleteOptions{})
+		}
+
+		return 0, err
+	}
+
+	return newObjectId, nil
+}
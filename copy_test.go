@@ -0,0 +1,103 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"log"
+	"os"
+	"testing"
+)
+
+func TestCopyFile(t *testing.T) {
+	dev, err := Initialize(Init{})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	storages, err := FetchStorages(dev)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	sid := storages[0].Sid
+
+	Convey("General | CopyFile", t, func() {
+		destination := "/mtp-test-files/temp_dir/test_CopyFile"
+
+		objectId, fi, err := CopyFile(dev, sid, 0, "/mtp-test-files/mock_dir1/a.txt", destination)
+		So(err, ShouldBeNil)
+		So(fi.ObjectId, ShouldEqual, objectId)
+		So(fi.Name, ShouldEqual, "a.txt")
+		So(fi.FullPath, ShouldEqual, destination+"/a.txt")
+
+		// the source is left alone
+		source, err := GetObjectFromPath(dev, sid, "/mtp-test-files/mock_dir1/a.txt")
+		So(err, ShouldBeNil)
+		So(source.ObjectId, ShouldNotEqual, objectId)
+		So(source.Size, ShouldEqual, fi.Size)
+
+		// the directories are copied with their contents
+		_, fi, err = CopyFile(dev, sid, 0, "/mtp-test-files/mock_dir1/3", destination)
+		So(err, ShouldBeNil)
+		So(fi.IsDir, ShouldBeTrue)
+
+		_, err = GetObjectFromPath(dev, sid, destination+"/3/2/b.txt")
+		So(err, ShouldBeNil)
+
+		err = DeleteFile(dev, sid, []FileProp{{0, destination}})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Keep both through the host | copyObjectThroughHost", t, func() {
+		destination := "/mtp-test-files/temp_dir/test_CopyFileThroughHost"
+
+		existingId, _, err := CopyFile(dev, sid, 0, "/mtp-test-files/mock_dir1/a.txt", destination)
+		So(err, ShouldBeNil)
+
+		source, err := GetObjectFromPath(dev, sid, "/mtp-test-files/mock_dir1/a.txt")
+		So(err, ShouldBeNil)
+
+		dest, err := GetObjectFromPath(dev, sid, destination)
+		So(err, ShouldBeNil)
+
+		tempDir, err := ioutil.TempDir("", "mtpx-copy")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(tempDir)
+
+		// the same-named file in the destination is never replaced
+		_, err = copyObjectThroughHost(dev, sid, source, dest.ObjectId, "a.txt", tempDir)
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+
+		existing, err := GetObjectFromPath(dev, sid, destination+"/a.txt")
+		So(err, ShouldBeNil)
+		So(existing.ObjectId, ShouldEqual, existingId)
+
+		// the copy is sent under the free name and both files are kept
+		newObjectId, err := copyObjectThroughHost(dev, sid, source, dest.ObjectId, "a (1).txt", tempDir)
+		So(err, ShouldBeNil)
+
+		kept, err := GetObjectFromPath(dev, sid, destination+"/a (1).txt")
+		So(err, ShouldBeNil)
+		So(kept.ObjectId, ShouldEqual, newObjectId)
+		So(kept.Size, ShouldEqual, source.Size)
+
+		existing, err = GetObjectFromPath(dev, sid, destination+"/a.txt")
+		So(err, ShouldBeNil)
+		So(existing.ObjectId, ShouldEqual, existingId)
+
+		err = DeleteFile(dev, sid, []FileProp{{0, destination}})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Directory into itself | CopyFile | Should throw an error", t, func() {
+		_, _, err := CopyFile(dev, sid, 0, "/mtp-test-files/mock_dir1", "/mtp-test-files/mock_dir1/3")
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+	})
+
+	Convey("Existing destination | CopyFile | Should throw an error", t, func() {
+		_, _, err := CopyFile(dev, sid, 0, "/mtp-test-files/mock_dir1/1/a.txt", "/mtp-test-files/mock_dir1")
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+	})
+
+	Dispose(dev)
+}
@@ -0,0 +1,492 @@
+package mtpx
+
+import (
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/ganeshrvel/go-mtpx/devicepath"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// plan the transfer of [sources] from the local disk to the [destination] directory on the device
+// the plan lists what [UploadFiles] would send along with the conflicts and the space needed on the storage
+// nothing is written to the device
+func PlanUpload(dev *mtp.Device, storageId uint32, sources []string, destination string) (*CopyPlan, error) {
+	_destination := devicepath.Clean(destination)
+
+	var items []*CopyPlanItem
+	for _, source := range sources {
+		_source := filepath.Clean(source)
+		sourceParentPath := filepath.Dir(_source)
+
+		_, _, _, err := walkLocalFiles([]string{_source}, func(fi *os.FileInfo, fullPath string, err error) error {
+			if err != nil {
+				return err
+			}
+
+			sourceFilePath := filepath.Clean(fullPath)
+			_, destinationFilePath, err := mapLocalPathToDevicePath(sourceFilePath, sourceParentPath, _destination)
+			if err != nil {
+				return err
+			}
+
+			items = append(items, &CopyPlanItem{
+				Source:      sourceFilePath,
+				Destination: destinationFilePath,
+				IsDir:       (*fi).IsDir(),
+				Size:        (*fi).Size(),
+				ModTime:     (*fi).ModTime(),
+			})
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	groupPlanItems(Upload, items)
+
+	// look up the existing objects one directory listing at a time
+	listings := map[string][]*FileInfo{}
+	mode := PathMatching()
+
+	for _, item := range items {
+		parentPath, name := devicepath.Split(item.Destination)
+
+		children, ok := listings[parentPath]
+		if !ok {
+			fi, err := GetObjectFromPath(dev, storageId, parentPath)
+
+			switch err.(type) {
+			case nil:
+				if children, err = listDirectory(dev, storageId, fi.ObjectId, parentPath); err != nil {
+					return nil, err
+				}
+
+			// the parent directory is yet to be created
+			case InvalidPathError:
+
+			default:
+				return nil, err
+			}
+
+			listings[parentPath] = children
+		}
+
+		for _, child := range children {
+			if matchFilename(child.Name, name, mode) == noFilenameMatch {
+				continue
+			}
+
+			// an existing directory is reused, there is nothing to overwrite
+			if !item.IsDir && !child.IsDir {
+				item.Conflict = true
+				item.ExistingSize = child.Size
+			}
+
+			break
+		}
+	}
+
+	plan := &CopyPlan{
+		Direction:   Upload,
+		StorageId:   storageId,
+		Sources:     sources,
+		Destination: _destination,
+		Items:       items,
+	}
+
+	storages, err := FetchStorages(dev)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range storages {
+		if s.Sid == storageId {
+			plan.FreeSpace = s.Info.FreeSpaceInBytes
+			plan.FreeSpaceKnown = true
+		}
+	}
+
+	plan.summarize()
+
+	return plan, nil
+}
+
+// plan the transfer of [sources] from the device to the [destination] directory on the local disk
+// the plan lists what [DownloadFiles] would fetch along with the conflicts and the space needed on the local disk
+// nothing is written to the local disk
+func PlanDownload(dev *mtp.Device, storageId uint32, sources []string, destination string) (*CopyPlan, error) {
+	_destination := filepath.Clean(destination)
+
+	var items []*CopyPlanItem
+	for _, source := range sources {
+		_source := devicepath.Clean(source)
+		sourceParentPath, _ := devicepath.Split(_source)
+
+		_, _, _, err := Walk(dev, storageId, _source, true, false, false,
+			func(objectId uint32, fi *FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+
+				// filter out disallowed files
+				if isSkippedDisallowedFile(fi.Name) {
+					return nil
+				}
+
+				_, destinationFilePath, err := mapDevicePathToLocalPath(fi.FullPath, sourceParentPath, _destination)
+				if err != nil {
+					return err
+				}
+
+				items = append(items, &CopyPlanItem{
+					Source:      fi.FullPath,
+					Destination: destinationFilePath,
+					IsDir:       fi.IsDir,
+					Size:        fi.Size,
+					ModTime:     fi.ModTime,
+					Object:      fi,
+				})
+
+				return nil
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	groupPlanItems(Download, items)
+
+	for _, item := range items {
+		if item.IsDir {
+			continue
+		}
+
+		if existing, err := os.Stat(item.Destination); err == nil && !existing.IsDir() {
+			item.Conflict = true
+			item.ExistingSize = existing.Size()
+		}
+	}
+
+	plan := &CopyPlan{
+		Direction:   Download,
+		StorageId:   storageId,
+		Sources:     sources,
+		Destination: _destination,
+		Items:       items,
+	}
+
+	if freeSpace, err := localFreeSpace(existingLocalAncestor(_destination)); err == nil {
+		plan.FreeSpace = freeSpace
+		plan.FreeSpaceKnown = true
+	}
+
+	plan.summarize()
+
+	return plan, nil
+}
+
+// returns a copy of the plan with only the items for which [keep] returns true
+// the grouped items (see [CopyPlanItem.Group]) are kept or dropped together, following the verdict on the primary item
+// the totals, conflicts and the space requirements are worked out again
+// the parent directories of the remaining files are still created when the plan is executed
+func (p *CopyPlan) Filter(keep func(item *CopyPlanItem) bool) *CopyPlan {
+	keptGroups := map[string]bool{}
+	for _, item := range p.Items {
+		if item.Group != "" && item.Primary {
+			keptGroups[item.Group] = keep(item)
+		}
+	}
+
+	return p.filterItems(func(item *CopyPlanItem) bool {
+		if kept, ok := keptGroups[item.Group]; ok {
+			return kept
+		}
+
+		return keep(item)
+	})
+}
+
+// returns a copy of the plan with only the primary frames of the bursts and the still images of the motion photos
+// useful to keep the clutter out of a photo import. the RAW pairs are kept whole
+func (p *CopyPlan) PrimaryOnly() *CopyPlan {
+	return p.filterItems(func(item *CopyPlanItem) bool {
+		return item.Group == "" || item.Primary || item.GroupKind == GroupRawPair
+	})
+}
+
+// returns a copy of the plan with only the items for which [keep] returns true, the groups are not taken into account
+func (p *CopyPlan) filterItems(keep func(item *CopyPlanItem) bool) *CopyPlan {
+	filtered := *p
+	filtered.Items = nil
+
+	for _, item := range p.Items {
+		if keep(item) {
+			filtered.Items = append(filtered.Items, item)
+		}
+	}
+
+	filtered.summarize()
+
+	return &filtered
+}
+
+// work out the totals, conflicts and the space requirements from the items of the plan
+func (p *CopyPlan) summarize() {
+	p.TotalFiles = 0
+	p.TotalDirectories = 0
+	p.TotalSize = 0
+	p.RequiredSpace = 0
+	p.Conflicts = nil
+
+	for _, item := range p.Items {
+		if item.IsDir {
+			p.TotalDirectories += 1
+
+			continue
+		}
+
+		p.TotalFiles += 1
+		p.TotalSize += item.Size
+		p.RequiredSpace += item.Size
+
+		if item.Conflict {
+			p.Conflicts = append(p.Conflicts, item)
+			p.RequiredSpace -= item.ExistingSize
+		}
+	}
+
+	p.InsufficientSpace = p.FreeSpaceKnown && p.RequiredSpace > 0 && uint64(p.RequiredSpace) > p.FreeSpace
+}
+
+// Transfer the items of the [plan] in their order
+// the existing files at the destination are overwritten
+// return:
+// [bulkFilesSent]: total transferred files (directory count not included)
+// [bulkSizeSent]: total size of the transferred files
+func ExecuteCopyPlan(dev *mtp.Device, plan *CopyPlan, progressCb ProgressCb) (bulkFilesSent int64, bulkSizeSent int64, err error) {
+	bulkFilesSent, bulkSizeSent, _, err = runCopyPlan(dev, plan, transferOptions{preprocessFiles: true, progressCb: progressCb})
+
+	return bulkFilesSent, bulkSizeSent, err
+}
+
+// Transfer the items of the [plan] in their order, like [ExecuteCopyPlan]
+// a file which fails doesn't stop the transfer, it is recorded in the summary and the next item is transferred
+// return:
+// [summary]: result of every file, the files which failed can be retried using [RetryFailed]
+// [err]: error of the first file which failed, or the error which stopped the transfer
+func ExecuteCopyPlanWithSummary(dev *mtp.Device, plan *CopyPlan, progressCb ProgressCb) (summary *OperationSummary, err error) {
+	_, _, summary, err = runCopyPlan(dev, plan, transferOptions{preprocessFiles: true, progressCb: progressCb, continueOnError: true})
+
+	return summary, err
+}
+
+// run [executeCopyPlan] through the middlewares
+func runCopyPlan(dev *mtp.Device, plan *CopyPlan, opts transferOptions) (bulkFilesSent int64, bulkSizeSent int64, summary *OperationSummary, err error) {
+	op := &OperationInfo{StorageId: plan.StorageId, Sources: plan.Sources, Destination: plan.Destination}
+
+	switch plan.Direction {
+	case Upload:
+		op.Type = UploadFilesOp
+		op.Mutating = true
+
+	default:
+		op.Type = DownloadFilesOp
+	}
+
+	err = runMiddlewares(dev, op, func() error {
+		bulkFilesSent, bulkSizeSent, summary, err = executeCopyPlan(dev, plan, opts)
+
+		return err
+	})
+
+	return bulkFilesSent, bulkSizeSent, summary, err
+}
+
+// helper function for [ExecuteCopyPlan]
+// the download items without an [CopyPlanItem.Object] are looked up by their source path, see [RetryFailed]
+func executeCopyPlan(dev *mtp.Device, plan *CopyPlan, opts transferOptions) (bulkFilesSent int64, bulkSizeSent int64, summary *OperationSummary, err error) {
+	if plan.Direction == Upload {
+		if err := checkStorageWritable(dev, plan.StorageId, false); err != nil {
+			return 0, 0, nil, err
+		}
+	}
+
+	opType := DownloadFilesOp
+	if plan.Direction == Upload {
+		opType = UploadFilesOp
+	}
+
+	progressCb := opts.progressCb
+
+	// a retry keeps the policy of the transfer which it retries, see [RetryFailed]
+	if opts.disallowedFiles.Action == "" {
+		opts.disallowedFiles = FetchDisallowedFilesPolicy()
+	}
+
+	pInfo := ProgressInfo{
+		FileInfo:          &FileInfo{},
+		StartTime:         time.Now(),
+		LatestSentTime:    time.Now(),
+		Speed:             0,
+		TotalFiles:        plan.TotalFiles,
+		TotalDirectories:  plan.TotalDirectories,
+		FilesSent:         0,
+		FilesSentProgress: 0,
+		ActiveFileSize:    &TransferSizeInfo{},
+		BulkFileSize:      &TransferSizeInfo{Total: plan.TotalSize},
+		Status:            InProgress,
+		Breakdown:         newTransferBreakdown(),
+		Summary:           newOperationSummary(opType, plan.StorageId, opts),
+	}
+	summary = pInfo.Summary
+	defer func() {
+		summary.finish(err)
+	}()
+
+	// objectIds of the device directories which were created or found so far
+	destinationFilesDict := map[string]uint32{}
+
+	// error of the first file which failed, when the transfer moves on after the failures
+	var fileErr error
+
+	for _, item := range plan.Items {
+		destinationParentPath, destinationName := splitDestinationPath(plan.Direction, item.Destination)
+		fileFailed := false
+
+		if plan.Direction == Download {
+			sourceParentPath, _ := devicepath.Split(item.Source)
+			dfProps := &processDownloadFilesProps{
+				destinationFileParentPath: destinationParentPath,
+				destinationFilePath:       item.Destination,
+				sourceParentPath:          sourceParentPath,
+				bulkFilesSent:             bulkFilesSent,
+				bulkSizeSent:              bulkSizeSent,
+				totalFiles:                plan.TotalFiles,
+				totalSize:                 plan.TotalSize,
+			}
+
+			fi := item.Object
+			if fi == nil {
+				if fi, err = GetObjectFromPath(dev, plan.StorageId, item.Source); err != nil {
+					_, name := devicepath.Split(item.Source)
+					pInfo.recordFile(&FileResult{
+						Source: item.Source, Destination: item.Destination, Size: item.Size, Status: FileFailed, Err: err,
+					}, name, fileCategory(mtp.OFC_Undefined, name, false))
+					fileFailed = true
+				}
+			}
+
+			if err == nil {
+				err = processDownloadFiles(dev, &pInfo, fi, progressCb, dfProps)
+				bulkFilesSent = dfProps.bulkFilesSent
+				bulkSizeSent = dfProps.bulkSizeSent
+				fileFailed = dfProps.fileFailed
+			}
+		} else if item.IsDir {
+			var objId uint32
+			objId, err = makeNestedDirectory(dev, plan.StorageId, item.Destination)
+			destinationFilesDict[item.Destination] = objId
+		} else {
+			fileParentId, ok := destinationFilesDict[destinationParentPath]
+			if !ok {
+				fileParentId, err = makeNestedDirectory(dev, plan.StorageId, destinationParentPath)
+				if err != nil {
+					break
+				}
+
+				destinationFilesDict[destinationParentPath] = fileParentId
+			}
+
+			ufProps := &processUploadFilesProps{
+				sourceFilePath:            item.Source,
+				destinationFileParentPath: destinationParentPath,
+				destinationFilePath:       item.Destination,
+				fileParentId:              fileParentId,
+				bulkFilesSent:             bulkFilesSent,
+				bulkSizeSent:              bulkSizeSent,
+				totalFiles:                plan.TotalFiles,
+				totalSize:                 plan.TotalSize,
+			}
+
+			_, err = processUploadFiles(dev, plan.StorageId, &pInfo, destinationName, item.Size, progressCb, ufProps)
+			bulkFilesSent = ufProps.bulkFilesSent
+			bulkSizeSent = ufProps.bulkSizeSent
+			fileFailed = ufProps.fileFailed
+		}
+
+		if err != nil && opts.continueOnError && fileFailed {
+			recordTransferError(dev)
+			if fileErr == nil {
+				fileErr = err
+			}
+
+			err = nil
+		}
+
+		if err != nil {
+			break
+		}
+	}
+
+	if err != nil {
+		recordTransferError(dev)
+
+		return bulkFilesSent, bulkSizeSent, summary, err
+	}
+
+	pInfo.Status = Completed
+	pInfo.SessionStats = FetchTransferStats(dev)
+	if err := progressCb(&pInfo, nil); err != nil {
+		return bulkFilesSent, bulkSizeSent, summary, err
+	}
+
+	return bulkFilesSent, bulkSizeSent, summary, fileErr
+}
+
+// the nearest ancestor of [localPath] which exists on the local disk
+func existingLocalAncestor(localPath string) string {
+	for {
+		if fileExistsLocal(localPath) {
+			return localPath
+		}
+
+		parentPath := filepath.Dir(localPath)
+		if parentPath == localPath {
+			return localPath
+		}
+
+		localPath = parentPath
+	}
+}
+
+// split [fullPath], a path on the source side of a transfer in [direction]
+// the uploads read from the local disk and the downloads from the device
+func splitSourcePath(direction TransferDirection, fullPath string) (parentPath, name string) {
+	if direction == Upload {
+		return filepath.Dir(fullPath), filepath.Base(fullPath)
+	}
+
+	return devicepath.Split(fullPath)
+}
+
+// split [fullPath], a path on the destination side of a transfer in [direction]
+// the uploads write to the device and the downloads to the local disk
+func splitDestinationPath(direction TransferDirection, fullPath string) (parentPath, name string) {
+	if direction == Download {
+		return filepath.Dir(fullPath), filepath.Base(fullPath)
+	}
+
+	return devicepath.Split(fullPath)
+}
+
+// join [parentPath] and [name] on the destination side of a transfer in [direction]
+func joinDestinationPath(direction TransferDirection, parentPath, name string) string {
+	if direction == Download {
+		return filepath.Join(parentPath, name)
+	}
+
+	return devicepath.Join(parentPath, name)
+}
@@ -0,0 +1,69 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCopyPlanFilter(t *testing.T) {
+	Convey("Test CopyPlan.Filter", t, func() {
+		plan := &CopyPlan{
+			Direction:      Upload,
+			FreeSpace:      150,
+			FreeSpaceKnown: true,
+			Items: []*CopyPlanItem{
+				{Destination: "/dst/a", IsDir: true},
+				{Destination: "/dst/a/b.txt", Size: 100},
+				{Destination: "/dst/a/c.txt", Size: 80, Conflict: true, ExistingSize: 50},
+			},
+		}
+		plan.summarize()
+
+		So(plan.TotalFiles, ShouldEqual, 2)
+		So(plan.TotalDirectories, ShouldEqual, 1)
+		So(plan.TotalSize, ShouldEqual, 180)
+		So(plan.RequiredSpace, ShouldEqual, 130)
+		So(len(plan.Conflicts), ShouldEqual, 1)
+		So(plan.InsufficientSpace, ShouldBeFalse)
+
+		filtered := plan.Filter(func(item *CopyPlanItem) bool {
+			return !item.Conflict
+		})
+
+		So(filtered.TotalFiles, ShouldEqual, 1)
+		So(filtered.TotalSize, ShouldEqual, 100)
+		So(filtered.RequiredSpace, ShouldEqual, 100)
+		So(len(filtered.Conflicts), ShouldEqual, 0)
+
+		// the original plan is left untouched
+		So(len(plan.Items), ShouldEqual, 3)
+
+		plan.FreeSpace = 120
+		plan.summarize()
+
+		So(plan.InsufficientSpace, ShouldBeTrue)
+
+		// a full destination
+		plan.FreeSpace = 0
+		plan.summarize()
+
+		So(plan.InsufficientSpace, ShouldBeTrue)
+
+		plan.FreeSpaceKnown = false
+		plan.summarize()
+
+		So(plan.InsufficientSpace, ShouldBeFalse)
+	})
+
+	Convey("Test existingLocalAncestor", t, func() {
+		dir := os.TempDir()
+
+		So(existingLocalAncestor(filepath.Join(dir, "mtpx-missing", "nested")), ShouldEqual, dir)
+
+		freeSpace, err := localFreeSpace(dir)
+		So(err, ShouldBeNil)
+		So(freeSpace, ShouldBeGreaterThan, 0)
+	})
+}
@@ -5,6 +5,7 @@ import (
 	. "github.com/smartystreets/goconvey/convey"
 	"log"
 	"math/rand"
+	"path"
 	"testing"
 )
 
@@ -116,5 +117,157 @@ func TestDeleteFile(t *testing.T) {
 		So(err, ShouldBeNil)
 	})
 
+	Convey("Delete an non existing object | using objectId | strict | DeleteFileWithOptions | Should throw an error", t, func() {
+		err = DeleteFileWithOptions(dev, sid, []FileProp{{1234567, ""}}, DeleteOptions{Strict: true})
+
+		So(err, ShouldHaveSameTypeAs, FileNotFoundError{})
+	})
+
+	Convey("Delete an non existing object | using fullPath | strict | DeleteFileWithOptions | Should throw an error", t, func() {
+		// test the directory '/mtp-test-files/temp_dir/test-DeleteFile/{random}'
+		directoryName := fmt.Sprintf("/mtp-test-files/temp_dir/test-DeleteFile/%x", rand.Int31())
+
+		err = DeleteFileWithOptions(dev, sid, []FileProp{{0, directoryName}}, DeleteOptions{Strict: true})
+
+		So(err, ShouldHaveSameTypeAs, FileNotFoundError{})
+	})
+
+	Dispose(dev)
+}
+
+func TestDeleteDirectoryContents(t *testing.T) {
+	dev, err := Initialize(Init{})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	storages, err := FetchStorages(dev)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	sid := storages[0].Sid
+
+	Convey("Delete the contents of a directory | DeleteDirectoryContents", t, func() {
+		// test the directory '/mtp-test-files/temp_dir/test-DeleteDirectoryContents/{random}'
+		directoryName := fmt.Sprintf("/mtp-test-files/temp_dir/test-DeleteDirectoryContents/%x", rand.Int31())
+
+		dirObjectId, err := MakeDirectory(dev, sid, directoryName)
+		So(err, ShouldBeNil)
+
+		_, err = MakeDirectory(dev, sid, fmt.Sprintf("%s/keep", directoryName))
+		So(err, ShouldBeNil)
+		_, err = MakeDirectory(dev, sid, fmt.Sprintf("%s/delete1", directoryName))
+		So(err, ShouldBeNil)
+		_, err = MakeDirectory(dev, sid, fmt.Sprintf("%s/delete2", directoryName))
+		So(err, ShouldBeNil)
+
+		// the nested objects are deleted along with their directory
+		_, err = MakeDirectory(dev, sid, fmt.Sprintf("%s/delete1/nested", directoryName))
+		So(err, ShouldBeNil)
+
+		var deleted []string
+		totalDeleted, err := DeleteDirectoryContents(dev, sid, FileProp{0, directoryName}, DeleteOptions{},
+			func(fi *FileInfo) bool {
+				return fi.Name != "keep"
+			},
+			func(fi *FileInfo, totalDeleted int64, err error) error {
+				So(err, ShouldBeNil)
+
+				deleted = append(deleted, fi.Name)
+
+				return nil
+			})
+
+		So(err, ShouldBeNil)
+		So(totalDeleted, ShouldEqual, 3)
+		So(deleted, ShouldContain, "nested")
+		So(deleted, ShouldContain, "delete1")
+		So(deleted, ShouldContain, "delete2")
+
+		// the directory itself and the filtered out objects should exist
+		fc, err := FileExists(dev, sid, []FileProp{{dirObjectId, ""}, {0, fmt.Sprintf("%s/keep", directoryName)}})
+		So(err, ShouldBeNil)
+		So(fc[0].Exists, ShouldBeTrue)
+		So(fc[1].Exists, ShouldBeTrue)
+	})
+
+	Convey("Delete the contents of a file | DeleteDirectoryContents | Should throw an error", t, func() {
+		// test the file '/mtp-test-files/a.txt'
+		_, err := DeleteDirectoryContents(dev, sid, FileProp{0, "/mtp-test-files/a.txt"}, DeleteOptions{}, nil, nil)
+
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+	})
+
+	Dispose(dev)
+}
+
+func TestDeleteDirectoryRecursive(t *testing.T) {
+	dev, err := Initialize(Init{})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	storages, err := FetchStorages(dev)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	sid := storages[0].Sid
+
+	Convey("Delete a directory with its contents | DeleteDirectoryRecursive", t, func() {
+		// test the directory '/mtp-test-files/temp_dir/test-DeleteDirectoryRecursive/{random}'
+		directoryName := fmt.Sprintf("/mtp-test-files/temp_dir/test-DeleteDirectoryRecursive/%x", rand.Int31())
+
+		_, err := MakeDirectory(dev, sid, fmt.Sprintf("%s/a/b", directoryName))
+		So(err, ShouldBeNil)
+		_, err = MakeDirectory(dev, sid, fmt.Sprintf("%s/c", directoryName))
+		So(err, ShouldBeNil)
+
+		var deleted []string
+		totalDeleted, err := DeleteDirectoryRecursive(dev, sid, FileProp{0, directoryName}, DeleteOptions{},
+			func(fi *FileInfo, totalDeleted int64, err error) error {
+				So(err, ShouldBeNil)
+
+				deleted = append(deleted, fi.Name)
+
+				return nil
+			})
+
+		So(err, ShouldBeNil)
+		So(totalDeleted, ShouldEqual, 4)
+		So(len(deleted), ShouldEqual, 4)
+
+		// the children are deleted before their parents
+		indexOf := func(name string) int {
+			for i, n := range deleted {
+				if n == name {
+					return i
+				}
+			}
+
+			return -1
+		}
+
+		So(indexOf("b"), ShouldBeBetween, -1, indexOf("a"))
+		So(deleted[3], ShouldEqual, path.Base(directoryName))
+
+		fc, err := FileExists(dev, sid, []FileProp{{0, directoryName}})
+		So(err, ShouldBeNil)
+		So(fc[0].Exists, ShouldBeFalse)
+	})
+
+	Convey("Delete a non existing directory | DeleteDirectoryRecursive | Should throw an error", t, func() {
+		_, err := DeleteDirectoryRecursive(dev, sid, FileProp{0, "/mtp-test-files/fake-DeleteDirectoryRecursive"}, DeleteOptions{}, nil)
+
+		So(err, ShouldHaveSameTypeAs, FileNotFoundError{})
+	})
+
+	Convey("Delete a file | DeleteDirectoryRecursive | Should throw an error", t, func() {
+		_, err := DeleteDirectoryRecursive(dev, sid, FileProp{0, "/mtp-test-files/a.txt"}, DeleteOptions{}, nil)
+
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+	})
+
 	Dispose(dev)
 }
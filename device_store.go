@@ -0,0 +1,244 @@
+package mtpx
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var deviceStoreLock sync.Mutex
+
+// returns the default location of the device store
+// eg: ~/.config/mtpx/devices.json on linux
+func DefaultDeviceStorePath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", DeviceStoreError{error: err}
+	}
+
+	return filepath.Join(configDir, deviceStoreDirName, deviceStoreFileName), nil
+}
+
+// remember the connected device in the device store
+// the serial number, storages and the connection time are updated while the existing alias is retained
+// if [storePath] is empty then [DefaultDeviceStorePath] will be used
+func RememberDevice(dev *mtp.Device, storePath string) (*DeviceRecord, error) {
+	info, err := FetchDeviceInfo(dev)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.SerialNumber == "" {
+		return nil, DeviceStoreError{error: fmt.Errorf("the device did not report a serial number")}
+	}
+
+	storages, err := FetchStorages(dev)
+	if err != nil {
+		return nil, err
+	}
+
+	var storageNames []string
+	for _, s := range storages {
+		storageNames = append(storageNames, s.Info.StorageDescription)
+	}
+
+	var record DeviceRecord
+	err = updateDeviceStore(storePath, func(c *deviceStoreContainer) error {
+		record = c.Devices[info.SerialNumber]
+
+		record.Serial = info.SerialNumber
+		record.Manufacturer = info.Manufacturer
+		record.Model = info.Model
+		record.Storages = storageNames
+		record.LastConnected = time.Now()
+
+		c.Devices[info.SerialNumber] = record
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// assign an [alias] to the device with the [serial] number
+// an empty [alias] removes the existing alias
+// if [storePath] is empty then [DefaultDeviceStorePath] will be used
+func SetDeviceAlias(storePath, serial, alias string) error {
+	if serial == "" {
+		return UnknownDeviceError{error: fmt.Errorf("serial number cannot be empty")}
+	}
+
+	return updateDeviceStore(storePath, func(c *deviceStoreContainer) error {
+		// an alias should point to a single device
+		if alias != "" {
+			for s, d := range c.Devices {
+				if s != serial && strings.EqualFold(d.Alias, alias) {
+					return DeviceStoreError{error: fmt.Errorf("alias %s is already assigned to the device %s", alias, s)}
+				}
+			}
+		}
+
+		record := c.Devices[serial]
+		record.Serial = serial
+		record.Alias = alias
+
+		c.Devices[serial] = record
+
+		return nil
+	})
+}
+
+// resolve a device using its [alias] or serial number
+// if [storePath] is empty then [DefaultDeviceStorePath] will be used
+func ResolveAlias(storePath, alias string) (*DeviceRecord, error) {
+	deviceStoreLock.Lock()
+	defer deviceStoreLock.Unlock()
+
+	c, err := readDeviceStore(storePath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range c.Devices {
+		if d.Alias != "" && strings.EqualFold(d.Alias, alias) {
+			return &d, nil
+		}
+	}
+
+	if d, ok := c.Devices[alias]; ok {
+		return &d, nil
+	}
+
+	return nil, UnknownDeviceError{error: fmt.Errorf("no device found for the alias: %s", alias)}
+}
+
+// list all the devices in the device store
+// the most recently connected device is listed first
+// if [storePath] is empty then [DefaultDeviceStorePath] will be used
+func ListKnownDevices(storePath string) ([]DeviceRecord, error) {
+	deviceStoreLock.Lock()
+	defer deviceStoreLock.Unlock()
+
+	c, err := readDeviceStore(storePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []DeviceRecord
+	for _, d := range c.Devices {
+		result = append(result, d)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].LastConnected.After(result[j].LastConnected)
+	})
+
+	return result, nil
+}
+
+// fetch the most recently connected device from the device store
+// if [storePath] is empty then [DefaultDeviceStorePath] will be used
+func LastUsedDevice(storePath string) (*DeviceRecord, error) {
+	devices, err := ListKnownDevices(storePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(devices) < 1 {
+		return nil, UnknownDeviceError{error: fmt.Errorf("no devices were remembered yet")}
+	}
+
+	return &devices[0], nil
+}
+
+// helper function to read, modify and write back the device store
+func updateDeviceStore(storePath string, cb func(c *deviceStoreContainer) error) error {
+	deviceStoreLock.Lock()
+	defer deviceStoreLock.Unlock()
+
+	c, err := readDeviceStore(storePath)
+	if err != nil {
+		return err
+	}
+
+	if err := cb(c); err != nil {
+		return err
+	}
+
+	return writeDeviceStore(storePath, c)
+}
+
+func readDeviceStore(storePath string) (*deviceStoreContainer, error) {
+	_storePath, err := resolveDeviceStorePath(storePath)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &deviceStoreContainer{Devices: map[string]DeviceRecord{}}
+
+	data, err := ioutil.ReadFile(_storePath)
+	if err != nil {
+		// a missing store is an empty store
+		if os.IsNotExist(err) {
+			return c, nil
+		}
+
+		return nil, DeviceStoreError{error: err}
+	}
+
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, DeviceStoreError{error: fmt.Errorf("invalid device store %s: %v", _storePath, err)}
+	}
+
+	if c.Devices == nil {
+		c.Devices = map[string]DeviceRecord{}
+	}
+
+	return c, nil
+}
+
+func writeDeviceStore(storePath string, c *deviceStoreContainer) error {
+	_storePath, err := resolveDeviceStorePath(storePath)
+	if err != nil {
+		return err
+	}
+
+	if err := makeLocalDirectory(filepath.Dir(_storePath)); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return DeviceStoreError{error: err}
+	}
+
+	// write to a temporary file first so that a crash won't leave a truncated store behind
+	tmpPath := fmt.Sprintf("%s.tmp", _storePath)
+	if err := ioutil.WriteFile(tmpPath, data, newLocalFileMode); err != nil {
+		return DeviceStoreError{error: err}
+	}
+
+	if err := os.Rename(tmpPath, _storePath); err != nil {
+		return DeviceStoreError{error: err}
+	}
+
+	return nil
+}
+
+func resolveDeviceStorePath(storePath string) (string, error) {
+	if storePath != "" {
+		return storePath, nil
+	}
+
+	return DefaultDeviceStorePath()
+}
@@ -0,0 +1,58 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"path/filepath"
+	"testing"
+)
+
+func TestDeviceStore(t *testing.T) {
+	Convey("Assign and resolve an alias | SetDeviceAlias | ResolveAlias", t, func() {
+		storePath := filepath.Join(newTempMocksDir("test_DeviceStore", true), "devices.json")
+
+		err := SetDeviceAlias(storePath, "ABC123", "pixel7")
+		So(err, ShouldBeNil)
+
+		d, err := ResolveAlias(storePath, "pixel7")
+		So(err, ShouldBeNil)
+		So(d.Serial, ShouldEqual, "ABC123")
+
+		// aliases are case insensitive
+		d, err = ResolveAlias(storePath, "Pixel7")
+		So(err, ShouldBeNil)
+		So(d.Serial, ShouldEqual, "ABC123")
+
+		// serial numbers resolve too
+		d, err = ResolveAlias(storePath, "ABC123")
+		So(err, ShouldBeNil)
+		So(d.Alias, ShouldEqual, "pixel7")
+
+		_, err = ResolveAlias(storePath, "galaxy")
+		So(err, ShouldHaveSameTypeAs, UnknownDeviceError{})
+	})
+
+	Convey("Duplicate aliases | SetDeviceAlias | Should throw an error", t, func() {
+		storePath := filepath.Join(newTempMocksDir("test_DeviceStore", true), "devices.json")
+
+		err := SetDeviceAlias(storePath, "ABC123", "pixel7")
+		So(err, ShouldBeNil)
+
+		err = SetDeviceAlias(storePath, "XYZ789", "pixel7")
+		So(err, ShouldHaveSameTypeAs, DeviceStoreError{})
+
+		// re-assigning the same alias to the same device is allowed
+		err = SetDeviceAlias(storePath, "ABC123", "pixel7")
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Empty store | ListKnownDevices | LastUsedDevice", t, func() {
+		storePath := filepath.Join(newTempMocksDir("test_DeviceStore", true), "devices.json")
+
+		devices, err := ListKnownDevices(storePath)
+		So(err, ShouldBeNil)
+		So(len(devices), ShouldEqual, 0)
+
+		_, err = LastUsedDevice(storePath)
+		So(err, ShouldHaveSameTypeAs, UnknownDeviceError{})
+	})
+}
@@ -0,0 +1,74 @@
+// Package devicepath manipulates the paths of the objects on an MTP device.
+// The device paths are always absolute and separated by forward slashes, whatever the host OS is.
+package devicepath
+
+import (
+	"path"
+	"strings"
+)
+
+// separator of the device path components
+const Separator = "/"
+
+// returns the shortest absolute path equivalent to [p]
+// a leading [Separator] is added if missing, eg: "DCIM//Camera/" => "/DCIM/Camera"
+func Clean(p string) string {
+	if !strings.HasPrefix(p, Separator) {
+		p = Separator + p
+	}
+
+	return path.Clean(p)
+}
+
+// joins the path components into a single clean absolute path, the empty components are ignored
+// eg: Join("/DCIM", "Camera", "a.jpg") => "/DCIM/Camera/a.jpg"
+func Join(elem ...string) string {
+	return Clean(path.Join(elem...))
+}
+
+// splits [p] into the parent directory and the name of the object
+// the root directory has no name, eg: Split("/") => ("/", "")
+func Split(p string) (dir, name string) {
+	_p := Clean(p)
+	if _p == Separator {
+		return Separator, ""
+	}
+
+	dir, name = path.Split(_p)
+
+	return Clean(dir), name
+}
+
+// returns the deepest directory which contains all the [paths]
+// a path is considered to contain itself, eg: CommonParent("/a/b", "/a/b/c") => "/a/b"
+// returns an empty string if no path is given
+func CommonParent(paths ...string) string {
+	if len(paths) < 1 {
+		return ""
+	}
+
+	parent := Clean(paths[0])
+
+	for _, p := range paths[1:] {
+		_p := Clean(p)
+
+		for !IsSubpath(parent, _p) {
+			parent, _ = Split(parent)
+		}
+	}
+
+	return parent
+}
+
+// returns true if [child] is [parent] or lies underneath it
+// the paths are compared component by component, eg: IsSubpath("/a/b", "/a/bc") => false
+func IsSubpath(parent, child string) bool {
+	_parent := Clean(parent)
+	_child := Clean(child)
+
+	if _parent == _child || _parent == Separator {
+		return true
+	}
+
+	return strings.HasPrefix(_child, _parent+Separator)
+}
@@ -0,0 +1,78 @@
+package devicepath
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestDevicePath(t *testing.T) {
+	Convey("Test Clean", t, func() {
+		filenameList := []string{"", ".", "/./", "././", "/../", "/", "//", "/abc", "//bcd", "/cde/", "/def//", "efg/", "fgh", "ghi/124", "hij/124/", "/ijk/124/"}
+		dirList := []string{"/", "/", "/", "/", "/", "/", "/", "/abc", "/bcd", "/cde", "/def", "/efg", "/fgh", "/ghi/124", "/hij/124", "/ijk/124"}
+
+		for i, f := range filenameList {
+			So(Clean(f), ShouldEqual, dirList[i])
+		}
+	})
+
+	Convey("Test Join", t, func() {
+		type s struct {
+			parentPath, filename, fullPath string
+		}
+
+		sl := []s{
+			{parentPath: "/", filename: "abc", fullPath: "/abc"},
+			{parentPath: "//", filename: "bcd", fullPath: "/bcd"},
+			{parentPath: "/", filename: "cde/", fullPath: "/cde"},
+			{parentPath: "/def", filename: "abc/", fullPath: "/def/abc"},
+			{parentPath: "/efg/", filename: "abc/", fullPath: "/efg/abc"},
+			{parentPath: "", filename: "", fullPath: "/"},
+			{parentPath: "/", filename: "", fullPath: "/"},
+			{parentPath: "", filename: "/", fullPath: "/"},
+			{parentPath: "/", filename: "/", fullPath: "/"},
+			{parentPath: "//", filename: "/", fullPath: "/"},
+			{parentPath: "/abc", filename: "def", fullPath: "/abc/def"},
+			{parentPath: "/abc/", filename: "/def/", fullPath: "/abc/def"},
+			{parentPath: "abc", filename: "def/ghi", fullPath: "/abc/def/ghi"},
+			{parentPath: "/abc", filename: "../def", fullPath: "/def"},
+		}
+
+		for _, f := range sl {
+			So(Join(f.parentPath, f.filename), ShouldEqual, f.fullPath)
+		}
+
+		So(Join("/DCIM", "Camera", "a.jpg"), ShouldEqual, "/DCIM/Camera/a.jpg")
+		So(Join(), ShouldEqual, "/")
+	})
+
+	Convey("Test Split", t, func() {
+		dir, name := Split("/DCIM/Camera/a.jpg")
+		So(dir, ShouldEqual, "/DCIM/Camera")
+		So(name, ShouldEqual, "a.jpg")
+
+		dir, name = Split("DCIM/")
+		So(dir, ShouldEqual, "/")
+		So(name, ShouldEqual, "DCIM")
+
+		dir, name = Split("/")
+		So(dir, ShouldEqual, "/")
+		So(name, ShouldEqual, "")
+	})
+
+	Convey("Test CommonParent", t, func() {
+		So(CommonParent(), ShouldEqual, "")
+		So(CommonParent("/a/b"), ShouldEqual, "/a/b")
+		So(CommonParent("/a/b", "/a/b/c"), ShouldEqual, "/a/b")
+		So(CommonParent("/a/b/c", "/a/b/d", "/a/bc"), ShouldEqual, "/a")
+		So(CommonParent("/a", "/b"), ShouldEqual, "/")
+	})
+
+	Convey("Test IsSubpath", t, func() {
+		So(IsSubpath("/a/b", "/a/b"), ShouldBeTrue)
+		So(IsSubpath("/a/b", "/a/b/c"), ShouldBeTrue)
+		So(IsSubpath("/a/b/", "a/b/c/../d"), ShouldBeTrue)
+		So(IsSubpath("/a/b", "/a/bc"), ShouldBeFalse)
+		So(IsSubpath("/a/b", "/a/b/../../c"), ShouldBeFalse)
+		So(IsSubpath("/", "/anything"), ShouldBeTrue)
+	})
+}
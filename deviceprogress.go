@@ -0,0 +1,138 @@
+package mtpx
+
+import (
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"sync"
+	"time"
+)
+
+type deviceSideProgress struct {
+	cb SizeProgressCb
+
+	// copy rate (bytes/sec) measured during the last device-side copy, 0 if none has completed yet
+	rate float64
+}
+
+var deviceSideProgresses = struct {
+	sync.Mutex
+	m map[*mtp.Device]*deviceSideProgress
+}{m: map[*mtp.Device]*deviceSideProgress{}}
+
+// report the progress of the copies done by the device itself to [cb], ie: [CopyFile] and the cross-storage moves
+// using the CopyObject and MoveObject operations, so that the UIs aren't stuck at 0% for minutes
+// the device gives no progress and can't be asked anything until the copy is over, so the progress is estimated using
+// the size of the file and the copy rate of the previous copies. the estimate stops short of [total] until the copy completes
+// the objectId passed to [cb] is the one of the source; the errors returned by [cb] are ignored as the copy can't be interrupted
+// [cb] is called from another goroutine and must not use the device
+// only the files larger than 16MB are reported. if [cb] is nil then the progress is no longer reported
+func SetDeviceSideProgressCb(dev *mtp.Device, cb SizeProgressCb) {
+	deviceSideProgresses.Lock()
+	defer deviceSideProgresses.Unlock()
+
+	if cb == nil {
+		delete(deviceSideProgresses.m, dev)
+
+		return
+	}
+
+	p, ok := deviceSideProgresses.m[dev]
+	if !ok {
+		p = &deviceSideProgress{}
+		deviceSideProgresses.m[dev] = p
+	}
+
+	p.cb = cb
+}
+
+// run the device-side copy [fn] of [fi] while reporting its estimated progress, see [SetDeviceSideProgressCb]
+// the device can't be asked anything until the copy is over, so the copy doesn't count towards the stall timeout
+func withDeviceSideProgress(dev *mtp.Device, fi *FileInfo, fn func() (uint32, error)) (uint32, error) {
+	var objectId uint32
+
+	err := withoutStallTimeout(dev, func() (err error) {
+		objectId, err = reportDeviceSideProgress(dev, fi, fn)
+
+		return err
+	})
+
+	return objectId, err
+}
+
+// see [withDeviceSideProgress]
+func reportDeviceSideProgress(dev *mtp.Device, fi *FileInfo, fn func() (uint32, error)) (uint32, error) {
+	deviceSideProgresses.Lock()
+	p, ok := deviceSideProgresses.m[dev]
+	var cb SizeProgressCb
+	var rate float64
+	if ok {
+		cb = p.cb
+		rate = p.rate
+	}
+	deviceSideProgresses.Unlock()
+
+	if cb == nil || fi.IsDir || fi.Size < deviceSideProgressMinSize {
+		return fn()
+	}
+
+	if rate <= 0 {
+		rate = defaultDeviceSideCopyRate
+	}
+
+	startTime := time.Now()
+	_ = cb(fi.Size, 0, fi.ObjectId, nil)
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(deviceSideProgressInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+
+			case <-ticker.C:
+				_ = cb(fi.Size, estimateDeviceSideProgress(fi.Size, rate, time.Since(startTime)), fi.ObjectId, nil)
+			}
+		}
+	}()
+
+	objectId, err := fn()
+
+	close(done)
+	<-stopped
+
+	if err != nil {
+		_ = cb(fi.Size, estimateDeviceSideProgress(fi.Size, rate, time.Since(startTime)), fi.ObjectId, err)
+
+		return objectId, err
+	}
+
+	if elapsed := time.Since(startTime); elapsed > 0 {
+		deviceSideProgresses.Lock()
+		if p, ok := deviceSideProgresses.m[dev]; ok {
+			p.rate = float64(fi.Size) / elapsed.Seconds()
+		}
+		deviceSideProgresses.Unlock()
+	}
+
+	_ = cb(fi.Size, fi.Size, fi.ObjectId, nil)
+
+	return objectId, nil
+}
+
+// estimate the bytes copied out of [total] after [elapsed] at [rate] bytes/sec
+// the estimate is held at 99% of [total] as only the device knows when the copy is over
+func estimateDeviceSideProgress(total int64, rate float64, elapsed time.Duration) int64 {
+	sent := int64(rate * elapsed.Seconds())
+
+	if limit := total * 99 / 100; sent > limit {
+		return limit
+	}
+
+	return sent
+}
@@ -0,0 +1,81 @@
+package mtpx
+
+import (
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDeviceSideProgress(t *testing.T) {
+	Convey("Test estimateDeviceSideProgress", t, func() {
+		So(estimateDeviceSideProgress(1000, 100, 0), ShouldEqual, 0)
+		So(estimateDeviceSideProgress(1000, 100, 2*time.Second), ShouldEqual, 200)
+		So(estimateDeviceSideProgress(1000, 100, time.Minute), ShouldEqual, 990)
+	})
+
+	Convey("Test withDeviceSideProgress", t, func() {
+		var mu sync.Mutex
+		var reported []int64
+		var lastErr error
+
+		SetDeviceSideProgressCb(nil, func(total, sent int64, objectId uint32, err error) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			// called from another goroutine, the values are checked afterwards
+			if total != deviceSideProgressMinSize || objectId != 7 {
+				sent = -1
+			}
+
+			reported = append(reported, sent)
+			lastErr = err
+
+			return nil
+		})
+		defer SetDeviceSideProgressCb(nil, nil)
+
+		fi := &FileInfo{ObjectId: 7, Size: deviceSideProgressMinSize}
+
+		objectId, err := withDeviceSideProgress(nil, fi, func() (uint32, error) {
+			time.Sleep(deviceSideProgressInterval + 100*time.Millisecond)
+
+			return 9, nil
+		})
+		So(err, ShouldBeNil)
+		So(objectId, ShouldEqual, 9)
+
+		mu.Lock()
+		So(len(reported), ShouldBeGreaterThanOrEqualTo, 3)
+		So(reported[0], ShouldEqual, 0)
+		So(reported[1], ShouldBeGreaterThan, 0)
+		So(reported, ShouldNotContain, int64(-1))
+		So(reported[len(reported)-1], ShouldEqual, deviceSideProgressMinSize)
+		mu.Unlock()
+
+		// the measured rate is kept for the next copy
+		So(deviceSideProgresses.m[nil].rate, ShouldBeGreaterThan, 0)
+
+		// the failed copies report the error
+		_, err = withDeviceSideProgress(nil, fi, func() (uint32, error) {
+			return 0, fmt.Errorf("copy failed")
+		})
+		So(err, ShouldBeError)
+
+		mu.Lock()
+		So(lastErr, ShouldBeError)
+		mu.Unlock()
+
+		// the small files are not reported
+		mu.Lock()
+		reported = nil
+		mu.Unlock()
+
+		_, err = withDeviceSideProgress(nil, &FileInfo{ObjectId: 7, Size: 10}, func() (uint32, error) {
+			return 9, nil
+		})
+		So(err, ShouldBeNil)
+		So(reported, ShouldBeEmpty)
+	})
+}
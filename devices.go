@@ -0,0 +1,83 @@
+package mtpx
+
+import (
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/ganeshrvel/usb"
+	"regexp"
+	"strings"
+)
+
+// List the connected MTP devices so that the users can pick one when more than one phone is plugged in
+// the devices are not kept open, pass the chosen one to [InitializeDevice]
+// the devices which can't be opened (eg: held by another application) are left out
+func ListDevices() ([]DeviceDescriptor, error) {
+	ctx := usb.NewContext()
+	defer ctx.Exit()
+
+	candidates, err := mtp.FindDevices(ctx)
+	if err != nil {
+		return nil, MtpDetectFailedError{error: err}
+	}
+
+	devices := []DeviceDescriptor{}
+	for _, dev := range candidates {
+		if d, ok := describeDevice(dev); ok {
+			devices = append(devices, d)
+		}
+
+		dev.Done()
+	}
+
+	return devices, nil
+}
+
+// open the device [descriptor] returned by [ListDevices]
+// same as [Initialize] with [Init.Device] set
+func InitializeDevice(descriptor DeviceDescriptor) (*mtp.Device, error) {
+	return Initialize(Init{Device: &descriptor})
+}
+
+// read the usb descriptors of the device
+func describeDevice(dev *mtp.Device) (DeviceDescriptor, bool) {
+	if err := dev.Open(); err != nil {
+		return DeviceDescriptor{}, false
+	}
+	defer dev.Close()
+
+	info, err := dev.GetUsbInfo()
+	if err != nil {
+		return DeviceDescriptor{}, false
+	}
+
+	return DeviceDescriptor{
+		VendorId:     info.IdVendor,
+		ProductId:    info.IdProduct,
+		Serial:       info.SerialNumber,
+		Manufacturer: info.Manufacturer,
+		Product:      info.Product,
+		FriendlyName: friendlyDeviceName(info.Manufacturer, info.Product),
+	}, true
+}
+
+// name of the device made out of its manufacturer and product names
+// the manufacturer is left out if the product name already starts with it, eg: "Samsung" and "Samsung Galaxy S20"
+func friendlyDeviceName(manufacturer, product string) string {
+	manufacturer = strings.TrimSpace(manufacturer)
+	product = strings.TrimSpace(product)
+
+	if manufacturer == "" || strings.HasPrefix(strings.ToLower(product), strings.ToLower(manufacturer)) {
+		return product
+	}
+
+	if product == "" {
+		return manufacturer
+	}
+
+	return fmt.Sprintf("%s %s", manufacturer, product)
+}
+
+// pattern matching the mtp id (manufacturer, product and serial number) of the device [d]
+func devicePattern(d *DeviceDescriptor) string {
+	return fmt.Sprintf("^%s$", regexp.QuoteMeta(strings.Join([]string{d.Manufacturer, d.Product, d.Serial}, " ")))
+}
@@ -0,0 +1,51 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"log"
+	"regexp"
+	"testing"
+)
+
+func TestListDevices(t *testing.T) {
+	Convey("Test ListDevices", t, func() {
+		devices, err := ListDevices()
+		So(err, ShouldBeNil)
+		So(devices, ShouldNotBeNil)
+	})
+
+	Convey("Test friendlyDeviceName", t, func() {
+		So(friendlyDeviceName("Google", "Pixel 4a"), ShouldEqual, "Google Pixel 4a")
+		So(friendlyDeviceName("SAMSUNG", "Samsung Galaxy S20"), ShouldEqual, "Samsung Galaxy S20")
+		So(friendlyDeviceName(" ", "Pixel 4a "), ShouldEqual, "Pixel 4a")
+		So(friendlyDeviceName("Garmin", ""), ShouldEqual, "Garmin")
+	})
+
+	Convey("Test devicePattern", t, func() {
+		re := regexp.MustCompile(devicePattern(&DeviceDescriptor{Manufacturer: "Google", Product: "Pixel (4a)", Serial: "0A1B"}))
+
+		So(re.MatchString("Google Pixel (4a) 0A1B"), ShouldBeTrue)
+		So(re.MatchString("Google Pixel (4a) 0A1B2"), ShouldBeFalse)
+		So(re.MatchString("Google Pixel 4a 0A1B"), ShouldBeFalse)
+	})
+}
+
+func TestInitializeDevice(t *testing.T) {
+	devices, err := ListDevices()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	Convey("Test InitializeDevice", t, func() {
+		So(len(devices), ShouldBeGreaterThan, 0)
+
+		dev, err := InitializeDevice(devices[0])
+		So(err, ShouldBeNil)
+
+		info, err := dev.GetUsbInfo()
+		So(err, ShouldBeNil)
+		So(info.SerialNumber, ShouldEqual, devices[0].Serial)
+
+		Dispose(dev)
+	})
+}
@@ -0,0 +1,171 @@
+package mtpx
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/ganeshrvel/usb"
+	"io/ioutil"
+	"time"
+)
+
+// report the usb link of the device and benchmark the transfers over it
+// a temp object is written to the temp directory of the storage, read back and deleted (see [CreateTempObject])
+// compare [DiagnosticsReport.WriteSpeed] and [DiagnosticsReport.ReadSpeed] with [DiagnosticsReport.LinkSpeed] to tell a slow link
+// (eg: a usb 2.0 cable on a usb 3.0 device) from a slow transfer
+// note: the link is looked up by the usb ids of the device, the first one is reported if several identical devices are plugged in
+func Diagnostics(dev *mtp.Device, opts DiagnosticsOptions) (report *DiagnosticsReport, err error) {
+	report, err = usbLinkInfo(dev)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.BenchmarkSize < 0 {
+		return report, nil
+	}
+
+	storageId := opts.StorageId
+	if storageId == 0 {
+		if storageId, err = firstWritableStorage(dev); err != nil {
+			return nil, err
+		}
+	}
+
+	size := opts.BenchmarkSize
+	if size == 0 {
+		size = defaultBenchmarkSize
+	}
+
+	op := &OperationInfo{Type: DiagnosticsOp, Mutating: true, StorageId: storageId, Destination: tempDirectoryPath}
+
+	err = runMiddlewares(dev, op, func() error {
+		return benchmarkTransfers(dev, storageId, size, report)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// speed and packet sizes of the usb link of the device
+func usbLinkInfo(dev *mtp.Device) (*DiagnosticsReport, error) {
+	usbInfo, err := dev.GetUsbInfo()
+	if err != nil {
+		return nil, DeviceInfoError{error: err}
+	}
+
+	ctx := usb.NewContext()
+	defer ctx.Exit()
+
+	devices, err := ctx.GetDeviceList()
+	if err != nil {
+		return nil, DeviceInfoError{error: err}
+	}
+	if len(devices) > 0 {
+		defer devices.Done()
+	}
+
+	for _, d := range devices {
+		descr, err := d.GetDeviceDescriptor()
+		if err != nil || descr.IdVendor != usbInfo.IdVendor || descr.IdProduct != usbInfo.IdProduct {
+			continue
+		}
+
+		report := &DiagnosticsReport{Speed: usbSpeed(d.GetDeviceSpeed())}
+		report.LinkSpeed = usbLinkSpeeds[report.Speed]
+		report.LinkLimited = report.Speed == UsbSpeedLow || report.Speed == UsbSpeedFull
+
+		if config, err := d.GetActiveConfigDescriptor(); err == nil {
+			report.MaxPacketSizeIn, report.MaxPacketSizeOut = bulkPacketSizes(config)
+		}
+
+		return report, nil
+	}
+
+	return nil, DeviceInfoError{error: fmt.Errorf("the usb device %04x:%04x was not found", usbInfo.IdVendor, usbInfo.IdProduct)}
+}
+
+func usbSpeed(speed int) UsbSpeed {
+	if s, ok := usbSpeeds[speed]; ok {
+		return s
+	}
+
+	return UsbSpeedUnknown
+}
+
+// maximum packet sizes of the bulk endpoints of the first interface which has a pair of them, ie: the mtp interface
+func bulkPacketSizes(config *usb.ConfigDescriptor) (in, out int) {
+	for _, iface := range config.Interfaces {
+		for _, alt := range iface.AltSetting {
+			in, out = 0, 0
+
+			for _, ep := range alt.EndPoints {
+				if ep.TransferType() != usb.TRANSFER_TYPE_BULK {
+					continue
+				}
+
+				if ep.Direction() == usb.ENDPOINT_IN {
+					in = int(ep.MaxPacketSize)
+				} else {
+					out = int(ep.MaxPacketSize)
+				}
+			}
+
+			if in > 0 && out > 0 {
+				return in, out
+			}
+		}
+	}
+
+	return 0, 0
+}
+
+// write an object of [size] bytes to the temp directory of the storage, read it back and delete it
+// the transfers go straight to the device so that the timings are not skewed by the cache, the quota and the retries of this package
+func benchmarkTransfers(dev *mtp.Device, storageId uint32, size int64, report *DiagnosticsReport) error {
+	if err := checkStorageWritable(dev, storageId, false); err != nil {
+		return err
+	}
+
+	if size > 0xFFFFFFFF {
+		return TooLargeError{error: fmt.Errorf("the benchmark size is limited to 4 GiB: %d", size)}
+	}
+
+	parentId, err := makeNestedDirectory(dev, storageId, tempDirectoryPath)
+	if err != nil {
+		return err
+	}
+
+	fObj := newFileObjectInfo(storageId, parentId, tempObjectName("benchmark-*"), mtp.OFC_Undefined, size, time.Now())
+
+	_, _, objectId, err := dev.SendObjectInfo(storageId, parentId, fObj)
+	if err != nil {
+		return SendObjectError{error: err}
+	}
+
+	defer func() {
+		_ = dev.DeleteObject(objectId)
+		invalidateCachedListing(dev, storageId, parentId)
+	}()
+
+	data := bytes.Repeat([]byte{0xa5}, int(size))
+
+	startTime := time.Now()
+	if err := dev.SendObject(bytes.NewReader(data), size, mtp.EmptyProgressFunc); err != nil {
+		return SendObjectError{error: err}
+	}
+	report.WriteDuration = time.Since(startTime)
+	report.WriteSpeed = transferRate(size, startTime)
+
+	startTime = time.Now()
+	if err := dev.GetObject(objectId, ioutil.Discard, mtp.EmptyProgressFunc); err != nil {
+		return FileTransferError{error: err}
+	}
+	report.ReadDuration = time.Since(startTime)
+	report.ReadSpeed = transferRate(size, startTime)
+
+	report.BenchmarkSize = size
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package mtpx
+
+import (
+	"github.com/ganeshrvel/usb"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestDiagnostics(t *testing.T) {
+	Convey("Test usbSpeed", t, func() {
+		So(usbSpeed(3), ShouldEqual, UsbSpeedHigh)
+		So(usbSpeed(0), ShouldEqual, UsbSpeedUnknown)
+		So(usbSpeed(42), ShouldEqual, UsbSpeedUnknown)
+	})
+
+	Convey("Test bulkPacketSizes", t, func() {
+		config := &usb.ConfigDescriptor{
+			Interfaces: []usb.Interface{
+				{AltSetting: []usb.InterfaceDescriptor{{
+					// a vendor interface with a single bulk endpoint
+					EndPoints: []usb.EndpointDescriptor{
+						{EndpointAddress: 0x81, Attributes: usb.TRANSFER_TYPE_BULK, MaxPacketSize: 64},
+					},
+				}}},
+				{AltSetting: []usb.InterfaceDescriptor{{
+					EndPoints: []usb.EndpointDescriptor{
+						{EndpointAddress: 0x81, Attributes: usb.TRANSFER_TYPE_BULK, MaxPacketSize: 512},
+						{EndpointAddress: 0x01, Attributes: usb.TRANSFER_TYPE_BULK, MaxPacketSize: 1024},
+						{EndpointAddress: 0x82, Attributes: usb.TRANSFER_TYPE_INTERRUPT, MaxPacketSize: 28},
+					},
+				}}},
+			},
+		}
+
+		in, out := bulkPacketSizes(config)
+		So(in, ShouldEqual, 512)
+		So(out, ShouldEqual, 1024)
+
+		in, out = bulkPacketSizes(&usb.ConfigDescriptor{})
+		So(in, ShouldEqual, 0)
+		So(out, ShouldEqual, 0)
+	})
+}
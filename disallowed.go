@@ -0,0 +1,73 @@
+package mtpx
+
+import (
+	"log"
+	"sync/atomic"
+)
+
+var disallowedFilesPolicy atomic.Value
+
+// current policy for the disallowed files
+func FetchDisallowedFilesPolicy() DisallowedFilesPolicy {
+	if policy, ok := disallowedFilesPolicy.Load().(DisallowedFilesPolicy); ok {
+		return policy
+	}
+
+	return DisallowedFilesPolicy{Names: defaultDisallowedFiles, Action: DisallowedFilesSkip}
+}
+
+// set which files are disallowed and what happens to them during the uploads and downloads
+// the [Walk] family uses [policy.Names] for [skipDisallowedFiles] irrespective of [policy.Action]
+// the default policy skips the [defaultDisallowedFiles]
+func SetDisallowedFilesPolicy(policy DisallowedFilesPolicy) {
+	if policy.Action == "" {
+		policy.Action = DisallowedFilesSkip
+	}
+
+	disallowedFilesPolicy.Store(policy)
+}
+
+// check whether [filename] is in the disallowed files list
+func isDisallowedFiles(filename string) bool {
+	contains, _ := StringContains(FetchDisallowedFilesPolicy().Names, filename)
+
+	return contains
+}
+
+// check whether [filename] is left out of the transfers by the disallowed files policy
+func isSkippedDisallowedFile(filename string) bool {
+	return FetchDisallowedFilesPolicy().skips(filename)
+}
+
+// like [isSkippedDisallowedFile], the skipped file is reported if the policy asks for it
+func skipDisallowedFile(fullPath, filename string) bool {
+	return FetchDisallowedFilesPolicy().skipFile(fullPath, filename)
+}
+
+// check whether [filename] is left out of the transfers by the policy
+func (policy DisallowedFilesPolicy) skips(filename string) bool {
+	if policy.Action == DisallowedFilesAllow {
+		return false
+	}
+
+	contains, _ := StringContains(policy.Names, filename)
+
+	return contains
+}
+
+// like [DisallowedFilesPolicy.skips], the skipped file is reported if the policy asks for it
+func (policy DisallowedFilesPolicy) skipFile(fullPath, filename string) bool {
+	if !policy.skips(filename) {
+		return false
+	}
+
+	if policy.Action == DisallowedFilesWarn {
+		if policy.WarnCb != nil {
+			policy.WarnCb(fullPath)
+		} else {
+			log.Printf("[disallowed] skipped: %s", fullPath)
+		}
+	}
+
+	return true
+}
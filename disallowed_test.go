@@ -0,0 +1,37 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestDisallowedFilesPolicy(t *testing.T) {
+	Convey("Test the default policy", t, func() {
+		So(FetchDisallowedFilesPolicy().Action, ShouldEqual, DisallowedFilesSkip)
+		So(isDisallowedFiles(".DS_Store"), ShouldBeTrue)
+		So(skipDisallowedFile("/a/.DS_Store", ".DS_Store"), ShouldBeTrue)
+		So(skipDisallowedFile("/a/b.txt", "b.txt"), ShouldBeFalse)
+	})
+
+	Convey("Test SetDisallowedFilesPolicy", t, func() {
+		defer SetDisallowedFilesPolicy(DisallowedFilesPolicy{Names: defaultDisallowedFiles})
+
+		var warned []string
+		SetDisallowedFilesPolicy(DisallowedFilesPolicy{
+			Names:  []string{"desktop.ini"},
+			Action: DisallowedFilesWarn,
+			WarnCb: func(fullPath string) {
+				warned = append(warned, fullPath)
+			},
+		})
+
+		So(isDisallowedFiles(".DS_Store"), ShouldBeFalse)
+		So(skipDisallowedFile("/a/desktop.ini", "desktop.ini"), ShouldBeTrue)
+		So(warned, ShouldResemble, []string{"/a/desktop.ini"})
+
+		SetDisallowedFilesPolicy(DisallowedFilesPolicy{Names: []string{"desktop.ini"}, Action: DisallowedFilesAllow})
+
+		So(isDisallowedFiles("desktop.ini"), ShouldBeTrue)
+		So(skipDisallowedFile("/a/desktop.ini", "desktop.ini"), ShouldBeFalse)
+	})
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd && !dragonfly && !windows
+// +build !linux,!darwin,!freebsd,!dragonfly,!windows
+
+package mtpx
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// the free space of the local file systems can't be read on this system
+func localFreeSpace(localPath string) (uint64, error) {
+	return 0, LocalFileError{error: fmt.Errorf("the free space of %s can't be read on %s", localPath, runtime.GOOS)}
+}
@@ -0,0 +1,17 @@
+//go:build linux || darwin || freebsd || dragonfly
+// +build linux darwin freebsd dragonfly
+
+package mtpx
+
+import "syscall"
+
+// free space (in bytes) available to the user on the local file system holding [localPath]
+func localFreeSpace(localPath string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(localPath, &stat); err != nil {
+		return 0, LocalFileError{error: err}
+	}
+
+	// the types of the fields vary between the systems, eg: [stat.Bavail] is signed on freebsd
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,24 @@
+package mtpx
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var getDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// free space (in bytes) available to the user on the local volume holding [localPath]
+func localFreeSpace(localPath string) (uint64, error) {
+	p, err := syscall.UTF16PtrFromString(localPath)
+	if err != nil {
+		return 0, LocalFileError{error: err}
+	}
+
+	var freeBytesAvailable uint64
+	r, _, err := getDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&freeBytesAvailable)), 0, 0)
+	if r == 0 {
+		return 0, LocalFileError{error: err}
+	}
+
+	return freeBytesAvailable, nil
+}
@@ -0,0 +1,656 @@
+package mtpx
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/xml"
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"mime"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const mediaServerDeviceType = "urn:schemas-upnp-org:device:MediaServer:1"
+
+const contentDirectoryServiceType = "urn:schemas-upnp-org:service:ContentDirectory:1"
+
+const connectionManagerServiceType = "urn:schemas-upnp-org:service:ConnectionManager:1"
+
+// objectId of the top level container of the media server
+const mediaRootId = "0"
+
+// Serve the media folders of the device to the smart TVs and the media players on the LAN as a DLNA media server
+// the server is announced on the network using SSDP, the players browse the folders through the UPnP ContentDirectory service
+// and the files are streamed with the range requests, see [ServeObject]
+// only the directories and the images, the videos and the audio files are listed, the hidden files are left out
+// the server runs until [MediaServer.Close] is called, close it before disposing the device
+func StartMediaServer(dev *mtp.Device, config MediaServerConfig) (*MediaServer, error) {
+	info, err := FetchDeviceInfo(dev)
+	if err != nil {
+		return nil, err
+	}
+
+	storageId := config.StorageId
+	if storageId == 0 {
+		storages, err := FetchStorages(dev)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(storages) < 1 {
+			return nil, NoStorageError{error: fmt.Errorf("no storage found")}
+		}
+
+		storageId = storages[0].Sid
+	}
+
+	if config.FriendlyName == "" {
+		config.FriendlyName = strings.TrimSpace(fmt.Sprintf("%s %s", info.Manufacturer, info.Model))
+	}
+
+	if len(config.Folders) < 1 {
+		config.Folders = DefaultMediaFolders
+	}
+
+	if config.NotifyInterval <= 0 {
+		config.NotifyInterval = defaultSsdpNotifyInterval
+	}
+
+	addr := config.Addr
+	if addr == "" {
+		addr = ":0"
+	}
+
+	s := &MediaServer{
+		dev:       dev,
+		storageId: storageId,
+		config:    config,
+		udn:       mediaServerUdn(info),
+		paths:     map[uint32]string{},
+		done:      make(chan struct{}),
+	}
+
+	for _, folder := range config.Folders {
+		fi, err := GetObjectFromPath(dev, storageId, folder)
+		if err != nil {
+			if _, ok := err.(InvalidPathError); ok {
+				continue
+			}
+
+			return nil, err
+		}
+
+		if !fi.IsDir {
+			continue
+		}
+
+		s.folders = append(s.folders, fi)
+		s.paths[fi.ObjectId] = fi.FullPath
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, MediaServerError{error: err}
+	}
+
+	s.listener = listener
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/description.xml", s.serveXml(s.deviceDescription))
+	mux.HandleFunc("/ContentDirectory.xml", s.serveXml(func() string { return contentDirectoryScpd }))
+	mux.HandleFunc("/ConnectionManager.xml", s.serveXml(func() string { return connectionManagerScpd }))
+	mux.HandleFunc("/ContentDirectory/control", s.handleContentDirectory)
+	mux.HandleFunc("/ConnectionManager/control", s.handleConnectionManager)
+	mux.HandleFunc("/ContentDirectory/event", handleEventSubscription)
+	mux.HandleFunc("/ConnectionManager/event", handleEventSubscription)
+	mux.HandleFunc("/media/", s.handleMedia)
+
+	s.server = &http.Server{Handler: mux}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		_ = s.server.Serve(listener)
+	}()
+
+	if err := s.startSsdp(); err != nil {
+		_ = s.server.Close()
+		s.wg.Wait()
+
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// address of the http server of the media server
+func (s *MediaServer) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// stop announcing the media server and shut down its http server
+func (s *MediaServer) Close() error {
+	var err error
+
+	s.closeOnce.Do(func() {
+		close(s.done)
+
+		s.sendByebye()
+		_ = s.ssdpConn.Close()
+
+		if cErr := s.server.Close(); cErr != nil {
+			err = MediaServerError{error: cErr}
+		}
+	})
+
+	s.wg.Wait()
+
+	return err
+}
+
+// unique device name of the media server, it stays the same across the sessions of the device
+// so that the players recognize the server again
+func mediaServerUdn(info *mtp.DeviceInfo) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("mtpx|%s|%s|%s", info.Manufacturer, info.Model, info.SerialNumber)))
+
+	// name based uuid, see rfc 4122
+	return formatUuid(sum[:16], 0x50)
+}
+
+// format the 16 bytes of [b] as a uuid of the [version], eg: "uuid:..."
+func formatUuid(b []byte, version byte) string {
+	b[6] = (b[6] & 0x0f) | version
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("uuid:%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+func (s *MediaServer) serveXml(body func() string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+		_, _ = fmt.Fprint(w, body())
+	}
+}
+
+func (s *MediaServer) deviceDescription() string {
+	return fmt.Sprintf(deviceDescriptionXml, mediaServerDeviceType, xmlEscape(s.config.FriendlyName), s.udn,
+		contentDirectoryServiceType, connectionManagerServiceType)
+}
+
+// answer the SOAP requests of the ContentDirectory service
+func (s *MediaServer) handleContentDirectory(w http.ResponseWriter, r *http.Request) {
+	action, req, err := parseSoapRequest(r)
+	if err != nil {
+		writeSoapFault(w, 402, "Invalid Args")
+
+		return
+	}
+
+	switch action {
+	case "Browse":
+		host := r.Host
+		if host == "" {
+			host = s.listener.Addr().String()
+		}
+
+		result, returned, total, err := s.browse(req, host)
+		if err != nil {
+			if _, ok := err.(InvalidPathError); ok {
+				writeSoapFault(w, 701, "No such object")
+			} else {
+				writeSoapFault(w, 501, "Action Failed")
+			}
+
+			return
+		}
+
+		writeSoapResponse(w, contentDirectoryServiceType, action, [][2]string{
+			{"Result", result},
+			{"NumberReturned", strconv.Itoa(returned)},
+			{"TotalMatches", strconv.Itoa(total)},
+			{"UpdateID", "0"},
+		})
+
+	case "GetSystemUpdateID":
+		writeSoapResponse(w, contentDirectoryServiceType, action, [][2]string{{"Id", "0"}})
+
+	case "GetSearchCapabilities":
+		writeSoapResponse(w, contentDirectoryServiceType, action, [][2]string{{"SearchCaps", ""}})
+
+	case "GetSortCapabilities":
+		writeSoapResponse(w, contentDirectoryServiceType, action, [][2]string{{"SortCaps", ""}})
+
+	default:
+		writeSoapFault(w, 401, "Invalid Action")
+	}
+}
+
+// answer the SOAP requests of the ConnectionManager service, a few players refuse a server without it
+func (s *MediaServer) handleConnectionManager(w http.ResponseWriter, r *http.Request) {
+	action, _, err := parseSoapRequest(r)
+	if err != nil {
+		writeSoapFault(w, 402, "Invalid Args")
+
+		return
+	}
+
+	switch action {
+	case "GetProtocolInfo":
+		contentTypes := map[string]bool{}
+		for _, contentType := range streamContentTypes {
+			contentTypes[contentType] = true
+		}
+
+		var source []string
+		for contentType := range contentTypes {
+			source = append(source, fmt.Sprintf("http-get:*:%s:*", contentType))
+		}
+		sort.Strings(source)
+
+		writeSoapResponse(w, connectionManagerServiceType, action, [][2]string{
+			{"Source", strings.Join(source, ",")},
+			{"Sink", ""},
+		})
+
+	case "GetCurrentConnectionIDs":
+		writeSoapResponse(w, connectionManagerServiceType, action, [][2]string{{"ConnectionIDs", "0"}})
+
+	default:
+		writeSoapFault(w, 401, "Invalid Action")
+	}
+}
+
+// the contents don't change while the server runs, the subscriptions are accepted and never notified
+func handleEventSubscription(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "SUBSCRIBE":
+		// a renewal carries the id of the subscription
+		sid := r.Header.Get("SID")
+		if sid == "" {
+			b := make([]byte, 16)
+			if _, err := rand.Read(b); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+
+				return
+			}
+
+			// random uuid, see rfc 4122
+			sid = formatUuid(b, 0x40)
+		}
+
+		w.Header().Set("SID", sid)
+		w.Header().Set("TIMEOUT", fmt.Sprintf("Second-%d", ssdpMaxAge))
+
+	case "UNSUBSCRIBE":
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// stream a file handed out by [MediaServer.browse]
+func (s *MediaServer) handleMedia(w http.ResponseWriter, r *http.Request) {
+	objectId, err := strconv.ParseUint(strings.TrimPrefix(r.URL.Path, "/media/"), 10, 32)
+	if err != nil {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	fullPath, ok := s.lookupPath(uint32(objectId))
+	if !ok {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	_ = ServeObject(s.dev, s.storageId, fullPath, w, r)
+}
+
+// list the objects requested by a Browse action
+// returns the DIDL-Lite document of the objects, the number of the objects in it and the total number of the matching objects
+func (s *MediaServer) browse(req *soapRequest, host string) (result string, returned, total int, err error) {
+	op := &OperationInfo{Type: BrowseMediaOp, StorageId: s.storageId, Sources: []string{req.ObjectID}}
+
+	err = runMiddlewares(s.dev, op, func() error {
+		var objects []*FileInfo
+		parentId := req.ObjectID
+
+		if req.BrowseFlag == "BrowseMetadata" {
+			if req.ObjectID == mediaRootId {
+				result = didlLite(s.rootContainer())
+				returned, total = 1, 1
+
+				return nil
+			}
+
+			fi, err := s.fetchObject(req.ObjectID)
+			if err != nil {
+				return err
+			}
+
+			parentId = s.parentId(fi)
+			objects = []*FileInfo{fi}
+		} else {
+			if objects, err = s.listObjects(req.ObjectID); err != nil {
+				return err
+			}
+		}
+
+		total = len(objects)
+		objects = paginate(objects, req.StartingIndex, req.RequestedCount)
+		returned = len(objects)
+
+		var entries []string
+		for _, fi := range objects {
+			entries = append(entries, didlObject(fi, parentId, host))
+		}
+
+		result = didlLite(entries...)
+
+		return nil
+	})
+
+	return result, returned, total, err
+}
+
+// children of the container [objectId] which can be played
+func (s *MediaServer) listObjects(objectId string) ([]*FileInfo, error) {
+	if objectId == mediaRootId {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		return append([]*FileInfo(nil), s.folders...), nil
+	}
+
+	fi, err := s.fetchObject(objectId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fi.IsDir {
+		return nil, InvalidPathError{error: fmt.Errorf("not a container: %s", objectId)}
+	}
+
+	lock := streamLock(s.dev)
+	lock.Lock()
+	children, err := listDirectory(s.dev, s.storageId, fi.ObjectId, fi.FullPath)
+	lock.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []*FileInfo
+	for _, child := range sortWalkObjects(children, true) {
+		if isHiddenFile(child.Name) || isDisallowedFiles(child.Name) {
+			continue
+		}
+
+		if _, ok := mediaUpnpClasses[child.Category]; !ok && !child.IsDir {
+			continue
+		}
+
+		objects = append(objects, child)
+	}
+
+	s.mu.Lock()
+	for _, child := range objects {
+		s.paths[child.ObjectId] = child.FullPath
+	}
+	s.mu.Unlock()
+
+	return objects, nil
+}
+
+// fetch an object which was handed out to the players
+func (s *MediaServer) fetchObject(objectId string) (*FileInfo, error) {
+	id, err := strconv.ParseUint(objectId, 10, 32)
+	if err != nil {
+		return nil, InvalidPathError{error: fmt.Errorf("invalid object id: %s", objectId)}
+	}
+
+	fullPath, ok := s.lookupPath(uint32(id))
+	if !ok {
+		return nil, InvalidPathError{error: fmt.Errorf("unknown object id: %s", objectId)}
+	}
+
+	lock := streamLock(s.dev)
+	lock.Lock()
+	defer lock.Unlock()
+
+	return GetObjectFromPath(s.dev, s.storageId, fullPath)
+}
+
+func (s *MediaServer) lookupPath(objectId uint32) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fullPath, ok := s.paths[objectId]
+
+	return fullPath, ok
+}
+
+// the folders are the children of the top level container
+func (s *MediaServer) parentId(fi *FileInfo) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, folder := range s.folders {
+		if folder.ObjectId == fi.ObjectId {
+			return mediaRootId
+		}
+	}
+
+	return strconv.FormatUint(uint64(fi.ParentId), 10)
+}
+
+func (s *MediaServer) rootContainer() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return fmt.Sprintf(`<container id="%s" parentID="-1" childCount="%d" restricted="1"><dc:title>%s</dc:title><upnp:class>object.container.storageFolder</upnp:class></container>`,
+		mediaRootId, len(s.folders), xmlEscape(s.config.FriendlyName))
+}
+
+// DIDL-Lite entry of the object
+func didlObject(fi *FileInfo, parentId, host string) string {
+	if fi.IsDir {
+		return fmt.Sprintf(`<container id="%d" parentID="%s" restricted="1"><dc:title>%s</dc:title><upnp:class>object.container.storageFolder</upnp:class></container>`,
+			fi.ObjectId, xmlEscape(parentId), xmlEscape(fi.Name))
+	}
+
+	upnpClass, ok := mediaUpnpClasses[fi.Category]
+	if !ok {
+		upnpClass = "object.item"
+	}
+
+	contentType := objectContentType(fi.Name)
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(fi.Name))
+	}
+
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	var date string
+	if !fi.ModTime.IsZero() {
+		date = fmt.Sprintf("<dc:date>%s</dc:date>", fi.ModTime.Format("2006-01-02T15:04:05"))
+	}
+
+	// DLNA.ORG_OP=01: the file can be seeked using the range requests
+	return fmt.Sprintf(`<item id="%d" parentID="%s" restricted="1"><dc:title>%s</dc:title><upnp:class>%s</upnp:class>%s<res size="%d" protocolInfo="http-get:*:%s:DLNA.ORG_OP=01">http://%s/media/%d</res></item>`,
+		fi.ObjectId, xmlEscape(parentId), xmlEscape(fi.Name), upnpClass, date, fi.Size, contentType, xmlEscape(host), fi.ObjectId)
+}
+
+func didlLite(entries ...string) string {
+	return `<DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/">` +
+		strings.Join(entries, "") + `</DIDL-Lite>`
+}
+
+// the objects from [start], up to [count] objects. if [count] is 0 then all of them
+func paginate(objects []*FileInfo, start, count int) []*FileInfo {
+	if start < 0 || start >= len(objects) {
+		return nil
+	}
+
+	objects = objects[start:]
+	if count > 0 && count < len(objects) {
+		objects = objects[:count]
+	}
+
+	return objects
+}
+
+// arguments of the SOAP actions, the arguments which an action doesn't take are left empty
+type soapRequest struct {
+	ObjectID       string
+	BrowseFlag     string
+	StartingIndex  int
+	RequestedCount int
+}
+
+// parse the action and the arguments of a SOAP request
+func parseSoapRequest(r *http.Request) (action string, req *soapRequest, err error) {
+	// eg: "urn:schemas-upnp-org:service:ContentDirectory:1#Browse"
+	soapAction := strings.Trim(r.Header.Get("SOAPACTION"), `"`)
+	if i := strings.LastIndex(soapAction, "#"); i > -1 {
+		action = soapAction[i+1:]
+	}
+
+	var envelope struct {
+		Body struct {
+			Action soapRequest `xml:",any"`
+		} `xml:"Body"`
+	}
+
+	if err := xml.NewDecoder(r.Body).Decode(&envelope); err != nil {
+		return action, nil, err
+	}
+
+	return action, &envelope.Body.Action, nil
+}
+
+func writeSoapResponse(w http.ResponseWriter, serviceType, action string, args [][2]string) {
+	var body strings.Builder
+	for _, arg := range args {
+		body.WriteString(fmt.Sprintf("<%s>%s</%s>", arg[0], xmlEscape(arg[1]), arg[0]))
+	}
+
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	_, _ = fmt.Fprintf(w, soapEnvelopeXml, fmt.Sprintf(`<u:%sResponse xmlns:u="%s">%s</u:%sResponse>`, action, serviceType, body.String(), action))
+}
+
+func writeSoapFault(w http.ResponseWriter, code int, description string) {
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	w.WriteHeader(http.StatusInternalServerError)
+	_, _ = fmt.Fprintf(w, soapEnvelopeXml, fmt.Sprintf(soapFaultXml, code, xmlEscape(description)))
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+
+	return b.String()
+}
+
+const soapEnvelopeXml = `<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>%s</s:Body></s:Envelope>`
+
+const soapFaultXml = `<s:Fault><faultcode>s:Client</faultcode><faultstring>UPnPError</faultstring><detail><UPnPError xmlns="urn:schemas-upnp-org:control-1-0"><errorCode>%d</errorCode><errorDescription>%s</errorDescription></UPnPError></detail></s:Fault>`
+
+const deviceDescriptionXml = `<?xml version="1.0" encoding="utf-8"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <specVersion><major>1</major><minor>0</minor></specVersion>
+  <device>
+    <deviceType>%s</deviceType>
+    <friendlyName>%s</friendlyName>
+    <manufacturer>mtpx</manufacturer>
+    <modelName>mtpx media server</modelName>
+    <UDN>%s</UDN>
+    <serviceList>
+      <service>
+        <serviceType>%s</serviceType>
+        <serviceId>urn:upnp-org:serviceId:ContentDirectory</serviceId>
+        <SCPDURL>/ContentDirectory.xml</SCPDURL>
+        <controlURL>/ContentDirectory/control</controlURL>
+        <eventSubURL>/ContentDirectory/event</eventSubURL>
+      </service>
+      <service>
+        <serviceType>%s</serviceType>
+        <serviceId>urn:upnp-org:serviceId:ConnectionManager</serviceId>
+        <SCPDURL>/ConnectionManager.xml</SCPDURL>
+        <controlURL>/ConnectionManager/control</controlURL>
+        <eventSubURL>/ConnectionManager/event</eventSubURL>
+      </service>
+    </serviceList>
+  </device>
+</root>`
+
+const contentDirectoryScpd = `<?xml version="1.0" encoding="utf-8"?>
+<scpd xmlns="urn:schemas-upnp-org:service-1-0">
+  <specVersion><major>1</major><minor>0</minor></specVersion>
+  <actionList>
+    <action><name>Browse</name><argumentList>
+      <argument><name>ObjectID</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_ObjectID</relatedStateVariable></argument>
+      <argument><name>BrowseFlag</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_BrowseFlag</relatedStateVariable></argument>
+      <argument><name>Filter</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_Filter</relatedStateVariable></argument>
+      <argument><name>StartingIndex</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_Index</relatedStateVariable></argument>
+      <argument><name>RequestedCount</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_Count</relatedStateVariable></argument>
+      <argument><name>SortCriteria</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_SortCriteria</relatedStateVariable></argument>
+      <argument><name>Result</name><direction>out</direction><relatedStateVariable>A_ARG_TYPE_Result</relatedStateVariable></argument>
+      <argument><name>NumberReturned</name><direction>out</direction><relatedStateVariable>A_ARG_TYPE_Count</relatedStateVariable></argument>
+      <argument><name>TotalMatches</name><direction>out</direction><relatedStateVariable>A_ARG_TYPE_Count</relatedStateVariable></argument>
+      <argument><name>UpdateID</name><direction>out</direction><relatedStateVariable>A_ARG_TYPE_UpdateID</relatedStateVariable></argument>
+    </argumentList></action>
+    <action><name>GetSystemUpdateID</name><argumentList>
+      <argument><name>Id</name><direction>out</direction><relatedStateVariable>SystemUpdateID</relatedStateVariable></argument>
+    </argumentList></action>
+    <action><name>GetSearchCapabilities</name><argumentList>
+      <argument><name>SearchCaps</name><direction>out</direction><relatedStateVariable>SearchCapabilities</relatedStateVariable></argument>
+    </argumentList></action>
+    <action><name>GetSortCapabilities</name><argumentList>
+      <argument><name>SortCaps</name><direction>out</direction><relatedStateVariable>SortCapabilities</relatedStateVariable></argument>
+    </argumentList></action>
+  </actionList>
+  <serviceStateTable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_ObjectID</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_BrowseFlag</name><dataType>string</dataType>
+      <allowedValueList><allowedValue>BrowseMetadata</allowedValue><allowedValue>BrowseDirectChildren</allowedValue></allowedValueList></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_Filter</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_Index</name><dataType>ui4</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_Count</name><dataType>ui4</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_SortCriteria</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_Result</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_UpdateID</name><dataType>ui4</dataType></stateVariable>
+    <stateVariable sendEvents="yes"><name>SystemUpdateID</name><dataType>ui4</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>SearchCapabilities</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>SortCapabilities</name><dataType>string</dataType></stateVariable>
+  </serviceStateTable>
+</scpd>`
+
+const connectionManagerScpd = `<?xml version="1.0" encoding="utf-8"?>
+<scpd xmlns="urn:schemas-upnp-org:service-1-0">
+  <specVersion><major>1</major><minor>0</minor></specVersion>
+  <actionList>
+    <action><name>GetProtocolInfo</name><argumentList>
+      <argument><name>Source</name><direction>out</direction><relatedStateVariable>SourceProtocolInfo</relatedStateVariable></argument>
+      <argument><name>Sink</name><direction>out</direction><relatedStateVariable>SinkProtocolInfo</relatedStateVariable></argument>
+    </argumentList></action>
+    <action><name>GetCurrentConnectionIDs</name><argumentList>
+      <argument><name>ConnectionIDs</name><direction>out</direction><relatedStateVariable>CurrentConnectionIDs</relatedStateVariable></argument>
+    </argumentList></action>
+  </actionList>
+  <serviceStateTable>
+    <stateVariable sendEvents="yes"><name>SourceProtocolInfo</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="yes"><name>SinkProtocolInfo</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="yes"><name>CurrentConnectionIDs</name><dataType>string</dataType></stateVariable>
+  </serviceStateTable>
+</scpd>`
@@ -0,0 +1,162 @@
+package mtpx
+
+import (
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMediaServer(t *testing.T) {
+	dev, err := Initialize(Init{})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	Convey("Testing StartMediaServer", t, func() {
+		s, err := StartMediaServer(dev, MediaServerConfig{Folders: []string{"/mtp-test-files", "/fake-dir"}})
+		So(err, ShouldBeNil)
+		defer s.Close()
+
+		baseUrl := fmt.Sprintf("http://%s", s.Addr().String())
+
+		resp, err := http.Get(baseUrl + "/description.xml")
+		So(err, ShouldBeNil)
+		body, _ := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		So(string(body), ShouldContainSubstring, s.udn)
+
+		// the folders which don't exist are left out
+		result := browseMediaServer(baseUrl, mediaRootId, "BrowseDirectChildren")
+		So(result, ShouldContainSubstring, "<TotalMatches>1</TotalMatches>")
+		So(result, ShouldContainSubstring, "mtp-test-files")
+
+		So(s.Close(), ShouldBeNil)
+	})
+
+	Dispose(dev)
+}
+
+// send a Browse request to the media server, returns the response
+func browseMediaServer(baseUrl, objectId, browseFlag string) string {
+	body := fmt.Sprintf(`<?xml version="1.0"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>
+<u:Browse xmlns:u="urn:schemas-upnp-org:service:ContentDirectory:1"><ObjectID>%s</ObjectID><BrowseFlag>%s</BrowseFlag><Filter>*</Filter><StartingIndex>0</StartingIndex><RequestedCount>0</RequestedCount><SortCriteria></SortCriteria></u:Browse>
+</s:Body></s:Envelope>`, objectId, browseFlag)
+
+	req, _ := http.NewRequest(http.MethodPost, baseUrl+"/ContentDirectory/control", strings.NewReader(body))
+	req.Header.Set("SOAPACTION", `"urn:schemas-upnp-org:service:ContentDirectory:1#Browse"`)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Panic(err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := ioutil.ReadAll(resp.Body)
+
+	return string(data)
+}
+
+func TestMediaServerContentDirectory(t *testing.T) {
+	s := &MediaServer{
+		config: MediaServerConfig{FriendlyName: "Pixel & Co"},
+		folders: []*FileInfo{
+			{Name: "DCIM", FullPath: "/DCIM", ObjectId: 10, IsDir: true},
+			{Name: "Music", FullPath: "/Music", ObjectId: 11, IsDir: true},
+		},
+		paths: map[uint32]string{10: "/DCIM", 11: "/Music"},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(s.handleContentDirectory))
+	defer server.Close()
+
+	browse := func(objectId, browseFlag string) string {
+		return browseMediaServer(server.URL, objectId, browseFlag)
+	}
+
+	Convey("Test browsing the top level | MediaServer", t, func() {
+		result := browse(mediaRootId, "BrowseDirectChildren")
+		So(result, ShouldContainSubstring, "<NumberReturned>2</NumberReturned>")
+		So(result, ShouldContainSubstring, "<TotalMatches>2</TotalMatches>")
+		So(result, ShouldContainSubstring, "&lt;container id=&#34;10&#34; parentID=&#34;0&#34;")
+
+		result = browse(mediaRootId, "BrowseMetadata")
+		So(result, ShouldContainSubstring, "<NumberReturned>1</NumberReturned>")
+		So(result, ShouldContainSubstring, "Pixel &amp;amp; Co")
+	})
+
+	Convey("Test browsing an unknown object | MediaServer", t, func() {
+		result := browse("999", "BrowseDirectChildren")
+		So(result, ShouldContainSubstring, "<errorCode>701</errorCode>")
+	})
+}
+
+func TestDidl(t *testing.T) {
+	Convey("Test the DIDL-Lite entries | didlObject", t, func() {
+		item := didlObject(&FileInfo{
+			Name:     "clip <1>.mp4",
+			ObjectId: 42,
+			Size:     2048,
+			Category: CategoryVideo,
+			ModTime:  time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+		}, "7", "192.168.1.2:8200")
+
+		So(item, ShouldEqual, `<item id="42" parentID="7" restricted="1"><dc:title>clip &lt;1&gt;.mp4</dc:title>`+
+			`<upnp:class>object.item.videoItem</upnp:class><dc:date>2021-01-02T03:04:05</dc:date>`+
+			`<res size="2048" protocolInfo="http-get:*:video/mp4:DLNA.ORG_OP=01">http://192.168.1.2:8200/media/42</res></item>`)
+
+		container := didlObject(&FileInfo{Name: "Camera", ObjectId: 7, IsDir: true}, "0", "")
+		So(container, ShouldStartWith, `<container id="7" parentID="0" restricted="1"><dc:title>Camera</dc:title>`)
+	})
+
+	Convey("Test paginate", t, func() {
+		objects := []*FileInfo{{ObjectId: 1}, {ObjectId: 2}, {ObjectId: 3}}
+
+		So(paginate(objects, 0, 0), ShouldHaveLength, 3)
+		So(paginate(objects, 1, 1)[0].ObjectId, ShouldEqual, 2)
+		So(paginate(objects, 2, 5), ShouldHaveLength, 1)
+		So(paginate(objects, 3, 0), ShouldBeEmpty)
+	})
+
+	Convey("Test the unique device name | mediaServerUdn", t, func() {
+		info := &mtp.DeviceInfo{Manufacturer: "Google", Model: "Pixel", SerialNumber: "ABC123"}
+
+		udn := mediaServerUdn(info)
+		So(regexp.MustCompile(`^uuid:[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(udn), ShouldBeTrue)
+		So(mediaServerUdn(info), ShouldEqual, udn)
+		So(mediaServerUdn(&mtp.DeviceInfo{Manufacturer: "Google", Model: "Pixel", SerialNumber: "XYZ"}), ShouldNotEqual, udn)
+	})
+}
+
+func TestSsdp(t *testing.T) {
+	Convey("Test parsing the searches | parseSsdpSearch", t, func() {
+		st, ok := parseSsdpSearch([]byte("M-SEARCH * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\nMAN: \"ssdp:discover\"\r\nMX: 2\r\nST: ssdp:all\r\n\r\n"))
+		So(ok, ShouldBeTrue)
+		So(st, ShouldEqual, "ssdp:all")
+
+		_, ok = parseSsdpSearch([]byte("NOTIFY * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\nNT: upnp:rootdevice\r\nNTS: ssdp:alive\r\n\r\n"))
+		So(ok, ShouldBeFalse)
+
+		_, ok = parseSsdpSearch([]byte("garbage"))
+		So(ok, ShouldBeFalse)
+	})
+
+	Convey("Test the search targets | MediaServer", t, func() {
+		s := &MediaServer{udn: "uuid:1234"}
+
+		So(s.searchTargets("ssdp:all"), ShouldHaveLength, 5)
+		So(s.searchTargets(mediaServerDeviceType), ShouldResemble, []string{mediaServerDeviceType})
+		So(s.searchTargets("urn:schemas-upnp-org:device:MediaRenderer:1"), ShouldBeEmpty)
+
+		So(s.usn("uuid:1234"), ShouldEqual, "uuid:1234")
+		So(s.usn("upnp:rootdevice"), ShouldEqual, "uuid:1234::upnp:rootdevice")
+	})
+}
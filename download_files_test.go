@@ -2,6 +2,7 @@ package mtpx
 
 import (
 	"fmt"
+	"github.com/ganeshrvel/go-mtpx/devicepath"
 	. "github.com/smartystreets/goconvey/convey"
 	"log"
 	"os"
@@ -123,7 +124,7 @@ func TestDownloadFiles(t *testing.T) {
 			"/mock_dir1/a.txt"}
 
 		_count := 0
-		err = filepath.Walk(getFullPath(destination, "mock_dir1"), func(path string, info os.FileInfo, err error) error {
+		err = filepath.Walk(devicepath.Join(destination, "mock_dir1"), func(path string, info os.FileInfo, err error) error {
 			So(path, ShouldEndWith, dirList1[_count])
 
 			_count += 1
@@ -131,6 +132,14 @@ func TestDownloadFiles(t *testing.T) {
 		})
 
 		So(err, ShouldBeNil)
+
+		// the modification date of the device file is kept
+		fi, err := GetObjectFromPath(dev, sid, "/mtp-test-files/mock_dir1/a.txt")
+		So(err, ShouldBeNil)
+
+		stat, err := os.Stat(devicepath.Join(destination, "mock_dir1/a.txt"))
+		So(err, ShouldBeNil)
+		So(stat.ModTime().Unix(), ShouldEqual, fi.ModTime.Unix())
 	})
 
 	Convey("Multiple directories | Random destination | DownloadFiles", t, func() {
@@ -803,7 +812,7 @@ func TestDownloadFiles(t *testing.T) {
 		}
 
 		_count := 0
-		err = filepath.Walk(getFullPath(destination, "4mb_txt_file"), func(path string, info os.FileInfo, err error) error {
+		err = filepath.Walk(devicepath.Join(destination, "4mb_txt_file"), func(path string, info os.FileInfo, err error) error {
 			So(path, ShouldEndWith, dirList1[_count])
 
 			_count += 1
@@ -917,7 +926,7 @@ func TestDownloadFiles(t *testing.T) {
 		}
 
 		_count := 0
-		err = filepath.Walk(getFullPath(destination, "4mb_txt_file"), func(path string, info os.FileInfo, err error) error {
+		err = filepath.Walk(devicepath.Join(destination, "4mb_txt_file"), func(path string, info os.FileInfo, err error) error {
 			So(path, ShouldEndWith, dirList1[_count])
 
 			_count += 1
@@ -1263,7 +1272,7 @@ func TestDownloadFiles(t *testing.T) {
 		}
 
 		_count := 0
-		err = filepath.Walk(getFullPath(destination, "4mb_txt_file"), func(path string, info os.FileInfo, err error) error {
+		err = filepath.Walk(devicepath.Join(destination, "4mb_txt_file"), func(path string, info os.FileInfo, err error) error {
 			So(path, ShouldEndWith, dirList1[_count])
 
 			_count += 1
@@ -1559,7 +1568,7 @@ func TestDownloadFiles(t *testing.T) {
 		}
 
 		_count := 0
-		err = filepath.Walk(getFullPath(destination, "4mb_txt_file"), func(path string, info os.FileInfo, err error) error {
+		err = filepath.Walk(devicepath.Join(destination, "4mb_txt_file"), func(path string, info os.FileInfo, err error) error {
 			So(path, ShouldEndWith, dirList1[_count])
 
 			_count += 1
@@ -1885,3 +1894,53 @@ func TestDownloadFiles(t *testing.T) {
 
 	Dispose(dev)
 }
+
+func TestDownloadFile(t *testing.T) {
+	dev, err := Initialize(Init{})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	storages, err := FetchStorages(dev)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	sid := storages[0].Sid
+
+	Convey("General | DownloadFile", t, func() {
+		destination := newTempMocksDir("test_DownloadFile", true)
+
+		fi, err := GetObjectFromPath(dev, sid, "/mtp-test-files/mock_dir1/a.txt")
+		So(err, ShouldBeNil)
+
+		// using the path, into a directory
+		n, err := DownloadFile(dev, sid, 0, "/mtp-test-files/mock_dir1/a.txt", destination)
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, fi.Size)
+
+		stat, err := os.Stat(filepath.Join(destination, "a.txt"))
+		So(err, ShouldBeNil)
+		So(stat.Size(), ShouldEqual, fi.Size)
+		So(stat.ModTime().Unix(), ShouldEqual, fi.ModTime.Unix())
+
+		// using the objectId, into a new file
+		localFile := filepath.Join(destination, "nested", "renamed.txt")
+		n, err = DownloadFile(dev, sid, fi.ObjectId, "", localFile)
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, fi.Size)
+		So(fileExistsLocal(localFile), ShouldBeTrue)
+	})
+
+	Convey("Directory | DownloadFile | should throw an error", t, func() {
+		_, err := DownloadFile(dev, sid, 0, "/mtp-test-files/mock_dir1", newTempMocksDir("test_DownloadFile", true))
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+	})
+
+	Convey("Invalid path | DownloadFile | should throw an error", t, func() {
+		_, err := DownloadFile(dev, sid, 0, "/mtp-test-files/fake_file.txt", newTempMocksDir("test_DownloadFile", true))
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+	})
+
+	Dispose(dev)
+}
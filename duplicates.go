@@ -0,0 +1,134 @@
+package mtpx
+
+import (
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/ganeshrvel/go-mtpx/devicepath"
+	"sort"
+	"sync"
+)
+
+// the duplicate policies of the devices, see [SetDuplicatePolicy]
+var deviceDuplicatePolicies = struct {
+	sync.Mutex
+	m map[*mtp.Device]DuplicatePolicy
+}{m: map[*mtp.Device]DuplicatePolicy{}}
+
+// current policy of the device used to pick one of the objects with the same name in a directory
+func FetchDuplicatePolicy(dev *mtp.Device) DuplicatePolicy {
+	deviceDuplicatePolicies.Lock()
+	defer deviceDuplicatePolicies.Unlock()
+
+	if policy, ok := deviceDuplicatePolicies.m[dev]; ok {
+		return policy
+	}
+
+	return DuplicateFirst
+}
+
+// set how a path of the device is resolved when more than one object in a directory has the name (mtp doesn't forbid it)
+// it applies to every component of the path, eg: in [GetObjectFromPath]. use [ResolveAll] to fetch all of them
+// the default is [DuplicateFirst]
+func SetDuplicatePolicy(dev *mtp.Device, policy DuplicatePolicy) {
+	deviceDuplicatePolicies.Lock()
+	defer deviceDuplicatePolicies.Unlock()
+
+	if policy == "" || policy == DuplicateFirst {
+		delete(deviceDuplicatePolicies.m, dev)
+
+		return
+	}
+
+	deviceDuplicatePolicies.m[dev] = policy
+}
+
+func disposeDuplicatePolicy(dev *mtp.Device) {
+	SetDuplicatePolicy(dev, DuplicateFirst)
+}
+
+// fetch all the objects at [fullPath]
+// the parent directories are resolved as usual (see [SetDuplicatePolicy]), every object in the parent directory which
+// matches the last component of the path is returned, ordered by their objectId
+// returns an [InvalidPathError] if there are none
+func ResolveAll(dev *mtp.Device, storageId uint32, fullPath string) ([]*FileInfo, error) {
+	_fullPath := devicepath.Clean(fullPath)
+
+	if fullPath == "" || _fullPath == devicepath.Separator {
+		fi, err := GetObjectFromPath(dev, storageId, fullPath)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*FileInfo{fi}, nil
+	}
+
+	parentPath, name := devicepath.Split(_fullPath)
+
+	parent, err := GetObjectFromPath(dev, storageId, parentPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if !parent.IsDir {
+		return nil, InvalidPathError{error: fmt.Errorf("path not found: %s", fullPath)}
+	}
+
+	candidates, err := matchingObjects(dev, storageId, parent.ObjectId, name, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(candidates) < 1 {
+		return nil, InvalidPathError{error: fmt.Errorf("path not found: %s", fullPath)}
+	}
+
+	sortByObjectId(candidates)
+
+	results := make([]*FileInfo, 0, len(candidates))
+	for _, fi := range candidates {
+		// the cached listings are shared, don't touch their objects
+		_fi := *fi
+		_fi.ParentPath = parentPath
+		_fi.FullPath = devicepath.Join(parentPath, fi.Name)
+
+		results = append(results, &_fi)
+	}
+
+	return results, nil
+}
+
+// pick one of the objects named [filename] in a directory using [policy]
+func pickDuplicate(candidates []*FileInfo, filename string, policy DuplicatePolicy) (*FileInfo, error) {
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+
+	picked := candidates[0]
+
+	switch policy {
+	case DuplicateError:
+		return nil, AmbiguousPathError{error: fmt.Errorf("%d objects are named %s", len(candidates), filename)}
+
+	case DuplicateNewest:
+		for _, fi := range candidates[1:] {
+			if fi.ModTime.After(picked.ModTime) || (fi.ModTime.Equal(picked.ModTime) && fi.ObjectId < picked.ObjectId) {
+				picked = fi
+			}
+		}
+
+	default:
+		for _, fi := range candidates[1:] {
+			if fi.ObjectId < picked.ObjectId {
+				picked = fi
+			}
+		}
+	}
+
+	return picked, nil
+}
+
+func sortByObjectId(objects []*FileInfo) {
+	sort.SliceStable(objects, func(i, j int) bool {
+		return objects[i].ObjectId < objects[j].ObjectId
+	})
+}
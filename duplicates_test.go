@@ -0,0 +1,76 @@
+package mtpx
+
+import (
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	. "github.com/smartystreets/goconvey/convey"
+	"log"
+	"testing"
+	"time"
+)
+
+func TestPickDuplicate(t *testing.T) {
+	now := time.Now()
+	candidates := []*FileInfo{
+		{ObjectId: 12, Name: "a.txt", ModTime: now.Add(-time.Hour)},
+		{ObjectId: 10, Name: "a.txt", ModTime: now.Add(-2 * time.Hour)},
+		{ObjectId: 14, Name: "a.txt", ModTime: now},
+		{ObjectId: 11, Name: "a.txt", ModTime: now},
+	}
+
+	Convey("Test picking the first object | pickDuplicate", t, func() {
+		fi, err := pickDuplicate(candidates, "a.txt", DuplicateFirst)
+		So(err, ShouldBeNil)
+		So(fi.ObjectId, ShouldEqual, 10)
+	})
+
+	Convey("Test picking the newest object | pickDuplicate", t, func() {
+		fi, err := pickDuplicate(candidates, "a.txt", DuplicateNewest)
+		So(err, ShouldBeNil)
+		So(fi.ObjectId, ShouldEqual, 11)
+	})
+
+	Convey("Test refusing the duplicates | pickDuplicate | Should throw an error", t, func() {
+		_, err := pickDuplicate(candidates, "a.txt", DuplicateError)
+		So(err, ShouldHaveSameTypeAs, AmbiguousPathError{})
+
+		fi, err := pickDuplicate(candidates[:1], "a.txt", DuplicateError)
+		So(err, ShouldBeNil)
+		So(fi.ObjectId, ShouldEqual, 12)
+	})
+
+	Convey("Test the default policy | FetchDuplicatePolicy", t, func() {
+		So(FetchDuplicatePolicy(nil), ShouldEqual, DuplicateFirst)
+
+		SetDuplicatePolicy(nil, DuplicateNewest)
+		defer disposeDuplicatePolicy(nil)
+
+		So(FetchDuplicatePolicy(nil), ShouldEqual, DuplicateNewest)
+		So(FetchDuplicatePolicy(&mtp.Device{}), ShouldEqual, DuplicateFirst)
+	})
+}
+
+func TestResolveAll(t *testing.T) {
+	dev, err := Initialize(Init{})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	storages, err := FetchStorages(dev)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	sid := storages[0].Sid
+
+	Convey("Testing ResolveAll", t, func() {
+		results, err := ResolveAll(dev, sid, "/mtp-test-files/mock_dir1/a.txt")
+		So(err, ShouldBeNil)
+		So(results, ShouldHaveLength, 1)
+		So(results[0].FullPath, ShouldEqual, "/mtp-test-files/mock_dir1/a.txt")
+
+		_, err = ResolveAll(dev, sid, "/mtp-test-files/mock_dir1/fake.txt")
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+	})
+
+	Dispose(dev)
+}
@@ -6,3 +6,204 @@ const (
 	InProgress TransferStatus = "InProgress"
 	Completed  TransferStatus = "Completed"
 )
+
+type WatchEventType string
+
+const (
+	ObjectAdded   WatchEventType = "ObjectAdded"
+	ObjectRemoved WatchEventType = "ObjectRemoved"
+	ObjectChanged WatchEventType = "ObjectChanged"
+)
+
+type DeviceEventType string
+
+const (
+	DeviceConnected    DeviceEventType = "DeviceConnected"
+	DeviceDisconnected DeviceEventType = "DeviceDisconnected"
+)
+
+type MtpEventType string
+
+const (
+	EventObjectAdded    MtpEventType = "EventObjectAdded"
+	EventObjectRemoved  MtpEventType = "EventObjectRemoved"
+	EventStorageAdded   MtpEventType = "EventStorageAdded"
+	EventStorageRemoved MtpEventType = "EventStorageRemoved"
+	EventStorageChanged MtpEventType = "EventStorageChanged"
+)
+
+type FsckIssueType string
+
+const (
+	OrphanObject  FsckIssueType = "OrphanObject"
+	DuplicateName FsckIssueType = "DuplicateName"
+	ObjectCycle   FsckIssueType = "ObjectCycle"
+)
+
+type ConflictPolicy string
+
+const (
+	ConflictDefault  ConflictPolicy = ""
+	ConflictKeepBoth ConflictPolicy = "KeepBoth"
+)
+
+type TransferDirection string
+
+const (
+	Download TransferDirection = "Download"
+	Upload   TransferDirection = "Upload"
+)
+
+type OperationType string
+
+const (
+	MakeDirectoryOp            OperationType = "MakeDirectory"
+	DeleteFileOp               OperationType = "DeleteFile"
+	DeleteDirectoryContentsOp  OperationType = "DeleteDirectoryContents"
+	DeleteDirectoryRecursiveOp OperationType = "DeleteDirectoryRecursive"
+	RenameFileOp               OperationType = "RenameFile"
+	MoveFileOp                 OperationType = "MoveFile"
+	MovePathOp                 OperationType = "MovePath"
+	CopyFileOp                 OperationType = "CopyFile"
+	UpdateObjectInfoOp         OperationType = "UpdateObjectInfo"
+	UploadFilesOp              OperationType = "UploadFiles"
+	UploadFileOp               OperationType = "UploadFile"
+	DownloadFilesOp            OperationType = "DownloadFiles"
+	DownloadFileOp             OperationType = "DownloadFile"
+	WriteFileOp                OperationType = "WriteFile"
+	DiagnosticsOp              OperationType = "Diagnostics"
+	HashFilesOp                OperationType = "HashFiles"
+	StreamObjectOp             OperationType = "StreamObject"
+	BrowseMediaOp              OperationType = "BrowseMedia"
+	VerifyCacheOp              OperationType = "VerifyCache"
+
+	// reported by the heartbeats only, [Walk] does not run through the middlewares
+	WalkOp OperationType = "Walk"
+)
+
+type CopyPlanGroupKind string
+
+const (
+	// a camera RAW file with its JPEG companion and XMP sidecar
+	GroupRawPair CopyPlanGroupKind = "RawPair"
+
+	// frames of a burst shot, the cover frame is the primary
+	GroupBurst CopyPlanGroupKind = "Burst"
+
+	// a still image with its short video clip, the image is the primary
+	// the motion photos which embed the video in the image are a group of their own
+	GroupMotionPhoto CopyPlanGroupKind = "MotionPhoto"
+)
+
+type FileResultStatus string
+
+const (
+	FileTransferred FileResultStatus = "Transferred"
+
+	// left out by the disallowed files policy
+	FileSkipped FileResultStatus = "Skipped"
+
+	FileFailed FileResultStatus = "Failed"
+)
+
+type SoundKind string
+
+const (
+	SoundRingtone     SoundKind = "Ringtone"
+	SoundNotification SoundKind = "Notification"
+	SoundAlarm        SoundKind = "Alarm"
+)
+
+type PathMatchMode string
+
+const (
+	// the path components are matched case-insensitively
+	PathMatchCaseInsensitive PathMatchMode = "CaseInsensitive"
+
+	// the path components are matched byte for byte against the device filenames
+	PathMatchExact PathMatchMode = "Exact"
+
+	// the path components are matched case-insensitively, ignoring the trailing spaces and dots (like Windows does)
+	PathMatchNormalized PathMatchMode = "Normalized"
+)
+
+// picks one of the objects with the same name in a directory, see [SetDuplicatePolicy]
+type DuplicatePolicy string
+
+const (
+	// the object with the lowest objectId, usually the one created first
+	// the uncached lookups take the first object listed by the device, which is usually the same one
+	DuplicateFirst DuplicatePolicy = "First"
+
+	// the object modified most recently
+	DuplicateNewest DuplicatePolicy = "Newest"
+
+	// the path is refused with an [AmbiguousPathError]
+	DuplicateError DuplicatePolicy = "Error"
+)
+
+type FileCategory string
+
+const (
+	CategoryImage    FileCategory = "Image"
+	CategoryVideo    FileCategory = "Video"
+	CategoryAudio    FileCategory = "Audio"
+	CategoryDocument FileCategory = "Document"
+	CategoryArchive  FileCategory = "Archive"
+	CategoryAPK      FileCategory = "APK"
+	CategoryOther    FileCategory = "Other"
+)
+
+type DisallowedFilesAction string
+
+const (
+	// the disallowed files are left out of the transfers
+	DisallowedFilesSkip DisallowedFilesAction = "Skip"
+
+	// the disallowed files are left out of the transfers and reported, see [DisallowedFilesPolicy.WarnCb]
+	DisallowedFilesWarn DisallowedFilesAction = "Warn"
+
+	// the disallowed files are transferred like any other file
+	DisallowedFilesAllow DisallowedFilesAction = "Allow"
+)
+
+// negotiated speed of the usb link
+type UsbSpeed string
+
+const (
+	UsbSpeedUnknown UsbSpeed = "Unknown"
+
+	// usb 1.0, 1.5 Mbit/s
+	UsbSpeedLow UsbSpeed = "Low"
+
+	// usb 1.1, 12 Mbit/s
+	UsbSpeedFull UsbSpeed = "Full"
+
+	// usb 2.0, 480 Mbit/s
+	UsbSpeedHigh UsbSpeed = "High"
+
+	// usb 3.0, 5 Gbit/s
+	UsbSpeedSuper UsbSpeed = "Super"
+)
+
+type HashAlgorithm string
+
+const (
+	HashSha256 HashAlgorithm = "sha256"
+	HashSha1   HashAlgorithm = "sha1"
+	HashMd5    HashAlgorithm = "md5"
+)
+
+// format of the report written by [ExportTree]
+type ExportFormat string
+
+const (
+	ExportCsv ExportFormat = "csv"
+	ExportTsv ExportFormat = "tsv"
+
+	// graphviz graph of the directories
+	ExportDot ExportFormat = "dot"
+
+	// nested [ExportNode] of the directories
+	ExportJsonTree ExportFormat = "json"
+)
@@ -1,5 +1,7 @@
 package mtpx
 
+import "github.com/ganeshrvel/go-mtpfs/mtp"
+
 type MtpDetectFailedError struct {
 	error
 }
@@ -51,3 +53,110 @@ type FileObjectError struct {
 type SendObjectError struct {
 	error
 }
+
+type DeviceStoreError struct {
+	error
+}
+
+type UnknownDeviceError struct {
+	error
+}
+
+type CacheDisabledError struct {
+	error
+}
+
+type DeleteRefusedError struct {
+	error
+
+	// response code of the device
+	Code mtp.RCError
+}
+
+type FileProtectedError struct {
+	error
+}
+
+type ReadOnlyStorageError struct {
+	error
+}
+
+type PartialReadError struct {
+	error
+}
+
+// eg: errors.Is(err, io.ErrUnexpectedEOF) for the objects which came back shorter than expected
+func (e PartialReadError) Unwrap() error {
+	return e.error
+}
+
+type MoveObjectError struct {
+	error
+}
+
+type CopyObjectError struct {
+	error
+}
+
+type DeviceBusyError struct {
+	error
+}
+
+type TooLargeError struct {
+	error
+}
+
+type AuditLogError struct {
+	error
+}
+
+type QuotaExceededError struct {
+	error
+
+	// bytes written to the device under the quota before it was exceeded
+	BytesWritten int64
+}
+
+type UnsupportedOperationError struct {
+	error
+}
+
+type TransferStalledError struct {
+	error
+}
+
+type OperationStalledError struct {
+	error
+}
+
+// the device is being shut down, see [Shutdown]
+type DeviceShutdownError struct {
+	error
+}
+
+type OperationCancelledError struct {
+	error
+}
+
+type SessionLostError struct {
+	error
+}
+
+type MediaServerError struct {
+	error
+}
+
+// the local disk doesn't have enough free space for a downloaded file
+type LocalSpaceError struct {
+	error
+}
+
+// more than one object in a directory has the name, see [DuplicateError]
+type AmbiguousPathError struct {
+	error
+}
+
+// the object or an object inside it is open, see [IsObjectInUse]
+type FileInUseError struct {
+	error
+}
@@ -0,0 +1,244 @@
+package mtpx
+
+import (
+	"context"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/ganeshrvel/go-mtpx/devicepath"
+	"sort"
+	"time"
+)
+
+// state of a storage used to derive the events
+type storageSnapshot struct {
+	freeSpace uint64
+	objects   map[uint32]bool
+}
+
+// state of the storages of a device, keyed by the storage id
+// the names and the dates of the objects are left out, listing them for every object at every poll is too costly
+type eventSnapshot map[uint32]*storageSnapshot
+
+// Subscribe to the changes made on the device, eg: to refresh a file browser when the user adds the files from the phone side
+// the changes are sent to [cb] as the MTP events [EventObjectAdded], [EventObjectRemoved], [EventStorageAdded],
+// [EventStorageRemoved] and [EventStorageChanged], along with the information of the added objects
+// note: the mtp library doesn't expose the interrupt endpoint which carries the events of the device, so the events are
+// derived by comparing the object handles of every storage every 2 seconds, a single request per storage.
+// the snapshots run between the chunks of the streamed files, see [Interleave]
+// only the handles and the free space are compared: a rename or an in-place edit on the device side keeps the handle
+// and is not reported, the modified files show up as an [EventStorageChanged] at best
+// if the metadata cache is enabled then the cached listings affected by the reported changes are invalidated. the
+// listings of the renamed objects are not, set a [CacheConfig.TTL] so that they are fetched again eventually
+// SubscribeEvents blocks until [ctx] is done or the device can't be read
+func SubscribeEvents(ctx context.Context, dev *mtp.Device, cb MtpEventCb) error {
+	var prev eventSnapshot
+	if err := Interleave(dev, func() (err error) {
+		prev, err = takeEventSnapshot(dev)
+
+		return err
+	}); err != nil {
+		return err
+	}
+
+	// information of the reported objects, to describe them once they are removed
+	known := map[uint32]*FileInfo{}
+
+	ticker := time.NewTicker(eventPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-ticker.C:
+		}
+
+		var next eventSnapshot
+		var events []MtpEvent
+
+		if err := Interleave(dev, func() (err error) {
+			next, err = takeEventSnapshot(dev)
+			if err != nil {
+				return err
+			}
+
+			events = diffEventSnapshots(prev, next)
+			describeEventObjects(dev, events, known)
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		prev = next
+
+		for _, e := range events {
+			invalidateEventCache(dev, e)
+
+			cb(e)
+		}
+	}
+}
+
+// fetch the free space and the object handles of every storage
+func takeEventSnapshot(dev *mtp.Device) (eventSnapshot, error) {
+	sids := mtp.Uint32Array{}
+	if err := dev.GetStorageIDs(&sids); err != nil {
+		return nil, StorageInfoError{error: err}
+	}
+
+	snapshot := eventSnapshot{}
+
+	for _, sid := range sids.Values {
+		var info mtp.StorageInfo
+		if err := dev.GetStorageInfo(sid, &info); err != nil {
+			return nil, StorageInfoError{error: err}
+		}
+
+		handles := mtp.Uint32Array{}
+		if err := dev.GetObjectHandles(sid, mtp.GOH_ALL_FORMATS, mtp.GOH_ALL_ASSOCS, &handles); err != nil {
+			return nil, ListDirectoryError{error: err}
+		}
+
+		objects := make(map[uint32]bool, len(handles.Values))
+		for _, objectId := range handles.Values {
+			objects[objectId] = true
+		}
+
+		snapshot[sid] = &storageSnapshot{freeSpace: info.FreeSpaceInBytes, objects: objects}
+	}
+
+	return snapshot, nil
+}
+
+// compare two snapshots and list the events between them
+// the storage removals come first, followed by the storage additions, the object removals, the object additions
+// and the storage changes. the events of each kind are sorted by the ids
+// the objects of the added and the removed storages are not reported one by one
+func diffEventSnapshots(prev, next eventSnapshot) []MtpEvent {
+	var events []MtpEvent
+
+	for _, sid := range sortedEventStorages(prev) {
+		if _, ok := next[sid]; !ok {
+			events = append(events, MtpEvent{Type: EventStorageRemoved, Code: mtp.EC_StoreRemoved, StorageId: sid})
+		}
+	}
+
+	for _, sid := range sortedEventStorages(next) {
+		if _, ok := prev[sid]; !ok {
+			events = append(events, MtpEvent{Type: EventStorageAdded, Code: mtp.EC_StoreAdded, StorageId: sid})
+		}
+	}
+
+	var removed, added, changed []MtpEvent
+
+	for _, sid := range sortedEventStorages(next) {
+		prevStorage, ok := prev[sid]
+		if !ok {
+			continue
+		}
+
+		nextStorage := next[sid]
+
+		for _, objectId := range missingObjects(prevStorage.objects, nextStorage.objects) {
+			removed = append(removed, MtpEvent{Type: EventObjectRemoved, Code: mtp.EC_ObjectRemoved, StorageId: sid, ObjectId: objectId})
+		}
+
+		for _, objectId := range missingObjects(nextStorage.objects, prevStorage.objects) {
+			added = append(added, MtpEvent{Type: EventObjectAdded, Code: mtp.EC_ObjectAdded, StorageId: sid, ObjectId: objectId})
+		}
+
+		if prevStorage.freeSpace != nextStorage.freeSpace {
+			changed = append(changed, MtpEvent{Type: EventStorageChanged, Code: mtp.EC_StorageInfoChanged, StorageId: sid})
+		}
+	}
+
+	events = append(events, removed...)
+	events = append(events, added...)
+
+	return append(events, changed...)
+}
+
+// list the objects of [a] which are not in [b], sorted by the ids
+func missingObjects(a, b map[uint32]bool) []uint32 {
+	var objectIds []uint32
+
+	for objectId := range a {
+		if !b[objectId] {
+			objectIds = append(objectIds, objectId)
+		}
+	}
+
+	sort.Slice(objectIds, func(i, j int) bool { return objectIds[i] < objectIds[j] })
+
+	return objectIds
+}
+
+// storage ids of the [snapshot] in ascending order
+func sortedEventStorages(snapshot eventSnapshot) []uint32 {
+	sids := make([]uint32, 0, len(snapshot))
+	for sid := range snapshot {
+		sids = append(sids, sid)
+	}
+
+	sort.Slice(sids, func(i, j int) bool { return sids[i] < sids[j] })
+
+	return sids
+}
+
+// fill in the [FileInfo] of the object [events] and keep the [known] objects up to date
+// the objects which were removed before they could be fetched are left without the information
+func describeEventObjects(dev *mtp.Device, events []MtpEvent, known map[uint32]*FileInfo) {
+	parentPaths := map[uint32]string{}
+
+	for i, e := range events {
+		switch e.Type {
+		case EventObjectRemoved:
+			events[i].FileInfo = known[e.ObjectId]
+			delete(known, e.ObjectId)
+
+		case EventObjectAdded:
+			fi, err := GetObjectFromObjectId(dev, e.ObjectId, "")
+			if err != nil {
+				continue
+			}
+
+			parentPath, err := resolveParentPath(dev, fi.ParentId, parentPaths)
+			if err != nil {
+				continue
+			}
+
+			fi.ParentPath = parentPath
+			fi.FullPath = devicepath.Join(parentPath, fi.Name)
+
+			events[i].FileInfo = fi
+			known[e.ObjectId] = fi
+
+		case EventStorageRemoved:
+			for objectId, fi := range known {
+				if fi.Info.StorageID == e.StorageId {
+					delete(known, objectId)
+				}
+			}
+		}
+	}
+}
+
+// drop the cached listings which the event [e] made stale
+func invalidateEventCache(dev *mtp.Device, e MtpEvent) {
+	switch e.Type {
+	case EventObjectAdded, EventObjectRemoved:
+		refreshStorageSpace(dev, e.StorageId)
+
+		if e.FileInfo == nil {
+			invalidateCachedStorage(dev, e.StorageId)
+
+			return
+		}
+
+		invalidateCachedListing(dev, e.StorageId, normalizeParentId(e.FileInfo.ParentId))
+
+	case EventStorageAdded, EventStorageRemoved:
+		invalidateCachedStorage(dev, e.StorageId)
+	}
+}
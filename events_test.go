@@ -0,0 +1,69 @@
+package mtpx
+
+import (
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestDiffEventSnapshots(t *testing.T) {
+	objects := func(objectIds ...uint32) map[uint32]bool {
+		m := map[uint32]bool{}
+		for _, objectId := range objectIds {
+			m[objectId] = true
+		}
+
+		return m
+	}
+
+	Convey("List the object events | diffEventSnapshots", t, func() {
+		prev := eventSnapshot{1: {freeSpace: 100, objects: objects(10, 11, 12)}}
+		next := eventSnapshot{1: {freeSpace: 80, objects: objects(10, 14, 13)}}
+
+		events := diffEventSnapshots(prev, next)
+		So(events, ShouldResemble, []MtpEvent{
+			{Type: EventObjectRemoved, Code: mtp.EC_ObjectRemoved, StorageId: 1, ObjectId: 11},
+			{Type: EventObjectRemoved, Code: mtp.EC_ObjectRemoved, StorageId: 1, ObjectId: 12},
+			{Type: EventObjectAdded, Code: mtp.EC_ObjectAdded, StorageId: 1, ObjectId: 13},
+			{Type: EventObjectAdded, Code: mtp.EC_ObjectAdded, StorageId: 1, ObjectId: 14},
+			{Type: EventStorageChanged, Code: mtp.EC_StorageInfoChanged, StorageId: 1},
+		})
+	})
+
+	Convey("List the storage events | diffEventSnapshots", t, func() {
+		prev := eventSnapshot{1: {freeSpace: 100, objects: objects(10)}, 2: {objects: objects(20)}}
+		next := eventSnapshot{1: {freeSpace: 100, objects: objects(10)}, 3: {objects: objects(30)}}
+
+		events := diffEventSnapshots(prev, next)
+		So(events, ShouldResemble, []MtpEvent{
+			{Type: EventStorageRemoved, Code: mtp.EC_StoreRemoved, StorageId: 2},
+			{Type: EventStorageAdded, Code: mtp.EC_StoreAdded, StorageId: 3},
+		})
+	})
+
+	Convey("No events for unchanged storages | diffEventSnapshots", t, func() {
+		prev := eventSnapshot{1: {freeSpace: 100, objects: objects(10, 11)}}
+		next := eventSnapshot{1: {freeSpace: 100, objects: objects(11, 10)}}
+
+		So(diffEventSnapshots(prev, next), ShouldBeEmpty)
+	})
+}
+
+func TestDescribeEventObjects(t *testing.T) {
+	Convey("Describe the removed objects using the known objects | describeEventObjects", t, func() {
+		fi := &FileInfo{ObjectId: 11, Info: &mtp.ObjectInfo{StorageID: 1}}
+		other := &FileInfo{ObjectId: 20, Info: &mtp.ObjectInfo{StorageID: 2}}
+		known := map[uint32]*FileInfo{11: fi, 20: other}
+
+		events := []MtpEvent{
+			{Type: EventObjectRemoved, StorageId: 1, ObjectId: 11},
+			{Type: EventObjectRemoved, StorageId: 1, ObjectId: 12},
+			{Type: EventStorageRemoved, StorageId: 2},
+		}
+
+		describeEventObjects(nil, events, known)
+		So(events[0].FileInfo, ShouldEqual, fi)
+		So(events[1].FileInfo, ShouldBeNil)
+		So(known, ShouldBeEmpty)
+	})
+}
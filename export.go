@@ -0,0 +1,237 @@
+package mtpx
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/ganeshrvel/go-mtpx/devicepath"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Write a report of the whole tree of [fullPath] to [w], eg: for the spreadsheets, the audits and the graph tools
+// [opts.Format]:
+// [ExportCsv] or [ExportTsv]: a flat list with a row per object, see [exportColumns]
+// the rows are written while the tree is walked, the tree is not held in the memory
+// [fullPath] itself is not a part of the list, a file is reported as is
+// [ExportDot] or [ExportJsonTree]: the hierarchy of the directories with the total size and the number of files of each subtree
+// [fullPath] is the root of the tree and should be a directory. the directories are held in the memory until the walk is over
+// return:
+// [totalFiles]: total number of files reported
+// [totalDirectories]: total number of directories reported
+func ExportTree(dev *mtp.Device, storageId uint32, fullPath string, w io.Writer, opts ExportOptions) (totalFiles, totalDirectories int64, err error) {
+	format := opts.Format
+	if format == "" {
+		format = ExportCsv
+	}
+
+	switch format {
+	case ExportCsv, ExportTsv:
+		return exportRows(dev, storageId, fullPath, w, format, opts)
+
+	case ExportDot, ExportJsonTree:
+		root, totalFiles, totalDirectories, err := buildExportTree(dev, storageId, fullPath, opts)
+		if err != nil {
+			return totalFiles, totalDirectories, err
+		}
+
+		if format == ExportDot {
+			err = writeExportDot(w, root)
+		} else {
+			err = writeExportJson(w, root)
+		}
+
+		return totalFiles, totalDirectories, err
+	}
+
+	return 0, 0, UnsupportedOperationError{error: fmt.Errorf("unknown export format: %s", format)}
+}
+
+// helper function to write the flat report of [ExportTree]
+func exportRows(dev *mtp.Device, storageId uint32, fullPath string, w io.Writer, format ExportFormat, opts ExportOptions) (totalFiles, totalDirectories int64, err error) {
+	cw := csv.NewWriter(w)
+	if format == ExportTsv {
+		cw.Comma = '\t'
+	}
+
+	if !opts.NoHeader {
+		if err := cw.Write(exportColumns); err != nil {
+			return 0, 0, LocalFileError{error: err}
+		}
+	}
+
+	_, totalFiles, totalDirectories, err = Walk(dev, storageId, fullPath, true, opts.SkipDisallowedFiles, opts.SkipHiddenFiles,
+		func(objectId uint32, fi *FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if err := cw.Write(exportRow(storageId, fi)); err != nil {
+				return LocalFileError{error: err}
+			}
+
+			return nil
+		})
+
+	cw.Flush()
+	if err != nil {
+		return totalFiles, totalDirectories, err
+	}
+
+	if err := cw.Error(); err != nil {
+		return totalFiles, totalDirectories, LocalFileError{error: err}
+	}
+
+	return totalFiles, totalDirectories, nil
+}
+
+// fields of the object in the order of [exportColumns]
+func exportRow(storageId uint32, fi *FileInfo) []string {
+	objectType := "file"
+	if fi.IsDir {
+		objectType = "directory"
+	}
+
+	var modTime string
+	if !fi.ModTime.IsZero() {
+		modTime = fi.ModTime.Format(time.RFC3339)
+	}
+
+	return []string{
+		fi.FullPath,
+		strconv.FormatInt(fi.Size, 10),
+		modTime,
+		objectType,
+		strconv.FormatUint(uint64(fi.ObjectId), 10),
+		strconv.FormatUint(uint64(storageId), 10),
+	}
+}
+
+// walk [fullPath] and build the tree of its directories with the sizes of their subtrees
+func buildExportTree(dev *mtp.Device, storageId uint32, fullPath string, opts ExportOptions) (root *ExportNode, totalFiles, totalDirectories int64, err error) {
+	_fullPath := devicepath.Clean(fullPath)
+
+	fi, err := GetObjectFromPath(dev, storageId, _fullPath)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	if !fi.IsDir {
+		return nil, 0, 0, InvalidPathError{error: fmt.Errorf("invalid path: %s. The object is not a directory", _fullPath)}
+	}
+
+	root = &ExportNode{Name: fi.Name, FullPath: _fullPath, ObjectId: fi.ObjectId}
+	nodes := map[string]*ExportNode{_fullPath: root}
+
+	// a directory is always visited before its objects
+	_, totalFiles, totalDirectories, err = Walk(dev, storageId, _fullPath, true, opts.SkipDisallowedFiles, opts.SkipHiddenFiles,
+		func(objectId uint32, fi *FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			parent, ok := nodes[devicepath.Clean(fi.ParentPath)]
+			if !ok {
+				return nil
+			}
+
+			if !fi.IsDir {
+				parent.Size += fi.Size
+				parent.TotalFiles += 1
+
+				return nil
+			}
+
+			node := &ExportNode{Name: fi.Name, FullPath: fi.FullPath, ObjectId: fi.ObjectId}
+			parent.Children = append(parent.Children, node)
+			nodes[devicepath.Clean(fi.FullPath)] = node
+
+			return nil
+		})
+	if err != nil {
+		return nil, totalFiles, totalDirectories, err
+	}
+
+	sumExportNode(root)
+
+	return root, totalFiles, totalDirectories, nil
+}
+
+// add the sizes and the files of the subdirectories to the [node]
+func sumExportNode(node *ExportNode) {
+	for _, child := range node.Children {
+		sumExportNode(child)
+
+		node.Size += child.Size
+		node.TotalFiles += child.TotalFiles
+	}
+}
+
+// write the tree as a graphviz digraph, eg: dot -Tsvg tree.dot > tree.svg
+func writeExportDot(w io.Writer, root *ExportNode) error {
+	bw := bufio.NewWriter(w)
+
+	_, _ = fmt.Fprintf(bw, "digraph \"%s\" {\n", dotEscape(root.FullPath))
+	_, _ = fmt.Fprintf(bw, "  node [shape=box];\n")
+
+	var writeNode func(node *ExportNode)
+	writeNode = func(node *ExportNode) {
+		name := node.Name
+		if node == root {
+			name = node.FullPath
+		}
+
+		_, _ = fmt.Fprintf(bw, "  n%d [label=\"%s\\n%s, %d files\", tooltip=\"%s\"];\n",
+			node.ObjectId, dotEscape(name), formatSize(node.Size), node.TotalFiles, dotEscape(node.FullPath))
+
+		for _, child := range node.Children {
+			_, _ = fmt.Fprintf(bw, "  n%d -> n%d;\n", node.ObjectId, child.ObjectId)
+			writeNode(child)
+		}
+	}
+	writeNode(root)
+
+	_, _ = fmt.Fprintf(bw, "}\n")
+
+	if err := bw.Flush(); err != nil {
+		return LocalFileError{error: err}
+	}
+
+	return nil
+}
+
+func writeExportJson(w io.Writer, root *ExportNode) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(root); err != nil {
+		return LocalFileError{error: err}
+	}
+
+	return nil
+}
+
+// escape [s] to be placed inside a quoted graphviz string
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+// human readable size, eg: "1.5 MB"
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp += 1
+	}
+
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,139 @@
+package mtpx
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	. "github.com/smartystreets/goconvey/convey"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportTree(t *testing.T) {
+	dev, err := Initialize(Init{})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	storages, err := FetchStorages(dev)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	sid := storages[0].Sid
+
+	Convey("Testing ExportTree | csv", t, func() {
+		var buf bytes.Buffer
+		totalFiles, totalDirectories, err := ExportTree(dev, sid, "/mtp-test-files/mock_dir1", &buf, ExportOptions{})
+		So(err, ShouldBeNil)
+
+		rows, err := csv.NewReader(&buf).ReadAll()
+		So(err, ShouldBeNil)
+		So(rows[0], ShouldResemble, exportColumns)
+		So(int64(len(rows)-1), ShouldEqual, totalFiles+totalDirectories)
+
+		for _, row := range rows[1:] {
+			So(row[0], ShouldStartWith, "/mtp-test-files/mock_dir1/")
+		}
+	})
+
+	Convey("Testing ExportTree | tsv", t, func() {
+		var buf bytes.Buffer
+		_, _, err := ExportTree(dev, sid, "/mtp-test-files/mock_dir1", &buf, ExportOptions{Format: ExportTsv, NoHeader: true})
+		So(err, ShouldBeNil)
+		So(buf.String(), ShouldStartWith, "/mtp-test-files/mock_dir1/")
+		So(strings.Count(strings.SplitN(buf.String(), "\n", 2)[0], "\t"), ShouldEqual, len(exportColumns)-1)
+	})
+
+	Convey("Testing ExportTree | json tree", t, func() {
+		var buf bytes.Buffer
+		totalFiles, _, err := ExportTree(dev, sid, "/mtp-test-files/mock_dir1", &buf, ExportOptions{Format: ExportJsonTree})
+		So(err, ShouldBeNil)
+
+		var root ExportNode
+		So(json.Unmarshal(buf.Bytes(), &root), ShouldBeNil)
+		So(root.FullPath, ShouldEqual, "/mtp-test-files/mock_dir1")
+		So(root.TotalFiles, ShouldEqual, totalFiles)
+		So(root.Children, ShouldNotBeEmpty)
+	})
+
+	Convey("Testing ExportTree | dot", t, func() {
+		var buf bytes.Buffer
+		_, _, err := ExportTree(dev, sid, "/mtp-test-files/mock_dir1", &buf, ExportOptions{Format: ExportDot})
+		So(err, ShouldBeNil)
+		So(buf.String(), ShouldStartWith, `digraph "/mtp-test-files/mock_dir1" {`)
+
+		// the tree formats need a directory
+		buf.Reset()
+		_, _, err = ExportTree(dev, sid, "/mtp-test-files/mock_dir1/a.txt", &buf, ExportOptions{Format: ExportDot})
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+	})
+
+	Convey("Testing ExportTree | invalid path", t, func() {
+		var buf bytes.Buffer
+		_, _, err := ExportTree(dev, sid, "/mtp-test-files/fake-dir", &buf, ExportOptions{})
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+	})
+
+	Dispose(dev)
+}
+
+func TestExportRow(t *testing.T) {
+	Convey("Test the rows of the flat report | exportRow", t, func() {
+		modTime := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+
+		row := exportRow(65537, &FileInfo{FullPath: "/DCIM/a, b.jpg", Size: 1024, ModTime: modTime, ObjectId: 12})
+		So(row, ShouldResemble, []string{"/DCIM/a, b.jpg", "1024", "2021-01-02T03:04:05Z", "file", "12", "65537"})
+
+		row = exportRow(65537, &FileInfo{FullPath: "/DCIM", IsDir: true, ObjectId: 3})
+		So(row, ShouldResemble, []string{"/DCIM", "0", "", "directory", "3", "65537"})
+	})
+
+	Convey("Test an unknown format | ExportTree", t, func() {
+		var buf bytes.Buffer
+		_, _, err := ExportTree(nil, 0, "/", &buf, ExportOptions{Format: "xml"})
+		So(err, ShouldHaveSameTypeAs, UnsupportedOperationError{})
+		So(buf.Len(), ShouldEqual, 0)
+	})
+}
+
+func TestExportNode(t *testing.T) {
+	Convey("Test the aggregate sizes | sumExportNode", t, func() {
+		leaf := &ExportNode{Name: "b", FullPath: "/a/b", ObjectId: 3, Size: 10, TotalFiles: 1}
+		child := &ExportNode{Name: "a", FullPath: "/a", ObjectId: 2, Size: 5, TotalFiles: 2, Children: []*ExportNode{leaf}}
+		root := &ExportNode{Name: "", FullPath: "/", ObjectId: 1, Size: 1, TotalFiles: 1, Children: []*ExportNode{child}}
+
+		sumExportNode(root)
+
+		So(leaf.Size, ShouldEqual, 10)
+		So(child.Size, ShouldEqual, 15)
+		So(child.TotalFiles, ShouldEqual, 3)
+		So(root.Size, ShouldEqual, 16)
+		So(root.TotalFiles, ShouldEqual, 4)
+	})
+
+	Convey("Test the graphviz output | writeExportDot", t, func() {
+		root := &ExportNode{FullPath: "/", ObjectId: 1, Size: 2048, TotalFiles: 2, Children: []*ExportNode{
+			{Name: `say "hi"`, FullPath: `/say "hi"`, ObjectId: 2, Size: 2048, TotalFiles: 2},
+		}}
+
+		var buf bytes.Buffer
+		So(writeExportDot(&buf, root), ShouldBeNil)
+		So(buf.String(), ShouldEqual, `digraph "/" {
+  node [shape=box];
+  n1 [label="/\n2.0 KB, 2 files", tooltip="/"];
+  n1 -> n2;
+  n2 [label="say \"hi\"\n2.0 KB, 2 files", tooltip="/say \"hi\""];
+}
+`)
+	})
+
+	Convey("Test formatSize", t, func() {
+		So(formatSize(0), ShouldEqual, "0 B")
+		So(formatSize(1023), ShouldEqual, "1023 B")
+		So(formatSize(1536), ShouldEqual, "1.5 KB")
+		So(formatSize(5*1024*1024*1024), ShouldEqual, "5.0 GB")
+	})
+}
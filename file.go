@@ -0,0 +1,619 @@
+package mtpx
+
+import (
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/ganeshrvel/go-mtpx/devicepath"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+var writeSpillSize atomic.Value
+
+// size above which the writes of a [File] are buffered in a local temp file rather than in memory
+func WriteSpillSize() int64 {
+	if size, ok := writeSpillSize.Load().(int64); ok && size > 0 {
+		return size
+	}
+
+	return defaultWriteSpillSize
+}
+
+// set the size above which the writes of a [File] are buffered in a local temp file rather than in memory
+// the files which are open when it changes keep the previous size. if [size] is 0 then the default of 16MB is used
+func SetWriteSpillSize(size int64) {
+	writeSpillSize.Store(size)
+}
+
+// Open the file [fullPath] for reading and writing, eg: to back the file handles of a mount
+// [flag] takes the [os.O_RDONLY], [os.O_WRONLY], [os.O_RDWR], [os.O_CREATE], [os.O_EXCL], [os.O_TRUNC] and [os.O_APPEND] flags
+// the objects can't be modified in place over MTP, so the writes are coalesced in a local buffer (see [SetWriteSpillSize])
+// and the whole file is sent to the device once by [File.Flush] or [File.Close]. the current content of the file is
+// read into the buffer on the first write unless the file is truncated
+// the reads of an unmodified file use the partial reads when the device supports them
+// the parent directory must exist. a new file is created on the device when it is flushed
+// the file can't be deleted, renamed or moved by the other operations until it is closed, see [IsObjectInUse]
+func OpenFile(dev *mtp.Device, storageId uint32, fullPath string, flag int) (*File, error) {
+	_fullPath := devicepath.Clean(fullPath)
+	parentPath, name := devicepath.Split(_fullPath)
+
+	if name == "" {
+		return nil, InvalidPathError{error: fmt.Errorf("invalid path: %s. The object is a directory", fullPath)}
+	}
+
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	if writable {
+		if err := checkStorageWritable(dev, storageId, false); err != nil {
+			return nil, err
+		}
+	}
+
+	parent, err := GetObjectFromPath(dev, storageId, parentPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if !parent.IsDir {
+		return nil, InvalidPathError{error: fmt.Errorf("invalid path: %s. The parent is not a directory", fullPath)}
+	}
+
+	f := &File{
+		dev:        dev,
+		storageId:  storageId,
+		parentId:   parent.ObjectId,
+		name:       name,
+		fullPath:   _fullPath,
+		writable:   writable,
+		appendOnly: flag&os.O_APPEND != 0,
+	}
+
+	fi, err := GetObjectFromParentIdAndFilename(dev, storageId, parent.ObjectId, name)
+	switch err.(type) {
+	case nil:
+		if flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0 {
+			return nil, InvalidPathError{error: fmt.Errorf("the file already exists: %s", fullPath)}
+		}
+
+		if fi.IsDir {
+			return nil, InvalidPathError{error: fmt.Errorf("invalid path: %s. The object is a directory", fullPath)}
+		}
+
+		if writable {
+			if err := handleProtectedObject(dev, fi, false); err != nil {
+				return nil, err
+			}
+		}
+
+		fi.ParentPath = parentPath
+		fi.FullPath = _fullPath
+		f.fi = fi
+		f.track()
+
+		if supportsOperation(dev, mtp.OC_GetPartialObject) {
+			f.reader = NewObjectReader(dev, fi)
+		}
+
+		if writable && flag&os.O_TRUNC != 0 {
+			f.buf = newWriteBuffer(WriteSpillSize())
+			f.dirty = fi.Size > 0
+		}
+
+	case FileNotFoundError:
+		if !writable || flag&os.O_CREATE == 0 {
+			return nil, err
+		}
+
+		// the new file is created on the device by the first flush
+		f.buf = newWriteBuffer(WriteSpillSize())
+		f.dirty = true
+
+	default:
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func newWriteBuffer(spillSize int64) *writeBuffer {
+	return &writeBuffer{spillSize: spillSize}
+}
+
+// read the current content of the file into the buffer, unless it is there already
+// the caller should hold the lock
+func (f *File) load() error {
+	if f.buf != nil {
+		return nil
+	}
+
+	buf := newWriteBuffer(WriteSpillSize())
+
+	if f.fi != nil && f.fi.Size > 0 {
+		rc, err := NewFileReader(f.dev, f.storageId, f.fi.ObjectId)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(buf, rc)
+		_ = rc.Close()
+
+		if err != nil {
+			buf.Close()
+
+			return err
+		}
+	}
+
+	f.buf = buf
+
+	return nil
+}
+
+// send the buffer to the device if it holds pending writes
+// the caller should hold the lock
+func (f *File) flush() error {
+	if !f.dirty {
+		return nil
+	}
+
+	// the file replaces its own object, only the other users of the object can hold the upload back
+	f.untrack()
+	defer f.track()
+
+	size := f.buf.size
+	op := &OperationInfo{Type: WriteFileOp, Mutating: true, StorageId: f.storageId, FileProps: []FileProp{{0, f.fullPath}}, Size: size}
+
+	return runMiddlewares(f.dev, op, func() error {
+		objectFormat := uint16(mtp.OFC_Undefined)
+		if f.fi != nil {
+			objectFormat = f.fi.Info.ObjectFormat
+		}
+
+		// the existing object is replaced once the new content is on the device, it is kept if the upload fails
+		name := f.name
+		if f.fi != nil {
+			name = tempObjectName(".mtpx-write-*")
+		}
+
+		startTime := time.Now()
+
+		objectId, err := sendObject(f.dev, f.storageId, f.parentId, name, objectFormat, time.Now(), io.NewSectionReader(f.buf, 0, size), size, f.fi == nil,
+			func(total, sent int64, objectId uint32, err error) error {
+				if err != nil {
+					return err
+				}
+
+				return touchOperation(f.dev)
+			})
+		if err != nil {
+			recordTransferError(f.dev)
+
+			return err
+		}
+
+		recordTransferredBytes(f.dev, Upload, size, time.Since(startTime))
+		recordTransferredFile(f.dev, Upload)
+
+		if f.fi != nil {
+			if err := replaceStagedObject(f.dev, f.storageId, f.fi.ObjectId, objectId, name, f.name); err != nil {
+				return err
+			}
+		}
+
+		refreshStorageSpace(f.dev, f.storageId)
+
+		parentPath, _ := devicepath.Split(f.fullPath)
+
+		fi, err := GetObjectFromObjectId(f.dev, objectId, parentPath)
+		if err != nil {
+			return err
+		}
+
+		f.fi = fi
+		f.dirty = false
+
+		// the device has the content now, the buffer isn't needed unless the device can't be read partially
+		if supportsOperation(f.dev, mtp.OC_GetPartialObject) {
+			f.reader = NewObjectReader(f.dev, fi)
+
+			f.buf.Close()
+			f.buf = nil
+		}
+
+		return nil
+	})
+}
+
+// replace the object [objectId] with the object [stagedObjectId] which was uploaded next to it as [stagedName]
+// the staged object is renamed to [name] once the original object is deleted
+// if the original object can't be deleted then the staged object is dropped and the original content is kept
+func replaceStagedObject(dev *mtp.Device, storageId, objectId, stagedObjectId uint32, stagedName, name string) error {
+	if err := deleteFile(dev, storageId, []FileProp{{objectId, ""}}, DeleteOptions{Strict: true}); err != nil {
+		_ = deleteFile(dev, storageId, []FileProp{{stagedObjectId, ""}}, DeleteOptions{})
+
+		return err
+	}
+
+	if _, err := renameFile(dev, storageId, FileProp{stagedObjectId, ""}, name); err != nil {
+		return FileTransferError{error: fmt.Errorf("the new content of %s was left in %s: %v", name, stagedName, err)}
+	}
+
+	return nil
+}
+
+// mark the object of the file as being in use, the previous mark is dropped
+// the caller should hold the lock
+func (f *File) track() {
+	f.untrack()
+
+	if f.fi != nil {
+		f.release = markObjectInUse(f.dev, f.fi.ObjectId)
+	}
+}
+
+// drop the in-use mark of the object of the file
+// the caller should hold the lock
+func (f *File) untrack() {
+	if f.release != nil {
+		f.release()
+		f.release = nil
+	}
+}
+
+func (f *File) Read(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+
+	n, err := f.readAt(p, f.offset)
+	f.offset += int64(n)
+
+	return n, err
+}
+
+// read len(p) bytes starting at [off] without moving the offset of [Read], it implements [io.ReaderAt]
+func (f *File) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset: %d", off)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+
+	var total int
+	for total < len(p) {
+		n, err := f.readAt(p[total:], off+int64(total))
+		total += n
+
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+
+	if f.appendOnly {
+		f.offset = f.size()
+	}
+
+	n, err := f.writeAt(p, f.offset)
+	f.offset += int64(n)
+
+	return n, err
+}
+
+// write [p] at [off] without moving the offset of [Write], it implements [io.WriterAt]
+func (f *File) WriteAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset: %d", off)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+
+	return f.writeAt(p, off)
+}
+
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.size()
+	default:
+		return 0, fmt.Errorf("invalid whence: %d", whence)
+	}
+
+	if offset < 0 {
+		return 0, fmt.Errorf("negative offset: %d", offset)
+	}
+
+	f.offset = offset
+
+	return offset, nil
+}
+
+// change the size of the file, the file is extended with zeroes
+func (f *File) Truncate(size int64) error {
+	if size < 0 {
+		return fmt.Errorf("negative size: %d", size)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return os.ErrClosed
+	}
+
+	if !f.writable {
+		return FilePermissionError{error: fmt.Errorf("the file is not open for writing: %s", f.fullPath)}
+	}
+
+	// the current content is not needed if all of it is cut off
+	if size == 0 && f.buf == nil {
+		f.buf = newWriteBuffer(WriteSpillSize())
+	}
+
+	if err := f.load(); err != nil {
+		return err
+	}
+
+	if err := f.buf.Truncate(size); err != nil {
+		return err
+	}
+
+	f.dirty = true
+
+	return nil
+}
+
+// current size of the file, the pending writes included
+func (f *File) Size() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.size()
+}
+
+// send the pending writes to the device
+// the file is sent as a whole and replaces the previous object, so [FileInfo.ObjectId] changes
+// the previous object is only deleted once the new content is on the device
+func (f *File) Flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return os.ErrClosed
+	}
+
+	return f.flush()
+}
+
+// send the pending writes to the device and release the local buffer
+// if the pending writes can't be sent then the file is left open with its buffer, so that [Close] can be retried
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return os.ErrClosed
+	}
+
+	if err := f.flush(); err != nil {
+		return err
+	}
+
+	if f.buf != nil {
+		f.buf.Close()
+		f.buf = nil
+	}
+
+	if f.reader != nil {
+		_ = f.reader.Close()
+	}
+
+	f.untrack()
+	f.closed = true
+
+	return nil
+}
+
+// the caller should hold the lock
+func (f *File) size() int64 {
+	if f.buf != nil {
+		return f.buf.size
+	}
+
+	if f.fi != nil {
+		return f.fi.Size
+	}
+
+	return 0
+}
+
+// the caller should hold the lock
+func (f *File) readAt(p []byte, off int64) (int, error) {
+	if len(p) < 1 {
+		return 0, nil
+	}
+
+	if f.buf == nil && f.fi != nil && f.reader == nil {
+		// the devices without the partial reads are read into the buffer once
+		if err := f.load(); err != nil {
+			return 0, err
+		}
+	}
+
+	if f.buf != nil {
+		return f.buf.ReadAt(p, off)
+	}
+
+	if f.reader == nil || off >= f.size() {
+		return 0, io.EOF
+	}
+
+	return f.reader.ReadAt(p, off)
+}
+
+// the caller should hold the lock
+func (f *File) writeAt(p []byte, off int64) (int, error) {
+	if !f.writable {
+		return 0, FilePermissionError{error: fmt.Errorf("the file is not open for writing: %s", f.fullPath)}
+	}
+
+	if err := f.load(); err != nil {
+		return 0, err
+	}
+
+	n, err := f.buf.WriteAt(p, off)
+	if n > 0 {
+		f.dirty = true
+	}
+
+	return n, err
+}
+
+// append [p] to the buffer
+func (b *writeBuffer) Write(p []byte) (int, error) {
+	return b.WriteAt(p, b.size)
+}
+
+func (b *writeBuffer) WriteAt(p []byte, off int64) (int, error) {
+	end := off + int64(len(p))
+
+	if b.f == nil && end > b.spillSize {
+		if err := b.spill(); err != nil {
+			return 0, err
+		}
+	}
+
+	if b.f != nil {
+		n, err := b.f.WriteAt(p, off)
+		if end := off + int64(n); end > b.size {
+			b.size = end
+		}
+
+		if err != nil {
+			return n, LocalFileError{error: err}
+		}
+
+		return n, nil
+	}
+
+	if end > b.size {
+		b.mem = append(b.mem, make([]byte, end-b.size)...)
+		b.size = end
+	}
+
+	return copy(b.mem[off:], p), nil
+}
+
+func (b *writeBuffer) ReadAt(p []byte, off int64) (int, error) {
+	if off >= b.size {
+		return 0, io.EOF
+	}
+
+	want := len(p)
+	if rest := b.size - off; int64(want) > rest {
+		want = int(rest)
+	}
+
+	var n int
+	if b.f != nil {
+		var err error
+		if n, err = b.f.ReadAt(p[:want], off); err != nil && err != io.EOF {
+			return n, LocalFileError{error: err}
+		}
+	} else {
+		n = copy(p[:want], b.mem[off:])
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+func (b *writeBuffer) Truncate(size int64) error {
+	if b.f == nil && size > b.spillSize {
+		if err := b.spill(); err != nil {
+			return err
+		}
+	}
+
+	if b.f != nil {
+		if err := b.f.Truncate(size); err != nil {
+			return LocalFileError{error: err}
+		}
+	} else if size <= b.size {
+		b.mem = b.mem[:size]
+	} else {
+		b.mem = append(b.mem, make([]byte, size-b.size)...)
+	}
+
+	b.size = size
+
+	return nil
+}
+
+// move the content of the buffer to a local temp file
+func (b *writeBuffer) spill() error {
+	f, err := ioutil.TempFile("", "mtpx-write-*")
+	if err != nil {
+		return LocalFileError{error: err}
+	}
+
+	if _, err := f.Write(b.mem); err != nil {
+		f.Close()
+		_ = os.Remove(f.Name())
+
+		return LocalFileError{error: err}
+	}
+
+	b.f, b.mem = f, nil
+
+	return nil
+}
+
+// drop the content of the buffer
+func (b *writeBuffer) Close() {
+	if b.f != nil {
+		b.f.Close()
+		_ = os.Remove(b.f.Name())
+		b.f = nil
+	}
+
+	b.mem, b.size = nil, 0
+}
@@ -0,0 +1,215 @@
+package mtpx
+
+import (
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func TestWriteBuffer(t *testing.T) {
+	Convey("Test writeBuffer", t, func() {
+		b := newWriteBuffer(8)
+		defer b.Close()
+
+		_, err := b.Write([]byte("hello"))
+		So(err, ShouldBeNil)
+		So(b.f, ShouldBeNil)
+
+		// the gaps are filled with zeroes
+		_, err = b.WriteAt([]byte("!"), 6)
+		So(err, ShouldBeNil)
+		So(b.size, ShouldEqual, 7)
+
+		p := make([]byte, 8)
+		n, err := b.ReadAt(p, 0)
+		So(err, ShouldEqual, io.EOF)
+		So(string(p[:n]), ShouldEqual, "hello\x00!")
+
+		// the buffer is moved to a temp file beyond the spill size
+		_, err = b.WriteAt([]byte("world"), 5)
+		So(err, ShouldBeNil)
+		So(b.f, ShouldNotBeNil)
+		So(b.mem, ShouldBeNil)
+		So(b.size, ShouldEqual, 10)
+
+		name := b.f.Name()
+
+		n, err = b.ReadAt(p[:4], 4)
+		So(err, ShouldBeNil)
+		So(string(p[:n]), ShouldEqual, "owor")
+
+		So(b.Truncate(3), ShouldBeNil)
+
+		n, err = b.ReadAt(p, 0)
+		So(err, ShouldEqual, io.EOF)
+		So(string(p[:n]), ShouldEqual, "hel")
+
+		// the temp file is removed
+		b.Close()
+		_, err = os.Stat(name)
+		So(os.IsNotExist(err), ShouldBeTrue)
+	})
+
+	Convey("Test truncating | writeBuffer", t, func() {
+		b := newWriteBuffer(8)
+		defer b.Close()
+
+		_, err := b.Write([]byte("abcdef"))
+		So(err, ShouldBeNil)
+
+		So(b.Truncate(2), ShouldBeNil)
+		So(b.Truncate(4), ShouldBeNil)
+
+		p := make([]byte, 4)
+		_, err = b.ReadAt(p, 0)
+		So(err, ShouldBeNil)
+		So(string(p), ShouldEqual, "ab\x00\x00")
+	})
+
+	Convey("Test WriteSpillSize", t, func() {
+		So(WriteSpillSize(), ShouldEqual, defaultWriteSpillSize)
+
+		SetWriteSpillSize(1024)
+		So(WriteSpillSize(), ShouldEqual, 1024)
+
+		SetWriteSpillSize(0)
+		So(WriteSpillSize(), ShouldEqual, defaultWriteSpillSize)
+	})
+}
+
+func TestOpenFile(t *testing.T) {
+	dev, err := Initialize(Init{})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	storages, err := FetchStorages(dev)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	sid := storages[0].Sid
+
+	directoryName := fmt.Sprintf("/mtp-test-files/temp_dir/test-OpenFile/%x", rand.Int31())
+	if _, err := MakeDirectory(dev, sid, directoryName); err != nil {
+		log.Panic(err)
+	}
+
+	Convey("Create and edit a file | OpenFile", t, func() {
+		fullPath := directoryName + "/a.txt"
+
+		f, err := OpenFile(dev, sid, fullPath, os.O_RDWR|os.O_CREATE|os.O_EXCL)
+		So(err, ShouldBeNil)
+
+		_, err = f.Write([]byte("hello world"))
+		So(err, ShouldBeNil)
+		So(f.Close(), ShouldBeNil)
+
+		// edit the middle of the file
+		f, err = OpenFile(dev, sid, fullPath, os.O_RDWR)
+		So(err, ShouldBeNil)
+
+		_, err = f.WriteAt([]byte("W"), 6)
+		So(err, ShouldBeNil)
+		So(f.Size(), ShouldEqual, 11)
+
+		b, err := ioutil.ReadAll(f)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "hello World")
+
+		So(f.Close(), ShouldBeNil)
+
+		f, err = OpenFile(dev, sid, fullPath, os.O_RDONLY)
+		So(err, ShouldBeNil)
+
+		b, err = ioutil.ReadAll(f)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "hello World")
+
+		_, err = f.Write([]byte("!"))
+		So(err, ShouldHaveSameTypeAs, FilePermissionError{})
+
+		So(f.Close(), ShouldBeNil)
+
+		_, err = OpenFile(dev, sid, fullPath, os.O_RDWR|os.O_CREATE|os.O_EXCL)
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+	})
+
+	Convey("Refuse to delete and rename an open file | OpenFile", t, func() {
+		fullPath := directoryName + "/b.txt"
+
+		f, err := OpenFile(dev, sid, fullPath, os.O_RDWR|os.O_CREATE)
+		So(err, ShouldBeNil)
+
+		_, err = f.Write([]byte("hello"))
+		So(err, ShouldBeNil)
+		So(f.Flush(), ShouldBeNil)
+
+		err = DeleteFile(dev, sid, []FileProp{{0, fullPath}})
+		So(err, ShouldHaveSameTypeAs, FileInUseError{})
+
+		_, err = RenameFile(dev, sid, FileProp{0, fullPath}, "c.txt")
+		So(err, ShouldHaveSameTypeAs, FileInUseError{})
+
+		// the directories holding an open file can't be deleted either
+		_, err = DeleteDirectoryRecursive(dev, sid, FileProp{0, directoryName}, DeleteOptions{}, nil)
+		So(err, ShouldHaveSameTypeAs, FileInUseError{})
+
+		// the file can still replace itself
+		_, err = f.Write([]byte(" world"))
+		So(err, ShouldBeNil)
+		So(f.Flush(), ShouldBeNil)
+
+		So(f.Close(), ShouldBeNil)
+
+		err = DeleteFile(dev, sid, []FileProp{{0, fullPath}})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Keep the pending writes when the flush fails | OpenFile", t, func() {
+		fullPath := directoryName + "/d.txt"
+
+		blocked := true
+		AddMiddleware(dev, func(op *OperationInfo, next func() error) error {
+			if blocked && op.Type == WriteFileOp {
+				return fmt.Errorf("blocked")
+			}
+
+			return next()
+		})
+
+		f, err := OpenFile(dev, sid, fullPath, os.O_RDWR|os.O_CREATE)
+		So(err, ShouldBeNil)
+
+		_, err = f.Write([]byte("hello"))
+		So(err, ShouldBeNil)
+
+		// the file is left open with its buffer
+		So(f.Close(), ShouldNotBeNil)
+		So(f.Size(), ShouldEqual, 5)
+
+		blocked = false
+		So(f.Close(), ShouldBeNil)
+
+		fi, err := GetObjectFromPath(dev, sid, fullPath)
+		So(err, ShouldBeNil)
+		So(fi.Size, ShouldEqual, 5)
+	})
+
+	Convey("Open a missing file | OpenFile | Should throw an error", t, func() {
+		_, err := OpenFile(dev, sid, directoryName+"/missing.txt", os.O_RDONLY)
+		So(err, ShouldHaveSameTypeAs, FileNotFoundError{})
+	})
+
+	err = DeleteFile(dev, sid, []FileProp{{0, "/mtp-test-files/temp_dir/test-OpenFile"}})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	Dispose(dev)
+}
@@ -0,0 +1,79 @@
+package mtpx
+
+import (
+	"context"
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"io"
+	"time"
+)
+
+// Follow a growing file on the device and stream the newly appended bytes to [w], like `tail -f`
+// the size of the object is polled every [interval] and only the bytes appended since the last poll are read
+// if the file shrinks (eg: a log rotation) then it is streamed again from the beginning
+// [interval]: time between two polls. if the value is 0 then [defaultFollowInterval] is used
+// FollowFile blocks until [ctx] is done, the object is deleted or an error occurs
+// return:
+// [totalWritten]: total number of bytes written to [w]
+func FollowFile(ctx context.Context, dev *mtp.Device, objectId uint32, w io.Writer, interval time.Duration) (totalWritten int64, err error) {
+	if interval <= 0 {
+		interval = defaultFollowInterval
+	}
+
+	fi, err := GetObjectFromObjectId(dev, objectId, "")
+	if err != nil {
+		return 0, err
+	}
+
+	if fi.IsDir {
+		return 0, InvalidPathError{error: fmt.Errorf("cannot follow a directory: %d", objectId)}
+	}
+
+	// start from the end of the file
+	offset := fi.Size
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return totalWritten, nil
+
+		case <-ticker.C:
+			fi, err := GetObjectFromObjectId(dev, objectId, "")
+			if err != nil {
+				if isInvalidObjectHandleError(err) {
+					return totalWritten, FileNotFoundError{error: fmt.Errorf("the object was deleted: %d", objectId)}
+				}
+
+				return totalWritten, err
+			}
+
+			// the file was truncated
+			if fi.Size < offset {
+				offset = 0
+			}
+
+			if fi.Size == offset {
+				continue
+			}
+
+			cw := &countingWriter{w: w}
+			err = readPartialObject(dev, objectId, cw, offset, fi.Size-offset)
+			totalWritten += cw.n
+			offset += cw.n
+
+			if err != nil {
+				return totalWritten, err
+			}
+		}
+	}
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+
+	return n, err
+}
@@ -0,0 +1,46 @@
+package mtpx
+
+import (
+	"context"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"log"
+	"testing"
+	"time"
+)
+
+func TestFollowFile(t *testing.T) {
+	dev, err := Initialize(Init{})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	storages, err := FetchStorages(dev)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	sid := storages[0].Sid
+
+	Convey("Follow an unchanged file | FollowFile", t, func() {
+		fi, err := GetObjectFromPath(dev, sid, "/mtp-test-files/mock_dir1/a.txt")
+		So(err, ShouldBeNil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		totalWritten, err := FollowFile(ctx, dev, fi.ObjectId, ioutil.Discard, 500*time.Millisecond)
+		So(err, ShouldBeNil)
+		So(totalWritten, ShouldEqual, 0)
+	})
+
+	Convey("Follow a directory | FollowFile | Should throw an error", t, func() {
+		fi, err := GetObjectFromPath(dev, sid, "/mtp-test-files/mock_dir1")
+		So(err, ShouldBeNil)
+
+		_, err = FollowFile(context.Background(), dev, fi.ObjectId, ioutil.Discard, 0)
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+	})
+
+	Dispose(dev)
+}
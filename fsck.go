@@ -0,0 +1,179 @@
+package mtpx
+
+import (
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"sort"
+)
+
+// Scan the storage for the inconsistencies in the object tree
+// reports:
+// [OrphanObject]: objects whose parent no longer exists
+// [DuplicateName]: objects sharing the same filename within a directory
+// [ObjectCycle]: objects whose chain of parents loops back on itself
+// use [opts.Repair] to move the orphan objects to [lostAndFoundPath]
+// the whole storage is listed, so this may take a while on the devices with a lot of objects
+func FsckStorage(dev *mtp.Device, storageId uint32, opts FsckOptions) (*FsckReport, error) {
+	handles := mtp.Uint32Array{}
+	if err := dev.GetObjectHandles(storageId, mtp.GOH_ALL_FORMATS, mtp.GOH_ALL_ASSOCS, &handles); err != nil {
+		return nil, ListDirectoryError{error: err}
+	}
+
+	objects := map[uint32]fsckObject{}
+	for _, objectId := range handles.Values {
+		obj := mtp.ObjectInfo{}
+		if err := dev.GetObjectInfo(objectId, &obj); err != nil {
+			// the object may have been removed while scanning
+			if isInvalidObjectHandleError(err) {
+				continue
+			}
+
+			return nil, FileObjectError{error: err}
+		}
+
+		objects[objectId] = fsckObject{objectId: objectId, parentId: obj.ParentObject, name: obj.Filename}
+	}
+
+	report := &FsckReport{
+		Checked: len(objects),
+		Issues:  findFsckIssues(objects),
+	}
+
+	if !opts.Repair {
+		return report, nil
+	}
+
+	var lostAndFoundId uint32
+	for _, issue := range report.Issues {
+		if issue.Type != OrphanObject {
+			continue
+		}
+
+		if lostAndFoundId == 0 {
+			objectId, err := MakeDirectory(dev, storageId, lostAndFoundPath)
+			if err != nil {
+				return report, err
+			}
+
+			lostAndFoundId = objectId
+		}
+
+		if err := moveObject(dev, issue.ObjectId, storageId, lostAndFoundId); err != nil {
+			return report, err
+		}
+
+		report.Repaired = append(report.Repaired, issue.ObjectId)
+	}
+
+	if len(report.Repaired) > 0 {
+		invalidateCachedStorage(dev, storageId)
+	}
+
+	return report, nil
+}
+
+// helper function to find the orphans, duplicate names and cycles among the [objects]
+// the issues are sorted by their type and objectId
+func findFsckIssues(objects map[uint32]fsckObject) []FsckIssue {
+	var issues []FsckIssue
+
+	isRoot := func(parentId uint32) bool {
+		return parentId == 0 || parentId == ParentObjectId
+	}
+
+	// orphans
+	for _, obj := range objects {
+		if isRoot(obj.parentId) {
+			continue
+		}
+
+		if _, ok := objects[obj.parentId]; !ok {
+			issues = append(issues, newFsckIssue(OrphanObject, obj, fmt.Sprintf("parent %d does not exist", obj.parentId)))
+		}
+	}
+
+	// duplicate names
+	siblings := map[uint32]map[string][]fsckObject{}
+	for _, obj := range objects {
+		parentId := normalizeParentId(obj.parentId)
+		if _, ok := siblings[parentId]; !ok {
+			siblings[parentId] = map[string][]fsckObject{}
+		}
+
+		siblings[parentId][obj.name] = append(siblings[parentId][obj.name], obj)
+	}
+
+	for _, names := range siblings {
+		for name, dups := range names {
+			if len(dups) < 2 {
+				continue
+			}
+
+			for _, obj := range dups {
+				issues = append(issues, newFsckIssue(DuplicateName, obj, fmt.Sprintf("%d objects named %s in the directory", len(dups), name)))
+			}
+		}
+	}
+
+	// cycles
+	// objects whose chain of parents has already been followed
+	resolved := map[uint32]bool{}
+	for objectId := range objects {
+		var chain []uint32
+		chainIndex := map[uint32]int{}
+
+		cur := objectId
+		for {
+			if resolved[cur] {
+				break
+			}
+
+			if idx, ok := chainIndex[cur]; ok {
+				for _, cycleId := range chain[idx:] {
+					issues = append(issues, newFsckIssue(ObjectCycle, objects[cycleId], "the chain of parents loops back to the object"))
+				}
+
+				break
+			}
+
+			obj, ok := objects[cur]
+			if !ok {
+				break
+			}
+
+			chainIndex[cur] = len(chain)
+			chain = append(chain, cur)
+
+			if isRoot(obj.parentId) {
+				break
+			}
+
+			cur = obj.parentId
+		}
+
+		for _, id := range chain {
+			resolved[id] = true
+		}
+	}
+
+	issueOrder := map[FsckIssueType]int{OrphanObject: 0, DuplicateName: 1, ObjectCycle: 2}
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Type != issues[j].Type {
+			return issueOrder[issues[i].Type] < issueOrder[issues[j].Type]
+		}
+
+		return issues[i].ObjectId < issues[j].ObjectId
+	})
+
+	return issues
+}
+
+func newFsckIssue(issueType FsckIssueType, obj fsckObject, detail string) FsckIssue {
+	return FsckIssue{
+		Type:     issueType,
+		ObjectId: obj.objectId,
+		ParentId: obj.parentId,
+		Name:     obj.name,
+		Detail:   detail,
+	}
+}
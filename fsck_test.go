@@ -0,0 +1,65 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"log"
+	"testing"
+)
+
+func TestFsckStorage(t *testing.T) {
+	dev, err := Initialize(Init{})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	storages, err := FetchStorages(dev)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	sid := storages[0].Sid
+
+	Convey("Scan the storage | FsckStorage", t, func() {
+		report, err := FsckStorage(dev, sid, FsckOptions{})
+
+		So(err, ShouldBeNil)
+		So(report.Checked, ShouldBeGreaterThan, 0)
+		So(len(report.Repaired), ShouldEqual, 0)
+	})
+
+	Dispose(dev)
+}
+
+func TestFindFsckIssues(t *testing.T) {
+	Convey("Test findFsckIssues", t, func() {
+		objects := map[uint32]fsckObject{
+			1: {objectId: 1, parentId: 0, name: "dir"},
+			2: {objectId: 2, parentId: 1, name: "a.txt"},
+			3: {objectId: 3, parentId: 1, name: "a.txt"},
+			4: {objectId: 4, parentId: 99, name: "orphan.txt"},
+			5: {objectId: 5, parentId: 6, name: "x"},
+			6: {objectId: 6, parentId: 5, name: "y"},
+			7: {objectId: 7, parentId: 5, name: "z"},
+		}
+
+		issues := findFsckIssues(objects)
+
+		So(len(issues), ShouldEqual, 5)
+
+		So(issues[0].Type, ShouldEqual, OrphanObject)
+		So(issues[0].ObjectId, ShouldEqual, 4)
+
+		So(issues[1].Type, ShouldEqual, DuplicateName)
+		So(issues[1].ObjectId, ShouldEqual, 2)
+		So(issues[2].Type, ShouldEqual, DuplicateName)
+		So(issues[2].ObjectId, ShouldEqual, 3)
+
+		// 7 hangs off the cycle but is not a part of it
+		So(issues[3].Type, ShouldEqual, ObjectCycle)
+		So(issues[3].ObjectId, ShouldEqual, 5)
+		So(issues[4].Type, ShouldEqual, ObjectCycle)
+		So(issues[4].ObjectId, ShouldEqual, 6)
+
+		So(len(findFsckIssues(map[uint32]fsckObject{1: {objectId: 1, parentId: ParentObjectId, name: "a.txt"}})), ShouldEqual, 0)
+	})
+}
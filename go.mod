@@ -4,6 +4,7 @@ go 1.15
 
 require (
 	github.com/ganeshrvel/go-mtpfs v1.0.4-0.20210103160034-fed7690a2f8a
+	github.com/ganeshrvel/usb v0.0.0-20210103155855-14d96f5ae403
 	github.com/smartystreets/goconvey v1.6.4
 	golang.org/x/sys v0.0.0-20201231184435-2d18734c6014 // indirect
 )
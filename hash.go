@@ -0,0 +1,227 @@
+package mtpx
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"hash"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// a file going through the hashing pipeline
+type hashJob struct {
+	fi     *FileInfo
+	chunks chan *[]byte
+
+	// set by the reader before [chunks] is closed
+	err error
+
+	sum string
+
+	// closed once the file is hashed
+	done chan struct{}
+}
+
+// Hash the contents of the files on the device, eg: to generate a manifest or to verify a backup
+// the files are read one after another while [opts.Workers] goroutines hash the data which was already read,
+// so that the usb bus stays busy while the digests are computed on the other cores
+// at most [hashPipelineDepth] chunks of [TransferChunkSize] bytes are read ahead of each hasher
+// the results are passed to [cb] one at a time and in the order of [files], from a goroutine of its own. a file which could not be read is reported with [FileHash.Err] and the rest are still hashed
+// returning an error from [cb] stops the hashing
+func HashFiles(dev *mtp.Device, files []*FileInfo, opts HashOptions, cb HashCb) error {
+	newHash, err := hashConstructor(opts.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	algorithm := opts.Algorithm
+	if algorithm == "" {
+		algorithm = HashSha256
+	}
+
+	var sources []string
+	var storageId uint32
+	for _, fi := range files {
+		sources = append(sources, fi.FullPath)
+
+		if fi.Info != nil {
+			storageId = fi.Info.StorageID
+		}
+	}
+
+	op := &OperationInfo{Type: HashFilesOp, StorageId: storageId, Sources: sources}
+
+	return runMiddlewares(dev, op, func() error {
+		return hashFiles(dev, files, opts.Workers, newHash, func(job *hashJob) error {
+			return cb(&FileHash{FileInfo: job.fi, Algorithm: algorithm, Sum: job.sum, Err: job.err})
+		})
+	})
+}
+
+// helper function for [HashFiles]
+func hashFiles(dev *mtp.Device, files []*FileInfo, workers int, newHash func() hash.Hash, cb func(job *hashJob) error) error {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	jobs := make(chan *hashJob, workers)
+	ordered := make(chan *hashJob, workers+1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for job := range jobs {
+				h := newHash()
+				for chunk := range job.chunks {
+					_, _ = h.Write(*chunk)
+					putTransferBuffer(chunk)
+				}
+
+				if job.err == nil {
+					job.sum = hex.EncodeToString(h.Sum(nil))
+				}
+
+				close(job.done)
+			}
+		}()
+	}
+
+	// deliver the results in order
+	var stopped int32
+	var cbErr error
+	collected := make(chan struct{})
+
+	go func() {
+		defer close(collected)
+
+		for job := range ordered {
+			<-job.done
+
+			if cbErr != nil {
+				continue
+			}
+
+			if err := cb(job); err != nil {
+				cbErr = err
+				atomic.StoreInt32(&stopped, 1)
+			}
+		}
+	}()
+
+	var err error
+	for _, fi := range files {
+		if atomic.LoadInt32(&stopped) == 1 {
+			break
+		}
+
+		job := &hashJob{fi: fi, chunks: make(chan *[]byte, hashPipelineDepth), done: make(chan struct{})}
+		ordered <- job
+		jobs <- job
+
+		if fi.IsDir {
+			job.err = InvalidPathError{error: fmt.Errorf("cannot hash a directory: %s", fi.FullPath)}
+			close(job.chunks)
+
+			continue
+		}
+
+		hw := &hashChunkWriter{dev: dev, chunks: job.chunks}
+		startTime := time.Now()
+
+		_err := withTransferTimeout(dev, func() error {
+			return dev.GetObject(fi.ObjectId, hw, mtp.EmptyProgressFunc)
+		})
+		if _err != nil {
+			recordTransferError(dev)
+
+			switch _err.(type) {
+			// the operation was aborted, the rest of the files are not read
+			case OperationCancelledError, OperationStalledError:
+				err = _err
+				job.err = _err
+
+			default:
+				job.err = FileTransferError{error: _err}
+			}
+		} else {
+			recordTransferredBytes(dev, Download, fi.Size, time.Since(startTime))
+		}
+
+		hw.flush()
+		close(job.chunks)
+
+		if err != nil {
+			break
+		}
+	}
+
+	close(jobs)
+	close(ordered)
+
+	wg.Wait()
+	<-collected
+
+	if err != nil {
+		return err
+	}
+
+	return cbErr
+}
+
+func hashConstructor(algorithm HashAlgorithm) (func() hash.Hash, error) {
+	switch algorithm {
+	case "", HashSha256:
+		return sha256.New, nil
+	case HashSha1:
+		return sha1.New, nil
+	case HashMd5:
+		return md5.New, nil
+	}
+
+	return nil, UnsupportedOperationError{error: fmt.Errorf("unknown hash algorithm: %v", algorithm)}
+}
+
+func (hw *hashChunkWriter) Write(p []byte) (int, error) {
+	if err := touchOperation(hw.dev); err != nil {
+		return 0, err
+	}
+
+	written := 0
+	for written < len(p) {
+		if hw.buf == nil {
+			hw.buf = getTransferBuffer(TransferChunkSize())
+			hw.n = 0
+		}
+
+		n := copy((*hw.buf)[hw.n:], p[written:])
+		hw.n += n
+		written += n
+
+		if hw.n == len(*hw.buf) {
+			hw.flush()
+		}
+	}
+
+	return written, nil
+}
+
+// pass the buffered bytes on to the hasher
+func (hw *hashChunkWriter) flush() {
+	if hw.buf == nil {
+		return
+	}
+
+	*hw.buf = (*hw.buf)[:hw.n]
+	hw.chunks <- hw.buf
+	hw.buf = nil
+}
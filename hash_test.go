@@ -0,0 +1,84 @@
+package mtpx
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestHashFiles(t *testing.T) {
+	Convey("Test hashConstructor", t, func() {
+		newHash, err := hashConstructor("")
+		So(err, ShouldBeNil)
+		So(newHash().Size(), ShouldEqual, sha256.Size)
+
+		_, err = hashConstructor(HashMd5)
+		So(err, ShouldBeNil)
+
+		_, err = hashConstructor("crc32")
+		So(err, ShouldHaveSameTypeAs, UnsupportedOperationError{})
+	})
+
+	Convey("Test hashChunkWriter", t, func() {
+		SetTransferChunkSize(minTransferChunkSize)
+		defer SetTransferChunkSize(0)
+
+		data := make([]byte, minTransferChunkSize*2+10)
+		for i := range data {
+			data[i] = byte(i)
+		}
+
+		chunks := make(chan *[]byte, 8)
+		hw := &hashChunkWriter{chunks: chunks}
+
+		n, err := hw.Write(data[:100])
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 100)
+
+		_, err = hw.Write(data[100:])
+		So(err, ShouldBeNil)
+
+		hw.flush()
+		close(chunks)
+
+		h := sha256.New()
+		var total int
+		for chunk := range chunks {
+			So(len(*chunk), ShouldBeLessThanOrEqualTo, minTransferChunkSize)
+
+			total += len(*chunk)
+			h.Write(*chunk)
+		}
+
+		sum := sha256.Sum256(data)
+		So(total, ShouldEqual, len(data))
+		So(hex.EncodeToString(h.Sum(nil)), ShouldEqual, hex.EncodeToString(sum[:]))
+	})
+
+	Convey("Test hashFiles keeps the order of the files", t, func() {
+		// the directories are never read from the device, a nil device is good enough here
+		var files []*FileInfo
+		for _, name := range []string{"/a", "/b", "/c", "/d", "/e"} {
+			files = append(files, &FileInfo{FullPath: name, IsDir: true})
+		}
+
+		newHash, _ := hashConstructor(HashSha256)
+
+		// [cb] runs on the collector goroutine, the assertions are made once the hashing returns
+		var order []string
+		var errs []error
+		err := hashFiles(nil, files, 2, newHash, func(job *hashJob) error {
+			order = append(order, job.fi.FullPath)
+			errs = append(errs, job.err)
+
+			return nil
+		})
+
+		So(err, ShouldBeNil)
+		So(order, ShouldResemble, []string{"/a", "/b", "/c", "/d", "/e"})
+		for _, e := range errs {
+			So(e, ShouldHaveSameTypeAs, InvalidPathError{})
+		}
+	})
+}
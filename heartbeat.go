@@ -0,0 +1,205 @@
+package mtpx
+
+import (
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// a running operation watched by the heartbeats
+type operationMonitor struct {
+	op        *OperationInfo
+	startTime time.Time
+	config    HeartbeatConfig
+
+	// unix nano time of the latest progress
+	lastActivity int64
+
+	// set to 1 once the operation is stalled
+	stalled int32
+
+	// number of the user callbacks and the device-side transactions running, the operation is not considered stalled meanwhile
+	// see [withoutStallTimeout]
+	holds int32
+
+	done chan struct{}
+}
+
+var deviceHeartbeats = struct {
+	sync.Mutex
+	config  map[*mtp.Device]HeartbeatConfig
+	running map[*mtp.Device]*operationMonitor
+}{config: map[*mtp.Device]HeartbeatConfig{}, running: map[*mtp.Device]*operationMonitor{}}
+
+// emit periodic heartbeats while an operation of the device is running and abort the operations which stall
+// the operations which may not make any visible progress for a long time (eg: listing a huge directory, a device side move)
+// are covered, the UIs can use the heartbeats to tell a slow operation from a hung one
+// the config applies to the operations started from now on. use an empty config to turn the heartbeats off
+func SetHeartbeat(dev *mtp.Device, config HeartbeatConfig) {
+	deviceHeartbeats.Lock()
+	defer deviceHeartbeats.Unlock()
+
+	if config.Cb == nil && config.StallTimeout <= 0 {
+		delete(deviceHeartbeats.config, dev)
+
+		return
+	}
+
+	if config.Interval <= 0 {
+		config.Interval = defaultHeartbeatInterval
+	}
+
+	// check for the stall at least as often as the stall timeout
+	if config.StallTimeout > 0 && config.StallTimeout < config.Interval {
+		config.Interval = config.StallTimeout
+	}
+
+	deviceHeartbeats.config[dev] = config
+}
+
+// start watching [op]
+// only the outermost operation is watched, the operations which are a part of it (eg: [MakeDirectory] inside [UploadFiles]) count towards its progress
+// call [stop] with the error of the operation once it returns. if the operation failed after it stalled then the
+// session of the device is reset (see [recoverStalledTransfer]), so that the next operation doesn't find the device hung
+func monitorOperation(dev *mtp.Device, op *OperationInfo) (stop func(err error)) {
+	deviceHeartbeats.Lock()
+	defer deviceHeartbeats.Unlock()
+
+	config, ok := deviceHeartbeats.config[dev]
+	if !ok {
+		return func(error) {}
+	}
+
+	if _, ok := deviceHeartbeats.running[dev]; ok {
+		return func(error) {}
+	}
+
+	m := &operationMonitor{
+		op:           op,
+		startTime:    time.Now(),
+		config:       config,
+		lastActivity: time.Now().UnixNano(),
+		done:         make(chan struct{}),
+	}
+	deviceHeartbeats.running[dev] = m
+
+	go m.run()
+
+	return func(err error) {
+		deviceHeartbeats.Lock()
+		if deviceHeartbeats.running[dev] == m {
+			delete(deviceHeartbeats.running, dev)
+		}
+		deviceHeartbeats.Unlock()
+
+		close(m.done)
+
+		if err != nil && atomic.LoadInt32(&m.stalled) == 1 {
+			_ = recoverStalledTransfer(dev)
+		}
+	}
+}
+
+// pass [fi] to the user callback [cb] of the running walk
+// the time spent in the callback doesn't count towards the stall timeout, the walk is touched again once it returns
+func runWalkCb(dev *mtp.Device, cb WalkCb, fi *FileInfo) error {
+	err := withoutStallTimeout(dev, func() error {
+		return cb(fi.ObjectId, fi, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	return touchOperation(dev)
+}
+
+// run [fn] without counting its time towards the stall timeout of the running operation, eg: a user callback or a
+// device-side copy which gives no progress until it is over
+// the operation is touched once [fn] returns
+func withoutStallTimeout(dev *mtp.Device, fn func() error) error {
+	deviceHeartbeats.Lock()
+	m, ok := deviceHeartbeats.running[dev]
+	deviceHeartbeats.Unlock()
+
+	if !ok {
+		return fn()
+	}
+
+	atomic.AddInt32(&m.holds, 1)
+	defer func() {
+		atomic.StoreInt64(&m.lastActivity, time.Now().UnixNano())
+		atomic.AddInt32(&m.holds, -1)
+	}()
+
+	return fn()
+}
+
+// record the progress of the running operation of the device
+// returns an [OperationStalledError] if the operation was stalled, the caller is expected to abort the operation
+// returns an [OperationCancelledError] if the device is being shut down, see [Shutdown]
+func touchOperation(dev *mtp.Device) error {
+	if err := checkCancelled(dev); err != nil {
+		return err
+	}
+
+	deviceHeartbeats.Lock()
+	m, ok := deviceHeartbeats.running[dev]
+	deviceHeartbeats.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	if atomic.LoadInt32(&m.stalled) == 1 {
+		return OperationStalledError{
+			error: fmt.Errorf("%v made no progress for %v", m.op.Type, m.config.StallTimeout),
+		}
+	}
+
+	atomic.StoreInt64(&m.lastActivity, time.Now().UnixNano())
+
+	return nil
+}
+
+// drop the heartbeat config of the device
+// the running operations are left alone
+func disposeHeartbeat(dev *mtp.Device) {
+	SetHeartbeat(dev, HeartbeatConfig{})
+}
+
+func (m *operationMonitor) run() {
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.done:
+			return
+
+		case now := <-ticker.C:
+			hb := m.heartbeat(now)
+
+			if hb.Stalled {
+				atomic.StoreInt32(&m.stalled, 1)
+			}
+
+			if m.config.Cb != nil {
+				m.config.Cb(hb)
+			}
+		}
+	}
+}
+
+func (m *operationMonitor) heartbeat(now time.Time) *Heartbeat {
+	idle := now.Sub(time.Unix(0, atomic.LoadInt64(&m.lastActivity)))
+
+	return &Heartbeat{
+		Operation: m.op,
+		StartTime: m.startTime,
+		Idle:      idle,
+		Stalled: atomic.LoadInt32(&m.stalled) == 1 ||
+			(m.config.StallTimeout > 0 && idle >= m.config.StallTimeout && atomic.LoadInt32(&m.holds) == 0),
+	}
+}
@@ -0,0 +1,133 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHeartbeat(t *testing.T) {
+	Convey("Test heartbeats of a running operation", t, func() {
+		// the heartbeats are keyed by the device, a nil device is good enough here
+		defer disposeHeartbeat(nil)
+
+		var mu sync.Mutex
+		var beats []*Heartbeat
+
+		SetHeartbeat(nil, HeartbeatConfig{
+			Cb: func(hb *Heartbeat) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				beats = append(beats, hb)
+			},
+			Interval: 10 * time.Millisecond,
+		})
+
+		op := &OperationInfo{Type: WalkOp, Sources: []string{"/DCIM"}}
+		stop := monitorOperation(nil, op)
+
+		// the nested operations are not watched separately
+		stopNested := monitorOperation(nil, &OperationInfo{Type: MakeDirectoryOp})
+		stopNested(nil)
+
+		for i := 0; i < 5; i++ {
+			time.Sleep(10 * time.Millisecond)
+			So(touchOperation(nil), ShouldBeNil)
+		}
+
+		stop(nil)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		So(len(beats), ShouldBeGreaterThan, 0)
+		for _, hb := range beats {
+			So(hb.Operation, ShouldEqual, op)
+			So(hb.Stalled, ShouldBeFalse)
+		}
+
+		// no operation is running
+		So(touchOperation(nil), ShouldBeNil)
+	})
+
+	Convey("Test a stalled operation", t, func() {
+		defer disposeHeartbeat(nil)
+
+		stalled := make(chan *Heartbeat, 1)
+
+		SetHeartbeat(nil, HeartbeatConfig{
+			Cb: func(hb *Heartbeat) {
+				if hb.Stalled {
+					select {
+					case stalled <- hb:
+					default:
+					}
+				}
+			},
+			StallTimeout: 20 * time.Millisecond,
+		})
+
+		stop := monitorOperation(nil, &OperationInfo{Type: RenameFileOp})
+		defer stop(nil)
+
+		So(touchOperation(nil), ShouldBeNil)
+
+		select {
+		case hb := <-stalled:
+			So(hb.Idle, ShouldBeGreaterThanOrEqualTo, 20*time.Millisecond)
+		case <-time.After(time.Second):
+			So("the operation was not reported as stalled", ShouldBeEmpty)
+		}
+
+		err := touchOperation(nil)
+		So(err, ShouldHaveSameTypeAs, OperationStalledError{})
+	})
+
+	Convey("Test a slow walk callback | runWalkCb", t, func() {
+		defer disposeHeartbeat(nil)
+
+		SetHeartbeat(nil, HeartbeatConfig{StallTimeout: 10 * time.Millisecond})
+
+		stop := monitorOperation(nil, &OperationInfo{Type: WalkOp})
+		defer stop(nil)
+
+		// the time spent in the callback is not a stall of the walk
+		err := runWalkCb(nil, func(objectId uint32, fi *FileInfo, err error) error {
+			time.Sleep(50 * time.Millisecond)
+
+			return nil
+		}, &FileInfo{ObjectId: 1})
+		So(err, ShouldBeNil)
+		So(touchOperation(nil), ShouldBeNil)
+	})
+
+	Convey("Test a slow device-side copy | withDeviceSideProgress", t, func() {
+		defer disposeHeartbeat(nil)
+
+		SetHeartbeat(nil, HeartbeatConfig{StallTimeout: 10 * time.Millisecond})
+
+		stop := monitorOperation(nil, &OperationInfo{Type: CopyFileOp})
+		defer stop(nil)
+
+		// the device gives no progress while it copies, that is not a stall of the operation
+		objectId, err := withDeviceSideProgress(nil, &FileInfo{ObjectId: 1}, func() (uint32, error) {
+			time.Sleep(50 * time.Millisecond)
+
+			return 2, nil
+		})
+		So(err, ShouldBeNil)
+		So(objectId, ShouldEqual, 2)
+		So(touchOperation(nil), ShouldBeNil)
+	})
+
+	Convey("Test disabled heartbeats", t, func() {
+		SetHeartbeat(nil, HeartbeatConfig{Interval: time.Millisecond})
+
+		stop := monitorOperation(nil, &OperationInfo{Type: WalkOp})
+		defer stop(nil)
+
+		So(touchOperation(nil), ShouldBeNil)
+	})
+}
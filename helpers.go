@@ -4,12 +4,87 @@ import (
 	"errors"
 	"fmt"
 	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/ganeshrvel/go-mtpx/devicepath"
+	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 )
 
+// helper function to select and configure the mtp device
+func openDevice(init Init) (*mtp.Device, error) {
+	var pattern string
+	if init.Device != nil {
+		pattern = devicePattern(init.Device)
+	}
+
+	dev, err := mtp.SelectDeviceWithDebugging(pattern, init.DebugMode)
+
+	if err != nil {
+		return nil, MtpDetectFailedError{error: err}
+	}
+
+	dev.MTPDebug = init.DebugMode
+	dev.DataDebug = init.DebugMode
+	dev.USBDebug = init.DebugMode
+
+	dev.Timeout = devTimeout
+
+	if err = dev.Configure(); err != nil {
+		dev.Close()
+
+		if isDeviceBusyError(err) {
+			return nil, DeviceBusyError{error: fmt.Errorf("the device is being used by another application: %v", err)}
+		}
+
+		return nil, ConfigureError{error: err}
+	}
+
+	setTransferStallTimeout(dev, init.TransferStallTimeout)
+
+	return dev, nil
+}
+
+// check whether the device advertises the operation [code]
+func supportsOperation(dev *mtp.Device, code uint16) bool {
+	info, err := FetchDeviceInfo(dev)
+	if err != nil {
+		return false
+	}
+
+	for _, c := range info.OperationsSupported {
+		if c == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// check whether the error was caused by another host application holding the device
+// [mtp.Device.Configure] wraps the response codes into plain errors, so the messages are matched as well
+func isDeviceBusyError(err error) bool {
+	if rc, ok := err.(mtp.RCError); ok {
+		switch rc {
+		case mtp.RC_DeviceBusy, mtp.RC_SessionAlreadyOpened, mtp.RC_OperationNotSupported:
+			return true
+		}
+
+		return false
+	}
+
+	msg := err.Error()
+	for _, pattern := range deviceBusyErrorPatterns {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // fetch the file size of the object
 func GetFileSize(dev *mtp.Device, obj *mtp.ObjectInfo, objectId uint32, isDir bool) (int64, error) {
 	if isDir {
@@ -49,6 +124,10 @@ func GetObjectFromObjectId(dev *mtp.Device, objectId uint32, parentPath string)
 		}, nil
 	}
 
+	if err := checkCancelled(dev); err != nil {
+		return nil, err
+	}
+
 	if err := dev.GetObjectInfo(objectId, &obj); err != nil {
 		return nil, FileObjectError{error: err}
 	}
@@ -59,12 +138,30 @@ func GetObjectFromObjectId(dev *mtp.Device, objectId uint32, parentPath string)
 		return nil, FileObjectError{error: err}
 	}
 
+	return newFileInfo(dev, objectId, &obj, size, parentPath), nil
+}
+
+// build the [FileInfo] of the object [objectId] out of its ObjectInfo dataset [obj] and its [size]
+// [parentPath] is required to keep track of the [fullPath] of the object
+func newFileInfo(dev *mtp.Device, objectId uint32, obj *mtp.ObjectInfo, size int64, parentPath string) *FileInfo {
+	var uid string
+	if PersistentUids() {
+		uid = persistentUid(dev, objectId)
+	}
+
+	return buildFileInfo(objectId, obj, size, parentPath, uid)
+}
+
+// helper function for [newFileInfo], the persistent unique identifier [uid] is already known
+func buildFileInfo(objectId uint32, obj *mtp.ObjectInfo, size int64, parentPath, uid string) *FileInfo {
+	isDir := isObjectADir(obj)
+
 	filename := obj.Filename
-	_parentPath := fixSlash(parentPath)
-	fullPath := getFullPath(_parentPath, filename)
+	_parentPath := devicepath.Clean(parentPath)
+	fullPath := devicepath.Join(_parentPath, filename)
 
 	return &FileInfo{
-		Info:       &obj,
+		Info:       obj,
 		Size:       size,
 		IsDir:      isDir,
 		ModTime:    obj.ModificationDate,
@@ -74,19 +171,88 @@ func GetObjectFromObjectId(dev *mtp.Device, objectId uint32, parentPath string)
 		Extension:  extension(obj.Filename, isDir),
 		ParentId:   obj.ParentObject,
 		ObjectId:   objectId,
-	}, nil
+		Category:   fileCategory(obj.ObjectFormat, obj.Filename, isDir),
+
+		ProtectionStatus: obj.ProtectionStatus,
+		WriteProtected:   isWriteProtected(obj.ProtectionStatus),
+		PersistentUid:    uid,
+	}
 }
 
 // fetch the object using [parentId] and [filename]
-// it matches the [filename] to the list of files in the directory
+// it matches the [filename] to the list of files in the directory, see [SetPathMatchMode]
+// a raw (byte for byte) match of the device filename is always preferred over a loose match
+// if more than one object matches then one of them is picked using [SetDuplicatePolicy]
 // Since the [parentPath] is unavailable here the [fullPath] property of the resulting object [FileInfo] may not be valid.
 func GetObjectFromParentIdAndFilename(dev *mtp.Device, storageId uint32, parentId uint32, filename string) (*FileInfo, error) {
+	// only [DuplicateNewest] and [DuplicateError] have to look at every object of the same name
+	policy := FetchDuplicatePolicy(dev)
+	all := policy == DuplicateNewest || policy == DuplicateError
+
+	candidates, err := matchingObjects(dev, storageId, parentId, filename, all)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(candidates) < 1 {
+		return nil, FileNotFoundError{error: fmt.Errorf("file not found: %s", filename)}
+	}
+
+	return pickDuplicate(candidates, filename, policy)
+}
+
+// fetch the objects inside [parentId] which match [filename], see [SetPathMatchMode]
+// the raw (byte for byte) matches are returned if there are any, the loose matches otherwise
+// unless [all] is set the uncached lookup stops at the first raw match
+func matchingObjects(dev *mtp.Device, storageId uint32, parentId uint32, filename string, all bool) ([]*FileInfo, error) {
+	mode := PathMatching()
+
+	// if the metadata cache is enabled then match the [filename] against the (cached) directory listing
+	if c := getDeviceCache(dev); c != nil {
+		if !cacheBypassed(dev) {
+			if candidates, ok := c.getMatches(storageId, parentId, filename, mode); ok {
+				return candidates, nil
+			}
+		}
+
+		children, err := listDirectory(dev, storageId, parentId, "")
+		if err != nil {
+			return nil, err
+		}
+
+		var exact, loose []*FileInfo
+		for _, fi := range children {
+			switch matchFilename(fi.Name, filename, mode) {
+			case exactFilenameMatch:
+				exact = append(exact, fi)
+
+			case looseFilenameMatch:
+				loose = append(loose, fi)
+			}
+		}
+
+		if len(exact) > 0 {
+			return exact, nil
+		}
+
+		return loose, nil
+	}
+
+	if err := checkCancelled(dev); err != nil {
+		return nil, err
+	}
+
 	handles := mtp.Uint32Array{}
 	if err := dev.GetObjectHandles(storageId, mtp.GOH_ALL_ASSOCS, parentId, &handles); err != nil {
 		return nil, FileObjectError{error: err}
 	}
 
+	var exactIds, looseIds []uint32
 	for _, objectId := range handles.Values {
+		if err := checkCancelled(dev); err != nil {
+			return nil, err
+		}
+
 		// fetch the ObjectFileName
 		var val mtp.StringValue
 		if err := dev.GetObjectPropValue(objectId, mtp.OPC_ObjectFileName, &val); err != nil {
@@ -95,22 +261,36 @@ func GetObjectFromParentIdAndFilename(dev *mtp.Device, storageId uint32, parentI
 
 		// if the ObjectFileName doesn't match the [filename] then skip the current iteration
 		// this will avoid fetching the whole object properties and improve the performance a bit.
-		if !strings.EqualFold(val.Value, filename) {
-			continue
+		switch matchFilename(val.Value, filename, mode) {
+		case exactFilenameMatch:
+			exactIds = append(exactIds, objectId)
+
+		case looseFilenameMatch:
+			looseIds = append(looseIds, objectId)
 		}
 
+		// a raw match wins over the loose ones, the rest of the objects are only needed to pick among the duplicates
+		if len(exactIds) > 0 && !all {
+			break
+		}
+	}
+
+	candidateIds := exactIds
+	if len(candidateIds) < 1 {
+		candidateIds = looseIds
+	}
+
+	candidates := make([]*FileInfo, 0, len(candidateIds))
+	for _, objectId := range candidateIds {
 		fi, err := GetObjectFromObjectId(dev, objectId, "")
 		if err != nil {
 			return nil, FileObjectError{error: err}
 		}
 
-		// return the current objectId if the filename == fi.Name
-		if strings.EqualFold(fi.Name, filename) {
-			return fi, nil
-		}
+		candidates = append(candidates, fi)
 	}
 
-	return nil, FileNotFoundError{error: fmt.Errorf("file not found: %s", filename)}
+	return candidates, nil
 }
 
 // fetch the object information using [fullPath]
@@ -120,13 +300,13 @@ func GetObjectFromPath(dev *mtp.Device, storageId uint32, fullPath string) (fInf
 		return nil, InvalidPathError{error: fmt.Errorf("path does not Exists. path: %s", fullPath)}
 	}
 
-	_filePath := fixSlash(fullPath)
+	_filePath := devicepath.Clean(fullPath)
 
-	if _filePath == PathSep {
+	if _filePath == devicepath.Separator {
 		return GetObjectFromObjectId(dev, ParentObjectId, "")
 	}
 
-	splittedFilePath := strings.Split(_filePath, PathSep)
+	splittedFilePath := strings.Split(_filePath, devicepath.Separator)
 
 	var objectId = uint32(ParentObjectId)
 	var resultCount = 0
@@ -200,13 +380,112 @@ func GetObjectFromObjectIdOrPath(dev *mtp.Device, storageId uint32, fileProp Fil
 	return fo, nil
 }
 
+// check if the error reports an invalid object handle (eg: the object was deleted)
+func isInvalidObjectHandleError(err error) bool {
+	switch v := err.(type) {
+	case FileObjectError:
+		return isInvalidObjectHandleError(v.error)
+
+	case mtp.RCError:
+		return v == mtp.RC_InvalidObjectHandle
+	}
+
+	return false
+}
+
+// check if the device refused to delete an object
+// returns the response code of the device
+func deleteRefusedCode(err error) (mtp.RCError, bool) {
+	v, ok := err.(mtp.RCError)
+	if !ok {
+		return 0, false
+	}
+
+	switch v {
+	case mtp.RC_ObjectWriteProtected, mtp.RC_StoreReadOnly, mtp.RC_AccessDenied, mtp.RC_PartialDeletion:
+		return v, true
+	}
+
+	return 0, false
+}
+
+// make sure that the storage accepts changes before sending any mutating request to the device
+// read-only storages would otherwise let the request time out
+// use [deleting] for the delete operations, a few read-only storages allow deleting the objects
+func checkStorageWritable(dev *mtp.Device, storageId uint32, deleting bool) error {
+	var info mtp.StorageInfo
+	if err := dev.GetStorageInfo(storageId, &info); err != nil {
+		return StorageInfoError{error: err}
+	}
+
+	switch info.AccessCapability {
+	case mtp.AC_ReadWrite:
+		return nil
+
+	case mtp.AC_ReadOnly_with_Object_Deletion:
+		if deleting {
+			return nil
+		}
+	}
+
+	return ReadOnlyStorageError{error: fmt.Errorf("the storage is read-only: %s", info.StorageDescription)}
+}
+
+// pick the first writable storage of the device
+func firstWritableStorage(dev *mtp.Device) (uint32, error) {
+	storages, err := FetchStorages(dev)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, s := range storages {
+		if !s.ReadOnly {
+			return s.Sid, nil
+		}
+	}
+
+	return 0, ReadOnlyStorageError{error: fmt.Errorf("the device has no writable storage")}
+}
+
+// check if the protection status prevents the object from being modified or deleted
+func isWriteProtected(protectionStatus uint16) bool {
+	return protectionStatus == mtp.PS_ReadOnly || protectionStatus == mtp.PS_MTP_ReadOnlyData
+}
+
+// helper function to make sure that a write-protected object can be deleted
+// if [force] is true then the protection status of the object is cleared
+// otherwise a [FileProtectedError] is returned
+func handleProtectedObject(dev *mtp.Device, fi *FileInfo, force bool) error {
+	if !fi.WriteProtected {
+		return nil
+	}
+
+	if !force {
+		return FileProtectedError{error: fmt.Errorf("the object is write-protected: %s", fi.FullPath)}
+	}
+
+	if err := dev.SetObjectPropValue(fi.ObjectId, mtp.OPC_ProtectionStatus, &uint16Value{Value: mtp.PS_NoProtection}); err != nil {
+		return FileProtectedError{error: fmt.Errorf("unable to clear the protection status of %s: %v", fi.FullPath, err)}
+	}
+
+	fi.ProtectionStatus = mtp.PS_NoProtection
+	fi.WriteProtected = false
+
+	return nil
+}
+
 // check if the object is a directory
 func isObjectADir(obj *mtp.ObjectInfo) bool {
 	return obj.ObjectFormat == mtp.OFC_Association
 }
 
 // helper function to create a directory
+// the callers are expected to have checked that the storage is writable, see [checkStorageWritable]
 func handleMakeDirectory(dev *mtp.Device, storageId, parentId uint32, filename string) (objectId uint32, err error) {
+	if err := checkCancelled(dev); err != nil {
+		return 0, err
+	}
+
 	send := mtp.ObjectInfo{
 		StorageID:        storageId,
 		ObjectFormat:     mtp.OFC_Association,
@@ -222,23 +501,67 @@ func handleMakeDirectory(dev *mtp.Device, storageId, parentId uint32, filename s
 		return 0, SendObjectError{error: err}
 	}
 
+	invalidateCachedListing(dev, storageId, parentId)
+
 	return objId, nil
 }
 
+// build the ObjectInfo of a new file named [name] of [size] bytes inside the directory [parentId]
+// the files of 4 GiB or more have the size of 0xFFFFFFFF, the device reads them until the end of the data
+func newFileObjectInfo(storageId, parentId uint32, name string, format uint16, size int64, modTime time.Time) *mtp.ObjectInfo {
+	var compressedSize uint32
+	if size > 0xFFFFFFFF {
+		compressedSize = 0xFFFFFFFF
+	} else {
+		compressedSize = uint32(size)
+	}
+
+	return &mtp.ObjectInfo{
+		StorageID:        storageId,
+		ObjectFormat:     format,
+		ParentObject:     parentId,
+		Filename:         name,
+		CompressedSize:   compressedSize,
+		ModificationDate: modTime,
+	}
+}
+
+// send [size] bytes of [r] to the device as the file [name] inside the directory [parentId], see [handleMakeFile]
+func sendObject(dev *mtp.Device, storageId, parentId uint32, name string, format uint16, modTime time.Time, r io.Reader, size int64,
+	overwriteExisting bool, progressCb SizeProgressCb) (objectId uint32, err error) {
+	return handleMakeFile(dev, storageId, newFileObjectInfo(storageId, parentId, name, format, size, modTime), r, size, overwriteExisting, progressCb)
+}
+
 // helper function to create a device file
-func handleMakeFile(dev *mtp.Device, storageId uint32, obj *mtp.ObjectInfo, fInfo *os.FileInfo, fileBuf *os.File, overwriteExisting bool, progressCb SizeProgressCb) (objectId uint32, err error) {
+func handleMakeFile(dev *mtp.Device, storageId uint32, obj *mtp.ObjectInfo, fileBuf io.Reader, size int64, overwriteExisting bool, progressCb SizeProgressCb) (objectId uint32, err error) {
+	if err := checkCancelled(dev); err != nil {
+		return 0, err
+	}
+
 	fi, err := GetObjectFromParentIdAndFilename(dev, storageId, obj.ParentObject, obj.Filename)
 
 	// file Exists
 	if err == nil {
+		// a directory is never replaced by a file
+		if fi.IsDir {
+			return 0, InvalidPathError{error: fmt.Errorf("a directory named %s already exists, it can't be overwritten by a file", obj.Filename)}
+		}
+
 		// if [overwriteExisting] is false then just return existing [objectId] of the exisiting file
 		if !overwriteExisting {
 			return fi.ObjectId, nil
 		}
 
+		// don't delete the existing file if the new one won't fit in the upload quota
+		if err := reserveUploadQuota(dev, size); err != nil {
+			return 0, err
+		}
+
 		fileProp := FileProp{fi.ObjectId, ""}
 		// if [overwriteExisting] is true then delete the existing file
-		if err := DeleteFile(dev, storageId, []FileProp{fileProp}); err != nil {
+		if err := deleteNestedFile(dev, storageId, []FileProp{fileProp}, DeleteOptions{}); err != nil {
+			releaseUploadQuota(dev, size)
+
 			return 0, err
 		}
 	} else {
@@ -249,24 +572,51 @@ func handleMakeFile(dev *mtp.Device, storageId uint32, obj *mtp.ObjectInfo, fInf
 		default:
 			return 0, err
 		}
+
+		if err := reserveUploadQuota(dev, size); err != nil {
+			return 0, err
+		}
 	}
 
 	// create a new object handle
 	_, _, objId, err := dev.SendObjectInfo(storageId, obj.ParentObject, obj)
 	if err != nil {
+		releaseUploadQuota(dev, size)
+
 		return objId, SendObjectError{error: err}
 	}
 
-	size := (*fInfo).Size()
+	invalidateCachedListing(dev, storageId, obj.ParentObject)
+
 	// send the bytes data to the newly create object handle
-	err = dev.SendObject(fileBuf, size, func(sent int64) error {
-		if err := progressCb(size, sent, objId, nil); err != nil {
-			return err
-		}
+	var totalSent int64 = 0
+	err = withTransferTimeout(dev, func() error {
+		return dev.SendObject(fileBuf, size, func(sent int64) error {
+			totalSent = sent
 
-		return nil
+			if err := progressCb(size, sent, objId, nil); err != nil {
+				return err
+			}
+
+			return nil
+		})
 	})
 	if err != nil {
+		releaseUploadQuota(dev, size-totalSent)
+
+		// an object can't be sent in parts, drop the incomplete one so that the upload can be retried
+		if isTransferStallError(err) {
+			if rErr := recoverStalledTransfer(dev); rErr != nil {
+				return objId, rErr
+			}
+
+			_ = dev.DeleteObject(objId)
+			releaseUploadQuota(dev, totalSent)
+			invalidateCachedListing(dev, storageId, obj.ParentObject)
+
+			return 0, TransferStalledError{error: fmt.Errorf("the upload of %v stalled at %d bytes: %v", obj.Filename, totalSent, err)}
+		}
+
 		return objId, SendObjectError{error: err}
 	}
 
@@ -275,25 +625,60 @@ func handleMakeFile(dev *mtp.Device, storageId uint32, obj *mtp.ObjectInfo, fInf
 
 // helper function to create a local file
 func handleMakeLocalFile(dev *mtp.Device, fi *FileInfo, destination string, progressCb SizeProgressCb) error {
+	// the interleaved operations can't delete or move the object halfway through the download
+	release := markObjectInUse(dev, fi.ObjectId)
+	defer release()
+
+	// an existing file at [destination] is kept if the disk is full
+	if err := checkLocalSpace(destination, fi.Size); err != nil {
+		return err
+	}
+
 	f, err := os.Create(destination)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
+	if err := prepareLocalFile(f, fi.Size); err != nil {
+		return err
+	}
+
 	var totalSent int64 = 0
-	err = dev.GetObject(fi.ObjectId, f, func(sent int64) error {
-		if err := progressCb(fi.Size, sent, fi.ObjectId, err); err != nil {
-			return err
-		}
+	sw := &sparseWriter{f: f}
+	cw := &countingWriter{w: sw}
+
+	// give way to the operations queued using [Interleave], see [SetInterleaveFrequency]
+	if chunks := interleaveFrequency(dev); chunks > 0 && supportsOperation(dev, mtp.OC_GetPartialObject) {
+		err = readInterleaved(dev, fi, cw, chunks, progressCb)
+		totalSent = cw.n
+	} else {
+		err = withTransferTimeout(dev, func() error {
+			return dev.GetObject(fi.ObjectId, cw, func(sent int64) error {
+				if err := progressCb(fi.Size, sent, fi.ObjectId, err); err != nil {
+					return err
+				}
+
+				totalSent = sent
+				return nil
+			})
+		})
+	}
+
+	// pick up from the last byte written to the file
+	if isTransferStallError(err) {
+		err = resumeStalledDownload(dev, fi, cw, cw.n, progressCb, err)
+		totalSent = cw.n
+	}
 
-		totalSent = sent
-		return nil
-	})
 	if err != nil {
 		return err
 	}
 
+	if err := sw.finish(); err != nil {
+		return err
+	}
+
 	// fix the incorrect sent size
 	if totalSent < fi.Size {
 		if err := progressCb(fi.Size, fi.Size, fi.ObjectId, err); err != nil {
@@ -304,13 +689,125 @@ func handleMakeLocalFile(dev *mtp.Device, fi *FileInfo, destination string, prog
 	return err
 }
 
+// move the object to [parentId]
+// the objectId of the object does not change
+// nothing is moved if the device is in the simulation mode
+func moveObject(dev *mtp.Device, objectId, storageId, parentId uint32) error {
+	if _, ok := getSimulation(dev); ok {
+		return nil
+	}
+
+	var req, rep mtp.Container
+	req.Code = mtp.OC_MoveObject
+	req.Param = []uint32{objectId, storageId, parentId}
+
+	if err := dev.RunTransaction(&req, &rep, nil, nil, 0, mtp.EmptyProgressFunc); err != nil {
+		return MoveObjectError{error: err}
+	}
+
+	return nil
+}
+
+// copy the object into [parentId], the directories are copied with their contents
+// returns the objectId of the copy
+// nothing is copied if the device is in the simulation mode
+func copyObject(dev *mtp.Device, objectId, storageId, parentId uint32) (uint32, error) {
+	if _, ok := getSimulation(dev); ok {
+		return 0, nil
+	}
+
+	var req, rep mtp.Container
+	req.Code = mtp.OC_CopyObject
+	req.Param = []uint32{objectId, storageId, parentId}
+
+	if err := dev.RunTransaction(&req, &rep, nil, nil, 0, mtp.EmptyProgressFunc); err != nil {
+		return 0, CopyObjectError{error: err}
+	}
+
+	if len(rep.Param) < 1 {
+		return 0, CopyObjectError{error: fmt.Errorf("the device did not return the objectId of the copy")}
+	}
+
+	return rep.Param[0], nil
+}
+
+// read [size] bytes of the object starting at [offset] and write them to [w]
+// the request is split into chunks, the chunk size adapts to the observed throughput of the device
+// a failed chunk is retried with a smaller chunk size, the bytes which were already written to [w] are not requested again
+// offsets beyond 4GB are read using the android extension
+func readPartialObject(dev *mtp.Device, objectId uint32, w io.Writer, offset, size int64) error {
+	return readPartialChunks(dev, w, offset, size, func(w io.Writer, offset, chunkSize int64) error {
+		return withTransferTimeout(dev, func() error {
+			if offset > math.MaxUint32 {
+				return dev.AndroidGetPartialObject64(objectId, w, offset, uint32(chunkSize))
+			}
+
+			return dev.GetPartialObject(objectId, w, uint32(offset), uint32(chunkSize))
+		})
+	})
+}
+
+// helper function for [readPartialObject], [readChunk] reads a single chunk of the object into [w]
+// a chunk which comes back empty ends the read with an [io.ErrUnexpectedEOF], eg: if the object shrank on the device
+func readPartialChunks(dev *mtp.Device, w io.Writer, offset, size int64,
+	readChunk func(w io.Writer, offset, chunkSize int64) error) error {
+	sizer := getChunkSizer(dev)
+	defer putChunkSizer(dev, sizer)
+
+	for size > 0 {
+		chunkSize := size
+		if chunkSize > sizer.size {
+			chunkSize = sizer.size
+		}
+
+		cw := &countingWriter{w: w}
+		startTime := time.Now()
+
+		err := readChunk(cw, offset, chunkSize)
+
+		offset += cw.n
+		size -= cw.n
+		recordTransferredBytes(dev, Download, cw.n, time.Since(startTime))
+
+		if err == nil && cw.n == 0 {
+			recordTransferError(dev)
+
+			return PartialReadError{error: fmt.Errorf("the device sent no data at the offset %d, %d bytes short: %w", offset, size, io.ErrUnexpectedEOF)}
+		}
+
+		if err != nil {
+			if !sizer.recordFailure() {
+				recordTransferError(dev)
+
+				return PartialReadError{error: err}
+			}
+
+			if isTransferStallError(err) {
+				if err := recoverStalledTransfer(dev); err != nil {
+					recordTransferError(dev)
+
+					return err
+				}
+			}
+
+			recordTransferRetry(dev)
+
+			continue
+		}
+
+		sizer.recordSuccess(cw.n, time.Since(startTime))
+	}
+
+	return nil
+}
+
 // helper function to fetch the contents inside a directory
 // use [recursive] to fetch the whole nested tree
 // [objectId] and [fullPath] are optional parameters
 // if [objectId] is not available then [fullPath] will be used to fetch the [objectId]
 // dont leave both [objectId] and [fullPath] empty
 // Tips: use [objectId] whenever possible to avoid traversing down the whole file tree to process and find the [objectId]
-// if [skipDisallowedFiles] is true then files matching the [disallowedFiles] list will be ignored
+// if [skipDisallowedFiles] is true then files matching the disallowed files list will be ignored, see [SetDisallowedFilesPolicy]
 // if [skipHiddenFiles] is true then hidden files (unix style) will be ignored
 // return:
 // [totalFiles]: total number of files
@@ -322,19 +819,19 @@ func proccessWalk(dev *mtp.Device, storageId uint32, fileProp FileProp, recursiv
 		return totalFiles, totalDirectories, err
 	}
 
-	handles := mtp.Uint32Array{}
-	if err := dev.GetObjectHandles(storageId, mtp.GOH_ALL_ASSOCS, fi.ObjectId, &handles); err != nil {
-		return totalFiles, totalDirectories, ListDirectoryError{error: err}
+	children, err := listDirectory(dev, storageId, fi.ObjectId, fileProp.FullPath)
+	if err != nil {
+		return totalFiles, totalDirectories, err
 	}
 
 	totalFiles = 0
 
-	for _, objId := range handles.Values {
-		fi, err := GetObjectFromObjectId(dev, objId, fileProp.FullPath)
-		if err != nil {
-			continue
+	for _, fi := range sortWalkObjects(children, WalkDirsFirst()) {
+		if err := touchOperation(dev); err != nil {
+			return totalFiles, totalDirectories, err
 		}
 
+		objId := fi.ObjectId
 		fName := (*fi).Name
 
 		// skip the object if it's a hidden file
@@ -342,7 +839,7 @@ func proccessWalk(dev *mtp.Device, storageId uint32, fileProp FileProp, recursiv
 			continue
 		}
 
-		// if the object file name matches [disallowedFiles] list then ignore it
+		// if the object file name matches the disallowed files list then ignore it
 		if skipDisallowedFiles && isDisallowedFiles(fName) {
 			continue
 		}
@@ -353,7 +850,7 @@ func proccessWalk(dev *mtp.Device, storageId uint32, fileProp FileProp, recursiv
 			totalFiles += 1
 		}
 
-		err = cb(objId, fi, nil)
+		err = runWalkCb(dev, cb, fi)
 		if err != nil {
 			return totalFiles, totalDirectories, err
 		}
@@ -382,6 +879,67 @@ func proccessWalk(dev *mtp.Device, storageId uint32, fileProp FileProp, recursiv
 	return totalFiles, totalDirectories, nil
 }
 
+// helper function to fetch the objects inside a directory
+// the listing is served from the metadata cache when it is enabled for the device and not bypassed, see [WithoutCache]
+// [parentPath] is used to build the [FullPath] of the objects
+// objects whose information could not be fetched are left out
+func listDirectory(dev *mtp.Device, storageId, parentId uint32, parentPath string) ([]*FileInfo, error) {
+	c := getDeviceCache(dev)
+	if c != nil && !cacheBypassed(dev) {
+		if children, ok := c.getListing(storageId, parentId, parentPath); ok {
+			return children, nil
+		}
+	}
+
+	children, err := fetchDirectory(dev, storageId, parentId, parentPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if c != nil {
+		c.setListing(storageId, parentId, children)
+	}
+
+	return children, nil
+}
+
+// helper function to fetch the objects inside a directory from the device, bypassing the metadata cache
+// [parentPath] is used to build the [FullPath] of the objects
+// objects whose information could not be fetched are left out
+func fetchDirectory(dev *mtp.Device, storageId, parentId uint32, parentPath string) ([]*FileInfo, error) {
+	if err := checkCancelled(dev); err != nil {
+		return nil, err
+	}
+
+	handles := mtp.Uint32Array{}
+	if err := dev.GetObjectHandles(storageId, mtp.GOH_ALL_ASSOCS, parentId, &handles); err != nil {
+		return nil, ListDirectoryError{error: err}
+	}
+
+	// fetch the information of all the children at once, the devices which can't do it are asked one object at a time
+	if len(handles.Values) > 0 && supportsObjectPropList(dev) {
+		if list, err := getChildrenPropList(dev, parentId); err == nil {
+			return childrenFromPropList(dev, storageId, parentId, parentPath, handles.Values, list)
+		}
+	}
+
+	var children []*FileInfo
+	for _, objId := range handles.Values {
+		if err := touchOperation(dev); err != nil {
+			return nil, err
+		}
+
+		fi, err := GetObjectFromObjectId(dev, objId, parentPath)
+		if err != nil {
+			continue
+		}
+
+		children = append(children, fi)
+	}
+
+	return children, nil
+}
+
 // create a local directory
 func makeLocalDirectory(filename string) error {
 	err := os.MkdirAll(filename, os.FileMode(newLocalDirectoryMode))
@@ -424,7 +982,7 @@ func walkLocalFiles(sources []string, cb LocalWalkCb) (totalFiles, totalDirector
 				}
 
 				// filter out disallowed files
-				if isDisallowedFiles(name) {
+				if isSkippedDisallowedFile(name) {
 					return nil
 				}
 
@@ -460,10 +1018,160 @@ func walkLocalFiles(sources []string, cb LocalWalkCb) (totalFiles, totalDirector
 	return totalFiles, totalDirectories, totalSize, nil
 }
 
+// helper function to send a local file to the device as a part of [UploadFiles]
+// [name] and [size] belong to the local file, the file is created inside [ufProps.fileParentId]
+func processUploadFiles(dev *mtp.Device, storageId uint32, pInfo *ProgressInfo, name string, size int64, progressCb ProgressCb, ufProps *processUploadFilesProps) (objectId uint32, err error) {
+	result := &FileResult{
+		Source:      ufProps.sourceFilePath,
+		Destination: ufProps.destinationFilePath,
+		Size:        size,
+		Status:      FileTransferred,
+	}
+	startTime := time.Now()
+	retries := transferRetries(dev)
+	ufProps.fileFailed = false
+
+	// read the local file
+	fileBuf, err := os.Open(ufProps.sourceFilePath)
+	if err != nil {
+		err = InvalidPathError{error: err}
+
+		result.Status, result.Err = FileFailed, err
+		result.Duration = time.Since(startTime)
+		pInfo.recordFile(result, name, fileCategory(mtp.OFC_Undefined, name, false))
+		ufProps.fileFailed = true
+
+		return 0, err
+	}
+	defer fileBuf.Close()
+
+	// the file may take a free name rather than replacing an existing one, see [SetConflictPolicy]
+	freeName, err := conflictFreeName(dev, storageId, ufProps.fileParentId, name)
+	if err != nil {
+		result.Status, result.Err = FileFailed, err
+		result.Duration = time.Since(startTime)
+		pInfo.recordFile(result, name, fileCategory(mtp.OFC_Undefined, name, false))
+		ufProps.fileFailed = true
+
+		return 0, err
+	}
+
+	if freeName != name {
+		name = freeName
+		ufProps.destinationFilePath = devicepath.Join(ufProps.destinationFileParentPath, name)
+		result.Destination = ufProps.destinationFilePath
+	}
+
+	fObj := newFileObjectInfo(storageId, ufProps.fileParentId, name, mtp.OFC_Undefined, size, time.Now())
+
+	// keep track of [bulkFilesSent]
+	ufProps.bulkFilesSent += 1
+
+	pInfo.FileInfo = &FileInfo{
+		Info:       fObj,
+		Size:       size,
+		IsDir:      false,
+		ModTime:    fObj.ModificationDate,
+		Name:       fObj.Filename,
+		FullPath:   ufProps.destinationFilePath,
+		ParentPath: ufProps.destinationFileParentPath,
+		Extension:  extension(fObj.Filename, false),
+		ParentId:   fObj.ParentObject,
+		Category:   fileCategory(fObj.ObjectFormat, fObj.Filename, false),
+	}
+	pInfo.LatestSentTime = time.Now()
+
+	// create file
+	var prevSentSize int64 = 0
+	// the transfer was cancelled or stopped by the progress callback, rather than the file failing
+	var aborted bool
+	objId, err := handleMakeFile(
+		dev, storageId, fObj, fileBuf, size,
+		true,
+		func(total, sent int64, objId uint32, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if err := touchOperation(dev); err != nil {
+				aborted = true
+
+				return err
+			}
+
+			pInfo.FileInfo.ObjectId = objId
+			pInfo.ActiveFileSize.Total = total
+			pInfo.ActiveFileSize.Sent = sent
+			pInfo.ActiveFileSize.Progress = Percent(float32(sent), float32(total))
+
+			chunkSize := sent - prevSentSize
+			ufProps.bulkSizeSent += chunkSize
+
+			pInfo.BulkFileSize.Sent = ufProps.bulkSizeSent
+			pInfo.BulkFileSize.Progress = Percent(float32(ufProps.bulkSizeSent), float32(ufProps.totalSize))
+			pInfo.ETA = estimateRemainingTime(pInfo.StartTime, ufProps.bulkSizeSent, ufProps.totalSize)
+
+			pInfo.Speed = transferRate(chunkSize, pInfo.LatestSentTime)
+			recordTransferredBytes(dev, Upload, chunkSize, time.Since(pInfo.LatestSentTime))
+
+			if err = progressCb(pInfo, nil); err != nil {
+				aborted = true
+
+				return err
+			}
+
+			pInfo.LatestSentTime = time.Now()
+			prevSentSize = sent
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		result.Status, result.Err = FileFailed, err
+		result.Duration, result.Retries = time.Since(startTime), transferRetries(dev)-retries
+		pInfo.recordFile(result, name, pInfo.FileInfo.Category)
+		ufProps.fileFailed = !aborted
+
+		return 0, err
+	}
+
+	result.ObjectId = objId
+	result.Duration, result.Retries = time.Since(startTime), transferRetries(dev)-retries
+	pInfo.recordFile(result, name, pInfo.FileInfo.Category)
+
+	preserveHiddenUpload(dev, objId, ufProps.sourceFilePath)
+
+	recordTransferredFile(dev, Upload)
+	refreshStorageSpace(dev, storageId)
+
+	pInfo.FilesSent = ufProps.bulkFilesSent
+	pInfo.FilesSentProgress = Percent(float32(ufProps.bulkFilesSent), float32(ufProps.totalFiles))
+
+	pInfo.FileInfo.ObjectId = objId
+
+	return objId, nil
+}
+
 func processDownloadFiles(dev *mtp.Device, pInfo *ProgressInfo, fi *FileInfo, progressCb ProgressCb, dfProps *processDownloadFilesProps) (err error) {
+	result := &FileResult{
+		Source:      fi.FullPath,
+		Destination: dfProps.destinationFilePath,
+		ObjectId:    fi.ObjectId,
+		Size:        fi.Size,
+		Status:      FileTransferred,
+	}
+	startTime := time.Now()
+	retries := transferRetries(dev)
+	dfProps.fileFailed = false
 
 	// filter out disallowed files
-	if isDisallowedFiles(fi.Name) {
+	if pInfo.Summary.options.disallowedFiles.skipFile(fi.FullPath, fi.Name) {
+		if !fi.IsDir {
+			result.Status = FileSkipped
+			pInfo.recordFile(result, fi.Name, fi.Category)
+		}
+
 		return nil
 	}
 
@@ -494,63 +1202,173 @@ func processDownloadFiles(dev *mtp.Device, pInfo *ProgressInfo, fi *FileInfo, pr
 
 	// create the local file
 	var prevSentSize int64 = 0
-	err = handleMakeLocalFile(dev, fi, dfProps.destinationFilePath,
-		func(total, sent int64, _ uint32, err error) error {
-			if err != nil {
-				return err
-			}
+	var fromCache bool
+	// the transfer was cancelled or stopped by the progress callback, rather than the file failing
+	var aborted bool
+	sizeProgressCb := func(total, sent int64, _ uint32, err error) error {
+		if err != nil {
+			return err
+		}
 
-			pInfo.ActiveFileSize.Total = total
-			pInfo.ActiveFileSize.Sent = sent
-			pInfo.ActiveFileSize.Progress = Percent(float32(sent), float32(total))
+		if err := touchOperation(dev); err != nil {
+			aborted = true
 
-			chunkSize := sent - prevSentSize
-			dfProps.bulkSizeSent += chunkSize
+			return err
+		}
 
-			pInfo.BulkFileSize.Sent = dfProps.bulkSizeSent
-			pInfo.BulkFileSize.Progress = Percent(float32(dfProps.bulkSizeSent), float32(dfProps.totalSize))
+		pInfo.ActiveFileSize.Total = total
+		pInfo.ActiveFileSize.Sent = sent
+		pInfo.ActiveFileSize.Progress = Percent(float32(sent), float32(total))
 
-			pInfo.Speed = transferRate(chunkSize, pInfo.LatestSentTime)
-			if err = progressCb(pInfo, nil); err != nil {
-				return err
-			}
+		chunkSize := sent - prevSentSize
+		dfProps.bulkSizeSent += chunkSize
 
-			pInfo.LatestSentTime = time.Now()
-			prevSentSize = sent
+		pInfo.BulkFileSize.Sent = dfProps.bulkSizeSent
+		pInfo.BulkFileSize.Progress = Percent(float32(dfProps.bulkSizeSent), float32(dfProps.totalSize))
+		pInfo.ETA = estimateRemainingTime(pInfo.StartTime, dfProps.bulkSizeSent, dfProps.totalSize)
+
+		pInfo.Speed = transferRate(chunkSize, pInfo.LatestSentTime)
+		if !fromCache {
+			recordTransferredBytes(dev, Download, chunkSize, time.Since(pInfo.LatestSentTime))
+		}
+
+		if err = progressCb(pInfo, nil); err != nil {
+			aborted = true
+
+			return err
+		}
+
+		pInfo.LatestSentTime = time.Now()
+		prevSentSize = sent
+
+		return nil
+	}
+
+	// the unchanged files are served from the content cache, see [Init.EnableContentCache]
+	cacheKey := contentCacheKey(dev, fi)
+	fromCache, err = fetchCachedContent(dev, cacheKey, dfProps.destinationFilePath)
+	if err == nil {
+		if fromCache {
+			err = sizeProgressCb(fi.Size, fi.Size, fi.ObjectId, nil)
+		} else if err = handleMakeLocalFile(dev, fi, dfProps.destinationFilePath, sizeProgressCb); err == nil {
+			storeCachedContent(dev, cacheKey, dfProps.destinationFilePath, fi.Size)
+		}
+	}
+	if err != nil {
+		result.Status, result.Err = FileFailed, err
+		result.Duration, result.Retries = time.Since(startTime), transferRetries(dev)-retries
+		pInfo.recordFile(result, fi.Name, fi.Category)
+		dfProps.fileFailed = !aborted
+
+		return err
+	}
+
+	// keep the modification date of the device file so that the local copy mirrors the device
+	if err = os.Chtimes(dfProps.destinationFilePath, time.Now(), fi.ModTime); err != nil {
+		err = LocalFileError{error: err}
+	} else {
+		// the file may have been renamed to a dotfile
+		result.Destination, err = preserveHiddenDownload(dev, fi.ObjectId, dfProps.destinationFilePath)
+	}
+	if err != nil {
+		result.Status, result.Err = FileFailed, err
+	}
+
+	result.Duration, result.Retries = time.Since(startTime), transferRetries(dev)-retries
+	result.Cached = fromCache
+	pInfo.recordFile(result, fi.Name, fi.Category)
 
-			return nil
-		})
 	if err != nil {
 		return err
 	}
 
+	if !fromCache {
+		recordTransferredFile(dev, Download)
+	}
+
 	pInfo.FilesSent = dfProps.bulkFilesSent
 	pInfo.FilesSentProgress = Percent(float32(dfProps.bulkFilesSent), float32(dfProps.totalFiles))
 
 	return nil
 }
 
-func processDownloadFilesError(dfProps *processDownloadFilesProps, err error) (bulkFilesSent, bulkSizeSent int64, error error) {
+func processDownloadFilesError(dfProps *processDownloadFilesProps, summary *OperationSummary, err error) (bulkFilesSent, bulkSizeSent int64, _summary *OperationSummary, error error) {
 	if err != nil {
 		switch err.(type) {
-		case InvalidPathError:
-			return dfProps.bulkFilesSent, dfProps.bulkSizeSent, err
+		case InvalidPathError, LocalSpaceError:
+			return dfProps.bulkFilesSent, dfProps.bulkSizeSent, summary, err
 
 		case *os.PathError:
 			if errors.Is(err, os.ErrPermission) {
-				return dfProps.bulkFilesSent, dfProps.bulkSizeSent, FilePermissionError{error: err}
+				return dfProps.bulkFilesSent, dfProps.bulkSizeSent, summary, FilePermissionError{error: err}
 			}
 
 			if errors.Is(err, os.ErrNotExist) {
-				return dfProps.bulkFilesSent, dfProps.bulkSizeSent, InvalidPathError{error: err}
+				return dfProps.bulkFilesSent, dfProps.bulkSizeSent, summary, InvalidPathError{error: err}
 			}
 
-			return dfProps.bulkFilesSent, dfProps.bulkSizeSent, LocalFileError{error: err}
+			return dfProps.bulkFilesSent, dfProps.bulkSizeSent, summary, LocalFileError{error: err}
 		default:
-			return dfProps.bulkFilesSent, dfProps.bulkSizeSent,
+			return dfProps.bulkFilesSent, dfProps.bulkSizeSent, summary,
 				FileTransferError{error: fmt.Errorf("an error occured while downloading the files. %+v", err.Error())}
 		}
 	}
 
-	return bulkFilesSent, bulkSizeSent, err
+	return bulkFilesSent, bulkSizeSent, summary, err
+}
+
+func (f FileProp) String() string {
+	if f.ObjectId == 0 {
+		return f.FullPath
+	}
+
+	return fmt.Sprintf("objectId %d", f.ObjectId)
+}
+
+func (sw *sparseWriter) Write(p []byte) (int, error) {
+	written := 0
+
+	for len(p) > 0 {
+		// the holes are only left for the whole blocks which are aligned to the file offset
+		n := sparseBlockSize - int(sw.offset%sparseBlockSize)
+		if n > len(p) {
+			n = len(p)
+		}
+
+		block := p[:n]
+
+		if n == sparseBlockSize && isZeroBlock(block) {
+			sw.pendingHole = true
+		} else {
+			if sw.pendingHole {
+				if _, err := sw.f.Seek(sw.offset, io.SeekStart); err != nil {
+					return written, err
+				}
+
+				sw.pendingHole = false
+			}
+
+			m, err := sw.f.Write(block)
+			if err != nil {
+				sw.offset += int64(m)
+
+				return written + m, err
+			}
+		}
+
+		sw.offset += int64(n)
+		written += n
+		p = p[n:]
+	}
+
+	return written, nil
+}
+
+// extend the file over the trailing hole
+func (sw *sparseWriter) finish() error {
+	if !sw.pendingHole {
+		return nil
+	}
+
+	return sw.f.Truncate(sw.offset)
 }
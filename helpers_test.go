@@ -1,8 +1,16 @@
 package mtpx
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
 	. "github.com/smartystreets/goconvey/convey"
+	"io"
+	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -744,3 +752,129 @@ TestGetObjectFromObjectIdOrPath(t *testing.T) {
 
 	Dispose(dev)
 }
+
+func TestHandleProtectedObject(t *testing.T) {
+	Convey("Test isWriteProtected", t, func() {
+		So(isWriteProtected(mtp.PS_NoProtection), ShouldBeFalse)
+		So(isWriteProtected(mtp.PS_ReadOnly), ShouldBeTrue)
+		So(isWriteProtected(mtp.PS_MTP_ReadOnlyData), ShouldBeTrue)
+		So(isWriteProtected(mtp.PS_MTP_NonTransferableData), ShouldBeFalse)
+	})
+
+	Convey("Write-protected object | handleProtectedObject | Should throw an error", t, func() {
+		fi := &FileInfo{ObjectId: 1, FullPath: "/a.txt", ProtectionStatus: mtp.PS_ReadOnly, WriteProtected: true}
+
+		err := handleProtectedObject(nil, fi, false)
+		So(err, ShouldHaveSameTypeAs, FileProtectedError{})
+
+		err = handleProtectedObject(nil, &FileInfo{ObjectId: 2, FullPath: "/b.txt"}, false)
+		So(err, ShouldBeNil)
+	})
+}
+
+func TestIsDeviceBusyError(t *testing.T) {
+	Convey("Test isDeviceBusyError", t, func() {
+		So(isDeviceBusyError(mtp.RCError(mtp.RC_DeviceBusy)), ShouldBeTrue)
+		So(isDeviceBusyError(mtp.RCError(mtp.RC_InvalidObjectHandle)), ShouldBeFalse)
+		So(isDeviceBusyError(fmt.Errorf("OpenSession after reset: %v", mtp.RCError(mtp.RC_SessionAlreadyOpened))), ShouldBeTrue)
+		So(isDeviceBusyError(fmt.Errorf("opening after reset: LIBUSB_ERROR_BUSY")), ShouldBeTrue)
+		So(isDeviceBusyError(fmt.Errorf("opening after reset: LIBUSB_ERROR_NO_DEVICE")), ShouldBeFalse)
+	})
+}
+
+func TestSparseWriter(t *testing.T) {
+	Convey("Test writing the zero blocks as holes | sparseWriter", t, func() {
+		dir, err := ioutil.TempDir("", "mtpx-sparse")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		data := make([]byte, 4*sparseBlockSize+10)
+		copy(data, "head")
+		copy(data[2*sparseBlockSize+5:], "middle")
+
+		write := func(name string, data []byte, chunkSize int) []byte {
+			f, err := os.Create(filepath.Join(dir, name))
+			So(err, ShouldBeNil)
+
+			sw := &sparseWriter{f: f}
+			for p := data; len(p) > 0; {
+				n := chunkSize
+				if n > len(p) {
+					n = len(p)
+				}
+
+				written, err := sw.Write(p[:n])
+				So(err, ShouldBeNil)
+				So(written, ShouldEqual, n)
+
+				p = p[n:]
+			}
+
+			So(sw.finish(), ShouldBeNil)
+			So(f.Close(), ShouldBeNil)
+
+			result, err := ioutil.ReadFile(filepath.Join(dir, name))
+			So(err, ShouldBeNil)
+
+			return result
+		}
+
+		So(write("a", data, 1000), ShouldResemble, data)
+		So(write("b", data, len(data)), ShouldResemble, data)
+
+		// a trailing hole
+		zeroes := make([]byte, 2*sparseBlockSize)
+		So(write("c", zeroes, sparseBlockSize/2), ShouldResemble, zeroes)
+	})
+}
+
+// a device which holds [data] and sends at most [maxChunk] bytes per partial read, nothing past the end of [data]
+type fakePartialDevice struct {
+	data     []byte
+	maxChunk int64
+	reads    int
+}
+
+func (d *fakePartialDevice) readChunk(w io.Writer, offset, chunkSize int64) error {
+	d.reads += 1
+
+	if offset >= int64(len(d.data)) {
+		return nil
+	}
+
+	end := offset + chunkSize
+	if d.maxChunk > 0 && end > offset+d.maxChunk {
+		end = offset + d.maxChunk
+	}
+	if end > int64(len(d.data)) {
+		end = int64(len(d.data))
+	}
+
+	_, err := w.Write(d.data[offset:end])
+
+	return err
+}
+
+func TestReadPartialChunks(t *testing.T) {
+	Convey("Read the short chunks until the size is reached | readPartialChunks", t, func() {
+		d := &fakePartialDevice{data: []byte("hello world"), maxChunk: 3}
+
+		var buf bytes.Buffer
+		err := readPartialChunks(nil, &buf, 2, 9, d.readChunk)
+		So(err, ShouldBeNil)
+		So(buf.String(), ShouldEqual, "llo world")
+	})
+
+	Convey("An object which shrank | readPartialChunks | Should throw an error", t, func() {
+		d := &fakePartialDevice{data: []byte("hello")}
+
+		var buf bytes.Buffer
+		err := readPartialChunks(nil, &buf, 0, 11, d.readChunk)
+		So(err, ShouldHaveSameTypeAs, PartialReadError{})
+		So(errors.Is(err, io.ErrUnexpectedEOF), ShouldBeTrue)
+		So(buf.String(), ShouldEqual, "hello")
+
+		// the read stops at the first empty chunk instead of asking the device again and again
+		So(d.reads, ShouldEqual, 2)
+	})
+}
@@ -0,0 +1,88 @@
+package mtpx
+
+import (
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+var preserveHiddenFiles atomic.Value
+
+// whether the hidden status of the files is carried across the uploads and downloads
+func PreserveHiddenFiles() bool {
+	if preserve, ok := preserveHiddenFiles.Load().(bool); ok {
+		return preserve
+	}
+
+	return false
+}
+
+// set whether the hidden status of the files is carried across the uploads and downloads
+// uploads: the local dotfiles (and the files with the hidden attribute on Windows) get the MTP Hidden property
+// downloads: the files with the MTP Hidden property are given the hidden attribute on Windows, elsewhere they are renamed to dotfiles
+// the devices which don't support the Hidden property are left alone
+// the default is false
+func SetPreserveHiddenFiles(preserve bool) {
+	preserveHiddenFiles.Store(preserve)
+}
+
+// check whether the object has the MTP Hidden property set
+func isObjectHidden(dev *mtp.Device, objectId uint32) bool {
+	var val uint16Value
+	if err := dev.GetObjectPropValue(objectId, mtp.OPC_Hidden, &val); err != nil {
+		return false
+	}
+
+	return val.Value != 0
+}
+
+// set the MTP Hidden property of the uploaded object if the local file at [localPath] is hidden
+// the error is ignored as most of the devices don't support changing the property
+func preserveHiddenUpload(dev *mtp.Device, objectId uint32, localPath string) {
+	if !PreserveHiddenFiles() {
+		return
+	}
+
+	if !isHiddenFile(filepath.Base(localPath)) && !hasHiddenAttributeLocal(localPath) {
+		return
+	}
+
+	_ = dev.SetObjectPropValue(objectId, mtp.OPC_Hidden, &uint16Value{Value: 1})
+}
+
+// hide the downloaded file at [localPath] if the object has the MTP Hidden property set
+// returns the path of the file, which changes if the file had to be renamed to a dotfile
+// an existing local dotfile is not overwritten, the file then keeps its name
+func preserveHiddenDownload(dev *mtp.Device, objectId uint32, localPath string) (string, error) {
+	if !PreserveHiddenFiles() || !isObjectHidden(dev, objectId) {
+		return localPath, nil
+	}
+
+	if supportsHiddenAttributeLocal {
+		if err := setHiddenAttributeLocal(localPath); err != nil {
+			return localPath, LocalFileError{error: err}
+		}
+
+		return localPath, nil
+	}
+
+	name := filepath.Base(localPath)
+	if strings.HasPrefix(name, ".") {
+		return localPath, nil
+	}
+
+	hiddenPath := filepath.Join(filepath.Dir(localPath), "."+name)
+	if _, err := os.Lstat(hiddenPath); err == nil {
+		return localPath, nil
+	} else if !os.IsNotExist(err) {
+		return localPath, LocalFileError{error: err}
+	}
+
+	if err := os.Rename(localPath, hiddenPath); err != nil {
+		return localPath, LocalFileError{error: err}
+	}
+
+	return hiddenPath, nil
+}
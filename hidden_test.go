@@ -0,0 +1,17 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestPreserveHiddenFiles(t *testing.T) {
+	Convey("Test SetPreserveHiddenFiles", t, func() {
+		So(PreserveHiddenFiles(), ShouldBeFalse)
+
+		SetPreserveHiddenFiles(true)
+		So(PreserveHiddenFiles(), ShouldBeTrue)
+
+		SetPreserveHiddenFiles(false)
+	})
+}
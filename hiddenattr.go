@@ -0,0 +1,15 @@
+//go:build !windows
+// +build !windows
+
+package mtpx
+
+// the local file system has no hidden attribute, the dotfiles are hidden instead
+const supportsHiddenAttributeLocal = false
+
+func hasHiddenAttributeLocal(localPath string) bool {
+	return false
+}
+
+func setHiddenAttributeLocal(localPath string) error {
+	return nil
+}
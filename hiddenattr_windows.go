@@ -0,0 +1,35 @@
+package mtpx
+
+import "syscall"
+
+const supportsHiddenAttributeLocal = true
+
+// check whether the local file at [localPath] has the hidden attribute
+func hasHiddenAttributeLocal(localPath string) bool {
+	p, err := syscall.UTF16PtrFromString(localPath)
+	if err != nil {
+		return false
+	}
+
+	attrs, err := syscall.GetFileAttributes(p)
+	if err != nil {
+		return false
+	}
+
+	return attrs&syscall.FILE_ATTRIBUTE_HIDDEN != 0
+}
+
+// add the hidden attribute to the local file at [localPath]
+func setHiddenAttributeLocal(localPath string) error {
+	p, err := syscall.UTF16PtrFromString(localPath)
+	if err != nil {
+		return err
+	}
+
+	attrs, err := syscall.GetFileAttributes(p)
+	if err != nil {
+		return err
+	}
+
+	return syscall.SetFileAttributes(p, attrs|syscall.FILE_ATTRIBUTE_HIDDEN)
+}
@@ -0,0 +1,188 @@
+package mtpx
+
+import (
+	"context"
+	"github.com/ganeshrvel/usb"
+	"sort"
+	"time"
+)
+
+// position of a device on the usb bus, it stays the same while the device is plugged in
+type usbPort struct {
+	bus     uint8
+	address uint8
+}
+
+// Watch the MTP devices being plugged in and unplugged, so that the applications can react without calling [Initialize] in a loop
+// the usb bus is scanned every second and a [DeviceConnected] or [DeviceDisconnected] event is sent to [cb] for every change.
+// the devices which are connected when the watch starts are reported as connected first
+// the watch opens a usb handle of each new device just long enough to read its string descriptors (manufacturer, product
+// and serial number), no interface is claimed and no MTP session is started. so a device in use by this or another
+// application is reported as well, unless the platform refuses to open the handle; it is then picked up once it is released
+// WatchDevices blocks until [ctx] is done
+func WatchDevices(ctx context.Context, cb DeviceEventCb) error {
+	c := usb.NewContext()
+	defer c.Exit()
+
+	prev := map[usbPort]DeviceDescriptor{}
+
+	ticker := time.NewTicker(deviceWatchInterval)
+	defer ticker.Stop()
+
+	for {
+		next, err := scanDevices(c, prev)
+		if err != nil {
+			return MtpDetectFailedError{error: err}
+		}
+
+		for _, e := range diffDevices(prev, next) {
+			cb(e)
+		}
+
+		prev = next
+
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-ticker.C:
+		}
+	}
+}
+
+// list the MTP devices on the usb bus
+// the devices in [known] are not described again
+func scanDevices(c *usb.Context, known map[usbPort]DeviceDescriptor) (map[usbPort]DeviceDescriptor, error) {
+	devices, err := c.GetDeviceList()
+	if err != nil {
+		return nil, err
+	}
+	if len(devices) > 0 {
+		defer devices.Done()
+	}
+
+	found := map[usbPort]DeviceDescriptor{}
+
+	for _, d := range devices {
+		port := usbPort{bus: d.GetBusNumber(), address: d.GetDeviceAddress()}
+
+		if descriptor, ok := known[port]; ok {
+			found[port] = descriptor
+
+			continue
+		}
+
+		// the devices which can't be described yet (eg: still being set up) are picked up by the next scan
+		if descriptor, ok := describeUsbDevice(d); ok {
+			found[port] = descriptor
+		}
+	}
+
+	return found, nil
+}
+
+// read the usb descriptors of [d] if it has an MTP interface
+// the string descriptors need an open handle, it is closed again before returning
+func describeUsbDevice(d *usb.Device) (DeviceDescriptor, bool) {
+	dd, err := d.GetDeviceDescriptor()
+	if err != nil {
+		return DeviceDescriptor{}, false
+	}
+
+	config, err := d.GetActiveConfigDescriptor()
+	if err != nil || !hasMtpInterface(config) {
+		return DeviceDescriptor{}, false
+	}
+
+	h, err := d.Open()
+	if err != nil {
+		return DeviceDescriptor{}, false
+	}
+	defer h.Close()
+
+	var strs [3]string
+	for i, index := range []byte{dd.Manufacturer, dd.Product, dd.SerialNumber} {
+		if strs[i], err = h.GetStringDescriptorASCII(index); err != nil {
+			return DeviceDescriptor{}, false
+		}
+	}
+
+	return DeviceDescriptor{
+		VendorId:     dd.IdVendor,
+		ProductId:    dd.IdProduct,
+		Manufacturer: strs[0],
+		Product:      strs[1],
+		Serial:       strs[2],
+		FriendlyName: friendlyDeviceName(strs[0], strs[1]),
+	}, true
+}
+
+// check whether the configuration has an interface with the bulk in, bulk out and interrupt endpoints of the MTP
+func hasMtpInterface(config *usb.ConfigDescriptor) bool {
+	for _, iface := range config.Interfaces {
+		for _, alt := range iface.AltSetting {
+			if len(alt.EndPoints) != 3 {
+				continue
+			}
+
+			var bulkIn, bulkOut, interruptIn bool
+			for _, ep := range alt.EndPoints {
+				switch {
+				case ep.Direction() == usb.ENDPOINT_IN && ep.TransferType() == usb.TRANSFER_TYPE_INTERRUPT:
+					interruptIn = true
+				case ep.Direction() == usb.ENDPOINT_IN && ep.TransferType() == usb.TRANSFER_TYPE_BULK:
+					bulkIn = true
+				case ep.Direction() == usb.ENDPOINT_OUT && ep.TransferType() == usb.TRANSFER_TYPE_BULK:
+					bulkOut = true
+				}
+			}
+
+			if bulkIn && bulkOut && interruptIn {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// compare two scans of the usb bus, the disconnected devices are listed first
+// the events of each type are sorted by the position of the devices on the bus
+func diffDevices(prev, next map[usbPort]DeviceDescriptor) []DeviceEvent {
+	var removed, added []usbPort
+
+	for port := range prev {
+		if _, ok := next[port]; !ok {
+			removed = append(removed, port)
+		}
+	}
+
+	for port := range next {
+		if _, ok := prev[port]; !ok {
+			added = append(added, port)
+		}
+	}
+
+	sortUsbPorts(removed)
+	sortUsbPorts(added)
+
+	var events []DeviceEvent
+	for _, port := range removed {
+		events = append(events, DeviceEvent{Type: DeviceDisconnected, Device: prev[port]})
+	}
+	for _, port := range added {
+		events = append(events, DeviceEvent{Type: DeviceConnected, Device: next[port]})
+	}
+
+	return events
+}
+
+func sortUsbPorts(ports []usbPort) {
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].bus != ports[j].bus {
+			return ports[i].bus < ports[j].bus
+		}
+
+		return ports[i].address < ports[j].address
+	})
+}
@@ -0,0 +1,55 @@
+package mtpx
+
+import (
+	"context"
+	"github.com/ganeshrvel/usb"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestWatchDevices(t *testing.T) {
+	Convey("Test diffDevices", t, func() {
+		pixel := DeviceDescriptor{Serial: "pixel"}
+		galaxy := DeviceDescriptor{Serial: "galaxy"}
+		kindle := DeviceDescriptor{Serial: "kindle"}
+
+		prev := map[usbPort]DeviceDescriptor{{1, 4}: pixel, {1, 2}: galaxy}
+		next := map[usbPort]DeviceDescriptor{{1, 4}: pixel, {2, 7}: kindle}
+
+		So(diffDevices(prev, next), ShouldResemble, []DeviceEvent{
+			{Type: DeviceDisconnected, Device: galaxy},
+			{Type: DeviceConnected, Device: kindle},
+		})
+
+		So(diffDevices(next, next), ShouldBeEmpty)
+
+		// the devices found by the first scan are reported as connected
+		So(diffDevices(map[usbPort]DeviceDescriptor{}, prev), ShouldResemble, []DeviceEvent{
+			{Type: DeviceConnected, Device: galaxy},
+			{Type: DeviceConnected, Device: pixel},
+		})
+	})
+
+	Convey("Test hasMtpInterface", t, func() {
+		mtpAlt := usb.InterfaceDescriptor{EndPoints: []usb.EndpointDescriptor{
+			{EndpointAddress: 0x81, Attributes: usb.TRANSFER_TYPE_BULK},
+			{EndpointAddress: 0x02, Attributes: usb.TRANSFER_TYPE_BULK},
+			{EndpointAddress: 0x83, Attributes: usb.TRANSFER_TYPE_INTERRUPT},
+		}}
+		storageAlt := usb.InterfaceDescriptor{EndPoints: []usb.EndpointDescriptor{
+			{EndpointAddress: 0x81, Attributes: usb.TRANSFER_TYPE_BULK},
+			{EndpointAddress: 0x02, Attributes: usb.TRANSFER_TYPE_BULK},
+		}}
+
+		So(hasMtpInterface(&usb.ConfigDescriptor{Interfaces: []usb.Interface{{AltSetting: []usb.InterfaceDescriptor{storageAlt, mtpAlt}}}}), ShouldBeTrue)
+		So(hasMtpInterface(&usb.ConfigDescriptor{Interfaces: []usb.Interface{{AltSetting: []usb.InterfaceDescriptor{storageAlt}}}}), ShouldBeFalse)
+	})
+
+	Convey("Test stopping | WatchDevices", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := WatchDevices(ctx, func(event DeviceEvent) {})
+		So(err, ShouldBeNil)
+	})
+}
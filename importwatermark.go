@@ -0,0 +1,197 @@
+package mtpx
+
+import (
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/ganeshrvel/go-mtpx/devicepath"
+	"time"
+)
+
+// List the files inside [folder] which were added or modified since the last import recorded for the device,
+// the most recent first, so that an importer only copies the new photos across the restarts and the devices
+// the state of the imports is kept in the device store along with the serial number of the device and the storage,
+// see [RecordImport]. only [folder] is walked, the rest of the storage is not listed
+// if [limit] is greater than 0 then at most [limit] files are returned
+// the files sharing the modification date of the last imported file are listed again, except that file itself
+// if [storePath] is empty then [DefaultDeviceStorePath] will be used
+func ListSinceLastImport(dev *mtp.Device, storageId uint32, storePath, folder string, limit int) ([]*FileInfo, *ImportWatermark, error) {
+	serial, err := deviceSerial(dev)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	watermark, err := FetchImportWatermark(storePath, serial, storageId, folder)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	since := watermark.LastImportTime
+	if !since.IsZero() {
+		since = since.Add(-time.Nanosecond)
+	}
+
+	recent, err := listRecentFromSnapshot(dev, storageId, watermark.Folder, since)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return newestFiles(filterSinceWatermark(recent, watermark), limit), watermark, nil
+}
+
+// Move the watermark of [folder] of the storage [storageId] past the [imported] files, eg: once the files listed by
+// [ListSinceLastImport] are copied
+// the watermark never moves back, importing older files leaves it as is
+// if [storePath] is empty then [DefaultDeviceStorePath] will be used
+func RecordImport(dev *mtp.Device, storageId uint32, storePath, folder string, imported []*FileInfo) (*ImportWatermark, error) {
+	serial, err := deviceSerial(dev)
+	if err != nil {
+		return nil, err
+	}
+
+	return recordImport(storePath, serial, storageId, folder, imported)
+}
+
+// fetch the state of the imports from [folder] of the storage [storageId] of the device with the [serial] number
+// a folder which was never imported from has an empty watermark, so that all of its files are new
+// the same folder on another storage (eg: the SD card) has a watermark of its own
+// if [storePath] is empty then [DefaultDeviceStorePath] will be used
+func FetchImportWatermark(storePath, serial string, storageId uint32, folder string) (*ImportWatermark, error) {
+	if serial == "" {
+		return nil, UnknownDeviceError{error: fmt.Errorf("serial number cannot be empty")}
+	}
+
+	_folder := devicepath.Clean(folder)
+
+	deviceStoreLock.Lock()
+	defer deviceStoreLock.Unlock()
+
+	c, err := readDeviceStore(storePath)
+	if err != nil {
+		return nil, err
+	}
+
+	watermark, ok := c.Devices[serial].Imports[importWatermarkKey(storageId, _folder)]
+	if !ok {
+		watermark = ImportWatermark{StorageId: storageId, Folder: _folder}
+	}
+
+	return &watermark, nil
+}
+
+// save the state of the imports from [watermark.Folder] of the storage [watermark.StorageId] of the device with the [serial] number
+// an empty [watermark.LastImportTime] forgets the imports from the folder
+// if [storePath] is empty then [DefaultDeviceStorePath] will be used
+func SetImportWatermark(storePath, serial string, watermark ImportWatermark) error {
+	if serial == "" {
+		return UnknownDeviceError{error: fmt.Errorf("serial number cannot be empty")}
+	}
+
+	watermark.Folder = devicepath.Clean(watermark.Folder)
+
+	return updateDeviceStore(storePath, func(c *deviceStoreContainer) error {
+		setImportWatermark(c, serial, watermark)
+
+		return nil
+	})
+}
+
+// helper function for [RecordImport]
+func recordImport(storePath, serial string, storageId uint32, folder string, imported []*FileInfo) (*ImportWatermark, error) {
+	if serial == "" {
+		return nil, UnknownDeviceError{error: fmt.Errorf("serial number cannot be empty")}
+	}
+
+	_folder := devicepath.Clean(folder)
+
+	var files []*FileInfo
+	for _, fi := range imported {
+		if !fi.IsDir {
+			files = append(files, fi)
+		}
+	}
+
+	var watermark ImportWatermark
+	err := updateDeviceStore(storePath, func(c *deviceStoreContainer) error {
+		watermark = c.Devices[serial].Imports[importWatermarkKey(storageId, _folder)]
+		watermark.StorageId = storageId
+		watermark.Folder = _folder
+
+		if newest := newestFiles(files, 1); len(newest) > 0 && newest[0].ModTime.After(watermark.LastImportTime) {
+			watermark.LastImportTime = newest[0].ModTime
+			watermark.LastSeenObjectUID = newest[0].PersistentUid
+		}
+
+		setImportWatermark(c, serial, watermark)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &watermark, nil
+}
+
+// store the [watermark] in the record of the device with the [serial] number
+func setImportWatermark(c *deviceStoreContainer, serial string, watermark ImportWatermark) {
+	record := c.Devices[serial]
+	record.Serial = serial
+
+	key := importWatermarkKey(watermark.StorageId, watermark.Folder)
+
+	if watermark.LastImportTime.IsZero() {
+		delete(record.Imports, key)
+	} else {
+		if record.Imports == nil {
+			record.Imports = map[string]ImportWatermark{}
+		}
+
+		record.Imports[key] = watermark
+	}
+
+	c.Devices[serial] = record
+}
+
+// key of the watermark of [folder] of the storage [storageId] in [DeviceRecord.Imports], eg: "65537:/DCIM/Camera"
+func importWatermarkKey(storageId uint32, folder string) string {
+	return fmt.Sprintf("%d:%s", storageId, folder)
+}
+
+// keep the files of [files] inside [watermark.Folder] which are newer than the watermark
+// the last imported file is left out, the other files sharing its modification date are kept
+func filterSinceWatermark(files []*FileInfo, watermark *ImportWatermark) []*FileInfo {
+	var result []*FileInfo
+
+	for _, fi := range files {
+		if fi.IsDir || !devicepath.IsSubpath(watermark.Folder, fi.FullPath) {
+			continue
+		}
+
+		if fi.ModTime.Before(watermark.LastImportTime) {
+			continue
+		}
+
+		if watermark.LastSeenObjectUID != "" && fi.PersistentUid == watermark.LastSeenObjectUID &&
+			fi.ModTime.Equal(watermark.LastImportTime) {
+			continue
+		}
+
+		result = append(result, fi)
+	}
+
+	return result
+}
+
+// serial number of the device, as used by the device store
+func deviceSerial(dev *mtp.Device) (string, error) {
+	info, err := FetchDeviceInfo(dev)
+	if err != nil {
+		return "", err
+	}
+
+	if info.SerialNumber == "" {
+		return "", DeviceStoreError{error: fmt.Errorf("the device did not report a serial number")}
+	}
+
+	return info.SerialNumber, nil
+}
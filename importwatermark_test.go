@@ -0,0 +1,109 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestImportWatermark(t *testing.T) {
+	t1 := time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+
+	Convey("Record and fetch the imports | RecordImport | FetchImportWatermark", t, func() {
+		storePath := filepath.Join(newTempMocksDir("test_ImportWatermark", true), "devices.json")
+
+		// nothing was imported yet
+		w, err := FetchImportWatermark(storePath, "ABC123", 65537, "/DCIM/Camera/")
+		So(err, ShouldBeNil)
+		So(w.Folder, ShouldEqual, "/DCIM/Camera")
+		So(w.LastImportTime.IsZero(), ShouldBeTrue)
+
+		w, err = recordImport(storePath, "ABC123", 65537, "/DCIM/Camera", []*FileInfo{
+			{ObjectId: 1, ModTime: t1, PersistentUid: "uid-1"},
+			{ObjectId: 2, ModTime: t2, PersistentUid: "uid-2"},
+			{ObjectId: 3, ModTime: t2.Add(time.Hour), IsDir: true},
+		})
+		So(err, ShouldBeNil)
+		So(w.LastImportTime, ShouldEqual, t2)
+		So(w.LastSeenObjectUID, ShouldEqual, "uid-2")
+
+		// the watermarks are kept per device, per folder
+		w, err = FetchImportWatermark(storePath, "ABC123", 65537, "/DCIM/Camera")
+		So(err, ShouldBeNil)
+		So(w.LastImportTime.Equal(t2), ShouldBeTrue)
+		So(w.LastSeenObjectUID, ShouldEqual, "uid-2")
+
+		w, err = FetchImportWatermark(storePath, "XYZ789", 65537, "/DCIM/Camera")
+		So(err, ShouldBeNil)
+		So(w.LastImportTime.IsZero(), ShouldBeTrue)
+
+		w, err = FetchImportWatermark(storePath, "ABC123", 65537, "/Pictures")
+		So(err, ShouldBeNil)
+		So(w.LastImportTime.IsZero(), ShouldBeTrue)
+
+		// and per storage
+		w, err = FetchImportWatermark(storePath, "ABC123", 131073, "/DCIM/Camera")
+		So(err, ShouldBeNil)
+		So(w.StorageId, ShouldEqual, 131073)
+		So(w.LastImportTime.IsZero(), ShouldBeTrue)
+
+		// the watermark never moves back
+		w, err = recordImport(storePath, "ABC123", 65537, "/DCIM/Camera", []*FileInfo{{ObjectId: 4, ModTime: t1}})
+		So(err, ShouldBeNil)
+		So(w.LastImportTime.Equal(t2), ShouldBeTrue)
+
+		// the alias of the device is retained
+		So(SetDeviceAlias(storePath, "ABC123", "pixel7"), ShouldBeNil)
+		d, err := ResolveAlias(storePath, "pixel7")
+		So(err, ShouldBeNil)
+		So(d.Imports["65537:/DCIM/Camera"].LastSeenObjectUID, ShouldEqual, "uid-2")
+
+		// an empty watermark forgets the imports
+		So(SetImportWatermark(storePath, "ABC123", ImportWatermark{StorageId: 65537, Folder: "/DCIM/Camera"}), ShouldBeNil)
+		w, err = FetchImportWatermark(storePath, "ABC123", 65537, "/DCIM/Camera")
+		So(err, ShouldBeNil)
+		So(w.LastImportTime.IsZero(), ShouldBeTrue)
+	})
+
+	Convey("Empty serial | FetchImportWatermark | Should throw an error", t, func() {
+		_, err := FetchImportWatermark("", "", 65537, "/DCIM")
+		So(err, ShouldHaveSameTypeAs, UnknownDeviceError{})
+
+		err = SetImportWatermark("", "", ImportWatermark{Folder: "/DCIM", LastImportTime: t1})
+		So(err, ShouldHaveSameTypeAs, UnknownDeviceError{})
+	})
+}
+
+func TestFilterSinceWatermark(t *testing.T) {
+	t1 := time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	Convey("Test filterSinceWatermark", t, func() {
+		files := []*FileInfo{
+			{ObjectId: 1, FullPath: "/DCIM/Camera/a.jpg", ModTime: t1.Add(-time.Second)},
+			{ObjectId: 2, FullPath: "/DCIM/Camera/b.jpg", ModTime: t1, PersistentUid: "uid-2"},
+			{ObjectId: 3, FullPath: "/DCIM/Camera/c.jpg", ModTime: t1, PersistentUid: "uid-3"},
+			{ObjectId: 4, FullPath: "/DCIM/Camera/2021/d.jpg", ModTime: t1.Add(time.Second)},
+			{ObjectId: 5, FullPath: "/DCIM/Cameras/e.jpg", ModTime: t1.Add(time.Second)},
+			{ObjectId: 6, FullPath: "/DCIM/Camera/2021", ModTime: t1.Add(time.Second), IsDir: true},
+		}
+
+		watermark := &ImportWatermark{Folder: "/DCIM/Camera", LastImportTime: t1, LastSeenObjectUID: "uid-2"}
+
+		var objectIds []uint32
+		for _, fi := range filterSinceWatermark(files, watermark) {
+			objectIds = append(objectIds, fi.ObjectId)
+		}
+
+		So(objectIds, ShouldResemble, []uint32{3, 4})
+
+		// everything inside the folder is new without a watermark
+		objectIds = nil
+		for _, fi := range filterSinceWatermark(files, &ImportWatermark{StorageId: 65537, Folder: "/DCIM/Camera"}) {
+			objectIds = append(objectIds, fi.ObjectId)
+		}
+
+		So(objectIds, ShouldResemble, []uint32{1, 2, 3, 4})
+	})
+}
@@ -3,6 +3,7 @@ package mtpx
 import (
 	"github.com/ganeshrvel/go-mtpfs/mtp"
 	. "github.com/smartystreets/goconvey/convey"
+	"strings"
 	"testing"
 )
 
@@ -25,6 +26,23 @@ func TestMtpInitialize(t *testing.T) {
 		So(info, ShouldNotBeNil)
 	})
 
+	Convey("Testing Ping", t, func() {
+		latency, err := Ping(dev)
+
+		So(err, ShouldBeNil)
+		So(latency, ShouldBeGreaterThan, 0)
+	})
+
+	Convey("Testing Diagnostics", t, func() {
+		report, err := Diagnostics(dev, DiagnosticsOptions{BenchmarkSize: 1024 * 1024})
+
+		So(err, ShouldBeNil)
+		So(report.Speed, ShouldNotEqual, UsbSpeedUnknown)
+		So(report.MaxPacketSizeIn, ShouldBeGreaterThan, 0)
+		So(report.WriteSpeed, ShouldBeGreaterThan, 0)
+		So(report.ReadSpeed, ShouldBeGreaterThan, 0)
+	})
+
 	Convey("Testing FetchStorages", t, func() {
 		storages, err := FetchStorages(dev)
 
@@ -32,7 +50,62 @@ func TestMtpInitialize(t *testing.T) {
 
 		So(err, ShouldBeNil)
 		So(sid, ShouldEqual, 0x10001)
+
+		// every storage keeps its own id
+		for i := 1; i < len(storages); i++ {
+			So(storages[i].Sid, ShouldNotEqual, storages[i-1].Sid)
+		}
+
+		So(storages[0].Description, ShouldNotBeBlank)
+		So(storages[0].TotalBytes, ShouldBeGreaterThanOrEqualTo, storages[0].FreeBytes)
+	})
+
+	Convey("Testing SelectStorageByName", t, func() {
+		storages, err := FetchStorages(dev)
+		So(err, ShouldBeNil)
+
+		s, err := SelectStorageByName(dev, strings.ToUpper(storages[0].Description))
+		So(err, ShouldBeNil)
+		So(s.Sid, ShouldEqual, storages[0].Sid)
+	})
+
+	Convey("Testing CreateTempObject", t, func() {
+		objectId, fullPath, err := CreateTempObject(dev, sid, "test-*.txt", strings.NewReader("hello"), 5)
+
+		So(err, ShouldBeNil)
+		So(objectId, ShouldBeGreaterThan, 0)
+		So(fullPath, ShouldStartWith, tempDirectoryPath+"/test-")
+
+		err = CleanupTemp(dev, sid)
+		So(err, ShouldBeNil)
+
+		fc, err := FileExists(dev, sid, []FileProp{{0, tempDirectoryPath}})
+		So(err, ShouldBeNil)
+		So(fc[0].Exists, ShouldBeFalse)
 	})
 
 	Dispose(dev)
 }
+
+func TestSelectStorageByName(t *testing.T) {
+	Convey("Test selectStorageByName", t, func() {
+		storages := []StorageData{
+			{Sid: 0x10001, Description: "Internal shared storage"},
+			{Sid: 0x20001, Description: "SD card", VolumeLabel: "NIKON"},
+		}
+
+		s, err := selectStorageByName(storages, "sd CARD")
+		So(err, ShouldBeNil)
+		So(s.Sid, ShouldEqual, 0x20001)
+
+		s, err = selectStorageByName(storages, "nikon")
+		So(err, ShouldBeNil)
+		So(s.Sid, ShouldEqual, 0x20001)
+
+		_, err = selectStorageByName(storages, "")
+		So(err, ShouldHaveSameTypeAs, NoStorageError{})
+
+		_, err = selectStorageByName(storages, "USB storage")
+		So(err, ShouldHaveSameTypeAs, NoStorageError{})
+	})
+}
@@ -0,0 +1,65 @@
+package mtpx
+
+import (
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"io"
+	"sync"
+)
+
+var deviceInterleaves = struct {
+	sync.Mutex
+	m map[*mtp.Device]int
+}{m: map[*mtp.Device]int{}}
+
+// let the operations queued using [Interleave] run while the files are being downloaded, eg: to keep the UI browsable
+// during a multi-gigabyte copy
+// the downloaded files are read using the partial reads, [chunks] transfer chunks (see [TransferChunkSize]) at a time,
+// and the queued operations run in between. fewer chunks keep the UI snappier, more chunks keep the transfer faster
+// the devices which don't support the partial reads are read in a single transaction as before.
+// the uploads are always sent in a single transaction and can't be interleaved with
+// if [chunks] is 0 then the interleaving is turned off
+// note: don't start a transfer from inside [Interleave], it would wait for itself
+func SetInterleaveFrequency(dev *mtp.Device, chunks int) {
+	deviceInterleaves.Lock()
+	defer deviceInterleaves.Unlock()
+
+	if chunks <= 0 {
+		delete(deviceInterleaves.m, dev)
+
+		return
+	}
+
+	deviceInterleaves.m[dev] = chunks
+}
+
+// number of transfer chunks read between the interleaved operations, 0 if the interleaving is off
+func interleaveFrequency(dev *mtp.Device) int {
+	deviceInterleaves.Lock()
+	defer deviceInterleaves.Unlock()
+
+	return deviceInterleaves.m[dev]
+}
+
+// read the file [fi] into [w] holding the stream lock for [chunks] transfer chunks at a time
+// the operations waiting in [Interleave] run between them
+func readInterleaved(dev *mtp.Device, fi *FileInfo, w io.Writer, chunks int, progressCb SizeProgressCb) error {
+	pw := &progressWriter{w: w, total: fi.Size, objectId: fi.ObjectId, progressCb: progressCb}
+	lock := streamLock(dev)
+
+	for pw.sent < fi.Size {
+		size := TransferChunkSize() * int64(chunks)
+		if remaining := fi.Size - pw.sent; size > remaining {
+			size = remaining
+		}
+
+		lock.Lock()
+		err := readPartialObject(dev, fi.ObjectId, pw, pw.sent, size)
+		lock.Unlock()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
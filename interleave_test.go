@@ -0,0 +1,63 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"testing"
+)
+
+func TestInterleaveFrequency(t *testing.T) {
+	Convey("Test setting the interleave frequency | SetInterleaveFrequency", t, func() {
+		So(interleaveFrequency(nil), ShouldEqual, 0)
+
+		SetInterleaveFrequency(nil, 4)
+		So(interleaveFrequency(nil), ShouldEqual, 4)
+
+		SetInterleaveFrequency(nil, -1)
+		So(interleaveFrequency(nil), ShouldEqual, 0)
+	})
+}
+
+func TestInterleavedDownload(t *testing.T) {
+	dev, err := Initialize(Init{})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	storages, err := FetchStorages(dev)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	sid := storages[0].Sid
+
+	Convey("Testing the interleaved downloads | SetInterleaveFrequency", t, func() {
+		SetInterleaveFrequency(dev, 1)
+		defer SetInterleaveFrequency(dev, 0)
+
+		destination := newTempMocksDir("test_InterleavedDownload", true)
+
+		expected, err := ioutil.ReadFile(getTestMocksAsset("mock_dir1/a.txt"))
+		So(err, ShouldBeNil)
+
+		n, err := DownloadFile(dev, sid, 0, "/mtp-test-files/mock_dir1/a.txt", destination)
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, len(expected))
+
+		data, err := ioutil.ReadFile(filepath.Join(destination, "a.txt"))
+		So(err, ShouldBeNil)
+		So(data, ShouldResemble, expected)
+
+		// the queued operations still run
+		err = Interleave(dev, func() error {
+			_, err := GetObjectFromPath(dev, sid, "/mtp-test-files/mock_dir1")
+
+			return err
+		})
+		So(err, ShouldBeNil)
+	})
+
+	Dispose(dev)
+}
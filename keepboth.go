@@ -0,0 +1,169 @@
+package mtpx
+
+import (
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the conflict policies and the keep-both templates of the devices, see [SetConflictPolicy] and [SetKeepBothTemplate]
+var deviceConflictSettings = struct {
+	sync.Mutex
+	m map[*mtp.Device]conflictSettings
+}{m: map[*mtp.Device]conflictSettings{}}
+
+// set how the uploads and the copies of the device deal with an existing file of the same name
+// with [ConflictKeepBoth] the incoming file takes a free name picked by [KeepBothName] and the existing file is kept.
+// [UploadFiles], [UploadFile], [ExecuteCopyPlan] and [CopyFile] follow the policy
+// the default is [ConflictDefault]: the uploads replace the existing file and [CopyFile] refuses the copy
+func SetConflictPolicy(dev *mtp.Device, policy ConflictPolicy) {
+	deviceConflictSettings.Lock()
+	defer deviceConflictSettings.Unlock()
+
+	settings := deviceConflictSettings.m[dev]
+	settings.policy = policy
+	deviceConflictSettings.m[dev] = settings
+}
+
+// current conflict policy of the device, see [SetConflictPolicy]
+func FetchConflictPolicy(dev *mtp.Device) ConflictPolicy {
+	deviceConflictSettings.Lock()
+	defer deviceConflictSettings.Unlock()
+
+	return deviceConflictSettings.m[dev].policy
+}
+
+// current template of the names given to the objects of the device which are kept along with an existing one,
+// see [SetKeepBothTemplate]
+func KeepBothTemplate(dev *mtp.Device) string {
+	deviceConflictSettings.Lock()
+	defer deviceConflictSettings.Unlock()
+
+	if template := deviceConflictSettings.m[dev].template; template != "" {
+		return template
+	}
+
+	return defaultKeepBothTemplate
+}
+
+// set the template of the names given by [KeepBothName] for the device, eg: "{name} ({n}){ext}" or "{name}-{timestamp}{ext}"
+// {name} is the name without the extension, {ext} is the extension along with the dot (empty for the directories),
+// {n} is a counter starting at 1 and {timestamp} is the current local time (eg: 20210102-150405)
+// the template must hold {n} or {timestamp}. the default is "{name} ({n}){ext}"
+func SetKeepBothTemplate(dev *mtp.Device, template string) error {
+	if !strings.Contains(template, "{n}") && !strings.Contains(template, "{timestamp}") {
+		return InvalidPathError{error: fmt.Errorf("the keep-both template must hold {n} or {timestamp}: %s", template)}
+	}
+
+	if strings.ContainsAny(template, "/\\") {
+		return InvalidPathError{error: fmt.Errorf("the keep-both template can't hold a path separator: %s", template)}
+	}
+
+	deviceConflictSettings.Lock()
+	defer deviceConflictSettings.Unlock()
+
+	settings := deviceConflictSettings.m[dev]
+	settings.template = template
+	deviceConflictSettings.m[dev] = settings
+
+	return nil
+}
+
+func disposeConflictSettings(dev *mtp.Device) {
+	deviceConflictSettings.Lock()
+	defer deviceConflictSettings.Unlock()
+
+	delete(deviceConflictSettings.m, dev)
+}
+
+// Pick a name for [filename] which is free inside the directory [parentId], so that an incoming object can be kept
+// along with an existing one of the same name (eg: "IMG_0001 (1).jpg"), see [SetKeepBothTemplate]
+// [filename] is returned as is if it is free
+// the directory is listed once and the names are checked against the listing, the same way as [GetObjectFromPath]
+// matches them (see [SetPathMatchMode]). if the template has no {n} and the name is taken, " ({n})" is added before the
+// extension until it is free
+func KeepBothName(dev *mtp.Device, storageId, parentId uint32, filename string, isDir bool) (string, error) {
+	children, err := listDirectory(dev, storageId, parentId, "")
+	if err != nil {
+		return "", err
+	}
+
+	names := make([]string, 0, len(children))
+	for _, fi := range children {
+		names = append(names, fi.Name)
+	}
+
+	return pickKeepBothName(names, filename, isDir, KeepBothTemplate(dev), PathMatching(), time.Now()), nil
+}
+
+// name of the incoming file [filename] inside the directory [parentId] under the conflict policy of the device
+// [filename] is returned as is unless the policy is [ConflictKeepBoth]
+func conflictFreeName(dev *mtp.Device, storageId, parentId uint32, filename string) (string, error) {
+	if FetchConflictPolicy(dev) != ConflictKeepBoth {
+		return filename, nil
+	}
+
+	return KeepBothName(dev, storageId, parentId, filename, false)
+}
+
+// pick a name for [filename] which doesn't match any of the [taken] names
+func pickKeepBothName(taken []string, filename string, isDir bool, template string, mode PathMatchMode, now time.Time) string {
+	isTaken := func(name string) bool {
+		for _, t := range taken {
+			if matchFilename(t, name, mode) != noFilenameMatch {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if !isTaken(filename) {
+		return filename
+	}
+
+	name, ext := splitExtension(filename, isDir)
+	timestamp := now.Format(keepBothTimestampLayout)
+
+	render := func(n int) string {
+		return strings.NewReplacer(
+			"{name}", name,
+			"{ext}", ext,
+			"{n}", strconv.Itoa(n),
+			"{timestamp}", timestamp,
+		).Replace(template)
+	}
+
+	if !strings.Contains(template, "{n}") {
+		if candidate := render(0); !isTaken(candidate) {
+			return candidate
+		}
+
+		name, ext = splitExtension(render(0), isDir)
+		template = "{name} ({n}){ext}"
+	}
+
+	for n := 1; ; n++ {
+		if candidate := render(n); !isTaken(candidate) {
+			return candidate
+		}
+	}
+}
+
+// split [filename] into the name and the extension along with the dot
+// the directories and the dotfiles (eg: ".profile") have no extension
+func splitExtension(filename string, isDir bool) (name, ext string) {
+	if isDir {
+		return filename, ""
+	}
+
+	i := strings.LastIndex(filename, ".")
+	if i < 1 {
+		return filename, ""
+	}
+
+	return filename[:i], filename[i:]
+}
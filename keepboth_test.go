@@ -0,0 +1,76 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestPickKeepBothName(t *testing.T) {
+	now := time.Date(2021, 1, 2, 15, 4, 5, 0, time.Local)
+
+	Convey("Number the names | pickKeepBothName", t, func() {
+		taken := []string{"a.txt", "a (1).txt", "b"}
+
+		So(pickKeepBothName(taken, "c.txt", false, defaultKeepBothTemplate, PathMatchCaseInsensitive, now), ShouldEqual, "c.txt")
+		So(pickKeepBothName(taken, "a.txt", false, defaultKeepBothTemplate, PathMatchCaseInsensitive, now), ShouldEqual, "a (2).txt")
+		So(pickKeepBothName(taken, "b", true, defaultKeepBothTemplate, PathMatchCaseInsensitive, now), ShouldEqual, "b (1)")
+
+		// the names are matched the same way as the paths
+		So(pickKeepBothName(taken, "A.TXT", false, defaultKeepBothTemplate, PathMatchCaseInsensitive, now), ShouldEqual, "A (2).TXT")
+		So(pickKeepBothName(taken, "A.TXT", false, defaultKeepBothTemplate, PathMatchExact, now), ShouldEqual, "A.TXT")
+	})
+
+	Convey("Timestamp the names | pickKeepBothName", t, func() {
+		taken := []string{"a.txt"}
+
+		So(pickKeepBothName(taken, "a.txt", false, "{name}-{timestamp}{ext}", PathMatchExact, now), ShouldEqual, "a-20210102-150405.txt")
+
+		// a counter is added once the timestamped name is taken too
+		taken = append(taken, "a-20210102-150405.txt")
+		So(pickKeepBothName(taken, "a.txt", false, "{name}-{timestamp}{ext}", PathMatchExact, now), ShouldEqual, "a-20210102-150405 (1).txt")
+	})
+}
+
+func TestKeepBothTemplate(t *testing.T) {
+	Convey("Test SetKeepBothTemplate | SetConflictPolicy", t, func() {
+		defer disposeConflictSettings(nil)
+
+		So(KeepBothTemplate(nil), ShouldEqual, defaultKeepBothTemplate)
+		So(FetchConflictPolicy(nil), ShouldEqual, ConflictDefault)
+
+		So(SetKeepBothTemplate(nil, "{name}-{n}{ext}"), ShouldBeNil)
+		So(KeepBothTemplate(nil), ShouldEqual, "{name}-{n}{ext}")
+
+		So(SetKeepBothTemplate(nil, "{name}{ext}"), ShouldHaveSameTypeAs, InvalidPathError{})
+		So(SetKeepBothTemplate(nil, "copies/{name}-{n}{ext}"), ShouldHaveSameTypeAs, InvalidPathError{})
+		So(KeepBothTemplate(nil), ShouldEqual, "{name}-{n}{ext}")
+
+		// the template is kept along with the policy
+		SetConflictPolicy(nil, ConflictKeepBoth)
+		So(FetchConflictPolicy(nil), ShouldEqual, ConflictKeepBoth)
+		So(KeepBothTemplate(nil), ShouldEqual, "{name}-{n}{ext}")
+
+		// the names are left as is without the keep-both policy
+		SetConflictPolicy(nil, ConflictDefault)
+		name, err := conflictFreeName(nil, 0, 0, "a.txt")
+		So(err, ShouldBeNil)
+		So(name, ShouldEqual, "a.txt")
+	})
+}
+
+func TestSplitExtension(t *testing.T) {
+	Convey("Test splitExtension", t, func() {
+		name, ext := splitExtension("a.tar.gz", false)
+		So(name, ShouldEqual, "a.tar")
+		So(ext, ShouldEqual, ".gz")
+
+		name, ext = splitExtension(".profile", false)
+		So(name, ShouldEqual, ".profile")
+		So(ext, ShouldEqual, "")
+
+		name, ext = splitExtension("dir.d", true)
+		So(name, ShouldEqual, "dir.d")
+		So(ext, ShouldEqual, "")
+	})
+}
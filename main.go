@@ -4,39 +4,110 @@ import (
 	"errors"
 	"fmt"
 	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/ganeshrvel/go-mtpx/devicepath"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 )
 
 // todo: work on documentations
-// todo: hotplug
 
 // initialize the mtp device
+// if another application holds the device (eg: Android File Transfer, Image Capture) then a [DeviceBusyError] is returned
+// use [init.BusyTimeout] to wait for the device to be released
 // returns mtp device
 func Initialize(init Init) (*mtp.Device, error) {
-	dev, err := mtp.SelectDeviceWithDebugging("", init.DebugMode)
+	deadline := time.Now().Add(init.BusyTimeout)
 
-	if err != nil {
-		return nil, MtpDetectFailedError{error: err}
-	}
+	for {
+		dev, err := openDevice(init)
+		if err == nil {
+			if init.Transcript.Path != "" || init.Transcript.Writer != nil {
+				if err := StartTranscript(dev, init.Transcript); err != nil {
+					dev.Close()
 
-	dev.MTPDebug = init.DebugMode
-	dev.DataDebug = init.DebugMode
-	dev.USBDebug = init.DebugMode
+					return nil, err
+				}
+			}
 
-	dev.Timeout = devTimeout
+			if init.EnableCache {
+				enableDeviceCache(dev, init.CacheConfig)
+			}
 
-	if err = dev.Configure(); err != nil {
-		return nil, ConfigureError{error: err}
-	}
+			if init.EnableContentCache {
+				if err := enableContentCache(dev, init.ContentCache); err != nil {
+					_ = StopTranscript(dev)
+					disableDeviceCache(dev)
+					dev.Close()
+
+					return nil, err
+				}
+			}
 
-	return dev, nil
+			ResetTransferStats(dev)
+
+			if init.DeviceProfile != nil {
+				SetDeviceProfile(dev, init.DeviceProfile)
+			} else {
+				selectDeviceProfile(dev)
+			}
+
+			AddMiddleware(dev, init.Middlewares...)
+
+			if init.Simulate {
+				enableSimulation(dev, init.SimulateCb)
+			}
+
+			SetUploadQuota(dev, init.UploadQuota)
+			SetHeartbeat(dev, init.Heartbeat)
+
+			if init.StorageSpaceCb != nil {
+				SetStorageSpaceCb(dev, init.StorageSpaceCb)
+			}
+
+			if init.OnDisconnect != nil {
+				OnDisconnect(dev, init.OnDisconnect)
+			}
+
+			// the temp objects of a run which crashed are left behind
+			cleanupStaleTempObjects(dev)
+
+			return dev, nil
+		}
+
+		if _, ok := err.(DeviceBusyError); !ok || time.Now().Add(deviceBusyRetryInterval).After(deadline) {
+			return nil, err
+		}
+
+		time.Sleep(deviceBusyRetryInterval)
+	}
 }
 
 // close the mtp device
+// the running operations are not waited for, use [Shutdown] to let them finish first
 func Dispose(dev *mtp.Device) {
+	disableDeviceCache(dev)
+	disableContentCache(dev)
+	resetChunkSizer(dev)
+	disposeTransferStats(dev)
+	disposeMiddlewares(dev)
+	disableSimulation(dev)
+	SetUploadQuota(dev, 0)
+	SetInterleaveFrequency(dev, 0)
+	disposeHeartbeat(dev)
+	SetStorageSpaceCb(dev, nil)
+	SetDeviceSideProgressCb(dev, nil)
+	disposeDeviceProfile(dev)
+	_ = StopTranscript(dev)
+	disposeStreamLock(dev)
+	disposeOpenObjects(dev)
+	disposeLifecycle(dev)
+	disposeConflictSettings(dev)
+	disposeTransferStallTimeout(dev)
+	disposeDuplicatePolicy(dev)
+
 	dev.Close()
 }
 
@@ -52,6 +123,27 @@ func FetchDeviceInfo(dev *mtp.Device) (*mtp.DeviceInfo, error) {
 	return &info, nil
 }
 
+// check whether the session with the device is alive
+// a single request which needs an open session is sent, the daemons and the connection pools can use it to spot the dead sessions before queueing the work
+// returns the round-trip time of the request
+// returns a [SessionLostError] if the device didn't answer, the device has to be initialized again
+func Ping(dev *mtp.Device) (latency time.Duration, err error) {
+	release, err := beginOperation(dev)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { release(err) }()
+
+	var sids mtp.Uint32Array
+
+	startTime := time.Now()
+	if err := dev.GetStorageIDs(&sids); err != nil {
+		return 0, SessionLostError{error: err}
+	}
+
+	return time.Since(startTime), nil
+}
+
 // fetch storages
 func FetchStorages(dev *mtp.Device) ([]StorageData, error) {
 	sids := mtp.Uint32Array{}
@@ -71,78 +163,187 @@ func FetchStorages(dev *mtp.Device) ([]StorageData, error) {
 			return nil, StorageInfoError{error: err}
 		}
 
+		info.StorageDescription = profileStorageName(dev, info.StorageDescription)
+
 		result = append(result, StorageData{
-			Sid:  sid,
-			Info: info,
+			Sid:            sid,
+			Info:           info,
+			Description:    info.StorageDescription,
+			VolumeLabel:    info.VolumeLabel,
+			FreeBytes:      info.FreeSpaceInBytes,
+			TotalBytes:     info.MaxCapability,
+			FilesystemType: mtp.FST_names[int(info.FilesystemType)],
+			Removable:      info.StorageType == mtp.ST_RemovableROM || info.StorageType == mtp.ST_RemovableRAM,
+			ReadOnly:       info.AccessCapability != mtp.AC_ReadWrite,
+			AllowsDeletion: info.AccessCapability != mtp.AC_ReadOnly,
 		})
 	}
 
 	return result, nil
 }
 
+// fetch the storage whose description or volume label is [name], the case is ignored
+// eg: SelectStorageByName(dev, "SD card")
+// returns a [NoStorageError] if there is no such storage
+func SelectStorageByName(dev *mtp.Device, name string) (*StorageData, error) {
+	storages, err := FetchStorages(dev)
+	if err != nil {
+		return nil, err
+	}
+
+	return selectStorageByName(storages, name)
+}
+
+// helper function for [SelectStorageByName]
+func selectStorageByName(storages []StorageData, name string) (*StorageData, error) {
+	if name == "" {
+		return nil, NoStorageError{error: fmt.Errorf("the name of the storage cannot be empty")}
+	}
+
+	for i, s := range storages {
+		if strings.EqualFold(s.Description, name) || strings.EqualFold(s.VolumeLabel, name) {
+			return &storages[i], nil
+		}
+	}
+
+	return nil, NoStorageError{error: fmt.Errorf("no storage named %s", name)}
+}
+
 // create a new directory recursively using [fullPath]
 // The path will be created if it does not Exists
 func MakeDirectory(dev *mtp.Device, storageId uint32, fullPath string) (objectId uint32, err error) {
-	_fullPath := fixSlash(fullPath)
+	op := &OperationInfo{Type: MakeDirectoryOp, Mutating: true, StorageId: storageId, FileProps: []FileProp{{0, fullPath}}}
+
+	err = runMiddlewares(dev, op, func() error {
+		if err := checkStorageWritable(dev, storageId, false); err != nil {
+			return err
+		}
+
+		objectId, err = makeDirectory(dev, storageId, fullPath)
+
+		return err
+	})
 
-	if _fullPath == PathSep {
+	return objectId, err
+}
+
+// [MakeDirectory] as a part of the running operation, see [runNestedMiddlewares]
+func makeNestedDirectory(dev *mtp.Device, storageId uint32, fullPath string) (objectId uint32, err error) {
+	op := &OperationInfo{Type: MakeDirectoryOp, Mutating: true, StorageId: storageId, FileProps: []FileProp{{0, fullPath}}}
+
+	err = runNestedMiddlewares(dev, op, func() error {
+		objectId, err = makeDirectory(dev, storageId, fullPath)
+
+		return err
+	})
+
+	return objectId, err
+}
+
+// helper function for [MakeDirectory]
+func makeDirectory(dev *mtp.Device, storageId uint32, fullPath string) (objectId uint32, err error) {
+	_fullPath := devicepath.Clean(fullPath)
+
+	if _fullPath == devicepath.Separator {
 		return ParentObjectId, nil
 	}
-	splittedFullPath := strings.Split(_fullPath, PathSep)
+	splittedFullPath := strings.Split(_fullPath, devicepath.Separator)
 
 	objectId = uint32(ParentObjectId)
 	const skipIndex = 1
 
 	for _, fName := range splittedFullPath[skipIndex:] {
-		// fetch the parent object and
-		fi, err := GetObjectFromParentIdAndFilename(dev, storageId, objectId, fName)
-
+		objectId, err = fetchOrMakeDirectory(dev, storageId, objectId, fName)
 		if err != nil {
-			switch err.(type) {
-			case FileNotFoundError:
-				// if object does not Exists then create a new directory
-				_newObjectId, err := handleMakeDirectory(dev, storageId, objectId, fName)
-				if err != nil {
-					return 0, err
-				}
+			return 0, err
+		}
+	}
 
-				objectId = _newObjectId
+	return objectId, nil
+}
 
-				continue
-			default:
-				return 0, err
-			}
-		}
+// create the directory [fullPath] inside [parentId] if it does not exist
+// [parentId] is the objectId of the parent directory of [fullPath], the path is not resolved from the root
+// the directory passes through the middlewares as a [MakeDirectory] operation nested in the running transfer
+func makeDirectoryInParent(dev *mtp.Device, storageId, parentId uint32, fullPath string) (objectId uint32, err error) {
+	op := &OperationInfo{Type: MakeDirectoryOp, Mutating: true, StorageId: storageId, FileProps: []FileProp{{0, fullPath}}}
 
-		// if the object Exists but if it's a file then throw an error
-		if !fi.IsDir {
-			return 0, InvalidPathError{error: fmt.Errorf("invalid path: %s. The object is not a directory", fName)}
+	err = runNestedMiddlewares(dev, op, func() error {
+		_, name := devicepath.Split(fullPath)
+		objectId, err = fetchOrMakeDirectory(dev, storageId, parentId, name)
+
+		return err
+	})
+
+	return objectId, err
+}
+
+// returns the objectId of the directory [filename] inside [parentId], the directory is created if it does not exist
+func fetchOrMakeDirectory(dev *mtp.Device, storageId, parentId uint32, filename string) (objectId uint32, err error) {
+	fi, err := GetObjectFromParentIdAndFilename(dev, storageId, parentId, filename)
+	if err != nil {
+		switch err.(type) {
+		case FileNotFoundError:
+			// if object does not Exists then create a new directory
+			return handleMakeDirectory(dev, storageId, parentId, filename)
+
+		default:
+			return 0, err
 		}
+	}
 
-		objectId = fi.ObjectId
+	// if the object Exists but if it's a file then throw an error
+	if !fi.IsDir {
+		return 0, InvalidPathError{error: fmt.Errorf("invalid path: %s. The object is not a directory", filename)}
 	}
 
-	return objectId, nil
+	return fi.ObjectId, nil
 }
 
 // List the contents in a directory
 // use [recursive] to fetch the whole nested tree
 // Tip: use [objectId] whenever possible to avoid traversing down the whole file tree to process and find the [objectId]
-// if [skipDisallowedFiles] is true then files matching the [disallowedFiles] list will be ignored
+// if [skipDisallowedFiles] is true then files matching the disallowed files list will be ignored, see [SetDisallowedFilesPolicy]
 // if [skipHiddenFiles] is true then hidden files (unix style) will be ignored
+// the objects of a directory are always visited in the order of their names, see [SetWalkDirsFirst]
 // return:
 // [objectId]: objectId of the file/diectory
 // [totalFiles]: total number of files
 // [totalDirectories]: total number of directories
 func Walk(dev *mtp.Device, storageId uint32, fullPath string, recursive, skipDisallowedFiles,
 	skipHiddenFiles bool, cb WalkCb) (objectId uint32, totalFiles, totalDirectories int64, err error) {
+	release, err := beginOperation(dev)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer func() { release(err) }()
+
+	return walk(dev, storageId, fullPath, recursive, skipDisallowedFiles, skipHiddenFiles, cb)
+}
+
+// [Walk] as a part of the running operation, see [runNestedMiddlewares]
+func walkNested(dev *mtp.Device, storageId uint32, fullPath string, recursive, skipDisallowedFiles,
+	skipHiddenFiles bool, cb WalkCb) (objectId uint32, totalFiles, totalDirectories int64, err error) {
+	if err := checkCancelled(dev); err != nil {
+		return 0, 0, 0, err
+	}
+
+	return walk(dev, storageId, fullPath, recursive, skipDisallowedFiles, skipHiddenFiles, cb)
+}
+
+// helper function for [Walk]
+func walk(dev *mtp.Device, storageId uint32, fullPath string, recursive, skipDisallowedFiles,
+	skipHiddenFiles bool, cb WalkCb) (objectId uint32, totalFiles, totalDirectories int64, err error) {
+	stop := monitorOperation(dev, &OperationInfo{Type: WalkOp, StorageId: storageId, Sources: []string{fullPath}})
+	defer func() { stop(err) }()
+
 	// fetch the objectId from [objectId] and/or [fullPath] parameters
 	fi, err := GetObjectFromPath(dev, storageId, fullPath)
 	if err != nil {
 		return 0, totalFiles, totalDirectories, err
 	}
 
-	// if the object file name matches [disallowedFiles] list then return an error
+	// if the object file name matches the disallowed files list then return an error
 	if skipDisallowedFiles {
 		fName := (*fi).Name
 		if ok := isDisallowedFiles(fName); ok {
@@ -152,7 +353,7 @@ func Walk(dev *mtp.Device, storageId uint32, fullPath string, recursive, skipDis
 
 	// if the object is a file then return objectId
 	if !fi.IsDir {
-		err := cb(fi.ObjectId, fi, nil)
+		err := runWalkCb(dev, cb, fi)
 		if err != nil {
 			return 0, totalFiles, totalDirectories, err
 		}
@@ -170,6 +371,45 @@ func Walk(dev *mtp.Device, storageId uint32, fullPath string, recursive, skipDis
 	return fi.ObjectId, totalFiles, totalDirectories, nil
 }
 
+// List the contents of several directories in a single pass, eg: WalkMultiple(dev, sid, DefaultPrefetchPaths, true, ...)
+// if [recursive] is true then the paths nested inside another path are walked only once, as a part of the outer path
+// every object is passed to [cb] only once, even if it is reached through more than one of the [fullPaths]
+// see [Walk] for the rest of the parameters
+// return:
+// [totalFiles]: total number of files
+// [totalDirectories]: total number of directories
+func WalkMultiple(dev *mtp.Device, storageId uint32, fullPaths []string, recursive, skipDisallowedFiles,
+	skipHiddenFiles bool, cb WalkCb) (totalFiles, totalDirectories int64, err error) {
+	visited := map[uint32]bool{}
+
+	for _, fullPath := range walkRoots(fullPaths, recursive) {
+		_, _, _, err := Walk(dev, storageId, fullPath, recursive, skipDisallowedFiles, skipHiddenFiles,
+			func(objectId uint32, fi *FileInfo, err error) error {
+				if err != nil {
+					return cb(objectId, fi, err)
+				}
+
+				if visited[objectId] {
+					return nil
+				}
+				visited[objectId] = true
+
+				if fi.IsDir {
+					totalDirectories += 1
+				} else {
+					totalFiles += 1
+				}
+
+				return cb(objectId, fi, nil)
+			})
+		if err != nil {
+			return totalFiles, totalDirectories, err
+		}
+	}
+
+	return totalFiles, totalDirectories, nil
+}
+
 // check if a file Exists
 // returns Exists: bool, isDir: bool, objectId: uint32
 // Since the [parentPath] is unavailable here the [fullPath] property of the resulting object [FileInfo] may not be valid.
@@ -192,7 +432,7 @@ func FileExists(dev *mtp.Device, storageId uint32, fileProps []FileProp) (fc []F
 				}
 
 			default:
-				return []FileExistsContainer{}, nil
+				return nil, err
 			}
 
 		} else {
@@ -211,20 +451,269 @@ func FileExists(dev *mtp.Device, storageId uint32, fileProps []FileProp) (fc []F
 // if [objectId] is not available then [fullPath] will be used to fetch the [objectId]
 // dont leave both [objectId] and [fullPath] empty
 // Tip: use [objectId] whenever possible to avoid traversing down the whole file tree to process and find the [objectId]
+// objects which do not exist are skipped silently and write-protected objects are not deleted, see [DeleteFileWithOptions]
 func DeleteFile(dev *mtp.Device, storageId uint32, fileProps []FileProp) error {
+	return DeleteFileWithOptions(dev, storageId, fileProps, DeleteOptions{})
+}
+
+// Delete a file/directory, like [DeleteFile]
+// objects which do not exist are skipped silently unless [opts.Strict] is true
+// write-protected objects are not deleted unless [opts.Force] is true
+func DeleteFileWithOptions(dev *mtp.Device, storageId uint32, fileProps []FileProp, opts DeleteOptions) error {
+	op := &OperationInfo{Type: DeleteFileOp, Mutating: true, StorageId: storageId, FileProps: fileProps}
+
+	return runMiddlewares(dev, op, func() error {
+		return deleteFile(dev, storageId, fileProps, opts)
+	})
+}
+
+// [DeleteFile] as a part of the running operation, see [runNestedMiddlewares]
+func deleteNestedFile(dev *mtp.Device, storageId uint32, fileProps []FileProp, opts DeleteOptions) error {
+	op := &OperationInfo{Type: DeleteFileOp, Mutating: true, StorageId: storageId, FileProps: fileProps}
+
+	return runNestedMiddlewares(dev, op, func() error {
+		return deleteFile(dev, storageId, fileProps, opts)
+	})
+}
+
+// helper function for [DeleteFileWithOptions]
+func deleteFile(dev *mtp.Device, storageId uint32, fileProps []FileProp, opts DeleteOptions) error {
+	if err := checkStorageWritable(dev, storageId, true); err != nil {
+		return err
+	}
+
 	for _, fileProp := range fileProps {
+		if err := touchOperation(dev); err != nil {
+			return err
+		}
+
 		fc, err := FileExists(dev, storageId, []FileProp{fileProp})
 		if err != nil {
-			return nil
+			return err
 		}
 
 		if !fc[0].Exists {
-			return nil
+			if opts.Strict {
+				return FileNotFoundError{error: fmt.Errorf("file not found: %s", fileProp)}
+			}
+
+			continue
 		}
 
-		if err := dev.DeleteObject(fc[0].FileInfo.ObjectId); err != nil {
+		fi := fc[0].FileInfo
+		if err := checkObjectNotInUse(dev, fi); err != nil {
+			return err
+		}
+
+		if err := handleProtectedObject(dev, fi, opts.Force); err != nil {
+			return err
+		}
+
+		if err := dev.DeleteObject(fi.ObjectId); err != nil {
+			if code, ok := deleteRefusedCode(err); ok && opts.Strict {
+				return DeleteRefusedError{
+					error: fmt.Errorf("the device refused to delete %s: %v", fileProp, err),
+					Code:  code,
+				}
+			}
+
 			return FileObjectError{error: err}
 		}
+
+		// deleting a directory takes the whole subtree with it
+		if fi.IsDir {
+			invalidateCachedStorage(dev, storageId)
+		} else {
+			invalidateCachedListing(dev, storageId, fi.ParentId)
+		}
+	}
+
+	return nil
+}
+
+// Delete the contents of a directory while keeping the directory itself
+// eg: clear the '/DCIM/Screenshots' directory
+// [objectId] and [fullPath] are optional parameters
+// if [objectId] is not available then [fullPath] will be used to fetch the [objectId]
+// [filterCb]: return true to delete the object, it is called for the objects directly inside the directory. if nil then all
+// the objects inside the directory are deleted. a deleted directory is deleted along with everything inside it
+// [progressCb]: called after each object is deleted, the nested ones included. returning an error stops the deletion
+// return:
+// [totalDeleted]: total number of deleted objects, the nested objects of the deleted directories included
+func DeleteDirectoryContents(dev *mtp.Device, storageId uint32, fileProp FileProp, opts DeleteOptions,
+	filterCb FileFilterCb, progressCb DeleteProgressCb) (totalDeleted int64, err error) {
+	op := &OperationInfo{Type: DeleteDirectoryContentsOp, Mutating: true, StorageId: storageId, FileProps: []FileProp{fileProp}}
+
+	err = runMiddlewares(dev, op, func() error {
+		totalDeleted, err = deleteDirectoryContents(dev, storageId, fileProp, opts, filterCb, progressCb)
+
+		return err
+	})
+
+	return totalDeleted, err
+}
+
+// helper function for [DeleteDirectoryContents]
+func deleteDirectoryContents(dev *mtp.Device, storageId uint32, fileProp FileProp, opts DeleteOptions,
+	filterCb FileFilterCb, progressCb DeleteProgressCb) (totalDeleted int64, err error) {
+	if err := checkStorageWritable(dev, storageId, true); err != nil {
+		return 0, err
+	}
+
+	fi, err := GetObjectFromObjectIdOrPath(dev, storageId, fileProp)
+	if err != nil {
+		return 0, err
+	}
+
+	if !fi.IsDir {
+		return 0, InvalidPathError{error: fmt.Errorf("invalid path: %s. The object is not a directory", fileProp)}
+	}
+
+	children, err := listDirectory(dev, storageId, fi.ObjectId, fi.FullPath)
+	if err != nil {
+		return 0, err
+	}
+
+	deletedDir := false
+	defer func() {
+		if deletedDir {
+			invalidateCachedStorage(dev, storageId)
+		} else if totalDeleted > 0 {
+			invalidateCachedListing(dev, storageId, fi.ObjectId)
+		}
+	}()
+
+	for _, child := range children {
+		if filterCb != nil && !filterCb(child) {
+			continue
+		}
+
+		if err := checkObjectNotInUse(dev, child); err != nil {
+			return totalDeleted, err
+		}
+
+		// the directories are deleted along with everything inside them, the nested listings go stale even if that fails halfway
+		if child.IsDir {
+			deletedDir = true
+		}
+
+		if err := deleteSubtree(dev, storageId, child, opts, progressCb, &totalDeleted); err != nil {
+			return totalDeleted, err
+		}
+	}
+
+	return totalDeleted, nil
+}
+
+// Delete a directory along with everything inside it
+// the subtree is walked depth-first and the files are deleted before the directories holding them, as many devices refuse
+// to delete a directory which isn't empty
+// [objectId] and [fullPath] are optional parameters
+// if [objectId] is not available then [fullPath] will be used to fetch the [objectId]
+// unlike [DeleteFile] a [FileNotFoundError] is returned if the directory does not exist
+// [progressCb]: called after each object is deleted. returning an error stops the deletion
+// return:
+// [totalDeleted]: total number of deleted objects, the directory itself and the nested objects included
+func DeleteDirectoryRecursive(dev *mtp.Device, storageId uint32, fileProp FileProp, opts DeleteOptions,
+	progressCb DeleteProgressCb) (totalDeleted int64, err error) {
+	op := &OperationInfo{Type: DeleteDirectoryRecursiveOp, Mutating: true, StorageId: storageId, FileProps: []FileProp{fileProp}}
+
+	err = runMiddlewares(dev, op, func() error {
+		totalDeleted, err = deleteDirectoryRecursive(dev, storageId, fileProp, opts, progressCb)
+
+		return err
+	})
+
+	return totalDeleted, err
+}
+
+// helper function for [DeleteDirectoryRecursive]
+func deleteDirectoryRecursive(dev *mtp.Device, storageId uint32, fileProp FileProp, opts DeleteOptions,
+	progressCb DeleteProgressCb) (totalDeleted int64, err error) {
+	if err := checkStorageWritable(dev, storageId, true); err != nil {
+		return 0, err
+	}
+
+	fc, err := FileExists(dev, storageId, []FileProp{fileProp})
+	if err != nil {
+		return 0, err
+	}
+
+	if !fc[0].Exists {
+		return 0, FileNotFoundError{error: fmt.Errorf("file not found: %s", fileProp)}
+	}
+
+	fi := fc[0].FileInfo
+
+	if !fi.IsDir {
+		return 0, InvalidPathError{error: fmt.Errorf("invalid path: %s. The object is not a directory", fileProp)}
+	}
+
+	if fi.ObjectId == ParentObjectId {
+		return 0, InvalidPathError{error: fmt.Errorf("the root directory cannot be deleted")}
+	}
+
+	if err := checkObjectNotInUse(dev, fi); err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if totalDeleted > 0 {
+			invalidateCachedStorage(dev, storageId)
+		}
+	}()
+
+	err = deleteSubtree(dev, storageId, fi, opts, progressCb, &totalDeleted)
+
+	return totalDeleted, err
+}
+
+// delete the children of [fi] depth-first and then [fi] itself
+func deleteSubtree(dev *mtp.Device, storageId uint32, fi *FileInfo, opts DeleteOptions,
+	progressCb DeleteProgressCb, totalDeleted *int64) error {
+	if fi.IsDir {
+		children, err := listDirectory(dev, storageId, fi.ObjectId, fi.FullPath)
+		if err != nil {
+			return err
+		}
+
+		// the files first, then the subdirectories
+		sort.SliceStable(children, func(i, j int) bool {
+			return !children[i].IsDir && children[j].IsDir
+		})
+
+		for _, child := range children {
+			if err := deleteSubtree(dev, storageId, child, opts, progressCb, totalDeleted); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := touchOperation(dev); err != nil {
+		return err
+	}
+
+	if err := handleProtectedObject(dev, fi, opts.Force); err != nil {
+		return err
+	}
+
+	if err := dev.DeleteObject(fi.ObjectId); err != nil {
+		if code, ok := deleteRefusedCode(err); ok && opts.Strict {
+			return DeleteRefusedError{
+				error: fmt.Errorf("the device refused to delete %s: %v", fi.FullPath, err),
+				Code:  code,
+			}
+		}
+
+		return FileObjectError{error: err}
+	}
+
+	*totalDeleted += 1
+
+	if progressCb != nil {
+		if err := progressCb(fi, *totalDeleted, nil); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -238,6 +727,23 @@ func DeleteFile(dev *mtp.Device, storageId uint32, fileProps []FileProp) error {
 // return
 // [objectId]: objectId of the file/diectory
 func RenameFile(dev *mtp.Device, storageId uint32, fileProp FileProp, newFileName string) (objectId uint32, err error) {
+	op := &OperationInfo{Type: RenameFileOp, Mutating: true, StorageId: storageId, FileProps: []FileProp{fileProp}, NewFileName: newFileName}
+
+	err = runMiddlewares(dev, op, func() error {
+		objectId, err = renameFile(dev, storageId, fileProp, newFileName)
+
+		return err
+	})
+
+	return objectId, err
+}
+
+// helper function for [RenameFile]
+func renameFile(dev *mtp.Device, storageId uint32, fileProp FileProp, newFileName string) (objectId uint32, err error) {
+	if err := checkStorageWritable(dev, storageId, false); err != nil {
+		return 0, err
+	}
+
 	fc, err := FileExists(dev, storageId, []FileProp{fileProp})
 	if err != nil {
 		return 0, err
@@ -249,6 +755,10 @@ func RenameFile(dev *mtp.Device, storageId uint32, fileProp FileProp, newFileNam
 
 	fi := fc[0].FileInfo
 
+	if err := checkObjectNotInUse(dev, fi); err != nil {
+		return 0, err
+	}
+
 	if err := dev.SetObjectPropValue(fi.ObjectId, mtp.OPC_ObjectFileName, &mtp.StringValue{Value: newFileName}); err != nil {
 		switch v := err.(type) {
 		case mtp.RCError:
@@ -260,6 +770,8 @@ func RenameFile(dev *mtp.Device, storageId uint32, fileProp FileProp, newFileNam
 		return 0, FileObjectError{error: err}
 	}
 
+	invalidateCachedListing(dev, storageId, fi.ParentId)
+
 	return fi.ObjectId, nil
 }
 
@@ -272,7 +784,54 @@ func RenameFile(dev *mtp.Device, storageId uint32, fileProp FileProp, newFileNam
 // [bulkFilesSent]: total transferred files (directory count not included)
 // [bulkSizeSent]: total size of the uploaded files
 func UploadFiles(dev *mtp.Device, storageId uint32, sources []string, destination string, preprocessFiles bool, preprocessCb LocalPreprocessCb, progressCb ProgressCb) (destinationObjectId uint32, bulkFilesSent int64, bulkSizeSent int64, err error) {
-	_destination := fixSlash(destination)
+	opts := transferOptions{preprocessFiles: preprocessFiles, localPreprocessCb: preprocessCb, progressCb: progressCb}
+
+	destinationObjectId, bulkFilesSent, bulkSizeSent, _, err = runUploadFiles(dev, storageId, sources, destination, opts)
+
+	return destinationObjectId, bulkFilesSent, bulkSizeSent, err
+}
+
+// Transfer files from the local disk to the device, like [UploadFiles]
+// a file which fails doesn't stop the transfer, it is recorded in the summary and the next file is sent
+// return:
+// [summary]: result of every file, the files which failed can be retried using [RetryFailed]
+// [err]: error of the first file which failed, or the error which stopped the transfer
+func UploadFilesWithSummary(dev *mtp.Device, storageId uint32, sources []string, destination string, preprocessFiles bool, preprocessCb LocalPreprocessCb, progressCb ProgressCb) (summary *OperationSummary, err error) {
+	opts := transferOptions{
+		preprocessFiles:   preprocessFiles,
+		localPreprocessCb: preprocessCb,
+		progressCb:        progressCb,
+		continueOnError:   true,
+	}
+
+	_, _, _, summary, err = runUploadFiles(dev, storageId, sources, destination, opts)
+
+	return summary, err
+}
+
+// run [uploadFiles] through the middlewares
+func runUploadFiles(dev *mtp.Device, storageId uint32, sources []string, destination string, opts transferOptions) (destinationObjectId uint32, bulkFilesSent int64, bulkSizeSent int64, summary *OperationSummary, err error) {
+	op := &OperationInfo{Type: UploadFilesOp, Mutating: true, StorageId: storageId, Sources: sources, Destination: destination}
+
+	err = runMiddlewares(dev, op, func() error {
+		destinationObjectId, bulkFilesSent, bulkSizeSent, summary, err = uploadFiles(dev, storageId, sources, destination, opts)
+
+		return err
+	})
+
+	return destinationObjectId, bulkFilesSent, bulkSizeSent, summary, err
+}
+
+// helper function for [UploadFiles]
+func uploadFiles(dev *mtp.Device, storageId uint32, sources []string, destination string, opts transferOptions) (destinationObjectId uint32, bulkFilesSent int64, bulkSizeSent int64, summary *OperationSummary, err error) {
+	if err := checkStorageWritable(dev, storageId, false); err != nil {
+		return 0, 0, 0, nil, err
+	}
+
+	preprocessFiles, preprocessCb, progressCb := opts.preprocessFiles, opts.localPreprocessCb, opts.progressCb
+	opts.disallowedFiles = FetchDisallowedFilesPolicy()
+
+	_destination := devicepath.Clean(destination)
 
 	pInfo := ProgressInfo{
 		FileInfo:          &FileInfo{},
@@ -286,7 +845,13 @@ func UploadFiles(dev *mtp.Device, storageId uint32, sources []string, destinatio
 		ActiveFileSize:    &TransferSizeInfo{},
 		BulkFileSize:      &TransferSizeInfo{},
 		Status:            InProgress,
+		Breakdown:         newTransferBreakdown(),
+		Summary:           newOperationSummary(UploadFilesOp, storageId, opts),
 	}
+	summary = pInfo.Summary
+	defer func() {
+		summary.finish(err)
+	}()
 
 	// if [preprocessFiles] is true then fetch the total number of files from the file tree
 	// total number of files in the current upload session
@@ -312,6 +877,10 @@ func UploadFiles(dev *mtp.Device, storageId uint32, sources []string, destinatio
 				return err
 			}
 
+			if err := touchOperation(dev); err != nil {
+				return err
+			}
+
 			if (*fi).IsDir() {
 				return nil
 			}
@@ -324,7 +893,7 @@ func UploadFiles(dev *mtp.Device, storageId uint32, sources []string, destinatio
 		})
 
 		if err != nil {
-			return 0, bulkFilesSent, bulkSizeSent, err
+			return 0, bulkFilesSent, bulkSizeSent, summary, err
 		}
 
 		totalFiles = _totalFiles
@@ -332,17 +901,20 @@ func UploadFiles(dev *mtp.Device, storageId uint32, sources []string, destinatio
 		totalSize = _totalSize
 	}
 
-	destParentId, err := MakeDirectory(dev, storageId, _destination)
+	destParentId, err := makeNestedDirectory(dev, storageId, _destination)
 	if err != nil {
-		return 0, bulkFilesSent, bulkSizeSent, err
+		return 0, bulkFilesSent, bulkSizeSent, summary, err
 	}
 
 	pInfo.TotalFiles = totalFiles
 	pInfo.TotalDirectories = totalDirectories
 	pInfo.BulkFileSize.Total = totalSize
 
+	// error of the first file which failed, when the transfer moves on after the failures
+	var fileErr error
+
 	for _, source := range sources {
-		_source := fixSlash(source)
+		_source := filepath.Clean(source)
 		sourceParentPath := filepath.Dir(_source)
 
 		destinationFilesDict := map[string]uint32{
@@ -356,6 +928,10 @@ func UploadFiles(dev *mtp.Device, storageId uint32, sources []string, destinatio
 					return err
 				}
 
+				if err := touchOperation(dev); err != nil {
+					return err
+				}
+
 				name := fInfo.Name()
 
 				// don't follow symlinks
@@ -364,16 +940,26 @@ func UploadFiles(dev *mtp.Device, storageId uint32, sources []string, destinatio
 				}
 
 				// filter out disallowed files
-				if isDisallowedFiles(name) {
+				if opts.disallowedFiles.skipFile(path, name) {
+					if !fInfo.IsDir() {
+						pInfo.recordFile(
+							&FileResult{Source: filepath.Clean(path), Size: fInfo.Size(), Status: FileSkipped},
+							name, fileCategory(mtp.OFC_Undefined, name, false),
+						)
+					}
+
 					return nil
 				}
 
-				sourceFilePath := fixSlash(path)
+				sourceFilePath := filepath.Clean(path)
 
 				// map the local files path to the mtp files path
-				destinationParentPath, destinationFilePath := mapSourcePathToDestinationPath(
+				destinationParentPath, destinationFilePath, err := mapLocalPathToDevicePath(
 					sourceFilePath, sourceParentPath, _destination,
 				)
+				if err != nil {
+					return err
+				}
 
 				size := fInfo.Size()
 				isDir := fInfo.IsDir()
@@ -381,8 +967,9 @@ func UploadFiles(dev *mtp.Device, storageId uint32, sources []string, destinatio
 				// if the object is a directory then create a directory using [MakeDirectory] or [MakeDirectory]
 				if isDir {
 					// if the parent path Exists within the [destinationFilesDict] then fetch the [parentId] (value) and make the destination directory
-					if _, ok := destinationFilesDict[destinationParentPath]; ok {
-						objId, err := MakeDirectory(dev, storageId, destinationFilePath)
+					// inside it, the path is not resolved from the root again. this keeps the re-uploads into an existing tree cheap
+					if parentId, ok := destinationFilesDict[destinationParentPath]; ok {
+						objId, err := makeDirectoryInParent(dev, storageId, parentId, destinationFilePath)
 						if err != nil {
 							return err
 						}
@@ -393,7 +980,7 @@ func UploadFiles(dev *mtp.Device, storageId uint32, sources []string, destinatio
 						// if the parent path DOES NOT Exists within the [destinationFilesDict] create a new directory using costlier [MakeDirectory] method
 						// this is a fallback situation
 					} else {
-						objId, err := MakeDirectory(dev, storageId, _destination)
+						objId, err := makeNestedDirectory(dev, storageId, destinationFilePath)
 						if err != nil {
 							return err
 						}
@@ -415,147 +1002,237 @@ func UploadFiles(dev *mtp.Device, storageId uint32, sources []string, destinatio
 
 				} else {
 					// if [destinationParentPath] DOES NOT Exists within [destinationFilesDict] then create the parent directory using [MakeDirectory] and use the resulting objId as [parentId]
-					objId, err := MakeDirectory(dev, storageId, destinationParentPath)
+					objId, err := makeNestedDirectory(dev, storageId, destinationParentPath)
 
 					if err != nil {
 						return err
 					}
 
 					// append the current objectId to [destinationFilesDict]
-					destinationFilesDict[destinationFilePath] = objId
+					destinationFilesDict[destinationParentPath] = objId
 
 					fileParentId = objId
 				}
 
-				// read the local file
-				fileBuf, err := os.Open(sourceFilePath)
-				if err != nil {
-					return InvalidPathError{error: err}
+				ufProps := &processUploadFilesProps{
+					sourceFilePath:            sourceFilePath,
+					destinationFileParentPath: destinationParentPath,
+					destinationFilePath:       destinationFilePath,
+					fileParentId:              fileParentId,
+					bulkFilesSent:             bulkFilesSent,
+					bulkSizeSent:              bulkSizeSent,
+					totalFiles:                totalFiles,
+					totalSize:                 totalSize,
 				}
-				defer fileBuf.Close()
 
-				var compressedSize uint32
+				objId, err := processUploadFiles(dev, storageId, &pInfo, name, size, progressCb, ufProps)
+				bulkFilesSent = ufProps.bulkFilesSent
+				bulkSizeSent = ufProps.bulkSizeSent
+				if err != nil {
+					if !opts.continueOnError || !ufProps.fileFailed {
+						return err
+					}
 
-				// assign compressedSize of the file
-				if size > 0xFFFFFFFF {
-					compressedSize = 0xFFFFFFFF
-				} else {
-					compressedSize = uint32(size)
-				}
+					recordTransferError(dev)
+					if fileErr == nil {
+						fileErr = err
+					}
 
-				fObj := mtp.ObjectInfo{
-					StorageID:        storageId,
-					ObjectFormat:     mtp.OFC_Undefined,
-					ParentObject:     fileParentId,
-					Filename:         name,
-					CompressedSize:   compressedSize,
-					ModificationDate: time.Now(),
+					return nil
 				}
 
-				// keep track of [bulkFilesSent]
-				bulkFilesSent += 1
-
-				pInfo.FileInfo = &FileInfo{
-					Info:       &fObj,
-					Size:       size,
-					IsDir:      isDir,
-					ModTime:    fObj.ModificationDate,
-					Name:       fObj.Filename,
-					FullPath:   destinationFilePath,
-					ParentPath: destinationParentPath,
-					Extension:  extension(fObj.Filename, isDir),
-					ParentId:   fObj.ParentObject,
-				}
-				pInfo.LatestSentTime = time.Now()
-
-				// create file
-				var prevSentSize int64 = 0
-				objId, err := handleMakeFile(
-					dev, storageId, &fObj, &fInfo, fileBuf,
-					true,
-					func(total, sent int64, objId uint32, err error) error {
-						if err != nil {
-							return err
-						}
+				// append the current objectId to [destinationFilesDict]
+				destinationFilesDict[destinationFilePath] = objId
 
-						pInfo.FileInfo.ObjectId = objId
-						pInfo.ActiveFileSize.Total = total
-						pInfo.ActiveFileSize.Sent = sent
-						pInfo.ActiveFileSize.Progress = Percent(float32(sent), float32(total))
+				return nil
+			},
+		)
 
-						chunkSize := sent - prevSentSize
-						bulkSizeSent += chunkSize
+		if err != nil {
+			recordTransferError(dev)
 
-						pInfo.BulkFileSize.Sent = bulkSizeSent
-						pInfo.BulkFileSize.Progress = Percent(float32(bulkSizeSent), float32(totalSize))
+			return destParentId, bulkFilesSent, bulkSizeSent, summary, uploadFilesError(err)
+		}
+	}
 
-						pInfo.Speed = transferRate(chunkSize, pInfo.LatestSentTime)
-						if err = progressCb(&pInfo, nil); err != nil {
-							return err
-						}
+	pInfo.Status = Completed
+	pInfo.SessionStats = FetchTransferStats(dev)
+	if err := progressCb(&pInfo, nil); err != nil {
+		return destParentId, bulkFilesSent, bulkSizeSent, summary, err
+	}
 
-						pInfo.LatestSentTime = time.Now()
-						prevSentSize = sent
+	if fileErr != nil {
+		return destParentId, bulkFilesSent, bulkSizeSent, summary, uploadFilesError(fileErr)
+	}
 
-						return nil
-					},
-				)
+	return destParentId, bulkFilesSent, bulkSizeSent, summary, nil
+}
 
-				if err != nil {
-					return err
-				}
+// map the error which stopped [uploadFiles] to the error types of the package
+func uploadFilesError(err error) error {
+	switch err.(type) {
+	case InvalidPathError:
+		return err
 
-				pInfo.FilesSent = bulkFilesSent
-				pInfo.FilesSentProgress = Percent(float32(bulkFilesSent), float32(totalFiles))
+	case *os.PathError:
+		if errors.Is(err, os.ErrPermission) {
+			return FilePermissionError{error: err}
+		}
 
-				pInfo.FileInfo.ObjectId = objId
+		if errors.Is(err, os.ErrNotExist) {
+			return InvalidPathError{error: err}
+		}
 
-				// append the current objectId to [destinationFilesDict]
-				destinationFilesDict[destinationFilePath] = objId
+		return LocalFileError{error: err}
+	default:
+		return FileTransferError{error: fmt.Errorf("an error occured while uploading files. %+v", err.Error())}
+	}
+}
 
-				return nil
-			},
-		)
+// Transfer a single local file to the device
+// localPath: path of the local file, directories are not allowed (use [UploadFiles] instead)
+// destinationParentPath: fullPath to the destination directory, it is created if it does not exist
+// an existing file with the same name in [destinationParentPath] is overwritten (or kept under [ConflictKeepBoth], see
+// [SetConflictPolicy]), an existing directory returns an [InvalidPathError]
+// the modification date of the local file is kept
+// returns the [FileInfo] of the new file
+func UploadFile(dev *mtp.Device, storageId uint32, localPath, destinationParentPath string) (fi *FileInfo, err error) {
+	op := &OperationInfo{Type: UploadFileOp, Mutating: true, StorageId: storageId, Sources: []string{localPath}, Destination: destinationParentPath}
 
-		if err != nil {
-			switch err.(type) {
-			case InvalidPathError:
-				return destParentId, bulkFilesSent, bulkSizeSent, err
+	err = runMiddlewares(dev, op, func() error {
+		fi, err = uploadFile(dev, storageId, localPath, destinationParentPath)
 
-			case *os.PathError:
-				if errors.Is(err, os.ErrPermission) {
-					return destParentId, bulkFilesSent, bulkSizeSent, FilePermissionError{error: err}
-				}
+		return err
+	})
 
-				if errors.Is(err, os.ErrNotExist) {
-					return destParentId, bulkFilesSent, bulkSizeSent, InvalidPathError{error: err}
-				}
+	return fi, err
+}
 
-				return destParentId, bulkFilesSent, bulkSizeSent, LocalFileError{error: err}
-			default:
-				return destParentId, bulkFilesSent, bulkSizeSent,
-					FileTransferError{error: fmt.Errorf("an error occured while uploading files. %+v", err.Error())}
-			}
+// helper function for [UploadFile]
+func uploadFile(dev *mtp.Device, storageId uint32, localPath, destinationParentPath string) (*FileInfo, error) {
+	if err := checkStorageWritable(dev, storageId, false); err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(localPath)
+	if err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			return nil, FilePermissionError{error: err}
 		}
+
+		return nil, InvalidPathError{error: err}
 	}
 
-	pInfo.Status = Completed
-	if err := progressCb(&pInfo, nil); err != nil {
-		return destParentId, bulkFilesSent, bulkSizeSent, err
+	if stat.IsDir() {
+		return nil, InvalidPathError{error: fmt.Errorf("invalid path: %s. The source is a directory", localPath)}
+	}
+
+	fileBuf, err := os.Open(localPath)
+	if err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			return nil, FilePermissionError{error: err}
+		}
+
+		return nil, LocalFileError{error: err}
+	}
+	defer fileBuf.Close()
+
+	_destinationParentPath := devicepath.Clean(destinationParentPath)
+
+	parentId, err := makeNestedDirectory(dev, storageId, _destinationParentPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// the file may take a free name rather than replacing an existing one, see [SetConflictPolicy]
+	name, err := conflictFreeName(dev, storageId, parentId, stat.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	size := stat.Size()
+
+	startTime := time.Now()
+
+	objectId, err := sendObject(dev, storageId, parentId, name, mtp.OFC_Undefined, stat.ModTime(), fileBuf, size, true,
+		func(total, sent int64, objectId uint32, err error) error {
+			if err != nil {
+				return err
+			}
+
+			return touchOperation(dev)
+		})
+	if err != nil {
+		recordTransferError(dev)
+
+		return nil, err
 	}
 
-	return destParentId, bulkFilesSent, bulkSizeSent, nil
+	recordTransferredBytes(dev, Upload, size, time.Since(startTime))
+	recordTransferredFile(dev, Upload)
+
+	preserveHiddenUpload(dev, objectId, localPath)
+	refreshStorageSpace(dev, storageId)
+
+	return GetObjectFromObjectId(dev, objectId, _destinationParentPath)
 }
 
 // Transfer files from the device to the local disk
 // sources: can be the list of files/directories that are to be sent to the local disk
 // destination: fullPath to the destination directory
+// the directories are walked recursively and their structure is recreated inside [destination], the files keep the modification date of the device files
 // return:
 // [totalFiles]: total transferred files (directory count not included)
 // [totalSize]: total size of the uploaded files
 func DownloadFiles(dev *mtp.Device, storageId uint32, sources []string, destination string,
 	preprocessFiles bool, preprocessCb MtpPreprocessCb, progressCb ProgressCb) (bulkFilesSent int64, bulkSizeSent int64, err error) {
-	_destination := fixSlash(destination)
+	opts := transferOptions{preprocessFiles: preprocessFiles, mtpPreprocessCb: preprocessCb, progressCb: progressCb}
+
+	bulkFilesSent, bulkSizeSent, _, err = runDownloadFiles(dev, storageId, sources, destination, opts)
+
+	return bulkFilesSent, bulkSizeSent, err
+}
+
+// Transfer files from the device to the local disk, like [DownloadFiles]
+// a file which fails doesn't stop the transfer, it is recorded in the summary and the next file is fetched
+// return:
+// [summary]: result of every file, the files which failed can be retried using [RetryFailed]
+// [err]: error of the first file which failed, or the error which stopped the transfer
+func DownloadFilesWithSummary(dev *mtp.Device, storageId uint32, sources []string, destination string,
+	preprocessFiles bool, preprocessCb MtpPreprocessCb, progressCb ProgressCb) (summary *OperationSummary, err error) {
+	opts := transferOptions{
+		preprocessFiles: preprocessFiles,
+		mtpPreprocessCb: preprocessCb,
+		progressCb:      progressCb,
+		continueOnError: true,
+	}
+
+	_, _, summary, err = runDownloadFiles(dev, storageId, sources, destination, opts)
+
+	return summary, err
+}
+
+// run [downloadFiles] through the middlewares
+func runDownloadFiles(dev *mtp.Device, storageId uint32, sources []string, destination string,
+	opts transferOptions) (bulkFilesSent int64, bulkSizeSent int64, summary *OperationSummary, err error) {
+	op := &OperationInfo{Type: DownloadFilesOp, StorageId: storageId, Sources: sources, Destination: destination}
+
+	err = runMiddlewares(dev, op, func() error {
+		bulkFilesSent, bulkSizeSent, summary, err = downloadFiles(dev, storageId, sources, destination, opts)
+
+		return err
+	})
+
+	return bulkFilesSent, bulkSizeSent, summary, err
+}
+
+// helper function for [DownloadFiles]
+func downloadFiles(dev *mtp.Device, storageId uint32, sources []string, destination string,
+	opts transferOptions) (bulkFilesSent int64, bulkSizeSent int64, summary *OperationSummary, err error) {
+	preprocessFiles, preprocessCb, progressCb := opts.preprocessFiles, opts.mtpPreprocessCb, opts.progressCb
+	opts.disallowedFiles = FetchDisallowedFilesPolicy()
+
+	_destination := filepath.Clean(destination)
 
 	pInfo := ProgressInfo{
 		FileInfo:          &FileInfo{},
@@ -569,7 +1246,13 @@ func DownloadFiles(dev *mtp.Device, storageId uint32, sources []string, destinat
 		ActiveFileSize:    &TransferSizeInfo{},
 		BulkFileSize:      &TransferSizeInfo{},
 		Status:            InProgress,
+		Breakdown:         newTransferBreakdown(),
+		Summary:           newOperationSummary(DownloadFilesOp, storageId, opts),
 	}
+	summary = pInfo.Summary
+	defer func() {
+		summary.finish(err)
+	}()
 
 	// if [preprocessFiles] is true then fetch the total number of files from the file tree
 	// total number of files in the current download session
@@ -589,18 +1272,21 @@ func DownloadFiles(dev *mtp.Device, storageId uint32, sources []string, destinat
 	var cache = downloadFilesObjectCache{}
 	if preprocessFiles {
 		for _, source := range sources {
-			_source := fixSlash(source)
+			_source := devicepath.Clean(source)
 
-			_, _totalFiles, _totalDirectories, err := Walk(dev, storageId, _source, true, false, false,
+			_, _totalFiles, _totalDirectories, err := walkNested(dev, storageId, _source, true, false, false,
 				func(objectId uint32, fi *FileInfo, err error) error {
 					if err != nil {
 						return err
 					}
 
-					sourceParentPath := filepath.Dir(_source)
-					destinationFileParentPath, destinationFilePath := mapSourcePathToDestinationPath(
+					sourceParentPath, _ := devicepath.Split(_source)
+					destinationFileParentPath, destinationFilePath, err := mapDevicePathToLocalPath(
 						fi.FullPath, sourceParentPath, _destination,
 					)
+					if err != nil {
+						return err
+					}
 
 					cache[destinationFilePath] = downloadFilesObjectCacheContainer{
 						fileInfo:                  fi,
@@ -614,7 +1300,7 @@ func DownloadFiles(dev *mtp.Device, storageId uint32, sources []string, destinat
 					}
 
 					// filter out disallowed files
-					if isDisallowedFiles(fi.Name) {
+					if opts.disallowedFiles.skips(fi.Name) {
 						return nil
 					}
 
@@ -628,7 +1314,7 @@ func DownloadFiles(dev *mtp.Device, storageId uint32, sources []string, destinat
 				})
 
 			if err != nil {
-				return bulkFilesSent, bulkSizeSent, err
+				return bulkFilesSent, bulkSizeSent, summary, err
 			}
 
 			totalFiles += _totalFiles
@@ -647,6 +1333,23 @@ func DownloadFiles(dev *mtp.Device, storageId uint32, sources []string, destinat
 		totalSize:     totalSize,
 	}
 
+	// error of the first file which failed, when the transfer moves on after the failures
+	var fileErr error
+
+	// keep the error of a file which failed, returns false if the transfer has to stop at it
+	moveOnFrom := func(err error) bool {
+		if !opts.continueOnError || !dfProps.fileFailed {
+			return false
+		}
+
+		recordTransferError(dev)
+		if fileErr == nil {
+			fileErr = err
+		}
+
+		return true
+	}
+
 	if len(cache) > 0 {
 		for _, c := range cache {
 			dfProps.sourceParentPath = c.sourceParentPath
@@ -655,48 +1358,147 @@ func DownloadFiles(dev *mtp.Device, storageId uint32, sources []string, destinat
 
 			err := processDownloadFiles(dev, &pInfo, c.fileInfo, progressCb, dfProps)
 
-			if err != nil {
-				return processDownloadFilesError(dfProps, err)
+			if err != nil && !moveOnFrom(err) {
+				recordTransferError(dev)
+
+				return processDownloadFilesError(dfProps, summary, err)
 			}
 		}
 	} else {
 		for _, source := range sources {
-			_source := fixSlash(source)
+			_source := devicepath.Clean(source)
 
 			_, err := GetObjectFromPath(dev, storageId, _source)
 			if err != nil {
-				return dfProps.bulkFilesSent, dfProps.bulkSizeSent, err
+				return dfProps.bulkFilesSent, dfProps.bulkSizeSent, summary, err
 			}
 
-			_, _, _, wErr := Walk(dev, storageId, _source, true, false, false,
+			_, _, _, wErr := walkNested(dev, storageId, _source, true, false, false,
 				func(objectId uint32, fi *FileInfo, err error) error {
 					if err != nil {
 						return err
 					}
 
-					sourceParentPath := filepath.Dir(_source)
-					destinationFileParentPath, destinationFilePath := mapSourcePathToDestinationPath(
+					sourceParentPath, _ := devicepath.Split(_source)
+					destinationFileParentPath, destinationFilePath, err := mapDevicePathToLocalPath(
 						fi.FullPath, sourceParentPath, _destination,
 					)
+					if err != nil {
+						return err
+					}
 					dfProps.sourceParentPath = sourceParentPath
 					dfProps.destinationFileParentPath = destinationFileParentPath
 					dfProps.destinationFilePath = destinationFilePath
 
-					return processDownloadFiles(dev, &pInfo, fi, progressCb, dfProps)
+					if err := processDownloadFiles(dev, &pInfo, fi, progressCb, dfProps); err != nil && !moveOnFrom(err) {
+						return err
+					}
+
+					return nil
 				})
 
 			if wErr != nil {
-				return processDownloadFilesError(dfProps, wErr)
+				recordTransferError(dev)
+
+				return processDownloadFilesError(dfProps, summary, wErr)
 			}
 		}
 	}
 
 	pInfo.Status = Completed
+	pInfo.SessionStats = FetchTransferStats(dev)
 	if err := progressCb(&pInfo, nil); err != nil {
-		return dfProps.bulkFilesSent, dfProps.bulkSizeSent, err
+		return dfProps.bulkFilesSent, dfProps.bulkSizeSent, summary, err
+	}
+
+	if fileErr != nil {
+		return processDownloadFilesError(dfProps, summary, fileErr)
+	}
+
+	return dfProps.bulkFilesSent, dfProps.bulkSizeSent, summary, nil
+}
+
+// Transfer a single file from the device to the local disk
+// the file is resolved using [objectId] if it is non zero, otherwise using [fullPath]
+// localDestination: path of the local file, the parent directories are created if they do not exist.
+// if [localDestination] is an existing directory then the file is placed inside it using its device filename
+// an existing local file is overwritten and the modification date of the device file is kept
+// returns the number of bytes transferred
+func DownloadFile(dev *mtp.Device, storageId uint32, objectId uint32, fullPath, localDestination string) (bytesTransferred int64, err error) {
+	op := &OperationInfo{Type: DownloadFileOp, StorageId: storageId, FileProps: []FileProp{{objectId, fullPath}}, Destination: localDestination}
+
+	err = runMiddlewares(dev, op, func() error {
+		bytesTransferred, err = downloadFile(dev, storageId, objectId, fullPath, localDestination)
+
+		return err
+	})
+
+	return bytesTransferred, err
+}
+
+// helper function for [DownloadFile]
+func downloadFile(dev *mtp.Device, storageId uint32, objectId uint32, fullPath, localDestination string) (int64, error) {
+	fi, err := GetObjectFromObjectIdOrPath(dev, storageId, FileProp{objectId, fullPath})
+	if err != nil {
+		return 0, err
+	}
+
+	if fi.IsDir {
+		return 0, InvalidPathError{error: fmt.Errorf("invalid path: %s. The object is a directory", fi.FullPath)}
+	}
+
+	destination := localDestination
+	if stat, err := os.Stat(destination); err == nil && stat.IsDir() {
+		destination = filepath.Join(destination, fi.Name)
+	}
+
+	if err := makeLocalDirectory(filepath.Dir(destination)); err != nil {
+		return 0, err
+	}
+
+	startTime := time.Now()
+
+	var bytesTransferred int64
+	err = handleMakeLocalFile(dev, fi, destination, func(total, sent int64, objectId uint32, err error) error {
+		if err != nil {
+			return err
+		}
+
+		bytesTransferred = sent
+
+		return touchOperation(dev)
+	})
+	if err != nil {
+		recordTransferError(dev)
+
+		switch err.(type) {
+		case *os.PathError:
+			if errors.Is(err, os.ErrPermission) {
+				return bytesTransferred, FilePermissionError{error: err}
+			}
+
+			return bytesTransferred, LocalFileError{error: err}
+
+		case OperationStalledError, OperationCancelledError, TransferStalledError, LocalSpaceError:
+			return bytesTransferred, err
+
+		default:
+			return bytesTransferred, FileTransferError{error: fmt.Errorf("an error occured while downloading the file. %+v", err.Error())}
+		}
+	}
+
+	recordTransferredBytes(dev, Download, bytesTransferred, time.Since(startTime))
+	recordTransferredFile(dev, Download)
+
+	if err := os.Chtimes(destination, time.Now(), fi.ModTime); err != nil {
+		return bytesTransferred, LocalFileError{error: err}
+	}
+
+	if _, err := preserveHiddenDownload(dev, fi.ObjectId, destination); err != nil {
+		return bytesTransferred, err
 	}
 
-	return dfProps.bulkFilesSent, dfProps.bulkSizeSent, nil
+	return bytesTransferred, nil
 }
 
 func main() {}
@@ -0,0 +1,107 @@
+package mtpx
+
+import (
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"sync"
+)
+
+var deviceMiddlewares = struct {
+	sync.Mutex
+	m map[*mtp.Device][]Middleware
+}{m: map[*mtp.Device][]Middleware{}}
+
+// Register hooks which run around the operations of the device (eg: audit logging, policy enforcement, metrics)
+// the middlewares run in the order they were added, the first one being the outermost
+// covers [MakeDirectory], [DeleteFile], [DeleteDirectoryContents], [RenameFile], [UploadFiles], [DownloadFiles] and [WriteFileFromBytes]
+// note: the operations which are composed of other operations (eg: [UploadFiles] creating the directories) pass through the middlewares again for each of them
+// eg: block the deletes outside '/DCIM'
+//
+//	AddMiddleware(dev, func(op *OperationInfo, next func() error) error {
+//		if op.Type == DeleteFileOp && !allowed(op.FileProps) {
+//			return fmt.Errorf("delete not allowed")
+//		}
+//		return next()
+//	})
+func AddMiddleware(dev *mtp.Device, middlewares ...Middleware) {
+	if len(middlewares) < 1 {
+		return
+	}
+
+	deviceMiddlewares.Lock()
+	defer deviceMiddlewares.Unlock()
+
+	deviceMiddlewares.m[dev] = append(deviceMiddlewares.m[dev], middlewares...)
+}
+
+// drop the middlewares of the device
+func disposeMiddlewares(dev *mtp.Device) {
+	deviceMiddlewares.Lock()
+	defer deviceMiddlewares.Unlock()
+
+	delete(deviceMiddlewares.m, dev)
+}
+
+// run [fn] through the middlewares of the device
+// the operations which the profile of the device doesn't support are refused, see [DeviceProfile.UnsupportedOps]
+// the mutating operations are not run if the device is in the simulation mode
+func runMiddlewares(dev *mtp.Device, op *OperationInfo, fn func() error) (err error) {
+	return runOperation(dev, op, false, fn)
+}
+
+// like [runMiddlewares], for an operation which is a part of the running one (eg: [MakeDirectory] inside [UploadFiles])
+// the nested operation is not registered with [beginOperation], it is let through while [Shutdown] waits for the
+// running operation and refused once the running operations are cancelled
+func runNestedMiddlewares(dev *mtp.Device, op *OperationInfo, fn func() error) (err error) {
+	return runOperation(dev, op, true, fn)
+}
+
+// helper function for [runMiddlewares] and [runNestedMiddlewares]
+func runOperation(dev *mtp.Device, op *OperationInfo, nested bool, fn func() error) (err error) {
+	if err := checkProfileOperation(dev, op.Type); err != nil {
+		return err
+	}
+
+	if op.Mutating {
+		if cb, ok := getSimulation(dev); ok {
+			return simulateOperation(dev, op, cb)
+		}
+	}
+
+	if nested {
+		if err := checkCancelled(dev); err != nil {
+			return err
+		}
+	} else {
+		release, err := beginOperation(dev)
+		if err != nil {
+			return err
+		}
+		defer func() { release(err) }()
+	}
+
+	stop := monitorOperation(dev, op)
+	defer func() { stop(err) }()
+
+	done := transcribeOperation(dev, op)
+	defer func() { done(err) }()
+
+	// a failed operation may have changed the storage halfway through
+	if op.Mutating {
+		defer refreshStorageSpace(dev, op.StorageId)
+	}
+
+	deviceMiddlewares.Lock()
+	middlewares := append([]Middleware(nil), deviceMiddlewares.m[dev]...)
+	deviceMiddlewares.Unlock()
+
+	next := fn
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		mw, _next := middlewares[i], next
+
+		next = func() error {
+			return mw(op, _next)
+		}
+	}
+
+	return next()
+}
@@ -0,0 +1,60 @@
+package mtpx
+
+import (
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestMiddlewares(t *testing.T) {
+	Convey("Test runMiddlewares", t, func() {
+		// the middlewares are keyed by the device, a nil device is good enough here
+		defer disposeMiddlewares(nil)
+
+		var calls []string
+
+		AddMiddleware(nil, func(op *OperationInfo, next func() error) error {
+			calls = append(calls, "outer:before")
+			err := next()
+			calls = append(calls, "outer:after")
+
+			return err
+		}, func(op *OperationInfo, next func() error) error {
+			if op.Type == DeleteFileOp {
+				return fmt.Errorf("blocked")
+			}
+
+			calls = append(calls, "inner")
+
+			return next()
+		})
+
+		err := runMiddlewares(nil, &OperationInfo{Type: MakeDirectoryOp}, func() error {
+			calls = append(calls, "op")
+
+			return nil
+		})
+		So(err, ShouldBeNil)
+		So(calls, ShouldResemble, []string{"outer:before", "inner", "op", "outer:after"})
+
+		calls = nil
+		err = runMiddlewares(nil, &OperationInfo{Type: DeleteFileOp}, func() error {
+			calls = append(calls, "op")
+
+			return nil
+		})
+		So(err, ShouldBeError, "blocked")
+		So(calls, ShouldResemble, []string{"outer:before", "outer:after"})
+
+		disposeMiddlewares(nil)
+
+		calls = nil
+		err = runMiddlewares(nil, &OperationInfo{Type: DeleteFileOp}, func() error {
+			calls = append(calls, "op")
+
+			return nil
+		})
+		So(err, ShouldBeNil)
+		So(calls, ShouldResemble, []string{"op"})
+	})
+}
@@ -0,0 +1,498 @@
+package mtpx
+
+import (
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/ganeshrvel/go-mtpx/devicepath"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Move a file/directory into the directory [destinationParentPath]
+// the object is resolved using [objectId] if it is non zero, otherwise using [fullPath]
+// the destination directory is created if it does not exist
+// the device moves the object by itself if it supports the MoveObject operation, the objectId is kept in that case
+// otherwise the object is copied through a local temp directory and the source is deleted once the copy is complete
+// returns an [InvalidPathError] if an object with the same name exists in [destinationParentPath]
+// or if a directory is moved into itself
+// returns the objectId of the moved object
+func MoveFile(dev *mtp.Device, storageId uint32, objectId uint32, fullPath, destinationParentPath string) (newObjectId uint32, err error) {
+	op := &OperationInfo{Type: MoveFileOp, Mutating: true, StorageId: storageId, FileProps: []FileProp{{objectId, fullPath}}, Destination: destinationParentPath}
+
+	err = runMiddlewares(dev, op, func() error {
+		newObjectId, err = moveFile(dev, storageId, objectId, fullPath, destinationParentPath)
+
+		return err
+	})
+
+	return newObjectId, err
+}
+
+// helper function for [MoveFile]
+func moveFile(dev *mtp.Device, storageId uint32, objectId uint32, fullPath, destinationParentPath string) (uint32, error) {
+	if err := checkStorageWritable(dev, storageId, false); err != nil {
+		return 0, err
+	}
+
+	fi, err := GetObjectFromObjectIdOrPath(dev, storageId, FileProp{objectId, fullPath})
+	if err != nil {
+		return 0, err
+	}
+
+	if fi.ObjectId == ParentObjectId {
+		return 0, InvalidPathError{error: fmt.Errorf("the root directory cannot be moved")}
+	}
+
+	if err := checkObjectNotInUse(dev, fi); err != nil {
+		return 0, err
+	}
+
+	if err := handleProtectedObject(dev, fi, false); err != nil {
+		return 0, err
+	}
+
+	// the objects at the top level of the storage have no parent
+	parentId := fi.ParentId
+	if parentId == 0 {
+		parentId = ParentObjectId
+	}
+
+	ancestorId, exists, err := existingAncestor(dev, storageId, destinationParentPath)
+	if err != nil {
+		return 0, err
+	}
+
+	if exists && ancestorId == parentId {
+		return fi.ObjectId, nil
+	}
+
+	if err := checkDestinationParent(dev, storageId, fi, fi.Name, ancestorId, exists, destinationParentPath, "moved"); err != nil {
+		return 0, err
+	}
+
+	destParentId, err := makeNestedDirectory(dev, storageId, destinationParentPath)
+	if err != nil {
+		return 0, err
+	}
+
+	if supportsOperation(dev, mtp.OC_MoveObject) {
+		err := moveObjectWithProgress(dev, fi, storageId, destParentId)
+		if err == nil {
+			invalidateCachedListing(dev, storageId, fi.ParentId)
+			invalidateCachedListing(dev, storageId, destParentId)
+
+			return fi.ObjectId, nil
+		}
+
+		// some devices advertise the operation but refuse it
+		if !isOperationNotSupported(err) {
+			return 0, err
+		}
+	}
+
+	return moveFileThroughHost(dev, storageId, fi, destParentId)
+}
+
+// Rename and/or move the object at [sourcePath] to [destinationPath] in a single call, like [os.Rename]
+// the plain renames, the moves into another directory and the moves under a new name are all handled. the object is
+// renamed using the ObjectFileName property and moved as in [MoveFile], so the objectId is kept whenever the device allows it
+// the parent directory of [destinationPath] must exist
+// an existing file at [destinationPath] is replaced once the object is in place, unless the object is a directory.
+// an existing directory is never replaced
+// if the move fails halfway the object is put back at [sourcePath], the error names where it was left otherwise
+// returns an [InvalidPathError] if a directory is moved into itself
+// return:
+// [objectId]: objectId of the object at [destinationPath]
+func MovePath(dev *mtp.Device, storageId uint32, sourcePath, destinationPath string) (objectId uint32, err error) {
+	op := &OperationInfo{Type: MovePathOp, Mutating: true, StorageId: storageId, FileProps: []FileProp{{0, sourcePath}}, Destination: destinationPath}
+
+	err = runMiddlewares(dev, op, func() error {
+		objectId, err = movePath(dev, storageId, sourcePath, destinationPath)
+
+		return err
+	})
+
+	return objectId, err
+}
+
+// helper function for [MovePath]
+func movePath(dev *mtp.Device, storageId uint32, sourcePath, destinationPath string) (uint32, error) {
+	if err := checkStorageWritable(dev, storageId, false); err != nil {
+		return 0, err
+	}
+
+	fi, err := GetObjectFromPath(dev, storageId, sourcePath)
+	if err != nil {
+		return 0, err
+	}
+
+	if fi.ObjectId == ParentObjectId {
+		return 0, InvalidPathError{error: fmt.Errorf("the root directory cannot be moved")}
+	}
+
+	if err := checkObjectNotInUse(dev, fi); err != nil {
+		return 0, err
+	}
+
+	destParentPath, destName := devicepath.Split(devicepath.Clean(destinationPath))
+	if destName == "" {
+		return 0, InvalidPathError{error: fmt.Errorf("invalid destination path: %s", destinationPath)}
+	}
+
+	destParent, err := GetObjectFromPath(dev, storageId, destParentPath)
+	if err != nil {
+		return 0, err
+	}
+
+	if !destParent.IsDir {
+		return 0, InvalidPathError{error: fmt.Errorf("invalid path: %s. The parent is not a directory", destinationPath)}
+	}
+
+	moving := destParent.ObjectId != normalizeParentId(fi.ParentId)
+
+	if moving && fi.IsDir {
+		inside, err := isObjectInside(dev, destParent.ObjectId, fi.ObjectId)
+		if err != nil {
+			return 0, err
+		}
+
+		if inside {
+			return 0, InvalidPathError{error: fmt.Errorf("a directory cannot be moved into itself: %s", destinationPath)}
+		}
+	}
+
+	// the object to be replaced, if any
+	existing, err := objectNamedOtherThan(dev, storageId, destParent.ObjectId, destName, fi.ObjectId)
+	if err != nil {
+		return 0, err
+	}
+
+	if existing != nil {
+		if existing.IsDir {
+			return 0, InvalidPathError{error: fmt.Errorf("a directory exists at %s", destinationPath)}
+		}
+
+		if fi.IsDir {
+			return 0, InvalidPathError{error: fmt.Errorf("a directory cannot replace the file %s", destinationPath)}
+		}
+
+		// fail before anything is changed
+		if err := checkObjectNotInUse(dev, existing); err != nil {
+			return 0, err
+		}
+
+		if err := handleProtectedObject(dev, existing, false); err != nil {
+			return 0, err
+		}
+	}
+
+	if !moving && existing == nil && fi.Name == destName {
+		return fi.ObjectId, nil
+	}
+
+	// the object goes to the destination directory under [destName] unless the name is taken on either side,
+	// a temp name is used until the way is clear in that case
+	stagingName := destName
+	if existing != nil {
+		stagingName = tempObjectName(".mtpx-move-*")
+	} else if moving {
+		taken, err := objectNamedOtherThan(dev, storageId, fi.ParentId, destName, fi.ObjectId)
+		if err != nil {
+			return 0, err
+		}
+
+		if taken != nil {
+			stagingName = tempObjectName(".mtpx-move-*")
+		}
+	}
+
+	objectId := fi.ObjectId
+	name := fi.Name
+
+	if stagingName != name {
+		if _, err := renameFile(dev, storageId, FileProp{objectId, ""}, stagingName); err != nil {
+			return 0, err
+		}
+
+		name = stagingName
+	}
+
+	if moving {
+		newObjectId, err := moveFile(dev, storageId, objectId, "", destParentPath)
+		if err != nil {
+			// put the name back, the object hasn't moved
+			if name != fi.Name {
+				_, _ = renameFile(dev, storageId, FileProp{objectId, ""}, fi.Name)
+			}
+
+			return 0, err
+		}
+
+		objectId = newObjectId
+	}
+
+	if existing != nil {
+		if err := deleteFile(dev, storageId, []FileProp{{existing.ObjectId, ""}}, DeleteOptions{Strict: true}); err != nil {
+			return 0, restoreMovedObject(dev, storageId, fi, objectId, name, moving, destParentPath, err)
+		}
+	}
+
+	if name != destName {
+		if _, err := renameFile(dev, storageId, FileProp{objectId, ""}, destName); err != nil {
+			return 0, restoreMovedObject(dev, storageId, fi, objectId, name, moving, destParentPath, err)
+		}
+	}
+
+	return objectId, nil
+}
+
+// put the object [objectId], which was staged as [name] by [movePath], back in the place of [fi] after the error [cause]
+// the object is moved back out of [destParentPath] if [moved] is true and takes its original name again
+// returns [cause] once the object is back, otherwise an error which names the place where the object was left
+func restoreMovedObject(dev *mtp.Device, storageId uint32, fi *FileInfo, objectId uint32, name string, moved bool,
+	destParentPath string, cause error) error {
+	stagedPath := devicepath.Join(destParentPath, name)
+
+	if moved {
+		sourceParentPath, _ := devicepath.Split(fi.FullPath)
+
+		restoredObjectId, err := moveFile(dev, storageId, objectId, "", sourceParentPath)
+		if err != nil {
+			return FileTransferError{error: fmt.Errorf("%s was left at %s: %v", fi.FullPath, stagedPath, cause)}
+		}
+
+		objectId = restoredObjectId
+		stagedPath = devicepath.Join(sourceParentPath, name)
+	}
+
+	if name != fi.Name {
+		if _, err := renameFile(dev, storageId, FileProp{objectId, ""}, fi.Name); err != nil {
+			return FileTransferError{error: fmt.Errorf("%s was left at %s: %v", fi.FullPath, stagedPath, cause)}
+		}
+	}
+
+	return cause
+}
+
+// fetch the object named [filename] inside [parentId], unless it is [objectId]
+// returns nil if there is no such object
+func objectNamedOtherThan(dev *mtp.Device, storageId, parentId uint32, filename string, objectId uint32) (*FileInfo, error) {
+	fi, err := GetObjectFromParentIdAndFilename(dev, storageId, normalizeParentId(parentId), filename)
+	switch err.(type) {
+	case nil:
+
+	case FileNotFoundError:
+		return nil, nil
+
+	default:
+		return nil, err
+	}
+
+	if fi.ObjectId == objectId {
+		return nil, nil
+	}
+
+	return fi, nil
+}
+
+// move [fi] using the MoveObject operation
+// the moves across the storages copy the data and their progress is reported, see [SetDeviceSideProgressCb]
+func moveObjectWithProgress(dev *mtp.Device, fi *FileInfo, storageId, destParentId uint32) error {
+	if fi.Info.StorageID == storageId {
+		return withoutStallTimeout(dev, func() error {
+			return moveObject(dev, fi.ObjectId, storageId, destParentId)
+		})
+	}
+
+	_, err := withDeviceSideProgress(dev, fi, func() (uint32, error) {
+		return fi.ObjectId, moveObject(dev, fi.ObjectId, storageId, destParentId)
+	})
+
+	return err
+}
+
+// copy [fi] into [destParentId] through a local temp directory and delete the source
+// the source is left alone if the copy fails, the partial copy is removed
+func moveFileThroughHost(dev *mtp.Device, storageId uint32, fi *FileInfo, destParentId uint32) (uint32, error) {
+	tempDir, err := ioutil.TempDir("", "mtpx-move")
+	if err != nil {
+		return 0, LocalFileError{error: err}
+	}
+	defer os.RemoveAll(tempDir)
+
+	newObjectId, err := copyObjectThroughHost(dev, storageId, fi, destParentId, fi.Name, tempDir)
+	if err != nil {
+		if newObjectId != 0 {
+			_ = deleteNestedFile(dev, storageId, []FileProp{{newObjectId, ""}}, DeleteOptions{})
+		}
+
+		return 0, err
+	}
+
+	if err := deleteNestedFile(dev, storageId, []FileProp{{fi.ObjectId, ""}}, DeleteOptions{}); err != nil {
+		return newObjectId, err
+	}
+
+	return newObjectId, nil
+}
+
+// copy [fi] into [parentId] as [name] using a temp file inside [tempDir], the directories are copied recursively
+// an object named [name] in [parentId] is never replaced, an [InvalidPathError] is returned instead
+// returns the objectId of the copy, it is set even if the copy of a directory failed halfway through
+func copyObjectThroughHost(dev *mtp.Device, storageId uint32, fi *FileInfo, parentId uint32, name, tempDir string) (uint32, error) {
+	if _, err := GetObjectFromParentIdAndFilename(dev, storageId, parentId, name); err == nil {
+		return 0, InvalidPathError{error: fmt.Errorf("an object named %s already exists in the destination", name)}
+	} else if _, ok := err.(FileNotFoundError); !ok {
+		return 0, err
+	}
+
+	return copyTreeThroughHost(dev, storageId, fi, parentId, name, tempDir)
+}
+
+// helper function for [copyObjectThroughHost], the children of a directory are copied into the new directory
+func copyTreeThroughHost(dev *mtp.Device, storageId uint32, fi *FileInfo, parentId uint32, name, tempDir string) (uint32, error) {
+	if fi.IsDir {
+		dirId, err := handleMakeDirectory(dev, storageId, parentId, name)
+		if err != nil {
+			return 0, err
+		}
+
+		children, err := listDirectory(dev, storageId, fi.ObjectId, fi.FullPath)
+		if err != nil {
+			return dirId, err
+		}
+
+		for _, child := range children {
+			if _, err := copyTreeThroughHost(dev, storageId, child, dirId, child.Name, tempDir); err != nil {
+				return dirId, err
+			}
+		}
+
+		return dirId, nil
+	}
+
+	localPath := filepath.Join(tempDir, strconv.FormatUint(uint64(fi.ObjectId), 10))
+	defer os.Remove(localPath)
+
+	noProgress := func(total, sent int64, objectId uint32, err error) error {
+		if err != nil {
+			return err
+		}
+
+		return touchOperation(dev)
+	}
+
+	if err := handleMakeLocalFile(dev, fi, localPath, noProgress); err != nil {
+		return 0, FileTransferError{error: fmt.Errorf("an error occured while copying %s: %v", fi.Name, err)}
+	}
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return 0, LocalFileError{error: err}
+	}
+	defer f.Close()
+
+	return sendObject(dev, storageId, parentId, name, fi.Info.ObjectFormat, fi.ModTime, f, fi.Size, false, noProgress)
+}
+
+// check whether [objectId] is [ancestorId] or lies inside it, by following the parents of [objectId] up to the root
+func isObjectInside(dev *mtp.Device, objectId, ancestorId uint32) (bool, error) {
+	visited := map[uint32]bool{}
+
+	for objectId != ParentObjectId && objectId != 0 && !visited[objectId] {
+		if objectId == ancestorId {
+			return true, nil
+		}
+
+		visited[objectId] = true
+
+		fi, err := GetObjectFromObjectId(dev, objectId, "")
+		if err != nil {
+			return false, err
+		}
+
+		objectId = fi.ParentId
+	}
+
+	return false, nil
+}
+
+// resolve the directories of [fullPath] which exist, nothing is created
+// return:
+// [objectId]: objectId of the deepest directory of [fullPath] which exists
+// [exists]: true if the whole of [fullPath] exists
+func existingAncestor(dev *mtp.Device, storageId uint32, fullPath string) (objectId uint32, exists bool, err error) {
+	_fullPath := devicepath.Clean(fullPath)
+
+	objectId = ParentObjectId
+	if _fullPath == devicepath.Separator {
+		return objectId, true, nil
+	}
+
+	const skipIndex = 1
+
+	for _, fName := range strings.Split(_fullPath, devicepath.Separator)[skipIndex:] {
+		fi, err := GetObjectFromParentIdAndFilename(dev, storageId, objectId, fName)
+		if err != nil {
+			if _, ok := err.(FileNotFoundError); ok {
+				return objectId, false, nil
+			}
+
+			return 0, false, err
+		}
+
+		// a file in the way is reported when the directory is made
+		if !fi.IsDir {
+			return objectId, false, nil
+		}
+
+		objectId = fi.ObjectId
+	}
+
+	return objectId, true, nil
+}
+
+// check that [fi] can be put into [destinationParentPath] as [name] before the directory is made, so that a refused move
+// or copy leaves no new directories behind. [ancestorId] and [exists] are the result of [existingAncestor] for
+// [destinationParentPath]. the new directories are made inside [ancestorId], a directory is inside itself if [ancestorId] is
+func checkDestinationParent(dev *mtp.Device, storageId uint32, fi *FileInfo, name string, ancestorId uint32, exists bool,
+	destinationParentPath, verb string) error {
+	if fi.IsDir {
+		inside, err := isObjectInside(dev, ancestorId, fi.ObjectId)
+		if err != nil {
+			return err
+		}
+
+		if inside {
+			return InvalidPathError{error: fmt.Errorf("a directory cannot be %s into itself: %s", verb, destinationParentPath)}
+		}
+	}
+
+	if !exists {
+		return nil
+	}
+
+	if _, err := GetObjectFromParentIdAndFilename(dev, storageId, ancestorId, name); err == nil {
+		return InvalidPathError{error: fmt.Errorf("an object named %s already exists in %s", name, destinationParentPath)}
+	}
+
+	return nil
+}
+
+// check whether the device refused the operation of [err] as unsupported
+func isOperationNotSupported(err error) bool {
+	switch e := err.(type) {
+	case MoveObjectError:
+		err = e.error
+
+	case CopyObjectError:
+		err = e.error
+	}
+
+	rc, ok := err.(mtp.RCError)
+
+	return ok && rc == mtp.RC_OperationNotSupported
+}
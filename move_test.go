@@ -0,0 +1,149 @@
+package mtpx
+
+import (
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	. "github.com/smartystreets/goconvey/convey"
+	"log"
+	"testing"
+)
+
+func TestMoveFile(t *testing.T) {
+	dev, err := Initialize(Init{})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	storages, err := FetchStorages(dev)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	sid := storages[0].Sid
+
+	Convey("General | MoveFile", t, func() {
+		source := "/mtp-test-files/temp_dir/test_MoveFile/source"
+		destination := "/mtp-test-files/temp_dir/test_MoveFile/destination"
+
+		_, _, _, err := UploadFiles(dev, sid, []string{getTestMocksAsset("mock_dir1")}, source, false, nil,
+			func(fi *ProgressInfo, err error) error {
+				return err
+			})
+		So(err, ShouldBeNil)
+
+		objectId, err := MoveFile(dev, sid, 0, source+"/mock_dir1/a.txt", destination)
+		So(err, ShouldBeNil)
+
+		fi, err := GetObjectFromPath(dev, sid, destination+"/a.txt")
+		So(err, ShouldBeNil)
+		So(fi.ObjectId, ShouldEqual, objectId)
+
+		_, err = GetObjectFromPath(dev, sid, source+"/mock_dir1/a.txt")
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+
+		// the directories are moved with their contents
+		_, err = MoveFile(dev, sid, 0, source+"/mock_dir1/3", destination)
+		So(err, ShouldBeNil)
+
+		_, err = GetObjectFromPath(dev, sid, destination+"/3/2/b.txt")
+		So(err, ShouldBeNil)
+
+		err = DeleteFile(dev, sid, []FileProp{{0, "/mtp-test-files/temp_dir/test_MoveFile"}})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Directory into itself | MoveFile | Should throw an error", t, func() {
+		_, err := MoveFile(dev, sid, 0, "/mtp-test-files/mock_dir1", "/mtp-test-files/mock_dir1/3")
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+	})
+
+	Convey("Existing destination | MoveFile | Should throw an error", t, func() {
+		_, err := MoveFile(dev, sid, 0, "/mtp-test-files/mock_dir1/1/a.txt", "/mtp-test-files/mock_dir1")
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+	})
+
+	Dispose(dev)
+}
+
+func TestMovePath(t *testing.T) {
+	dev, err := Initialize(Init{})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	storages, err := FetchStorages(dev)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	sid := storages[0].Sid
+
+	Convey("Rename and move | MovePath", t, func() {
+		source := "/mtp-test-files/temp_dir/test_MovePath/source"
+		destination := "/mtp-test-files/temp_dir/test_MovePath/destination"
+
+		_, _, _, err := UploadFiles(dev, sid, []string{getTestMocksAsset("mock_dir1")}, source, false, nil,
+			func(fi *ProgressInfo, err error) error {
+				return err
+			})
+		So(err, ShouldBeNil)
+
+		_, err = MakeDirectory(dev, sid, destination)
+		So(err, ShouldBeNil)
+
+		// a plain rename
+		objectId, err := MovePath(dev, sid, source+"/mock_dir1/a.txt", source+"/mock_dir1/renamed.txt")
+		So(err, ShouldBeNil)
+
+		fi, err := GetObjectFromPath(dev, sid, source+"/mock_dir1/renamed.txt")
+		So(err, ShouldBeNil)
+		So(fi.ObjectId, ShouldEqual, objectId)
+
+		// a move under a new name
+		objectId, err = MovePath(dev, sid, source+"/mock_dir1/renamed.txt", destination+"/moved.txt")
+		So(err, ShouldBeNil)
+
+		fi, err = GetObjectFromPath(dev, sid, destination+"/moved.txt")
+		So(err, ShouldBeNil)
+		So(fi.ObjectId, ShouldEqual, objectId)
+
+		_, err = GetObjectFromPath(dev, sid, source+"/mock_dir1/renamed.txt")
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+
+		// an existing file is replaced
+		objectId, err = MovePath(dev, sid, source+"/mock_dir1/1/a.txt", destination+"/moved.txt")
+		So(err, ShouldBeNil)
+
+		fi, err = GetObjectFromPath(dev, sid, destination+"/moved.txt")
+		So(err, ShouldBeNil)
+		So(fi.ObjectId, ShouldEqual, objectId)
+
+		// a directory can't replace a file
+		_, err = MovePath(dev, sid, source+"/mock_dir1/3", destination+"/moved.txt")
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+
+		// the parent of the destination must exist
+		_, err = MovePath(dev, sid, source+"/mock_dir1/3", destination+"/missing/3")
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+
+		err = DeleteFile(dev, sid, []FileProp{{0, "/mtp-test-files/temp_dir/test_MovePath"}})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Directory into itself | MovePath | Should throw an error", t, func() {
+		_, err := MovePath(dev, sid, "/mtp-test-files/mock_dir1", "/mtp-test-files/mock_dir1/3/mock_dir1")
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+	})
+
+	Dispose(dev)
+}
+
+func TestIsOperationNotSupported(t *testing.T) {
+	Convey("Test isOperationNotSupported", t, func() {
+		So(isOperationNotSupported(MoveObjectError{error: mtp.RCError(mtp.RC_OperationNotSupported)}), ShouldBeTrue)
+		So(isOperationNotSupported(mtp.RCError(mtp.RC_OperationNotSupported)), ShouldBeTrue)
+		So(isOperationNotSupported(MoveObjectError{error: mtp.RCError(mtp.RC_GeneralError)}), ShouldBeFalse)
+		So(isOperationNotSupported(CopyObjectError{error: mtp.RCError(mtp.RC_OperationNotSupported)}), ShouldBeTrue)
+		So(isOperationNotSupported(fmt.Errorf("some error")), ShouldBeFalse)
+	})
+}
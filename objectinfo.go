@@ -0,0 +1,172 @@
+package mtpx
+
+import (
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+)
+
+// Update the name, the modification date, the hidden status and the protection status of a file/directory in one call
+// [objectId] and [fullPath] of [fileProp] are optional parameters, see [RenameFile]
+// the properties which the device doesn't let change are looked up before anything is written, an [UnsupportedOperationError]
+// is returned if any of the requested changes can't be made and the object is left untouched in that case
+// the protection status is changed using the SetObjectProtection operation if the device doesn't support the property
+// note: the devices may still refuse a supported change, eg: the read-only storages, the changes made before it are kept
+// returns the updated information of the object
+func UpdateObjectInfo(dev *mtp.Device, storageId uint32, fileProp FileProp, changes ObjectInfoChanges) (fi *FileInfo, err error) {
+	op := &OperationInfo{Type: UpdateObjectInfoOp, Mutating: true, StorageId: storageId, FileProps: []FileProp{fileProp}}
+	if changes.Name != nil {
+		op.NewFileName = *changes.Name
+	}
+
+	err = runMiddlewares(dev, op, func() error {
+		fi, err = updateObjectInfo(dev, storageId, fileProp, changes)
+
+		return err
+	})
+
+	return fi, err
+}
+
+// helper function for [UpdateObjectInfo]
+func updateObjectInfo(dev *mtp.Device, storageId uint32, fileProp FileProp, changes ObjectInfoChanges) (*FileInfo, error) {
+	if err := checkStorageWritable(dev, storageId, false); err != nil {
+		return nil, err
+	}
+
+	fi, err := GetObjectFromObjectIdOrPath(dev, storageId, fileProp)
+	if err != nil {
+		return nil, err
+	}
+
+	if fi.ObjectId == ParentObjectId {
+		return nil, InvalidPathError{error: fmt.Errorf("the root directory cannot be updated")}
+	}
+
+	// renaming an open object would leave its handles with a stale path
+	if changes.Name != nil && *changes.Name != fi.Name {
+		if err := checkObjectNotInUse(dev, fi); err != nil {
+			return nil, err
+		}
+	}
+
+	supported := supportedObjectProps(dev, fi.Info.ObjectFormat)
+
+	// a read-only object has to be made writable before its other properties can change
+	// and made read-only only after they did
+	var protectionStatus uint16
+	var setProtectionFirst bool
+	if changes.WriteProtected != nil {
+		protectionStatus = mtp.PS_NoProtection
+		if *changes.WriteProtected {
+			protectionStatus = mtp.PS_ReadOnly
+		}
+
+		setProtectionFirst = !*changes.WriteProtected
+	}
+
+	var missing []string
+	if changes.Name != nil && !supported(mtp.OPC_ObjectFileName) {
+		missing = append(missing, "name")
+	}
+	if changes.ModTime != nil && !supported(mtp.OPC_DateModified) {
+		missing = append(missing, "modification date")
+	}
+	if changes.Hidden != nil && !supported(mtp.OPC_Hidden) {
+		missing = append(missing, "hidden status")
+	}
+	if changes.WriteProtected != nil && !supported(mtp.OPC_ProtectionStatus) && !supportsOperation(dev, mtp.OC_SetObjectProtection) {
+		missing = append(missing, "protection status")
+	}
+
+	if len(missing) > 0 {
+		return nil, UnsupportedOperationError{error: fmt.Errorf("the device does not support changing the %v of %s", missing, fi.FullPath)}
+	}
+
+	if changes.WriteProtected != nil && setProtectionFirst {
+		if err := setObjectProtection(dev, fi.ObjectId, protectionStatus, supported(mtp.OPC_ProtectionStatus)); err != nil {
+			return nil, err
+		}
+	}
+
+	if changes.Name != nil && *changes.Name != fi.Name {
+		if err := dev.SetObjectPropValue(fi.ObjectId, mtp.OPC_ObjectFileName, &mtp.StringValue{Value: *changes.Name}); err != nil {
+			return nil, FileObjectError{error: err}
+		}
+	}
+
+	if changes.ModTime != nil {
+		if err := dev.SetObjectPropValue(fi.ObjectId, mtp.OPC_DateModified, &timeValue{Value: *changes.ModTime}); err != nil {
+			return nil, FileObjectError{error: err}
+		}
+	}
+
+	if changes.Hidden != nil {
+		var hidden uint16
+		if *changes.Hidden {
+			hidden = 1
+		}
+
+		if err := dev.SetObjectPropValue(fi.ObjectId, mtp.OPC_Hidden, &uint16Value{Value: hidden}); err != nil {
+			return nil, FileObjectError{error: err}
+		}
+	}
+
+	if changes.WriteProtected != nil && !setProtectionFirst {
+		if err := setObjectProtection(dev, fi.ObjectId, protectionStatus, supported(mtp.OPC_ProtectionStatus)); err != nil {
+			return nil, err
+		}
+	}
+
+	invalidateCachedListing(dev, storageId, fi.ParentId)
+
+	return GetObjectFromObjectId(dev, fi.ObjectId, fi.ParentPath)
+}
+
+// returns a function which checks whether the device lets the property be changed for the objects of [objectFormat]
+// if the device can't tell then every property is assumed to be supported and the device has the final say
+func supportedObjectProps(dev *mtp.Device, objectFormat uint16) func(code uint16) bool {
+	if !supportsOperation(dev, mtp.OC_MTP_GetObjectPropsSupported) {
+		return func(code uint16) bool {
+			return true
+		}
+	}
+
+	var props mtp.Uint16Array
+	if err := dev.GetObjectPropsSupported(objectFormat, &props); err != nil {
+		return func(code uint16) bool {
+			return true
+		}
+	}
+
+	return func(code uint16) bool {
+		for _, c := range props.Values {
+			if c == code {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// set the protection status of the object using the ProtectionStatus property if [useProperty] is true
+// and using the SetObjectProtection operation otherwise
+func setObjectProtection(dev *mtp.Device, objectId uint32, protectionStatus uint16, useProperty bool) error {
+	if useProperty {
+		if err := dev.SetObjectPropValue(objectId, mtp.OPC_ProtectionStatus, &uint16Value{Value: protectionStatus}); err != nil {
+			return FileProtectedError{error: fmt.Errorf("unable to change the protection status of the object %d: %v", objectId, err)}
+		}
+
+		return nil
+	}
+
+	var req, rep mtp.Container
+	req.Code = mtp.OC_SetObjectProtection
+	req.Param = []uint32{objectId, uint32(protectionStatus)}
+
+	if err := dev.RunTransaction(&req, &rep, nil, nil, 0, mtp.EmptyProgressFunc); err != nil {
+		return FileProtectedError{error: fmt.Errorf("unable to change the protection status of the object %d: %v", objectId, err)}
+	}
+
+	return nil
+}
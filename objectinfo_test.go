@@ -0,0 +1,60 @@
+package mtpx
+
+import (
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"log"
+	"math/rand"
+	"testing"
+)
+
+func TestUpdateObjectInfo(t *testing.T) {
+	dev, err := Initialize(Init{})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	storages, err := FetchStorages(dev)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	sid := storages[0].Sid
+
+	Convey("Rename an object | UpdateObjectInfo", t, func() {
+		fileName := fmt.Sprintf("/mtp-test-files/temp_dir/test-UpdateObjectInfo/%x", rand.Int31())
+		newName := fmt.Sprintf("updated-%x", rand.Int31())
+
+		objectId, err := MakeDirectory(dev, sid, fileName)
+		So(err, ShouldBeNil)
+
+		fi, err := UpdateObjectInfo(dev, sid, FileProp{objectId, ""}, ObjectInfoChanges{Name: &newName})
+		So(err, ShouldBeNil)
+		So(fi.ObjectId, ShouldEqual, objectId)
+		So(fi.Name, ShouldEqual, newName)
+
+		// nothing to change
+		fi, err = UpdateObjectInfo(dev, sid, FileProp{objectId, ""}, ObjectInfoChanges{})
+		So(err, ShouldBeNil)
+		So(fi.Name, ShouldEqual, newName)
+	})
+
+	Convey("Root directory | UpdateObjectInfo | Should throw an error", t, func() {
+		newName := "root"
+
+		_, err := UpdateObjectInfo(dev, sid, FileProp{ParentObjectId, ""}, ObjectInfoChanges{Name: &newName})
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+	})
+
+	Convey("Missing object | UpdateObjectInfo | Should throw an error", t, func() {
+		_, err := UpdateObjectInfo(dev, sid, FileProp{0, "/mtp-test-files/fake-UpdateObjectInfo"}, ObjectInfoChanges{})
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+	})
+
+	err = DeleteFile(dev, sid, []FileProp{{0, "/mtp-test-files/temp_dir/test-UpdateObjectInfo"}})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	Dispose(dev)
+}
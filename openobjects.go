@@ -0,0 +1,110 @@
+package mtpx
+
+import (
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"sync"
+)
+
+// number of the open handles of every object of a device
+var deviceOpenObjects = struct {
+	sync.Mutex
+	m map[*mtp.Device]map[uint32]int
+}{m: map[*mtp.Device]map[uint32]int{}}
+
+// check whether the object [objectId] is open through [OpenFile], [NewFileReader] or [ServeObject], or being downloaded
+// the objects in use can't be deleted, renamed, moved or overwritten, a [FileInUseError] is returned instead
+// note: the readers returned by [NewObjectReader] are not tracked, as they don't need to be closed
+func IsObjectInUse(dev *mtp.Device, objectId uint32) bool {
+	deviceOpenObjects.Lock()
+	defer deviceOpenObjects.Unlock()
+
+	return deviceOpenObjects.m[dev][objectId] > 0
+}
+
+// mark the object [objectId] as being in use until the returned function is called
+// calling the returned function more than once is fine
+func markObjectInUse(dev *mtp.Device, objectId uint32) (release func()) {
+	deviceOpenObjects.Lock()
+	defer deviceOpenObjects.Unlock()
+
+	objects, ok := deviceOpenObjects.m[dev]
+	if !ok {
+		objects = map[uint32]int{}
+		deviceOpenObjects.m[dev] = objects
+	}
+
+	objects[objectId] += 1
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			deviceOpenObjects.Lock()
+			defer deviceOpenObjects.Unlock()
+
+			// the device may have been disposed of in the meantime
+			objects, ok := deviceOpenObjects.m[dev]
+			if !ok {
+				return
+			}
+
+			if objects[objectId] -= 1; objects[objectId] < 1 {
+				delete(objects, objectId)
+			}
+
+			if len(objects) < 1 {
+				delete(deviceOpenObjects.m, dev)
+			}
+		})
+	}
+}
+
+// objectIds of the objects of the device which are in use
+func openObjectIds(dev *mtp.Device) []uint32 {
+	deviceOpenObjects.Lock()
+	defer deviceOpenObjects.Unlock()
+
+	var objectIds []uint32
+	for objectId := range deviceOpenObjects.m[dev] {
+		objectIds = append(objectIds, objectId)
+	}
+
+	return objectIds
+}
+
+// returns a [FileInUseError] if [fi] is in use, or for the directories if any object inside them is in use
+// the objects which can't be looked up anymore (eg: removed from the phone side) don't hold the directories back
+func checkObjectNotInUse(dev *mtp.Device, fi *FileInfo) error {
+	for _, objectId := range openObjectIds(dev) {
+		inUse := objectId == fi.ObjectId
+
+		if !inUse && fi.IsDir {
+			if inside, err := isObjectInside(dev, objectId, fi.ObjectId); err == nil {
+				inUse = inside
+			}
+		}
+
+		if inUse {
+			return FileInUseError{error: fmt.Errorf("the object is in use: %s", describeObject(fi))}
+		}
+	}
+
+	return nil
+}
+
+// path of [fi] if it is known, the objectId otherwise
+func describeObject(fi *FileInfo) string {
+	if fi.FullPath != "" {
+		return fi.FullPath
+	}
+
+	return fmt.Sprintf("%d", fi.ObjectId)
+}
+
+func disposeOpenObjects(dev *mtp.Device) {
+	deviceOpenObjects.Lock()
+	defer deviceOpenObjects.Unlock()
+
+	delete(deviceOpenObjects.m, dev)
+}
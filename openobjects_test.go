@@ -0,0 +1,53 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestMarkObjectInUse(t *testing.T) {
+	Convey("Count the open handles of an object | markObjectInUse", t, func() {
+		defer disposeOpenObjects(nil)
+
+		release1 := markObjectInUse(nil, 10)
+		release2 := markObjectInUse(nil, 10)
+		So(IsObjectInUse(nil, 10), ShouldBeTrue)
+		So(IsObjectInUse(nil, 11), ShouldBeFalse)
+
+		// releasing twice drops a single handle
+		release1()
+		release1()
+		So(IsObjectInUse(nil, 10), ShouldBeTrue)
+
+		release2()
+		So(IsObjectInUse(nil, 10), ShouldBeFalse)
+		So(openObjectIds(nil), ShouldBeEmpty)
+	})
+
+	Convey("Release the objects of a disposed device | markObjectInUse", t, func() {
+		release := markObjectInUse(nil, 10)
+		disposeOpenObjects(nil)
+		So(IsObjectInUse(nil, 10), ShouldBeFalse)
+
+		release()
+		So(IsObjectInUse(nil, 10), ShouldBeFalse)
+	})
+}
+
+func TestCheckObjectNotInUse(t *testing.T) {
+	Convey("Refuse the objects in use | checkObjectNotInUse", t, func() {
+		defer disposeOpenObjects(nil)
+
+		fi := &FileInfo{ObjectId: 10, FullPath: "/a.txt"}
+		So(checkObjectNotInUse(nil, fi), ShouldBeNil)
+
+		release := markObjectInUse(nil, 10)
+
+		err := checkObjectNotInUse(nil, fi)
+		So(err, ShouldHaveSameTypeAs, FileInUseError{})
+		So(err.Error(), ShouldContainSubstring, "/a.txt")
+
+		release()
+		So(checkObjectNotInUse(nil, fi), ShouldBeNil)
+	})
+}
@@ -0,0 +1,61 @@
+package mtpx
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	noFilenameMatch = iota
+	looseFilenameMatch
+	exactFilenameMatch
+)
+
+var pathMatchMode atomic.Value
+
+// current mode used to match the path components against the device filenames
+func PathMatching() PathMatchMode {
+	if mode, ok := pathMatchMode.Load().(PathMatchMode); ok {
+		return mode
+	}
+
+	return PathMatchCaseInsensitive
+}
+
+// set how the path components are matched against the device filenames while resolving a path (eg: [GetObjectFromPath])
+// use [PathMatchExact] to reach the files whose names differ only by the case or by the trailing spaces and dots
+// use [PathMatchNormalized] to reach the files with the trailing spaces and dots without typing them out
+// the default is [PathMatchCaseInsensitive]
+func SetPathMatchMode(mode PathMatchMode) {
+	pathMatchMode.Store(mode)
+}
+
+// compare the device filename [name] with the path component [filename]
+func matchFilename(name, filename string, mode PathMatchMode) int {
+	if name == filename {
+		return exactFilenameMatch
+	}
+
+	switch mode {
+	case PathMatchExact:
+		return noFilenameMatch
+
+	case PathMatchNormalized:
+		if strings.EqualFold(normalizeFilename(name), normalizeFilename(filename)) {
+			return looseFilenameMatch
+		}
+
+		return noFilenameMatch
+	}
+
+	if strings.EqualFold(name, filename) {
+		return looseFilenameMatch
+	}
+
+	return noFilenameMatch
+}
+
+// strip the trailing spaces and dots of a filename
+func normalizeFilename(filename string) string {
+	return strings.TrimRight(filename, " .")
+}
@@ -0,0 +1,31 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestMatchFilename(t *testing.T) {
+	Convey("Test matchFilename", t, func() {
+		So(matchFilename("a.txt", "a.txt", PathMatchExact), ShouldEqual, exactFilenameMatch)
+		So(matchFilename("A.txt", "a.txt", PathMatchExact), ShouldEqual, noFilenameMatch)
+		So(matchFilename("notes. ", "notes", PathMatchExact), ShouldEqual, noFilenameMatch)
+
+		So(matchFilename("A.txt", "a.txt", PathMatchCaseInsensitive), ShouldEqual, looseFilenameMatch)
+		So(matchFilename("notes. ", "notes. ", PathMatchCaseInsensitive), ShouldEqual, exactFilenameMatch)
+		So(matchFilename("notes. ", "notes", PathMatchCaseInsensitive), ShouldEqual, noFilenameMatch)
+
+		So(matchFilename("Notes. ", "notes", PathMatchNormalized), ShouldEqual, looseFilenameMatch)
+		So(matchFilename("notes..", "notes.", PathMatchNormalized), ShouldEqual, looseFilenameMatch)
+		So(matchFilename("notes", "note", PathMatchNormalized), ShouldEqual, noFilenameMatch)
+	})
+
+	Convey("Test SetPathMatchMode", t, func() {
+		So(PathMatching(), ShouldEqual, PathMatchCaseInsensitive)
+
+		SetPathMatchMode(PathMatchExact)
+		So(PathMatching(), ShouldEqual, PathMatchExact)
+
+		SetPathMatchMode(PathMatchCaseInsensitive)
+	})
+}
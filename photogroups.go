@@ -0,0 +1,136 @@
+package mtpx
+
+import (
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// frames of a burst shot on Android, eg: "00000IMG_00000_BURST20190101123456789_COVER.jpg"
+var burstNamePattern = regexp.MustCompile(`(?i)BURST(\d{14,})`)
+
+// marks the cover frame of a burst
+const burstCoverMarker = "_cover"
+
+// motion photos which embed the video inside the image, eg: "MVIMG_20190101_123456.jpg", "PXL_20210101_123456789.MP.jpg"
+var embeddedMotionPhotoNamePattern = regexp.MustCompile(`(?i)(^MVIMG_.*|\.MP)\.jpe?g$`)
+
+// group the related photos of the plan, see [CopyPlanGroupKind]
+// an item belongs to one group at most, the RAW pairs take precedence over the bursts and the motion photos
+// [direction] tells whether the sources of the items are local paths or device paths
+func groupPlanItems(direction TransferDirection, items []*CopyPlanItem) {
+	pairPlanItems(direction, items)
+	groupBurstItems(direction, items)
+	groupMotionPhotoItems(direction, items)
+}
+
+// items of a group are in the same parent directory and share a part of their names
+type planGroupKey struct {
+	parentPath string
+	name       string
+}
+
+// group the frames of the burst shots
+// the frames are recognized by their names or by their parent directory being a time sequence association (PTP cameras)
+func groupBurstItems(direction TransferDirection, items []*CopyPlanItem) {
+	timeSequenceDirs := map[string]bool{}
+	for _, item := range items {
+		if item.IsDir && item.Object != nil && item.Object.Info != nil && item.Object.Info.AssociationType == mtp.AT_TimeSequence {
+			timeSequenceDirs[item.Source] = true
+		}
+	}
+
+	groups := map[planGroupKey][]*CopyPlanItem{}
+	var keys []planGroupKey
+
+	for _, item := range items {
+		if item.IsDir || item.Group != "" {
+			continue
+		}
+
+		var key planGroupKey
+
+		parentPath, name := splitSourcePath(direction, item.Source)
+		if m := burstNamePattern.FindStringSubmatch(name); m != nil {
+			key = planGroupKey{parentPath: parentPath, name: m[1]}
+		} else if timeSequenceDirs[parentPath] {
+			key = planGroupKey{parentPath: parentPath}
+		} else {
+			continue
+		}
+
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+
+		groups[key] = append(groups[key], item)
+	}
+
+	for _, key := range keys {
+		frames := groups[key]
+		if len(frames) < 2 {
+			continue
+		}
+
+		primary := frames[0]
+		for _, frame := range frames {
+			if _, name := splitSourcePath(direction, frame.Source); strings.Contains(strings.ToLower(name), burstCoverMarker) {
+				primary = frame
+
+				break
+			}
+		}
+
+		setItemGroup(frames, primary, GroupBurst)
+	}
+}
+
+// group the still images of the motion photos with their video clips
+// the motion photos which embed the video are a group of their own so that they can be told apart from the plain photos
+func groupMotionPhotoItems(direction TransferDirection, items []*CopyPlanItem) {
+	images := map[planGroupKey]*CopyPlanItem{}
+	videos := map[planGroupKey]*CopyPlanItem{}
+	var keys []planGroupKey
+
+	for _, item := range items {
+		if item.IsDir || item.Group != "" {
+			continue
+		}
+
+		parentPath, name := splitSourcePath(direction, item.Source)
+		ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))
+		key := planGroupKey{parentPath: strings.ToLower(parentPath), name: strings.ToLower(strings.TrimSuffix(name, filepath.Ext(name)))}
+
+		if ok, _ := StringContains(motionPhotoImageExtensions, ext); ok {
+			if _, exists := images[key]; !exists {
+				images[key] = item
+				keys = append(keys, key)
+			}
+		} else if ok, _ := StringContains(motionPhotoVideoExtensions, ext); ok {
+			videos[key] = item
+		}
+	}
+
+	for _, key := range keys {
+		image := images[key]
+
+		if video, ok := videos[key]; ok {
+			setItemGroup([]*CopyPlanItem{image, video}, image, GroupMotionPhoto)
+
+			continue
+		}
+
+		if _, name := splitSourcePath(direction, image.Source); embeddedMotionPhotoNamePattern.MatchString(name) {
+			setItemGroup([]*CopyPlanItem{image}, image, GroupMotionPhoto)
+		}
+	}
+}
+
+func setItemGroup(members []*CopyPlanItem, primary *CopyPlanItem, kind CopyPlanGroupKind) {
+	for _, m := range members {
+		m.Group = primary.Source
+		m.GroupKind = kind
+		m.Primary = m == primary
+	}
+}
@@ -0,0 +1,76 @@
+package mtpx
+
+import (
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestGroupPlanItems(t *testing.T) {
+	Convey("Test burst groups", t, func() {
+		items := []*CopyPlanItem{
+			{Source: "/DCIM/Camera/00000IMG_00000_BURST20190101123456789.jpg", Size: 10},
+			{Source: "/DCIM/Camera/00001IMG_00001_BURST20190101123456789_COVER.jpg", Size: 10},
+			{Source: "/DCIM/Camera/00002IMG_00002_BURST20190101123456789.jpg", Size: 10},
+			{Source: "/DCIM/Camera/IMG_3.jpg", Size: 10},
+			{Source: "/DCIM/SEQ", IsDir: true, Object: &FileInfo{Info: &mtp.ObjectInfo{AssociationType: mtp.AT_TimeSequence}}},
+			{Source: "/DCIM/SEQ/A.jpg", Size: 10},
+			{Source: "/DCIM/SEQ/B.jpg", Size: 10},
+		}
+
+		groupPlanItems(Download, items)
+
+		cover := "/DCIM/Camera/00001IMG_00001_BURST20190101123456789_COVER.jpg"
+		for _, item := range items[:3] {
+			So(item.Group, ShouldEqual, cover)
+			So(item.GroupKind, ShouldEqual, GroupBurst)
+		}
+		So(items[1].Primary, ShouldBeTrue)
+		So(items[0].Primary, ShouldBeFalse)
+
+		So(items[3].Group, ShouldBeEmpty)
+
+		// the first frame is the primary without a cover frame
+		So(items[5].Group, ShouldEqual, "/DCIM/SEQ/A.jpg")
+		So(items[5].Primary, ShouldBeTrue)
+		So(items[6].GroupKind, ShouldEqual, GroupBurst)
+
+		plan := &CopyPlan{Items: items}
+		plan.summarize()
+
+		primaries := plan.PrimaryOnly()
+		So(primaries.TotalFiles, ShouldEqual, 3)
+		So(primaries.TotalDirectories, ShouldEqual, 1)
+	})
+
+	Convey("Test motion photo groups", t, func() {
+		items := []*CopyPlanItem{
+			{Source: "/DCIM/Camera/IMG_1.HEIC", Size: 10},
+			{Source: "/DCIM/Camera/IMG_1.MOV", Size: 50},
+			{Source: "/DCIM/Camera/MVIMG_20190101_123456.jpg", Size: 10},
+			{Source: "/DCIM/Camera/PXL_20210101_123456789.MP.jpg", Size: 10},
+			{Source: "/DCIM/Camera/VID_1.mp4", Size: 50},
+			{Source: "/DCIM/Camera/IMG_2.jpg", Size: 10},
+		}
+
+		groupPlanItems(Download, items)
+
+		So(items[0].Group, ShouldEqual, "/DCIM/Camera/IMG_1.HEIC")
+		So(items[0].Primary, ShouldBeTrue)
+		So(items[1].Group, ShouldEqual, "/DCIM/Camera/IMG_1.HEIC")
+		So(items[1].GroupKind, ShouldEqual, GroupMotionPhoto)
+		So(items[1].Primary, ShouldBeFalse)
+
+		So(items[2].GroupKind, ShouldEqual, GroupMotionPhoto)
+		So(items[2].Primary, ShouldBeTrue)
+		So(items[3].GroupKind, ShouldEqual, GroupMotionPhoto)
+
+		So(items[4].Group, ShouldBeEmpty)
+		So(items[5].Group, ShouldBeEmpty)
+
+		plan := &CopyPlan{Items: items}
+		plan.summarize()
+
+		So(plan.PrimaryOnly().TotalFiles, ShouldEqual, 5)
+	})
+}
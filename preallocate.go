@@ -0,0 +1,63 @@
+package mtpx
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+var preallocateLocalFiles atomic.Value
+
+// whether the local files are preallocated to their size before they are downloaded
+func PreallocateLocalFiles() bool {
+	if preallocate, ok := preallocateLocalFiles.Load().(bool); ok {
+		return preallocate
+	}
+
+	return false
+}
+
+// set whether the local files are preallocated to their size before they are downloaded
+// the preallocated files are less fragmented and a full disk is found before the transfer starts instead of halfway through
+// it is supported on Linux, macOS and Windows, the other systems download the files as before
+// note: the blocks of zeroes in the preallocated files take up the disk space instead of being left as holes
+// the default is false
+func SetPreallocateLocalFiles(preallocate bool) {
+	preallocateLocalFiles.Store(preallocate)
+}
+
+// make sure that the local disk has room for a file of [size] bytes at [destination], before the file is created
+// the space of an existing file at [destination] counts as free since the file is overwritten
+// returns a [LocalSpaceError] if the disk is full
+func checkLocalSpace(destination string, size int64) error {
+	if size < 1 {
+		return nil
+	}
+
+	// the file systems which don't report the free space are left alone
+	freeSpace, err := localFreeSpace(filepath.Dir(destination))
+	if err != nil {
+		return nil
+	}
+
+	if stat, err := os.Stat(destination); err == nil && stat.Mode().IsRegular() {
+		freeSpace += uint64(stat.Size())
+	}
+
+	if uint64(size) > freeSpace {
+		return LocalSpaceError{error: fmt.Errorf("not enough space on the local disk for %s: %d bytes required, %d bytes free", destination, size, freeSpace)}
+	}
+
+	return nil
+}
+
+// preallocate [f] to [size] bytes if [PreallocateLocalFiles] is set, the size of the file is not changed
+// the free space is checked beforehand using [checkLocalSpace]
+func prepareLocalFile(f *os.File, size int64) error {
+	if size < 1 || !PreallocateLocalFiles() {
+		return nil
+	}
+
+	return allocateLocalFile(f, size)
+}
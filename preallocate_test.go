@@ -0,0 +1,54 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestPrepareLocalFile(t *testing.T) {
+	Convey("Test preallocating the local files | prepareLocalFile", t, func() {
+		SetPreallocateLocalFiles(true)
+		defer SetPreallocateLocalFiles(false)
+
+		f, err := ioutil.TempFile("", "mtpx-preallocate")
+		So(err, ShouldBeNil)
+		defer os.Remove(f.Name())
+		defer f.Close()
+
+		So(prepareLocalFile(f, 1024*1024), ShouldBeNil)
+
+		// the size of the file is not changed
+		stat, err := f.Stat()
+		So(err, ShouldBeNil)
+		So(stat.Size(), ShouldEqual, 0)
+
+		_, err = f.Write([]byte("abc"))
+		So(err, ShouldBeNil)
+
+		data, err := ioutil.ReadFile(f.Name())
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, "abc")
+	})
+
+	Convey("Test a full disk | checkLocalSpace | Should throw an error", t, func() {
+		f, err := ioutil.TempFile("", "mtpx-preallocate")
+		So(err, ShouldBeNil)
+		defer os.Remove(f.Name())
+		defer f.Close()
+
+		_, err = f.Write([]byte("abc"))
+		So(err, ShouldBeNil)
+
+		So(checkLocalSpace(f.Name(), 1024), ShouldBeNil)
+
+		err = checkLocalSpace(f.Name(), 1<<62)
+		So(err, ShouldHaveSameTypeAs, LocalSpaceError{})
+
+		// the existing file is left untouched
+		data, err := ioutil.ReadFile(f.Name())
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, "abc")
+	})
+}
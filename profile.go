@@ -0,0 +1,201 @@
+package mtpx
+
+import (
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"strings"
+	"sync"
+)
+
+var deviceProfileRegistry = struct {
+	sync.Mutex
+	profiles []*DeviceProfile
+}{}
+
+var deviceProfiles = struct {
+	sync.Mutex
+	m map[*mtp.Device]*DeviceProfile
+}{m: map[*mtp.Device]*DeviceProfile{}}
+
+// add the profiles to the ones which are picked from when a device is initialized
+// the registered profiles take precedence over [defaultDeviceProfiles] and over the ones registered before them
+func RegisterDeviceProfile(profiles ...*DeviceProfile) {
+	deviceProfileRegistry.Lock()
+	defer deviceProfileRegistry.Unlock()
+
+	for _, p := range profiles {
+		deviceProfileRegistry.profiles = append([]*DeviceProfile{p}, deviceProfileRegistry.profiles...)
+	}
+}
+
+// fetch the profile of the device. nil if none of the profiles matched the device
+func FetchDeviceProfile(dev *mtp.Device) *DeviceProfile {
+	deviceProfiles.Lock()
+	defer deviceProfiles.Unlock()
+
+	return deviceProfiles.m[dev]
+}
+
+// use [profile] for the device instead of the one picked by its usb ids
+// if [profile] is nil then the device is used without a profile
+func SetDeviceProfile(dev *mtp.Device, profile *DeviceProfile) {
+	deviceProfiles.Lock()
+	defer deviceProfiles.Unlock()
+
+	if profile == nil {
+		delete(deviceProfiles.m, dev)
+
+		return
+	}
+
+	deviceProfiles.m[dev] = profile
+}
+
+// pick the profile of the device by its usb vendor and product ids
+func selectDeviceProfile(dev *mtp.Device) {
+	usbInfo, err := dev.GetUsbInfo()
+	if err != nil {
+		return
+	}
+
+	if p := matchDeviceProfile(usbInfo.IdVendor, usbInfo.IdProduct); p != nil {
+		SetDeviceProfile(dev, p)
+	}
+}
+
+// find the profile for the usb ids
+// the profiles which list the product ids are preferred over the ones which cover the whole vendor
+func matchDeviceProfile(vendorId, productId uint16) *DeviceProfile {
+	deviceProfileRegistry.Lock()
+	profiles := append(append([]*DeviceProfile(nil), deviceProfileRegistry.profiles...), defaultDeviceProfiles...)
+	deviceProfileRegistry.Unlock()
+
+	var vendorMatch *DeviceProfile
+	for _, p := range profiles {
+		if p.VendorId != vendorId {
+			continue
+		}
+
+		if len(p.ProductIds) < 1 {
+			if vendorMatch == nil {
+				vendorMatch = p
+			}
+
+			continue
+		}
+
+		for _, id := range p.ProductIds {
+			if id == productId {
+				return p
+			}
+		}
+	}
+
+	return vendorMatch
+}
+
+// refuse the operations which the profile of the device lists as unsupported
+func checkProfileOperation(dev *mtp.Device, opType OperationType) error {
+	p := FetchDeviceProfile(dev)
+	if p == nil {
+		return nil
+	}
+
+	for _, t := range p.UnsupportedOps {
+		if t == opType {
+			return UnsupportedOperationError{error: fmt.Errorf("%v is not supported by the %v devices", opType, p.Name)}
+		}
+	}
+
+	return nil
+}
+
+// name of the storage to report for the device
+// the profile supplies a name for the storages which don't describe themselves
+func profileStorageName(dev *mtp.Device, description string) string {
+	if description != "" {
+		return description
+	}
+
+	if p := FetchDeviceProfile(dev); p != nil {
+		return p.StorageName
+	}
+
+	return description
+}
+
+// list the GPS tracks and activities on the storage, eg: the .fit and .gpx files of a Garmin device
+func Tracks(dev *mtp.Device, storageId uint32) ([]*FileInfo, error) {
+	return listProfileContent(dev, storageId, "tracks", func(p *DeviceProfile) ProfileContent { return p.Tracks })
+}
+
+// list the recordings on the storage, eg: the audio files of a voice recorder
+func Recordings(dev *mtp.Device, storageId uint32) ([]*FileInfo, error) {
+	return listProfileContent(dev, storageId, "recordings", func(p *DeviceProfile) ProfileContent { return p.Recordings })
+}
+
+// list the books on the storage, eg: the documents of an e-reader
+func Books(dev *mtp.Device, storageId uint32) ([]*FileInfo, error) {
+	return listProfileContent(dev, storageId, "books", func(p *DeviceProfile) ProfileContent { return p.Books })
+}
+
+// walk the directories of the [content] described by the profile of the device and collect the files with its extensions
+// the directories which are missing on the storage are skipped
+func listProfileContent(dev *mtp.Device, storageId uint32, kind string, content func(p *DeviceProfile) ProfileContent) ([]*FileInfo, error) {
+	p := FetchDeviceProfile(dev)
+	if p == nil {
+		return nil, UnsupportedOperationError{error: fmt.Errorf("the device has no profile which describes its %v", kind)}
+	}
+
+	c := content(p)
+	if len(c.Paths) < 1 {
+		return nil, UnsupportedOperationError{error: fmt.Errorf("the %v devices have no %v", p.Name, kind)}
+	}
+
+	var files []*FileInfo
+	for _, fullPath := range walkRoots(c.Paths, true) {
+		_, _, _, err := Walk(dev, storageId, fullPath, true, true, true, func(objectId uint32, fi *FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !fi.IsDir && c.matches(fi.Name) {
+				files = append(files, fi)
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			if _, ok := err.(InvalidPathError); ok {
+				continue
+			}
+
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// check whether the file has one of the extensions of the content
+// all the files match if no extensions are listed
+func (c ProfileContent) matches(filename string) bool {
+	if len(c.Extensions) < 1 {
+		return true
+	}
+
+	name := strings.ToLower(filename)
+	for _, ext := range c.Extensions {
+		if strings.HasSuffix(name, "."+strings.ToLower(ext)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// drop the profile of the device
+func disposeDeviceProfile(dev *mtp.Device) {
+	SetDeviceProfile(dev, nil)
+}
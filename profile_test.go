@@ -0,0 +1,74 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestMatchDeviceProfile(t *testing.T) {
+	Convey("Test matchDeviceProfile", t, func() {
+		So(matchDeviceProfile(0x091e, 0x1234).Name, ShouldEqual, "Garmin")
+		So(matchDeviceProfile(0x18d1, 0x4ee1), ShouldBeNil)
+
+		deviceProfileRegistry.Lock()
+		registered := deviceProfileRegistry.profiles
+		deviceProfileRegistry.Unlock()
+
+		defer func() {
+			deviceProfileRegistry.Lock()
+			deviceProfileRegistry.profiles = registered
+			deviceProfileRegistry.Unlock()
+		}()
+
+		RegisterDeviceProfile(
+			&DeviceProfile{Name: "Garmin Fenix", VendorId: 0x091e, ProductIds: []uint16{0x4cda}},
+			&DeviceProfile{Name: "Garmin Custom", VendorId: 0x091e},
+		)
+
+		// the product match wins over the vendor match
+		So(matchDeviceProfile(0x091e, 0x4cda).Name, ShouldEqual, "Garmin Fenix")
+
+		// the registered profiles win over the default ones
+		So(matchDeviceProfile(0x091e, 0x1234).Name, ShouldEqual, "Garmin Custom")
+	})
+}
+
+func TestDeviceProfileQuirks(t *testing.T) {
+	Convey("Test the quirks of a device profile", t, func() {
+		// the profiles are keyed by the device, a nil device is good enough here
+		defer disposeDeviceProfile(nil)
+
+		So(checkProfileOperation(nil, RenameFileOp), ShouldBeNil)
+		So(profileStorageName(nil, ""), ShouldEqual, "")
+
+		SetDeviceProfile(nil, &DeviceProfile{
+			Name:           "Recorder",
+			StorageName:    "Internal Memory",
+			UnsupportedOps: []OperationType{RenameFileOp},
+		})
+
+		So(checkProfileOperation(nil, RenameFileOp), ShouldHaveSameTypeAs, UnsupportedOperationError{})
+		So(checkProfileOperation(nil, DeleteFileOp), ShouldBeNil)
+
+		So(profileStorageName(nil, ""), ShouldEqual, "Internal Memory")
+		So(profileStorageName(nil, "SD card"), ShouldEqual, "SD card")
+
+		err := runMiddlewares(nil, &OperationInfo{Type: RenameFileOp}, func() error {
+			return nil
+		})
+		So(err, ShouldHaveSameTypeAs, UnsupportedOperationError{})
+
+		// the device has no books
+		_, err = Books(nil, 0)
+		So(err, ShouldHaveSameTypeAs, UnsupportedOperationError{})
+	})
+
+	Convey("Test ProfileContent.matches", t, func() {
+		c := ProfileContent{Extensions: []string{"fit", "gpx"}}
+
+		So(c.matches("2021-01-01.FIT"), ShouldBeTrue)
+		So(c.matches("track.gpx"), ShouldBeTrue)
+		So(c.matches("settings.xml"), ShouldBeFalse)
+		So(ProfileContent{}.matches("anything"), ShouldBeTrue)
+	})
+}
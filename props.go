@@ -0,0 +1,380 @@
+package mtpx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"io"
+	"time"
+)
+
+// Fetch the properties [props] (see mtp.OPC_*) of the objects [objectIds] using the MTP property lists
+// the device is asked for the properties of one object at a time, a request returns every wanted property of the object.
+// the objects are never looked up across the whole device, which would list every object on it
+// the objects which don't exist and the properties which the device doesn't report are left out of the result
+// returns an [UnsupportedOperationError] if the device doesn't support the property lists
+func GetPropertiesBulk(dev *mtp.Device, objectIds []uint32, props []uint16) (map[uint32]ObjectProps, error) {
+	result := map[uint32]ObjectProps{}
+
+	if len(objectIds) < 1 || len(props) < 1 {
+		return result, nil
+	}
+
+	if !supportsObjectPropList(dev) {
+		return nil, UnsupportedOperationError{error: fmt.Errorf("the device does not support the property lists")}
+	}
+
+	wantedProps := map[uint16]bool{}
+	for _, code := range props {
+		wantedProps[code] = true
+	}
+
+	// every property of the object, or the single property if only one is wanted
+	propCode := uint32(0xFFFFFFFF)
+	if len(wantedProps) == 1 {
+		propCode = uint32(props[0])
+	}
+
+	for _, objectId := range objectIds {
+		if _, ok := result[objectId]; ok {
+			continue
+		}
+
+		list, err := getObjectPropList(dev, objectId, propCode)
+		if err != nil {
+			// the object was deleted in the meantime
+			if isInvalidObjectHandleError(err) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		for code, value := range list[objectId] {
+			if !wantedProps[code] {
+				continue
+			}
+
+			if result[objectId] == nil {
+				result[objectId] = ObjectProps{}
+			}
+
+			result[objectId][code] = value
+		}
+	}
+
+	return result, nil
+}
+
+// run the GetObjectPropList operation for the object [objectId] (all the objects if 0xFFFFFFFF)
+// and the property [propCode] (all the properties if 0xFFFFFFFF)
+func getObjectPropList(dev *mtp.Device, objectId, propCode uint32) (map[uint32]ObjectProps, error) {
+	return runGetObjectPropList(dev, objectId, propCode, 0)
+}
+
+// run the GetObjectPropList operation for all the properties of the children of [parentId]
+func getChildrenPropList(dev *mtp.Device, parentId uint32) (map[uint32]ObjectProps, error) {
+	// the objects at the top level of the storages are the children of 0 with the depth 1
+	if parentId == ParentObjectId {
+		parentId = 0
+	}
+
+	return runGetObjectPropList(dev, parentId, 0xFFFFFFFF, 1)
+}
+
+// helper function to run the GetObjectPropList operation
+// [depth] 0 fetches the properties of [objectId] itself and 1 those of its children
+func runGetObjectPropList(dev *mtp.Device, objectId, propCode, depth uint32) (map[uint32]ObjectProps, error) {
+	var req, rep mtp.Container
+	req.Code = mtp.OC_MTP_GetObjPropList
+	// any format, no property group
+	req.Param = []uint32{objectId, 0, propCode, 0, depth}
+
+	var buf bytes.Buffer
+	if err := dev.RunTransaction(&req, &rep, &buf, nil, 0, mtp.EmptyProgressFunc); err != nil {
+		return nil, FileObjectError{error: err}
+	}
+
+	list, err := decodeObjectPropList(&buf)
+	if err != nil {
+		return nil, FileObjectError{error: err}
+	}
+
+	return list, nil
+}
+
+// decode an ObjectPropList dataset
+// the dataset is made up of the number of elements followed by the (objectId, property code, data type, value) elements
+func decodeObjectPropList(r io.Reader) (map[uint32]ObjectProps, error) {
+	var count struct {
+		Value uint32
+	}
+	if err := mtp.Decode(r, &count); err != nil {
+		return nil, err
+	}
+
+	list := map[uint32]ObjectProps{}
+
+	for i := uint32(0); i < count.Value; i++ {
+		var element struct {
+			ObjectId     uint32
+			PropertyCode uint16
+			DataType     uint16
+		}
+		if err := mtp.Decode(r, &element); err != nil {
+			return nil, err
+		}
+
+		value, err := decodePropValue(r, element.PropertyCode, element.DataType)
+		if err != nil {
+			return nil, err
+		}
+
+		if list[element.ObjectId] == nil {
+			list[element.ObjectId] = ObjectProps{}
+		}
+
+		list[element.ObjectId][element.PropertyCode] = value
+	}
+
+	return list, nil
+}
+
+// decode a single property value of the type [dataType] (see mtp.DTC_*)
+func decodePropValue(r io.Reader, propertyCode, dataType uint16) (interface{}, error) {
+	if dataType&mtp.DTC_ARRAY_MASK != 0 && dataType != mtp.DTC_STR {
+		var count uint32
+		if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+			return nil, err
+		}
+
+		values := make([]interface{}, 0, count)
+		for i := uint32(0); i < count; i++ {
+			value, err := decodePropValue(r, propertyCode, dataType&^mtp.DTC_ARRAY_MASK)
+			if err != nil {
+				return nil, err
+			}
+
+			values = append(values, value)
+		}
+
+		return values, nil
+	}
+
+	switch dataType {
+	case mtp.DTC_INT8:
+		var v int8
+		err := binary.Read(r, binary.LittleEndian, &v)
+
+		return v, err
+
+	case mtp.DTC_UINT8:
+		var v uint8
+		err := binary.Read(r, binary.LittleEndian, &v)
+
+		return v, err
+
+	case mtp.DTC_INT16:
+		var v int16
+		err := binary.Read(r, binary.LittleEndian, &v)
+
+		return v, err
+
+	case mtp.DTC_UINT16:
+		var v uint16
+		err := binary.Read(r, binary.LittleEndian, &v)
+
+		return v, err
+
+	case mtp.DTC_INT32:
+		var v int32
+		err := binary.Read(r, binary.LittleEndian, &v)
+
+		return v, err
+
+	case mtp.DTC_UINT32:
+		var v uint32
+		err := binary.Read(r, binary.LittleEndian, &v)
+
+		return v, err
+
+	case mtp.DTC_INT64:
+		var v int64
+		err := binary.Read(r, binary.LittleEndian, &v)
+
+		return v, err
+
+	case mtp.DTC_UINT64:
+		var v uint64
+		err := binary.Read(r, binary.LittleEndian, &v)
+
+		return v, err
+
+	case mtp.DTC_INT128, mtp.DTC_UINT128:
+		var v uint128Value
+		if err := binary.Read(r, binary.LittleEndian, &v); err != nil {
+			return nil, err
+		}
+
+		return [2]uint64{v.Lo, v.Hi}, nil
+
+	case mtp.DTC_STR:
+		if isDateProperty(propertyCode) {
+			var v timeValue
+			err := mtp.Decode(r, &v)
+
+			return v.Value, err
+		}
+
+		var v mtp.StringValue
+		err := mtp.Decode(r, &v)
+
+		return v.Value, err
+	}
+
+	return nil, fmt.Errorf("unknown data type %#x of the property %#x", dataType, propertyCode)
+}
+
+// check whether the string property holds a date
+func isDateProperty(propertyCode uint16) bool {
+	switch propertyCode {
+	case mtp.OPC_DateModified, mtp.OPC_DateCreated, mtp.OPC_DateAdded:
+		return true
+	}
+
+	return false
+}
+
+// build the [FileInfo] of the objects [objectIds] inside [parentId] out of the property [list] of the children
+// the objects which the device left out of the list, or reported only in part, are fetched one at a time
+// objects whose information could not be fetched are left out
+func childrenFromPropList(dev *mtp.Device, storageId, parentId uint32, parentPath string, objectIds []uint32,
+	list map[uint32]ObjectProps) ([]*FileInfo, error) {
+	var children []*FileInfo
+
+	for _, objectId := range objectIds {
+		if err := touchOperation(dev); err != nil {
+			return nil, err
+		}
+
+		fi, ok := fileInfoFromProps(dev, storageId, parentId, objectId, list[objectId], parentPath)
+		if !ok {
+			var err error
+			if fi, err = GetObjectFromObjectId(dev, objectId, parentPath); err != nil {
+				continue
+			}
+		}
+
+		children = append(children, fi)
+	}
+
+	return children, nil
+}
+
+// build the [FileInfo] of the object [objectId] inside [parentId] out of its properties [props]
+// [FileInfo.Info] only holds what the property list reports: the storage, the format, the parent, the name, the size,
+// the protection status, the association type and the dates. the thumbnail, pixel, keywords and the other fields of
+// the ObjectInfo dataset are left empty, fetch the object with [GetObjectFromObjectId] for them
+// the persistent unique identifier is read out of [props], it is only fetched on its own if the device left it out
+// returns false if the name, the format or the size of a file are missing
+func fileInfoFromProps(dev *mtp.Device, storageId, parentId, objectId uint32, props ObjectProps, parentPath string) (*FileInfo, bool) {
+	name, ok := props[mtp.OPC_ObjectFileName].(string)
+	if !ok {
+		return nil, false
+	}
+
+	format, ok := propUint(props[mtp.OPC_ObjectFormat])
+	if !ok {
+		return nil, false
+	}
+
+	// the objects at the top level of the storage have no parent
+	if parentId == ParentObjectId {
+		parentId = 0
+	}
+
+	obj := mtp.ObjectInfo{
+		StorageID:    storageId,
+		ObjectFormat: uint16(format),
+		ParentObject: parentId,
+		Filename:     name,
+	}
+
+	if v, ok := propUint(props[mtp.OPC_ProtectionStatus]); ok {
+		obj.ProtectionStatus = uint16(v)
+	}
+
+	if v, ok := propUint(props[mtp.OPC_AssociationType]); ok {
+		obj.AssociationType = uint16(v)
+	}
+
+	if v, ok := props[mtp.OPC_DateModified].(time.Time); ok {
+		obj.ModificationDate = v
+	}
+
+	if v, ok := props[mtp.OPC_DateCreated].(time.Time); ok {
+		obj.CaptureDate = v
+	}
+
+	var size int64
+	if !isObjectADir(&obj) {
+		v, ok := propUint(props[mtp.OPC_ObjectSize])
+		if !ok {
+			return nil, false
+		}
+
+		size = int64(v)
+	}
+
+	if size > 0xFFFFFFFF {
+		obj.CompressedSize = 0xFFFFFFFF
+	} else {
+		obj.CompressedSize = uint32(size)
+	}
+
+	if !PersistentUids() {
+		return buildFileInfo(objectId, &obj, size, parentPath, ""), true
+	}
+
+	uid, ok := persistentUidFromProps(props)
+	if !ok {
+		uid = persistentUid(dev, objectId)
+	}
+
+	return buildFileInfo(objectId, &obj, size, parentPath, uid), true
+}
+
+// value of an integer property, whatever the width the device reported it with
+func propUint(value interface{}) (uint64, bool) {
+	switch v := value.(type) {
+	case uint8:
+		return uint64(v), true
+
+	case uint16:
+		return uint64(v), true
+
+	case uint32:
+		return uint64(v), true
+
+	case uint64:
+		return v, true
+
+	case int8:
+		return uint64(v), true
+
+	case int16:
+		return uint64(v), true
+
+	case int32:
+		return uint64(v), true
+
+	case int64:
+		return uint64(v), true
+
+	case [2]uint64:
+		return v[0], true
+	}
+
+	return 0, false
+}
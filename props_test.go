@@ -0,0 +1,155 @@
+package mtpx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestDecodeObjectPropList(t *testing.T) {
+	type header struct {
+		ObjectId     uint32
+		PropertyCode uint16
+		DataType     uint16
+	}
+
+	Convey("Test decodeObjectPropList", t, func() {
+		modTime := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+
+		var buf bytes.Buffer
+		So(mtp.Encode(&buf, &struct{ Count uint32 }{5}), ShouldBeNil)
+
+		So(mtp.Encode(&buf, &header{7, mtp.OPC_ObjectSize, mtp.DTC_UINT64}), ShouldBeNil)
+		So(mtp.Encode(&buf, &struct{ Value uint64 }{1234}), ShouldBeNil)
+
+		So(mtp.Encode(&buf, &header{7, mtp.OPC_ObjectFileName, mtp.DTC_STR}), ShouldBeNil)
+		So(mtp.Encode(&buf, &mtp.StringValue{Value: "a.txt"}), ShouldBeNil)
+
+		So(mtp.Encode(&buf, &header{7, mtp.OPC_DateModified, mtp.DTC_STR}), ShouldBeNil)
+		So(mtp.Encode(&buf, &timeValue{Value: modTime}), ShouldBeNil)
+
+		So(mtp.Encode(&buf, &header{9, mtp.OPC_PersistantUniqueObjectIdentifier, mtp.DTC_UINT128}), ShouldBeNil)
+		So(mtp.Encode(&buf, &uint128Value{Lo: 1, Hi: 2}), ShouldBeNil)
+
+		So(mtp.Encode(&buf, &header{9, mtp.OPC_Keywords, mtp.DTC_ARRAY_MASK | mtp.DTC_UINT16}), ShouldBeNil)
+		So(binary.Write(&buf, binary.LittleEndian, &struct {
+			Count  uint32
+			Values [2]uint16
+		}{2, [2]uint16{3, 4}}), ShouldBeNil)
+
+		list, err := decodeObjectPropList(&buf)
+		So(err, ShouldBeNil)
+		So(len(list), ShouldEqual, 2)
+		So(list[7][mtp.OPC_ObjectSize], ShouldEqual, uint64(1234))
+		So(list[7][mtp.OPC_ObjectFileName], ShouldEqual, "a.txt")
+		So(list[7][mtp.OPC_DateModified].(time.Time).Equal(modTime), ShouldBeTrue)
+		So(list[9][mtp.OPC_PersistantUniqueObjectIdentifier], ShouldResemble, [2]uint64{1, 2})
+		So(list[9][mtp.OPC_Keywords], ShouldResemble, []interface{}{uint16(3), uint16(4)})
+	})
+
+	Convey("Unknown data type | decodeObjectPropList | Should throw an error", t, func() {
+		var buf bytes.Buffer
+		So(mtp.Encode(&buf, &struct{ Count uint32 }{1}), ShouldBeNil)
+		So(mtp.Encode(&buf, &header{7, mtp.OPC_ObjectSize, mtp.DTC_UNDEF}), ShouldBeNil)
+
+		_, err := decodeObjectPropList(&buf)
+		So(err, ShouldBeError)
+	})
+
+	Convey("No objects | GetPropertiesBulk", t, func() {
+		props, err := GetPropertiesBulk(nil, nil, []uint16{mtp.OPC_ObjectSize})
+		So(err, ShouldBeNil)
+		So(props, ShouldBeEmpty)
+	})
+}
+
+func TestFileInfoFromProps(t *testing.T) {
+	modTime := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	Convey("Build a file | fileInfoFromProps", t, func() {
+		props := ObjectProps{
+			mtp.OPC_ObjectFileName:   "a.jpg",
+			mtp.OPC_ObjectFormat:     uint16(mtp.OFC_EXIF_JPEG),
+			mtp.OPC_ObjectSize:       uint64(5000000000),
+			mtp.OPC_DateModified:     modTime,
+			mtp.OPC_ProtectionStatus: uint16(mtp.PS_ReadOnly),
+		}
+
+		fi, ok := fileInfoFromProps(nil, 1, ParentObjectId, 10, props, "/")
+		So(ok, ShouldBeTrue)
+		So(fi.ObjectId, ShouldEqual, 10)
+		So(fi.Name, ShouldEqual, "a.jpg")
+		So(fi.FullPath, ShouldEqual, "/a.jpg")
+		So(fi.Size, ShouldEqual, 5000000000)
+		So(fi.Info.CompressedSize, ShouldEqual, 0xFFFFFFFF)
+		So(fi.ModTime, ShouldEqual, modTime)
+		So(fi.IsDir, ShouldBeFalse)
+		So(fi.WriteProtected, ShouldBeTrue)
+		So(fi.Info.StorageID, ShouldEqual, 1)
+
+		// the objects at the top level of the storage have no parent
+		So(fi.ParentId, ShouldEqual, 0)
+	})
+
+	Convey("Build a directory | fileInfoFromProps", t, func() {
+		props := ObjectProps{
+			mtp.OPC_ObjectFileName: "DCIM",
+			mtp.OPC_ObjectFormat:   uint16(mtp.OFC_Association),
+		}
+
+		fi, ok := fileInfoFromProps(nil, 1, 20, 21, props, "/a")
+		So(ok, ShouldBeTrue)
+		So(fi.IsDir, ShouldBeTrue)
+		So(fi.ParentId, ShouldEqual, 20)
+		So(fi.FullPath, ShouldEqual, "/a/DCIM")
+	})
+
+	Convey("Read the persistent uid out of the properties | fileInfoFromProps", t, func() {
+		SetPersistentUids(true)
+		defer SetPersistentUids(false)
+
+		props := ObjectProps{
+			mtp.OPC_ObjectFileName:                   "DCIM",
+			mtp.OPC_ObjectFormat:                     uint16(mtp.OFC_Association),
+			mtp.OPC_PersistantUniqueObjectIdentifier: [2]uint64{2, 1},
+		}
+
+		// the device isn't asked for the identifier
+		fi, ok := fileInfoFromProps(nil, 1, 20, 21, props, "/")
+		So(ok, ShouldBeTrue)
+		So(fi.PersistentUid, ShouldEqual, "00000000000000010000000000000002")
+	})
+
+	Convey("Missing properties | fileInfoFromProps", t, func() {
+		_, ok := fileInfoFromProps(nil, 1, 20, 21, ObjectProps{mtp.OPC_ObjectFormat: uint16(mtp.OFC_Text)}, "/")
+		So(ok, ShouldBeFalse)
+
+		// the size of a file is required
+		_, ok = fileInfoFromProps(nil, 1, 20, 21, ObjectProps{
+			mtp.OPC_ObjectFileName: "a.txt",
+			mtp.OPC_ObjectFormat:   uint16(mtp.OFC_Text),
+		}, "/")
+		So(ok, ShouldBeFalse)
+	})
+}
+
+func TestPropUint(t *testing.T) {
+	Convey("Test propUint", t, func() {
+		v, ok := propUint(uint16(12))
+		So(ok, ShouldBeTrue)
+		So(v, ShouldEqual, 12)
+
+		v, ok = propUint(int32(7))
+		So(ok, ShouldBeTrue)
+		So(v, ShouldEqual, 7)
+
+		_, ok = propUint("12")
+		So(ok, ShouldBeFalse)
+
+		_, ok = propUint(nil)
+		So(ok, ShouldBeFalse)
+	})
+}
@@ -0,0 +1,80 @@
+package mtpx
+
+import (
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"sync"
+)
+
+type uploadQuota struct {
+	limit   int64
+	written int64
+}
+
+var deviceUploadQuotas = struct {
+	sync.Mutex
+	m map[*mtp.Device]*uploadQuota
+}{m: map[*mtp.Device]*uploadQuota{}}
+
+// limit the number of bytes written to the device to [quota] from now on
+// call it before an operation for a per-operation quota, or once for the whole session (see [Init.UploadQuota])
+// the bytes written so far are forgotten. if [quota] is 0 then the quota is removed
+func SetUploadQuota(dev *mtp.Device, quota int64) {
+	deviceUploadQuotas.Lock()
+	defer deviceUploadQuotas.Unlock()
+
+	if quota <= 0 {
+		delete(deviceUploadQuotas.m, dev)
+
+		return
+	}
+
+	deviceUploadQuotas.m[dev] = &uploadQuota{limit: quota}
+}
+
+// fetch the upload quota of the device and the bytes written under it
+// [quota] is 0 if the device has no quota
+func FetchUploadQuota(dev *mtp.Device) (quota, bytesWritten int64) {
+	deviceUploadQuotas.Lock()
+	defer deviceUploadQuotas.Unlock()
+
+	q, ok := deviceUploadQuotas.m[dev]
+	if !ok {
+		return 0, 0
+	}
+
+	return q.limit, q.written
+}
+
+// account [size] bytes about to be written to the device against its quota
+// a file which doesn't fit in the remaining quota is refused before any of it is sent
+func reserveUploadQuota(dev *mtp.Device, size int64) error {
+	deviceUploadQuotas.Lock()
+	defer deviceUploadQuotas.Unlock()
+
+	q, ok := deviceUploadQuotas.m[dev]
+	if !ok {
+		return nil
+	}
+
+	if q.written+size > q.limit {
+		return QuotaExceededError{
+			error:        fmt.Errorf("upload quota exceeded: %d of %d bytes written, %d more bytes requested", q.written, q.limit, size),
+			BytesWritten: q.written,
+		}
+	}
+
+	q.written += size
+
+	return nil
+}
+
+// give back the part of a reservation which was never written to the device
+func releaseUploadQuota(dev *mtp.Device, size int64) {
+	deviceUploadQuotas.Lock()
+	defer deviceUploadQuotas.Unlock()
+
+	if q, ok := deviceUploadQuotas.m[dev]; ok {
+		q.written -= size
+	}
+}
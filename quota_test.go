@@ -0,0 +1,35 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestUploadQuota(t *testing.T) {
+	Convey("Test upload quota", t, func() {
+		// the quotas are keyed by the device, a nil device is good enough here
+		defer SetUploadQuota(nil, 0)
+
+		So(reserveUploadQuota(nil, 1<<40), ShouldBeNil)
+
+		SetUploadQuota(nil, 100)
+
+		So(reserveUploadQuota(nil, 60), ShouldBeNil)
+		So(reserveUploadQuota(nil, 40), ShouldBeNil)
+
+		err := reserveUploadQuota(nil, 1)
+		So(err, ShouldHaveSameTypeAs, QuotaExceededError{})
+		So(err.(QuotaExceededError).BytesWritten, ShouldEqual, 100)
+
+		releaseUploadQuota(nil, 40)
+
+		quota, bytesWritten := FetchUploadQuota(nil)
+		So(quota, ShouldEqual, 100)
+		So(bytesWritten, ShouldEqual, 60)
+
+		SetUploadQuota(nil, 0)
+
+		quota, _ = FetchUploadQuota(nil)
+		So(quota, ShouldEqual, 0)
+	})
+}
@@ -0,0 +1,139 @@
+package mtpx
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// the role of a file inside a RAW pair
+type rawPairRole int
+
+const (
+	notPaired rawPairRole = iota
+	rawPairPrimary
+	rawPairCompanion
+	rawPairSidecar
+)
+
+// role of [filename] inside a RAW pair and the stem which it is paired by
+// eg: "IMG_1.CR2" is the primary, "IMG_1.JPG" a companion and both "IMG_1.xmp" and "IMG_1.CR2.xmp" are sidecars of "IMG_1"
+func rawPairName(filename string) (role rawPairRole, stem string) {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), "."))
+	stem = strings.TrimSuffix(filename, filepath.Ext(filename))
+
+	is := func(exts []string) bool {
+		ok, _ := StringContains(exts, ext)
+
+		return ok
+	}
+
+	switch {
+	case is(rawExtensions):
+		return rawPairPrimary, stem
+
+	case is(rawCompanionExtensions):
+		return rawPairCompanion, stem
+
+	case is(rawSidecarExtensions):
+		// the sidecars are named after either the stem or the full name of the RAW file
+		if role, _stem := rawPairName(stem); role == rawPairPrimary || role == rawPairCompanion {
+			return rawPairSidecar, _stem
+		}
+
+		return rawPairSidecar, stem
+	}
+
+	return notPaired, ""
+}
+
+// group the RAW files of the plan with their JPEG companions and XMP sidecars, see [CopyPlanItem.Group]
+// the files are paired when they are in the same directory and their names only differ by the extension (case-insensitively)
+// the destination names of the paired files take the stem of the RAW file so that they stay together after the copy
+// a file keeps its name if another file of the plan is already headed for the new one (case-insensitively)
+func pairPlanItems(direction TransferDirection, items []*CopyPlanItem) {
+	type member struct {
+		item *CopyPlanItem
+		role rawPairRole
+		stem string
+	}
+
+	groups := map[planGroupKey][]member{}
+	var keys []planGroupKey
+
+	// destinations of the plan, the renamed companions must not land on one of them
+	destinations := map[string]int{}
+
+	for _, item := range items {
+		destinations[strings.ToLower(item.Destination)]++
+
+		if item.IsDir {
+			continue
+		}
+
+		parentPath, name := splitSourcePath(direction, item.Source)
+		role, stem := rawPairName(name)
+		if role == notPaired {
+			continue
+		}
+
+		key := planGroupKey{parentPath: strings.ToLower(parentPath), name: strings.ToLower(stem)}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+
+		groups[key] = append(groups[key], member{item: item, role: role, stem: stem})
+	}
+
+	for _, key := range keys {
+		members := groups[key]
+		if len(members) < 2 {
+			continue
+		}
+
+		var primary *member
+		for i := range members {
+			if members[i].role == rawPairPrimary {
+				primary = &members[i]
+
+				break
+			}
+		}
+
+		// a JPEG with a sidecar is not a RAW pair
+		if primary == nil {
+			continue
+		}
+
+		var groupItems []*CopyPlanItem
+		for _, m := range members {
+			groupItems = append(groupItems, m.item)
+		}
+
+		setItemGroup(groupItems, primary.item, GroupRawPair)
+
+		for _, m := range members {
+			if m.stem == primary.stem {
+				continue
+			}
+
+			_, sourceName := splitSourcePath(direction, m.item.Source)
+			destinationParentPath, _ := splitDestinationPath(direction, m.item.Destination)
+			destination := joinDestinationPath(direction, destinationParentPath, primary.stem+strings.TrimPrefix(sourceName, m.stem))
+
+			key, currentKey := strings.ToLower(destination), strings.ToLower(m.item.Destination)
+
+			// the file itself doesn't count when only the case of its name changes
+			others := destinations[key]
+			if key == currentKey {
+				others--
+			}
+			if others > 0 {
+				continue
+			}
+
+			destinations[currentKey]--
+			destinations[key]++
+			m.item.Destination = destination
+		}
+	}
+}
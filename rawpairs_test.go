@@ -0,0 +1,93 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestRawPairName(t *testing.T) {
+	Convey("Test rawPairName", t, func() {
+		role, stem := rawPairName("IMG_1.CR2")
+		So(role, ShouldEqual, rawPairPrimary)
+		So(stem, ShouldEqual, "IMG_1")
+
+		role, stem = rawPairName("IMG_1.jpg")
+		So(role, ShouldEqual, rawPairCompanion)
+		So(stem, ShouldEqual, "IMG_1")
+
+		role, stem = rawPairName("IMG_1.xmp")
+		So(role, ShouldEqual, rawPairSidecar)
+		So(stem, ShouldEqual, "IMG_1")
+
+		role, stem = rawPairName("IMG_1.CR2.xmp")
+		So(role, ShouldEqual, rawPairSidecar)
+		So(stem, ShouldEqual, "IMG_1")
+
+		role, _ = rawPairName("notes.txt")
+		So(role, ShouldEqual, notPaired)
+	})
+}
+
+func TestPairPlanItems(t *testing.T) {
+	Convey("Test pairPlanItems", t, func() {
+		items := []*CopyPlanItem{
+			{Source: "/DCIM/100CANON", Destination: "/photos/100CANON", IsDir: true},
+			{Source: "/DCIM/100CANON/IMG_1.CR2", Destination: "/photos/100CANON/IMG_1.CR2", Size: 100},
+			{Source: "/DCIM/100CANON/img_1.jpg", Destination: "/photos/100CANON/img_1.jpg", Size: 10},
+			{Source: "/DCIM/100CANON/IMG_1.CR2.xmp", Destination: "/photos/100CANON/IMG_1.CR2.xmp", Size: 1},
+			{Source: "/DCIM/100CANON/IMG_2.jpg", Destination: "/photos/100CANON/IMG_2.jpg", Size: 10},
+			{Source: "/DCIM/100CANON/IMG_2.xmp", Destination: "/photos/100CANON/IMG_2.xmp", Size: 1},
+		}
+
+		pairPlanItems(Download, items)
+
+		So(items[0].Group, ShouldBeEmpty)
+		So(items[1].Group, ShouldEqual, "/DCIM/100CANON/IMG_1.CR2")
+		So(items[1].GroupKind, ShouldEqual, GroupRawPair)
+		So(items[1].Primary, ShouldBeTrue)
+		So(items[2].Primary, ShouldBeFalse)
+		So(items[2].Group, ShouldEqual, "/DCIM/100CANON/IMG_1.CR2")
+		So(items[3].Group, ShouldEqual, "/DCIM/100CANON/IMG_1.CR2")
+
+		// the companions are named after the RAW file
+		So(items[2].Destination, ShouldEqual, "/photos/100CANON/IMG_1.jpg")
+		So(items[3].Destination, ShouldEqual, "/photos/100CANON/IMG_1.CR2.xmp")
+
+		// a JPEG with a sidecar is not a RAW pair
+		So(items[4].Group, ShouldBeEmpty)
+		So(items[5].Group, ShouldBeEmpty)
+
+		Convey("the companions keep their names if another file has the new one", func() {
+			items := []*CopyPlanItem{
+				{Source: "/DCIM/IMG_3.CR2", Destination: "/photos/IMG_3.CR2", Size: 100},
+				{Source: "/DCIM/img_3.jpg", Destination: "/photos/img_3.jpg", Size: 10},
+				{Source: "/DCIM/img_3.xmp", Destination: "/photos/img_3.xmp", Size: 1},
+				{Source: "/DCIM/IMG_3.XMP", Destination: "/photos/IMG_3.XMP", Size: 1},
+			}
+
+			pairPlanItems(Download, items)
+
+			So(items[1].Destination, ShouldEqual, "/photos/IMG_3.jpg")
+			So(items[2].Destination, ShouldEqual, "/photos/img_3.xmp")
+			So(items[3].Destination, ShouldEqual, "/photos/IMG_3.XMP")
+		})
+
+		Convey("the pairs are filtered as a unit", func() {
+			plan := &CopyPlan{Direction: Download, Items: items}
+			plan.summarize()
+
+			// drop the JPEGs, the one paired with a RAW file follows the RAW file
+			filtered := plan.Filter(func(item *CopyPlanItem) bool {
+				return extension(item.Source, item.IsDir) != "jpg"
+			})
+			So(filtered.TotalFiles, ShouldEqual, 4)
+
+			// drop the RAW files along with their companions
+			filtered = plan.Filter(func(item *CopyPlanItem) bool {
+				return extension(item.Source, item.IsDir) != "CR2"
+			})
+			So(filtered.TotalFiles, ShouldEqual, 2)
+			So(filtered.Items[1].Source, ShouldEqual, "/DCIM/100CANON/IMG_2.jpg")
+		})
+	})
+}
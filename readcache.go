@@ -0,0 +1,64 @@
+package mtpx
+
+import (
+	"container/list"
+)
+
+// the most recently read blocks of a file, see [ObjectReader]
+// the blocks are aligned to [blockSize] so that the nearby reads are served by the same block
+type objectBlockCache struct {
+	blockSize int64
+	capacity  int
+
+	blocks map[int64]*list.Element
+	lru    *list.List
+}
+
+type objectBlock struct {
+	index int64
+	data  []byte
+}
+
+func newObjectBlockCache(blockSize int64, capacity int) *objectBlockCache {
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	return &objectBlockCache{
+		blockSize: blockSize,
+		capacity:  capacity,
+		blocks:    map[int64]*list.Element{},
+		lru:       list.New(),
+	}
+}
+
+// fetch the block [index] if it is cached
+func (c *objectBlockCache) get(index int64) ([]byte, bool) {
+	el, ok := c.blocks[index]
+	if !ok {
+		return nil, false
+	}
+
+	c.lru.MoveToFront(el)
+
+	return el.Value.(*objectBlock).data, true
+}
+
+// cache the block [index], the least recently used block is dropped if the cache is full
+func (c *objectBlockCache) put(index int64, data []byte) {
+	if el, ok := c.blocks[index]; ok {
+		el.Value.(*objectBlock).data = data
+		c.lru.MoveToFront(el)
+
+		return
+	}
+
+	c.blocks[index] = c.lru.PushFront(&objectBlock{index: index, data: data})
+
+	for c.lru.Len() > c.capacity {
+		el := c.lru.Back()
+
+		c.lru.Remove(el)
+		delete(c.blocks, el.Value.(*objectBlock).index)
+	}
+}
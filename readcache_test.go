@@ -0,0 +1,39 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestObjectBlockCache(t *testing.T) {
+	Convey("Test objectBlockCache", t, func() {
+		c := newObjectBlockCache(4, 2)
+
+		c.put(0, []byte("0123"))
+		c.put(1, []byte("4567"))
+
+		b, ok := c.get(0)
+		So(ok, ShouldBeTrue)
+		So(string(b), ShouldEqual, "0123")
+
+		// the least recently used block is dropped
+		c.put(2, []byte("89"))
+
+		_, ok = c.get(1)
+		So(ok, ShouldBeFalse)
+
+		_, ok = c.get(0)
+		So(ok, ShouldBeTrue)
+
+		b, ok = c.get(2)
+		So(ok, ShouldBeTrue)
+		So(string(b), ShouldEqual, "89")
+
+		// replacing a block keeps a single copy
+		c.put(2, []byte("8"))
+		So(c.lru.Len(), ShouldEqual, 2)
+
+		b, _ = c.get(2)
+		So(string(b), ShouldEqual, "8")
+	})
+}
@@ -0,0 +1,194 @@
+package mtpx
+
+import (
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/ganeshrvel/go-mtpx/devicepath"
+	"sort"
+	"time"
+)
+
+// List the objects of the storage which were added or modified after [since], the most recent first
+// if [limit] is greater than 0 then at most [limit] objects are returned
+// devices which support the MTP property lists are asked for the modification dates of all the objects in a single request,
+// the other devices (or if the request fails) are walked like [Watch] does and the dates are compared locally
+func ListRecent(dev *mtp.Device, storageId uint32, since time.Time, limit int) ([]*FileInfo, error) {
+	var recent []*FileInfo
+	var err error
+
+	if supportsObjectPropList(dev) {
+		recent, err = listRecentFromPropList(dev, storageId, since)
+	}
+
+	if recent == nil || err != nil {
+		recent, err = listRecentFromSnapshot(dev, storageId, devicepath.Separator, since)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return newestFiles(recent, limit), nil
+}
+
+// fetch the [n] newest files inside the directory [fullPath], the most recent first
+// if [n] is 0 then all the files are returned
+// the subdirectories are not descended into; hidden and disallowed files are left out
+// eg: LatestFiles(dev, sid, "/DCIM/Screenshots", 1) to grab the last screenshot
+func LatestFiles(dev *mtp.Device, storageId uint32, fullPath string, n int) ([]*FileInfo, error) {
+	var files []*FileInfo
+
+	_, _, _, err := Walk(dev, storageId, fullPath, false, true, true,
+		func(objectId uint32, fi *FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !fi.IsDir {
+				files = append(files, fi)
+			}
+
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return newestFiles(files, n), nil
+}
+
+// sort the [files] by their modification date, the most recent first, and keep the first [n] of them (all if [n] is 0)
+// the files with the same modification date are ordered by the [ObjectId], the newer objects usually having the higher ids
+func newestFiles(files []*FileInfo, n int) []*FileInfo {
+	sort.SliceStable(files, func(i, j int) bool {
+		if !files[i].ModTime.Equal(files[j].ModTime) {
+			return files[i].ModTime.After(files[j].ModTime)
+		}
+
+		return files[i].ObjectId > files[j].ObjectId
+	})
+
+	if n > 0 && len(files) > n {
+		files = files[:n]
+	}
+
+	return files
+}
+
+// check whether the device advertises the MTP GetObjectPropList operation
+func supportsObjectPropList(dev *mtp.Device) bool {
+	return supportsOperation(dev, mtp.OC_MTP_GetObjPropList)
+}
+
+// fetch the modification dates of all the objects on the device in a single request
+// only the objects which were modified after [since] are fetched individually
+func listRecentFromPropList(dev *mtp.Device, storageId uint32, since time.Time) ([]*FileInfo, error) {
+	list, err := getObjectPropList(dev, 0xFFFFFFFF, mtp.OPC_DateModified)
+	if err != nil {
+		return nil, ListDirectoryError{error: err}
+	}
+
+	dates, err := modificationDates(list)
+	if err != nil {
+		return nil, ListDirectoryError{error: err}
+	}
+
+	recent := []*FileInfo{}
+	parentPaths := map[uint32]string{}
+
+	for objectId, modTime := range dates {
+		if !modTime.After(since) {
+			continue
+		}
+
+		fi, err := GetObjectFromObjectId(dev, objectId, "")
+		if err != nil {
+			continue
+		}
+
+		if fi.Info.StorageID != storageId {
+			continue
+		}
+
+		parentPath, err := resolveParentPath(dev, fi.ParentId, parentPaths)
+		if err != nil {
+			return nil, err
+		}
+
+		fi.ParentPath = parentPath
+		fi.FullPath = devicepath.Join(parentPath, fi.Name)
+
+		recent = append(recent, fi)
+	}
+
+	return recent, nil
+}
+
+// walk the directory [fullPath] and pick the objects inside it which were modified after [since]
+func listRecentFromSnapshot(dev *mtp.Device, storageId uint32, fullPath string, since time.Time) ([]*FileInfo, error) {
+	snapshot, err := takeWatchSnapshot(dev, storageId, fullPath, true)
+	if err != nil {
+		return nil, err
+	}
+
+	recent := []*FileInfo{}
+	for _, fi := range snapshot {
+		if fi.ModTime.After(since) {
+			recent = append(recent, fi)
+		}
+	}
+
+	return recent, nil
+}
+
+// pick the modification dates out of the property list [list] which should hold nothing else
+func modificationDates(list map[uint32]ObjectProps) (map[uint32]time.Time, error) {
+	dates := map[uint32]time.Time{}
+
+	for objectId, values := range list {
+		for code, value := range values {
+			modTime, ok := value.(time.Time)
+			if code != mtp.OPC_DateModified || !ok {
+				return nil, fmt.Errorf("unexpected property %#x of the object %d", code, objectId)
+			}
+
+			dates[objectId] = modTime
+		}
+	}
+
+	return dates, nil
+}
+
+// build the full path of the directory [parentId] by following its ancestors up to the root directory
+// the paths which were already resolved are looked up in [parentPaths]
+func resolveParentPath(dev *mtp.Device, parentId uint32, parentPaths map[uint32]string) (string, error) {
+	parentId = normalizeParentId(parentId)
+	if parentId == ParentObjectId {
+		return devicepath.Separator, nil
+	}
+
+	if parentPath, ok := parentPaths[parentId]; ok {
+		if parentPath == "" {
+			return "", FileObjectError{error: fmt.Errorf("the ancestors of the object form a cycle: %d", parentId)}
+		}
+
+		return parentPath, nil
+	}
+
+	// mark the directory as being resolved to catch the cycles
+	parentPaths[parentId] = ""
+
+	obj := mtp.ObjectInfo{}
+	if err := dev.GetObjectInfo(parentId, &obj); err != nil {
+		return "", FileObjectError{error: err}
+	}
+
+	grandParentPath, err := resolveParentPath(dev, obj.ParentObject, parentPaths)
+	if err != nil {
+		return "", err
+	}
+
+	parentPath := devicepath.Join(grandParentPath, obj.Filename)
+	parentPaths[parentId] = parentPath
+
+	return parentPath, nil
+}
@@ -0,0 +1,79 @@
+package mtpx
+
+import (
+	"bytes"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestModificationDates(t *testing.T) {
+	type element struct {
+		ObjectId     uint32
+		PropertyCode uint16
+		DataType     uint16
+		ModTime      time.Time
+	}
+
+	decode := func(elements ...element) map[uint32]ObjectProps {
+		var buf bytes.Buffer
+		So(mtp.Encode(&buf, &struct{ Count uint32 }{uint32(len(elements))}), ShouldBeNil)
+
+		for i := range elements {
+			So(mtp.Encode(&buf, &elements[i]), ShouldBeNil)
+		}
+
+		list, err := decodeObjectPropList(&buf)
+		So(err, ShouldBeNil)
+
+		return list
+	}
+
+	Convey("Test modificationDates", t, func() {
+		t1 := time.Date(2020, 12, 31, 10, 20, 30, 0, time.UTC)
+		t2 := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+
+		dates, err := modificationDates(decode(
+			element{ObjectId: 7, PropertyCode: mtp.OPC_DateModified, DataType: mtp.DTC_STR, ModTime: t1},
+			element{ObjectId: 9, PropertyCode: mtp.OPC_DateModified, DataType: mtp.DTC_STR, ModTime: t2},
+		))
+
+		So(err, ShouldBeNil)
+		So(len(dates), ShouldEqual, 2)
+		So(dates[7].Equal(t1), ShouldBeTrue)
+		So(dates[9].Equal(t2), ShouldBeTrue)
+
+		_, err = modificationDates(decode(
+			element{ObjectId: 7, PropertyCode: mtp.OPC_ObjectFileName, DataType: mtp.DTC_STR, ModTime: t1},
+		))
+
+		So(err, ShouldBeError)
+	})
+}
+
+func TestNewestFiles(t *testing.T) {
+	Convey("Test newestFiles", t, func() {
+		t1 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+		t2 := t1.Add(time.Hour)
+
+		files := []*FileInfo{
+			{ObjectId: 1, ModTime: t1},
+			{ObjectId: 2, ModTime: t2},
+			{ObjectId: 3, ModTime: t1},
+		}
+
+		ids := func(l []*FileInfo) []uint32 {
+			var r []uint32
+			for _, fi := range l {
+				r = append(r, fi.ObjectId)
+			}
+
+			return r
+		}
+
+		So(ids(newestFiles(files, 0)), ShouldResemble, []uint32{2, 3, 1})
+		So(ids(newestFiles(files, 2)), ShouldResemble, []uint32{2, 3})
+		So(ids(newestFiles(files, 5)), ShouldResemble, []uint32{2, 3, 1})
+	})
+}
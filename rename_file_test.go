@@ -2,6 +2,7 @@ package mtpx
 
 import (
 	"fmt"
+	"github.com/ganeshrvel/go-mtpx/devicepath"
 	. "github.com/smartystreets/goconvey/convey"
 	"log"
 	"math/rand"
@@ -65,7 +66,7 @@ func TestRenameFile(t *testing.T) {
 		time.Sleep(10000)
 
 		//try renaming the object using using the same [newFileName]
-		objId, err = RenameFile(dev, sid, FileProp{0, getFullPath("/mtp-test-files/temp_dir/test-RenameFile/", renameRandFileName)}, renameRandFileName)
+		objId, err = RenameFile(dev, sid, FileProp{0, devicepath.Join("/mtp-test-files/temp_dir/test-RenameFile/", renameRandFileName)}, renameRandFileName)
 
 		So(err, ShouldBeNil)
 		So(objId, ShouldEqual, objectId)
@@ -0,0 +1,112 @@
+package mtpx
+
+import (
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"os"
+)
+
+// Transfer the files which failed in a previous bulk transfer again
+// only the failed files of the [summary] are sent, each one to the destination recorded in the summary (eg: the renamed
+// companions of the RAW pairs) and with the options of the original transfer: the progress and preprocess callbacks
+// and the disallowed files policy
+// the files are retried even if one of them fails again
+// return:
+// [retried]: summary of the retry, the files which are still failing can be retried again using it
+// [err]: error of the first file which failed again, or the error which stopped the retry
+func RetryFailed(dev *mtp.Device, summary *OperationSummary) (retried *OperationSummary, err error) {
+	if summary == nil {
+		return nil, InvalidPathError{error: fmt.Errorf("no summary to retry")}
+	}
+
+	if summary.Type != UploadFilesOp && summary.Type != DownloadFilesOp {
+		return nil, UnsupportedOperationError{error: fmt.Errorf("%v can't be retried", summary.Type)}
+	}
+
+	plan := retryPlan(summary)
+
+	op := &OperationInfo{Type: summary.Type, StorageId: summary.StorageId, Sources: plan.Sources}
+	op.Mutating = summary.Type == UploadFilesOp
+
+	err = runMiddlewares(dev, op, func() error {
+		retried, err = retryFailed(dev, plan, summary.options)
+
+		return err
+	})
+
+	return retried, err
+}
+
+// helper function for [RetryFailed]
+func retryFailed(dev *mtp.Device, plan *CopyPlan, opts transferOptions) (*OperationSummary, error) {
+	if opts.progressCb == nil {
+		opts.progressCb = func(*ProgressInfo, error) error { return nil }
+	}
+	opts.continueOnError = true
+
+	if opts.preprocessFiles {
+		if err := preprocessRetries(dev, plan, opts); err != nil {
+			return nil, err
+		}
+
+		plan.summarize()
+	}
+
+	_, _, retried, err := executeCopyPlan(dev, plan, opts)
+
+	return retried, err
+}
+
+// plan the transfer of the failed files of the [summary] to their recorded destinations
+// the objects of the downloads are looked up again when the plan is executed, see [executeCopyPlan]
+func retryPlan(summary *OperationSummary) *CopyPlan {
+	plan := &CopyPlan{Direction: Download, StorageId: summary.StorageId}
+	if summary.Type == UploadFilesOp {
+		plan.Direction = Upload
+	}
+
+	for _, r := range summary.Failures() {
+		plan.Sources = append(plan.Sources, r.Source)
+		plan.Items = append(plan.Items, &CopyPlanItem{Source: r.Source, Destination: r.Destination, Size: r.Size})
+	}
+
+	return plan
+}
+
+// run the preprocess callback of the original transfer over the files which are retried
+func preprocessRetries(dev *mtp.Device, plan *CopyPlan, opts transferOptions) error {
+	for _, item := range plan.Items {
+		if plan.Direction == Upload {
+			if opts.localPreprocessCb == nil {
+				continue
+			}
+
+			fi, err := os.Stat(item.Source)
+			if err != nil {
+				// the file is reported as failed when the plan is executed
+				continue
+			}
+
+			item.Size = fi.Size()
+			if err := opts.localPreprocessCb(&fi, item.Source, nil); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		fi, err := GetObjectFromPath(dev, plan.StorageId, item.Source)
+		if err != nil {
+			continue
+		}
+
+		item.Object, item.Size = fi, fi.Size
+		if opts.mtpPreprocessCb != nil {
+			if err := opts.mtpPreprocessCb(fi, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestRetryFailed(t *testing.T) {
+	Convey("Test RetryFailed", t, func() {
+		_, err := RetryFailed(nil, nil)
+		So(err, ShouldBeError)
+
+		_, err = RetryFailed(nil, newOperationSummary(DeleteFileOp, 1, transferOptions{}))
+		So(err, ShouldHaveSameTypeAs, UnsupportedOperationError{})
+	})
+
+	Convey("Test retryPlan", t, func() {
+		summary := newOperationSummary(UploadFilesOp, 1, transferOptions{preprocessFiles: true})
+		summary.add(&FileResult{Source: "/tmp/a/1.jpg", Destination: "/DCIM/a/1.jpg", Size: 10, Status: FileFailed})
+		summary.add(&FileResult{Source: "/tmp/a/2.jpg", Destination: "/DCIM/a/2.jpg", Size: 20, Status: FileTransferred})
+
+		// the companions of the RAW pairs keep the destination names which they were renamed to
+		summary.add(&FileResult{Source: "/tmp/b/IMG_1.jpg", Destination: "/DCIM/b/img_1.jpg", Size: 30, Status: FileFailed})
+
+		plan := retryPlan(summary)
+
+		So(plan.Direction, ShouldEqual, Upload)
+		So(plan.StorageId, ShouldEqual, 1)
+		So(plan.Sources, ShouldResemble, []string{"/tmp/a/1.jpg", "/tmp/b/IMG_1.jpg"})
+		So(plan.Items, ShouldHaveLength, 2)
+		So(plan.Items[0].Destination, ShouldEqual, "/DCIM/a/1.jpg")
+		So(plan.Items[1].Destination, ShouldEqual, "/DCIM/b/img_1.jpg")
+		So(plan.Items[1].Size, ShouldEqual, 30)
+	})
+}
@@ -0,0 +1,210 @@
+package mtpx
+
+import (
+	"context"
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"sync"
+	"time"
+)
+
+// operations in flight and the disconnect hooks of a device
+type deviceLifecycle struct {
+	running int
+
+	// closed once the running operations are done, set while [Shutdown] waits for them
+	drained chan struct{}
+
+	shuttingDown bool
+	cancelled    bool
+
+	// the device is closed by the last of the running operations, once [Shutdown] gave up waiting for them
+	closeOnDrain bool
+
+	disconnected bool
+	onDisconnect []DisconnectCb
+}
+
+var deviceLifecycles = struct {
+	sync.Mutex
+	m map[*mtp.Device]*deviceLifecycle
+}{m: map[*mtp.Device]*deviceLifecycle{}}
+
+// call [cb] when an operation finds that the device was unplugged or stopped responding
+// the callbacks are called once per device, a device which was shut down using [Shutdown] or [Dispose] doesn't call them
+func OnDisconnect(dev *mtp.Device, cb DisconnectCb) {
+	deviceLifecycles.Lock()
+	defer deviceLifecycles.Unlock()
+
+	l := getLifecycle(dev)
+	l.onDisconnect = append(l.onDisconnect, cb)
+}
+
+// close the device once the running operations are done
+// the new operations are refused with a [DeviceShutdownError] right away
+// if [ctx] is done before the running operations then they are cancelled, they return an [OperationCancelledError]
+// at their next progress point. the operations which are still running after [shutdownCancelTimeout] are abandoned,
+// Shutdown returns and the last of them closes the device once it returns, a transaction in flight is never cut off
+// the session is then closed, the usb interface is released and the device is disposed
+// returns the error of [ctx] if the running operations had to be cancelled
+func Shutdown(ctx context.Context, dev *mtp.Device) error {
+	deviceLifecycles.Lock()
+	l := getLifecycle(dev)
+	l.shuttingDown = true
+
+	var drained chan struct{}
+	if l.running > 0 {
+		if l.drained == nil {
+			l.drained = make(chan struct{})
+		}
+
+		drained = l.drained
+	}
+	deviceLifecycles.Unlock()
+
+	var err error
+	if drained != nil {
+		select {
+		case <-drained:
+		case <-ctx.Done():
+			err = ctx.Err()
+
+			deviceLifecycles.Lock()
+			l.cancelled = true
+			deviceLifecycles.Unlock()
+
+			select {
+			case <-drained:
+			case <-time.After(shutdownCancelTimeout):
+				// the operations may be waiting on the device in the middle of a transaction
+				deviceLifecycles.Lock()
+				l.closeOnDrain = l.running > 0
+				abandoned := l.closeOnDrain
+				deviceLifecycles.Unlock()
+
+				if abandoned {
+					return err
+				}
+			}
+		}
+	}
+
+	closeShutDevice(dev)
+
+	return err
+}
+
+// close the session and dispose the device which was shut down
+func closeShutDevice(dev *mtp.Device) {
+	// the device may be gone already
+	_ = dev.CloseSession()
+
+	Dispose(dev)
+}
+
+// register a running operation of the device
+// returns a [DeviceShutdownError] if the device is being shut down
+// the operations which are a part of a running operation (eg: [MakeDirectory] inside [UploadFiles]) are not registered
+// again, see [runNestedMiddlewares]
+// call [release] with the error of the operation once it returns
+func beginOperation(dev *mtp.Device) (release func(err error), err error) {
+	deviceLifecycles.Lock()
+	defer deviceLifecycles.Unlock()
+
+	l := getLifecycle(dev)
+	if l.shuttingDown {
+		return nil, DeviceShutdownError{error: fmt.Errorf("the device is shutting down")}
+	}
+
+	l.running += 1
+
+	return func(err error) {
+		deviceLifecycles.Lock()
+		l.running -= 1
+
+		var closeDevice bool
+		if l.running < 1 {
+			if l.drained != nil {
+				close(l.drained)
+				l.drained = nil
+			}
+
+			closeDevice, l.closeOnDrain = l.closeOnDrain, false
+		}
+
+		notify := err != nil && len(l.onDisconnect) > 0 && !l.shuttingDown && !l.disconnected
+		deviceLifecycles.Unlock()
+
+		if closeDevice {
+			closeShutDevice(dev)
+
+			return
+		}
+
+		if notify {
+			checkDisconnected(dev, l, err)
+		}
+	}, nil
+}
+
+// returns an [OperationCancelledError] if [Shutdown] gave up waiting for the running operations of the device
+// or if the context of a running operation is done, see [runWithContext]
+func checkCancelled(dev *mtp.Device) error {
+	deviceLifecycles.Lock()
+	l, ok := deviceLifecycles.m[dev]
+	cancelled := ok && l.cancelled
+	deviceLifecycles.Unlock()
+
+	if cancelled {
+		return OperationCancelledError{error: fmt.Errorf("the operation was cancelled as the device is shutting down")}
+	}
+
+	return checkDeviceContexts(dev)
+}
+
+// call the disconnect hooks if the device is no longer reachable
+// go-mtpfs closes the device when a transaction fails with a usb error, the usb descriptors can't be read after that
+func checkDisconnected(dev *mtp.Device, l *deviceLifecycle, err error) {
+	if _, usbErr := dev.GetUsbInfo(); usbErr == nil {
+		return
+	}
+
+	deviceLifecycles.Lock()
+	if l.disconnected || l.shuttingDown {
+		deviceLifecycles.Unlock()
+
+		return
+	}
+
+	l.disconnected = true
+	callbacks := append([]DisconnectCb(nil), l.onDisconnect...)
+	deviceLifecycles.Unlock()
+
+	for _, cb := range callbacks {
+		cb(err)
+	}
+}
+
+func getLifecycle(dev *mtp.Device) *deviceLifecycle {
+	l, ok := deviceLifecycles.m[dev]
+	if !ok {
+		l = &deviceLifecycle{}
+		deviceLifecycles.m[dev] = l
+	}
+
+	return l
+}
+
+// drop the disconnect hooks of the device
+// the operations which are still running keep their own reference to the lifecycle
+func disposeLifecycle(dev *mtp.Device) {
+	deviceLifecycles.Lock()
+	defer deviceLifecycles.Unlock()
+
+	if l, ok := deviceLifecycles.m[dev]; ok {
+		// a disposed device doesn't report a disconnect
+		l.shuttingDown = true
+	}
+
+	delete(deviceLifecycles.m, dev)
+}
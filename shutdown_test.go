@@ -0,0 +1,50 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestShutdown(t *testing.T) {
+	Convey("Test beginOperation", t, func() {
+		// the lifecycles are keyed by the device, a nil device is good enough here
+		defer disposeLifecycle(nil)
+
+		release, err := beginOperation(nil)
+		So(err, ShouldBeNil)
+
+		deviceLifecycles.Lock()
+		l := getLifecycle(nil)
+		l.shuttingDown = true
+		l.drained = make(chan struct{})
+		drained := l.drained
+		deviceLifecycles.Unlock()
+
+		// the operations which are a part of the running one are let through, the unrelated new ones are refused
+		So(runNestedMiddlewares(nil, &OperationInfo{Type: MakeDirectoryOp}, func() error { return nil }), ShouldBeNil)
+
+		_, err = beginOperation(nil)
+		So(err, ShouldHaveSameTypeAs, DeviceShutdownError{})
+
+		So(checkCancelled(nil), ShouldBeNil)
+
+		deviceLifecycles.Lock()
+		l.cancelled = true
+		deviceLifecycles.Unlock()
+
+		So(checkCancelled(nil), ShouldHaveSameTypeAs, OperationCancelledError{})
+		So(touchOperation(nil), ShouldHaveSameTypeAs, OperationCancelledError{})
+
+		err = runNestedMiddlewares(nil, &OperationInfo{Type: MakeDirectoryOp}, func() error { return nil })
+		So(err, ShouldHaveSameTypeAs, OperationCancelledError{})
+
+		release(nil)
+
+		_, ok := <-drained
+		So(ok, ShouldBeFalse)
+		So(l.running, ShouldEqual, 0)
+
+		_, err = beginOperation(nil)
+		So(err, ShouldHaveSameTypeAs, DeviceShutdownError{})
+	})
+}
@@ -0,0 +1,126 @@
+package mtpx
+
+import (
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"log"
+	"os"
+	"sync"
+)
+
+var deviceSimulations = struct {
+	sync.Mutex
+	m map[*mtp.Device]SimulateCb
+}{m: map[*mtp.Device]SimulateCb{}}
+
+// check whether the device is in the simulation mode
+func IsSimulated(dev *mtp.Device) bool {
+	_, ok := getSimulation(dev)
+
+	return ok
+}
+
+// put the device in the simulation mode
+// if [cb] is nil then the simulated operations are logged
+func enableSimulation(dev *mtp.Device, cb SimulateCb) {
+	if cb == nil {
+		cb = logSimulatedOperation
+	}
+
+	deviceSimulations.Lock()
+	defer deviceSimulations.Unlock()
+
+	deviceSimulations.m[dev] = cb
+}
+
+func disableSimulation(dev *mtp.Device) {
+	deviceSimulations.Lock()
+	defer deviceSimulations.Unlock()
+
+	delete(deviceSimulations.m, dev)
+}
+
+func getSimulation(dev *mtp.Device) (SimulateCb, bool) {
+	deviceSimulations.Lock()
+	defer deviceSimulations.Unlock()
+
+	cb, ok := deviceSimulations.m[dev]
+
+	return cb, ok
+}
+
+// resolve the paths and sizes which the operation would touch and report them to [cb]
+func simulateOperation(dev *mtp.Device, op *OperationInfo, cb SimulateCb) error {
+	so := &SimulatedOperation{Operation: op}
+
+	switch op.Type {
+	case UploadFilesOp, UploadFileOp:
+		totalFiles, _, totalSize, err := walkLocalFiles(op.Sources, func(fi *os.FileInfo, fullPath string, err error) error {
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		so.TotalFiles = totalFiles
+		so.TotalSize = totalSize
+
+	case WriteFileOp:
+		so.TotalFiles = 1
+		so.TotalSize = op.Size
+	}
+
+	for _, fileProp := range op.FileProps {
+		fi, err := GetObjectFromObjectIdOrPath(dev, op.StorageId, fileProp)
+		if err != nil {
+			switch err.(type) {
+			// the object would be created by the operation
+			case InvalidPathError, FileNotFoundError:
+				continue
+
+			default:
+				return err
+			}
+		}
+
+		so.Objects = append(so.Objects, fi)
+
+		// only the deletes and renames act on the existing objects
+		if op.Type == MakeDirectoryOp || op.Type == WriteFileOp {
+			continue
+		}
+
+		if !fi.IsDir {
+			so.TotalFiles += 1
+			so.TotalSize += fi.Size
+
+			continue
+		}
+
+		_, _, err = proccessWalk(dev, op.StorageId, FileProp{fi.ObjectId, fi.FullPath}, true, false, false, func(objectId uint32, fi *FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !fi.IsDir {
+				so.TotalFiles += 1
+				so.TotalSize += fi.Size
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return cb(so)
+}
+
+func logSimulatedOperation(so *SimulatedOperation) error {
+	op := so.Operation
+
+	log.Printf("[simulate] %s storageId: %d, objects: %v, sources: %v, destination: %s, files: %d, size: %d bytes",
+		op.Type, op.StorageId, op.FileProps, op.Sources, op.Destination, so.TotalFiles, so.TotalSize)
+
+	return nil
+}
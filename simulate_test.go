@@ -0,0 +1,47 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestSimulateOperation(t *testing.T) {
+	Convey("Test simulation mode", t, func() {
+		var simulated []*SimulatedOperation
+
+		// the simulations are keyed by the device, a nil device is good enough here
+		enableSimulation(nil, func(so *SimulatedOperation) error {
+			simulated = append(simulated, so)
+
+			return nil
+		})
+		defer disableSimulation(nil)
+
+		So(IsSimulated(nil), ShouldBeTrue)
+
+		ran := false
+		err := runMiddlewares(nil, &OperationInfo{Type: UploadFilesOp, Mutating: true, Sources: []string{getTestMocksAsset("mock_dir1")}}, func() error {
+			ran = true
+
+			return nil
+		})
+		So(err, ShouldBeNil)
+		So(ran, ShouldBeFalse)
+		So(len(simulated), ShouldEqual, 1)
+		So(simulated[0].TotalFiles, ShouldBeGreaterThan, 0)
+		So(simulated[0].TotalSize, ShouldBeGreaterThan, 0)
+
+		// the operations which do not change the device still run
+		err = runMiddlewares(nil, &OperationInfo{Type: DownloadFilesOp}, func() error {
+			ran = true
+
+			return nil
+		})
+		So(err, ShouldBeNil)
+		So(ran, ShouldBeTrue)
+		So(len(simulated), ShouldEqual, 1)
+
+		disableSimulation(nil)
+		So(IsSimulated(nil), ShouldBeFalse)
+	})
+}
@@ -0,0 +1,203 @@
+package mtpx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+)
+
+// Install an audio file as a ringtone, notification or alarm sound on an Android device
+// the file is placed in the directory which the sound pickers of the device list (eg: '/Ringtones') and sent with the object format of its audio codec
+// an existing sound with the same name is overwritten
+// use [opts.Trim] to cut a WAV file down before it is sent
+// returns the objectId of the installed sound
+func InstallSound(dev *mtp.Device, localPath string, kind SoundKind, opts InstallSoundOptions) (objectId uint32, err error) {
+	destination, ok := soundDirectories[kind]
+	if !ok {
+		return 0, InvalidPathError{error: fmt.Errorf("unknown sound kind: %v", kind)}
+	}
+
+	storageId := opts.StorageId
+	if storageId == 0 {
+		if storageId, err = firstWritableStorage(dev); err != nil {
+			return 0, err
+		}
+	}
+
+	op := &OperationInfo{Type: UploadFilesOp, Mutating: true, StorageId: storageId, Sources: []string{localPath}, Destination: destination}
+
+	err = runMiddlewares(dev, op, func() error {
+		objectId, err = installSound(dev, storageId, localPath, destination, opts)
+
+		return err
+	})
+
+	return objectId, err
+}
+
+// helper function for [InstallSound]
+func installSound(dev *mtp.Device, storageId uint32, localPath, destination string, opts InstallSoundOptions) (objectId uint32, err error) {
+	if err := checkStorageWritable(dev, storageId, false); err != nil {
+		return 0, err
+	}
+
+	filename := filepath.Base(localPath)
+	if opts.FileName != "" {
+		filename = opts.FileName
+	}
+
+	format := objectFormat(filename)
+	if formatCategories[format] != CategoryAudio {
+		return 0, InvalidPathError{error: fmt.Errorf("not a supported audio file: %s", filename)}
+	}
+
+	data, err := ioutil.ReadFile(localPath)
+	if err != nil {
+		return 0, InvalidPathError{error: err}
+	}
+
+	var duration time.Duration
+	if opts.Trim != nil {
+		if format != mtp.OFC_WAV {
+			return 0, UnsupportedOperationError{error: fmt.Errorf("only the WAV files can be trimmed: %s", filename)}
+		}
+
+		if data, duration, err = trimWav(data, opts.Trim.Start, opts.Trim.End); err != nil {
+			return 0, err
+		}
+	}
+
+	parentId, err := makeNestedDirectory(dev, storageId, destination)
+	if err != nil {
+		return 0, err
+	}
+
+	size := int64(len(data))
+
+	startTime := time.Now()
+
+	objectId, err = sendObject(dev, storageId, parentId, filename, format, time.Now(), bytes.NewReader(data), size, true,
+		func(total, sent int64, objectId uint32, err error) error {
+			return err
+		})
+	if err != nil {
+		recordTransferError(dev)
+
+		return objectId, err
+	}
+
+	recordTransferredBytes(dev, Upload, size, time.Since(startTime))
+	recordTransferredFile(dev, Upload)
+
+	// the metadata is a hint for the sound pickers, most of the devices read it from the file itself
+	// hence the errors are ignored
+	if opts.Title != "" {
+		_ = dev.SetObjectPropValue(objectId, mtp.OPC_Name, &mtp.StringValue{Value: opts.Title})
+	}
+
+	if duration > 0 {
+		_ = dev.SetObjectPropValue(objectId, mtp.OPC_Duration, &uint32Value{Value: uint32(duration / time.Millisecond)})
+	}
+
+	return objectId, nil
+}
+
+// cut a PCM WAV file down to the part between [start] and [end]
+// if [end] is 0 then the sound is kept until its end. the chunks other than the format and the data are dropped
+// returns the new file and its duration
+func trimWav(data []byte, start, end time.Duration) ([]byte, time.Duration, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, 0, InvalidPathError{error: fmt.Errorf("not a WAV file")}
+	}
+
+	var fmtChunk, samples []byte
+	for offset := 12; offset+8 <= len(data); {
+		id := string(data[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		body := offset + 8
+
+		if size < 0 || body+size > len(data) {
+			// tolerate a data chunk which runs past the end of a truncated file
+			if id != "data" {
+				return nil, 0, InvalidPathError{error: fmt.Errorf("corrupt WAV chunk: %s", id)}
+			}
+
+			size = len(data) - body
+		}
+
+		switch id {
+		case "fmt ":
+			fmtChunk = data[body : body+size]
+		case "data":
+			samples = data[body : body+size]
+		}
+
+		// the chunks are padded to an even size
+		offset = body + size + size%2
+	}
+
+	if len(fmtChunk) < 16 || samples == nil {
+		return nil, 0, InvalidPathError{error: fmt.Errorf("the WAV file has no audio")}
+	}
+
+	if audioFormat := binary.LittleEndian.Uint16(fmtChunk[0:2]); audioFormat != 1 {
+		return nil, 0, UnsupportedOperationError{error: fmt.Errorf("only the PCM WAV files can be trimmed, format: %d", audioFormat)}
+	}
+
+	byteRate := int64(binary.LittleEndian.Uint32(fmtChunk[8:12]))
+	blockAlign := int64(binary.LittleEndian.Uint16(fmtChunk[12:14]))
+	if byteRate < 1 || blockAlign < 1 {
+		return nil, 0, InvalidPathError{error: fmt.Errorf("corrupt WAV format")}
+	}
+
+	// offsets of the whole sample frames
+	toOffset := func(d time.Duration) int64 {
+		o := int64(d) * byteRate / int64(time.Second)
+		o -= o % blockAlign
+
+		if o > int64(len(samples)) {
+			o = int64(len(samples)) - int64(len(samples))%blockAlign
+		}
+
+		return o
+	}
+
+	from := toOffset(start)
+	to := int64(len(samples))
+	if end > 0 {
+		to = toOffset(end)
+	}
+
+	if from >= to {
+		return nil, 0, InvalidPathError{error: fmt.Errorf("nothing is left of the sound after trimming it to %v-%v", start, end)}
+	}
+
+	trimmed := samples[from:to]
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(4+8+len(fmtChunk)+len(fmtChunk)%2+8+len(trimmed)+len(trimmed)%2))
+	buf.WriteString("WAVE")
+
+	buf.WriteString("fmt ")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(fmtChunk)))
+	buf.Write(fmtChunk)
+	if len(fmtChunk)%2 == 1 {
+		buf.WriteByte(0)
+	}
+
+	buf.WriteString("data")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(trimmed)))
+	buf.Write(trimmed)
+	if len(trimmed)%2 == 1 {
+		buf.WriteByte(0)
+	}
+
+	duration := time.Duration(int64(len(trimmed)) * int64(time.Second) / byteRate)
+
+	return buf.Bytes(), duration, nil
+}
@@ -0,0 +1,71 @@
+package mtpx
+
+import (
+	"bytes"
+	"encoding/binary"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+// a mono 8-bit PCM WAV file of [seconds] at 1000 samples per second, the samples count up from 0
+func testWav(seconds int) []byte {
+	samples := make([]byte, seconds*1000)
+	for i := range samples {
+		samples[i] = byte(i)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(4+8+16+8+len(samples)))
+	buf.WriteString("WAVE")
+
+	buf.WriteString("fmt ")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(16))
+	_ = binary.Write(&buf, binary.LittleEndian, []uint16{1, 1})
+	_ = binary.Write(&buf, binary.LittleEndian, []uint32{1000, 1000})
+	_ = binary.Write(&buf, binary.LittleEndian, []uint16{1, 8})
+
+	// a chunk which is dropped by the trimming
+	buf.WriteString("LIST")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(3))
+	buf.Write([]byte{1, 2, 3, 0})
+
+	buf.WriteString("data")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(samples)))
+	buf.Write(samples)
+
+	return buf.Bytes()
+}
+
+func TestTrimWav(t *testing.T) {
+	Convey("Test trimWav", t, func() {
+		data, duration, err := trimWav(testWav(3), 500*time.Millisecond, 1500*time.Millisecond)
+		So(err, ShouldBeNil)
+		So(duration, ShouldEqual, time.Second)
+
+		So(string(data[0:4]), ShouldEqual, "RIFF")
+		So(binary.LittleEndian.Uint32(data[4:8]), ShouldEqual, len(data)-8)
+		So(string(data[36:40]), ShouldEqual, "data")
+		So(binary.LittleEndian.Uint32(data[40:44]), ShouldEqual, 1000)
+
+		// the first sample left is the one at 500ms
+		So(data[44], ShouldEqual, byte(500%256))
+
+		// keep the sound until its end
+		_, duration, err = trimWav(testWav(3), 2*time.Second, 0)
+		So(err, ShouldBeNil)
+		So(duration, ShouldEqual, time.Second)
+
+		// the end is capped to the length of the sound
+		_, duration, err = trimWav(testWav(1), 0, 10*time.Second)
+		So(err, ShouldBeNil)
+		So(duration, ShouldEqual, time.Second)
+
+		_, _, err = trimWav(testWav(1), 2*time.Second, 0)
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+
+		_, _, err = trimWav([]byte("ID3 not a wav file"), 0, 0)
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+	})
+}
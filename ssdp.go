@@ -0,0 +1,168 @@
+package mtpx
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// announce the media server on the network and answer the searches of the players
+func (s *MediaServer) startSsdp() error {
+	group, err := net.ResolveUDPAddr("udp4", ssdpAddr)
+	if err != nil {
+		return MediaServerError{error: err}
+	}
+
+	conn, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		return MediaServerError{error: fmt.Errorf("unable to join the ssdp multicast group: %v", err)}
+	}
+
+	s.ssdpConn = conn
+
+	s.wg.Add(2)
+	go s.answerSearches(conn)
+	go s.announce(group)
+
+	return nil
+}
+
+// reply to the M-SEARCH requests which are looking for the media server
+func (s *MediaServer) answerSearches(conn *net.UDPConn) {
+	defer s.wg.Done()
+
+	buf := make([]byte, 4096)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			// the connection is closed by [MediaServer.Close]
+			return
+		}
+
+		st, ok := parseSsdpSearch(buf[:n])
+		if !ok {
+			continue
+		}
+
+		for _, target := range s.searchTargets(st) {
+			s.sendSsdp(from, func(location string) string {
+				return fmt.Sprintf("HTTP/1.1 200 OK\r\nCACHE-CONTROL: max-age=%d\r\nDATE: %s\r\nEXT:\r\nLOCATION: %s\r\nSERVER: %s\r\nST: %s\r\nUSN: %s\r\n\r\n",
+					ssdpMaxAge, time.Now().UTC().Format(http.TimeFormat), location, ssdpServerName(), target, s.usn(target))
+			})
+		}
+	}
+}
+
+// send the alive notifications every [MediaServerConfig.NotifyInterval] until the server is closed
+func (s *MediaServer) announce(group *net.UDPAddr) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.NotifyInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, nt := range s.notificationTypes() {
+			s.sendSsdp(group, func(location string) string {
+				return fmt.Sprintf("NOTIFY * HTTP/1.1\r\nHOST: %s\r\nCACHE-CONTROL: max-age=%d\r\nLOCATION: %s\r\nNT: %s\r\nNTS: ssdp:alive\r\nSERVER: %s\r\nUSN: %s\r\n\r\n",
+					ssdpAddr, ssdpMaxAge, location, nt, ssdpServerName(), s.usn(nt))
+			})
+		}
+
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tell the players that the server is going away
+func (s *MediaServer) sendByebye() {
+	group, err := net.ResolveUDPAddr("udp4", ssdpAddr)
+	if err != nil {
+		return
+	}
+
+	for _, nt := range s.notificationTypes() {
+		s.sendSsdp(group, func(string) string {
+			return fmt.Sprintf("NOTIFY * HTTP/1.1\r\nHOST: %s\r\nNT: %s\r\nNTS: ssdp:byebye\r\nUSN: %s\r\n\r\n", ssdpAddr, nt, s.usn(nt))
+		})
+	}
+}
+
+// send the ssdp message built by [message] to [to]
+// the location of the device description is built using the address of the interface which reaches [to]
+// the errors are ignored, the players search again
+func (s *MediaServer) sendSsdp(to *net.UDPAddr, message func(location string) string) {
+	conn, err := net.DialUDP("udp4", nil, to)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	_, port, err := net.SplitHostPort(s.listener.Addr().String())
+	if err != nil {
+		return
+	}
+
+	localIp := conn.LocalAddr().(*net.UDPAddr).IP.String()
+	location := fmt.Sprintf("http://%s/description.xml", net.JoinHostPort(localIp, port))
+
+	_, _ = conn.Write([]byte(message(location)))
+}
+
+// the device, the services and the unique name which the server is announced as
+func (s *MediaServer) notificationTypes() []string {
+	return []string{"upnp:rootdevice", s.udn, mediaServerDeviceType, contentDirectoryServiceType, connectionManagerServiceType}
+}
+
+// the notification types matching the search target [st] of an M-SEARCH request
+func (s *MediaServer) searchTargets(st string) []string {
+	if st == "ssdp:all" {
+		return s.notificationTypes()
+	}
+
+	for _, nt := range s.notificationTypes() {
+		if nt == st {
+			return []string{nt}
+		}
+	}
+
+	return nil
+}
+
+// unique service name of the notification type
+func (s *MediaServer) usn(nt string) string {
+	if nt == s.udn {
+		return s.udn
+	}
+
+	return fmt.Sprintf("%s::%s", s.udn, nt)
+}
+
+// returns the search target of an M-SEARCH request
+// returns false if the message is not a discovery request
+func parseSsdpSearch(data []byte) (st string, ok bool) {
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		return "", false
+	}
+
+	if req.Method != "M-SEARCH" || strings.Trim(req.Header.Get("MAN"), `"`) != "ssdp:discover" {
+		return "", false
+	}
+
+	st = req.Header.Get("ST")
+
+	return st, st != ""
+}
+
+// eg: "linux/1.0 UPnP/1.0 mtpx/1.0"
+func ssdpServerName() string {
+	return fmt.Sprintf("%s/1.0 UPnP/1.0 mtpx/1.0", runtime.GOOS)
+}
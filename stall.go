@@ -0,0 +1,131 @@
+package mtpx
+
+import (
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// usb timeout of the object transfers in milliseconds, see [Init.TransferStallTimeout]
+var deviceTransferTimeouts = struct {
+	sync.Mutex
+	m map[*mtp.Device]int
+}{m: make(map[*mtp.Device]int)}
+
+func setTransferStallTimeout(dev *mtp.Device, timeout time.Duration) {
+	deviceTransferTimeouts.Lock()
+	defer deviceTransferTimeouts.Unlock()
+
+	if timeout <= 0 {
+		delete(deviceTransferTimeouts.m, dev)
+
+		return
+	}
+
+	deviceTransferTimeouts.m[dev] = int(timeout / time.Millisecond)
+}
+
+func disposeTransferStallTimeout(dev *mtp.Device) {
+	setTransferStallTimeout(dev, 0)
+}
+
+// run the object transfer [fn] with [Init.TransferStallTimeout] as the usb timeout
+// the rest of the requests keep [devTimeout], so a short stall timeout doesn't fail the slow listings and property reads
+func withTransferTimeout(dev *mtp.Device, fn func() error) error {
+	deviceTransferTimeouts.Lock()
+	timeout, ok := deviceTransferTimeouts.m[dev]
+	deviceTransferTimeouts.Unlock()
+
+	if !ok {
+		return fn()
+	}
+
+	dev.Timeout = timeout
+	defer func() { dev.Timeout = devTimeout }()
+
+	return fn()
+}
+
+// check whether the error was caused by a bulk transfer which made no progress within [mtp.Device.Timeout]
+// or by a transaction which went out of sync because of it
+func isTransferStallError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if _, ok := err.(mtp.SyncError); ok {
+		return true
+	}
+
+	msg := err.Error()
+	for _, pattern := range transferStallErrorPatterns {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cancel the wedged transaction and start a new session with the device
+// [mtp.Device.RunTransaction] drops the connection after a usb error; if it is still open then closing it resets the device
+// when the session can't be closed cleanly. the objectIds stay valid across the sessions
+func recoverStalledTransfer(dev *mtp.Device) error {
+	recordStallRecovery(dev)
+
+	// forget the session, the device has already dropped it if the connection was lost
+	_ = dev.CloseSession()
+	_ = dev.Close()
+
+	// give the device some rest
+	time.Sleep(transferStallRecoveryDelay)
+
+	if err := dev.Configure(); err != nil {
+		return TransferStalledError{error: fmt.Errorf("unable to recover from the stalled transfer: %v", err)}
+	}
+
+	return nil
+}
+
+// resume a download which stalled after [offset] bytes were written to [w]
+// the rest of the object is read using the partial reads, nothing is resumed if the device doesn't support them
+func resumeStalledDownload(dev *mtp.Device, fi *FileInfo, w io.Writer, offset int64, progressCb SizeProgressCb, stallErr error) error {
+	if err := recoverStalledTransfer(dev); err != nil {
+		return err
+	}
+
+	if !supportsOperation(dev, mtp.OC_GetPartialObject) {
+		return TransferStalledError{error: fmt.Errorf("the transfer of %v stalled at %d bytes: %v", fi.FullPath, offset, stallErr)}
+	}
+
+	pw := &progressWriter{w: w, total: fi.Size, sent: offset, objectId: fi.ObjectId, progressCb: progressCb}
+
+	return readPartialObject(dev, fi.ObjectId, pw, offset, fi.Size-offset)
+}
+
+// reports the bytes written to [w] through [progressCb]
+type progressWriter struct {
+	w          io.Writer
+	total      int64
+	sent       int64
+	objectId   uint32
+	progressCb SizeProgressCb
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	n, err := pw.w.Write(p)
+	pw.sent += int64(n)
+
+	if err != nil {
+		return n, err
+	}
+
+	if err := pw.progressCb(pw.total, pw.sent, pw.objectId, nil); err != nil {
+		return n, err
+	}
+
+	return n, nil
+}
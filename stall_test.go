@@ -0,0 +1,78 @@
+package mtpx
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestIsTransferStallError(t *testing.T) {
+	Convey("Test stalled transfer errors", t, func() {
+		So(isTransferStallError(nil), ShouldBeFalse)
+		So(isTransferStallError(fmt.Errorf("LIBUSB_ERROR_TIMEOUT")), ShouldBeTrue)
+		So(isTransferStallError(SendObjectError{error: fmt.Errorf("bulk write: LIBUSB_ERROR_PIPE")}), ShouldBeTrue)
+		So(isTransferStallError(mtp.SyncError("lost transaction")), ShouldBeTrue)
+		So(isTransferStallError(mtp.RCError(mtp.RC_AccessDenied)), ShouldBeFalse)
+		So(isTransferStallError(fmt.Errorf("file not found")), ShouldBeFalse)
+	})
+}
+
+func TestProgressWriter(t *testing.T) {
+	Convey("Test resumed transfer progress", t, func() {
+		var buf bytes.Buffer
+		var reported []int64
+
+		pw := &progressWriter{w: &buf, total: 10, sent: 4, objectId: 7, progressCb: func(total, sent int64, objectId uint32, err error) error {
+			So(total, ShouldEqual, 10)
+			So(objectId, ShouldEqual, 7)
+
+			reported = append(reported, sent)
+
+			return nil
+		}}
+
+		_, err := pw.Write([]byte("abc"))
+		So(err, ShouldBeNil)
+		_, err = pw.Write([]byte("def"))
+		So(err, ShouldBeNil)
+
+		So(buf.String(), ShouldEqual, "abcdef")
+		So(reported, ShouldResemble, []int64{7, 10})
+
+		pw.progressCb = func(total, sent int64, objectId uint32, err error) error {
+			return fmt.Errorf("cancelled")
+		}
+		_, err = pw.Write([]byte("g"))
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestWithTransferTimeout(t *testing.T) {
+	Convey("Test the usb timeout of the object transfers | withTransferTimeout", t, func() {
+		dev := &mtp.Device{Timeout: devTimeout}
+		defer disposeTransferStallTimeout(dev)
+
+		setTransferStallTimeout(dev, 2*time.Second)
+
+		err := withTransferTimeout(dev, func() error {
+			So(dev.Timeout, ShouldEqual, 2000)
+
+			return nil
+		})
+		So(err, ShouldBeNil)
+
+		// the rest of the requests keep the default timeout
+		So(dev.Timeout, ShouldEqual, devTimeout)
+
+		disposeTransferStallTimeout(dev)
+
+		_ = withTransferTimeout(dev, func() error {
+			So(dev.Timeout, ShouldEqual, devTimeout)
+
+			return nil
+		})
+	})
+}
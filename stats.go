@@ -0,0 +1,117 @@
+package mtpx
+
+import (
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"math"
+	"sync"
+	"time"
+)
+
+var deviceTransferStats = struct {
+	sync.Mutex
+	m map[*mtp.Device]*TransferStats
+}{m: map[*mtp.Device]*TransferStats{}}
+
+// fetch the transfer statistics of the device session
+// the statistics are collected from the moment the device was initialized or the statistics were reset
+func FetchTransferStats(dev *mtp.Device) *TransferStats {
+	deviceTransferStats.Lock()
+	defer deviceTransferStats.Unlock()
+
+	stats := *getTransferStats(dev)
+
+	if stats.ActiveTime > 0 {
+		stats.AverageSpeed = math.Round(float64(stats.BytesIn+stats.BytesOut)/float64(stats.ActiveTime.Nanoseconds())*1000*100) / 100
+	}
+
+	return &stats
+}
+
+// start collecting the transfer statistics afresh
+func ResetTransferStats(dev *mtp.Device) {
+	deviceTransferStats.Lock()
+	defer deviceTransferStats.Unlock()
+
+	deviceTransferStats.m[dev] = &TransferStats{StartTime: time.Now()}
+}
+
+// drop the transfer statistics of the device
+func disposeTransferStats(dev *mtp.Device) {
+	deviceTransferStats.Lock()
+	defer deviceTransferStats.Unlock()
+
+	delete(deviceTransferStats.m, dev)
+}
+
+// the caller should hold the lock
+func getTransferStats(dev *mtp.Device) *TransferStats {
+	stats, ok := deviceTransferStats.m[dev]
+	if !ok {
+		stats = &TransferStats{StartTime: time.Now()}
+		deviceTransferStats.m[dev] = stats
+	}
+
+	return stats
+}
+
+// record [n] bytes transferred in [elapsed] time
+func recordTransferredBytes(dev *mtp.Device, direction TransferDirection, n int64, elapsed time.Duration) {
+	deviceTransferStats.Lock()
+	defer deviceTransferStats.Unlock()
+
+	stats := getTransferStats(dev)
+
+	switch direction {
+	case Download:
+		stats.BytesIn += n
+
+	case Upload:
+		stats.BytesOut += n
+	}
+
+	if elapsed <= 0 {
+		return
+	}
+
+	stats.ActiveTime += elapsed
+
+	if rate := math.Round(float64(n)/float64(elapsed.Nanoseconds())*1000*100) / 100; rate > stats.PeakSpeed {
+		stats.PeakSpeed = rate
+	}
+}
+
+func recordTransferredFile(dev *mtp.Device, direction TransferDirection) {
+	deviceTransferStats.Lock()
+	defer deviceTransferStats.Unlock()
+
+	stats := getTransferStats(dev)
+
+	switch direction {
+	case Download:
+		stats.FilesDownloaded += 1
+
+	case Upload:
+		stats.FilesUploaded += 1
+	}
+}
+
+func recordTransferRetry(dev *mtp.Device) {
+	deviceTransferStats.Lock()
+	defer deviceTransferStats.Unlock()
+
+	getTransferStats(dev).Retries += 1
+}
+
+func recordStallRecovery(dev *mtp.Device) {
+	deviceTransferStats.Lock()
+	defer deviceTransferStats.Unlock()
+
+	getTransferStats(dev).StallRecoveries += 1
+}
+
+func recordTransferError(dev *mtp.Device) {
+	deviceTransferStats.Lock()
+	defer deviceTransferStats.Unlock()
+
+	getTransferStats(dev).Errors += 1
+}
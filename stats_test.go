@@ -0,0 +1,37 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestTransferStats(t *testing.T) {
+	Convey("Test transfer statistics", t, func() {
+		// the statistics are keyed by the device, a nil device is good enough here
+		ResetTransferStats(nil)
+		defer disposeTransferStats(nil)
+
+		recordTransferredBytes(nil, Download, 2000000, time.Second)
+		recordTransferredBytes(nil, Upload, 1000000, 250*time.Millisecond)
+		recordTransferredFile(nil, Download)
+		recordTransferredFile(nil, Upload)
+		recordTransferredFile(nil, Upload)
+		recordTransferRetry(nil)
+		recordTransferError(nil)
+
+		stats := FetchTransferStats(nil)
+		So(stats.BytesIn, ShouldEqual, 2000000)
+		So(stats.BytesOut, ShouldEqual, 1000000)
+		So(stats.FilesDownloaded, ShouldEqual, 1)
+		So(stats.FilesUploaded, ShouldEqual, 2)
+		So(stats.ActiveTime, ShouldEqual, 1250*time.Millisecond)
+		So(stats.AverageSpeed, ShouldEqual, 2.4)
+		So(stats.PeakSpeed, ShouldEqual, 4)
+		So(stats.Retries, ShouldEqual, 1)
+		So(stats.Errors, ShouldEqual, 1)
+
+		ResetTransferStats(nil)
+		So(FetchTransferStats(nil).BytesIn, ShouldEqual, 0)
+	})
+}
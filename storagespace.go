@@ -0,0 +1,94 @@
+package mtpx
+
+import (
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"sync"
+)
+
+type storageSpaceWatcher struct {
+	cb StorageSpaceCb
+
+	// last known free space of the storages
+	freeSpace map[uint32]uint64
+}
+
+var deviceStorageSpaces = struct {
+	sync.Mutex
+	m map[*mtp.Device]*storageSpaceWatcher
+}{m: map[*mtp.Device]*storageSpaceWatcher{}}
+
+// report the changes in the free space of the storages of the device to [cb]
+// the free space is checked after every mutating operation, every uploaded file and the changes picked up by [Watch]
+// so the storage gauges can be kept accurate without polling [FetchStorages]. if [cb] is nil then the changes are no longer reported
+func SetStorageSpaceCb(dev *mtp.Device, cb StorageSpaceCb) {
+	if cb == nil {
+		deviceStorageSpaces.Lock()
+		defer deviceStorageSpaces.Unlock()
+
+		delete(deviceStorageSpaces.m, dev)
+
+		return
+	}
+
+	w := &storageSpaceWatcher{cb: cb, freeSpace: map[uint32]uint64{}}
+
+	// the storages which fail here start being tracked from their first check
+	if storages, err := FetchStorages(dev); err == nil {
+		for _, s := range storages {
+			w.freeSpace[s.Sid] = s.Info.FreeSpaceInBytes
+		}
+	}
+
+	deviceStorageSpaces.Lock()
+	defer deviceStorageSpaces.Unlock()
+
+	deviceStorageSpaces.m[dev] = w
+}
+
+// fetch the free space of the storage and report it if it changed
+// nothing is fetched if no one is listening to the changes
+func refreshStorageSpace(dev *mtp.Device, storageId uint32) {
+	deviceStorageSpaces.Lock()
+	_, ok := deviceStorageSpaces.m[dev]
+	deviceStorageSpaces.Unlock()
+
+	if !ok {
+		return
+	}
+
+	var info mtp.StorageInfo
+	if err := dev.GetStorageInfo(storageId, &info); err != nil {
+		return
+	}
+
+	updateStorageSpace(dev, storageId, info.FreeSpaceInBytes, info.MaxCapability)
+}
+
+// record the free space of the storage and report it to the callback if it changed
+func updateStorageSpace(dev *mtp.Device, storageId uint32, freeSpace, capacity uint64) {
+	deviceStorageSpaces.Lock()
+
+	w, ok := deviceStorageSpaces.m[dev]
+	if !ok {
+		deviceStorageSpaces.Unlock()
+
+		return
+	}
+
+	oldFreeSpace, known := w.freeSpace[storageId]
+	w.freeSpace[storageId] = freeSpace
+	cb := w.cb
+
+	deviceStorageSpaces.Unlock()
+
+	if !known || oldFreeSpace == freeSpace {
+		return
+	}
+
+	cb(&StorageSpaceChange{
+		StorageId:    storageId,
+		OldFreeSpace: oldFreeSpace,
+		NewFreeSpace: freeSpace,
+		Capacity:     capacity,
+	})
+}
@@ -0,0 +1,43 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestUpdateStorageSpace(t *testing.T) {
+	Convey("Test storage space changes", t, func() {
+		var changes []*StorageSpaceChange
+
+		// the watchers are keyed by the device, a nil device is good enough here
+		deviceStorageSpaces.Lock()
+		deviceStorageSpaces.m[nil] = &storageSpaceWatcher{
+			cb: func(change *StorageSpaceChange) {
+				changes = append(changes, change)
+			},
+			freeSpace: map[uint32]uint64{1: 1000},
+		}
+		deviceStorageSpaces.Unlock()
+
+		defer SetStorageSpaceCb(nil, nil)
+
+		updateStorageSpace(nil, 1, 1000, 5000)
+		So(changes, ShouldBeEmpty)
+
+		updateStorageSpace(nil, 1, 400, 5000)
+		So(changes, ShouldResemble, []*StorageSpaceChange{{StorageId: 1, OldFreeSpace: 1000, NewFreeSpace: 400, Capacity: 5000}})
+
+		// the first check of an unknown storage is only recorded
+		updateStorageSpace(nil, 2, 300, 800)
+		So(len(changes), ShouldEqual, 1)
+
+		updateStorageSpace(nil, 2, 500, 800)
+		So(len(changes), ShouldEqual, 2)
+		So(changes[1].OldFreeSpace, ShouldEqual, 300)
+		So(changes[1].NewFreeSpace, ShouldEqual, 500)
+
+		SetStorageSpaceCb(nil, nil)
+		updateStorageSpace(nil, 1, 100, 5000)
+		So(len(changes), ShouldEqual, 2)
+	})
+}
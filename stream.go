@@ -0,0 +1,487 @@
+package mtpx
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the mtp session doesn't allow overlapping transactions, the requests of an http server arrive concurrently
+var deviceStreamLocks = struct {
+	sync.Mutex
+	m map[*mtp.Device]*sync.Mutex
+}{m: map[*mtp.Device]*sync.Mutex{}}
+
+// Serve the file at [fullPath] to an http request, eg: from a handler of the application's http server
+// the range requests are answered using the partial reads so that the videos can be scrubbed in a browser or a media player
+// without downloading them first. the conditional requests (If-Modified-Since, If-Range) are supported too
+// the content type is picked by the extension of the file, see [streamContentTypes]
+// the requests of the same device are read one chunk at a time, the other operations of the application
+// should not use the device while the files are being served
+// note: this package has no http server of its own
+// returns the error which was reported to the client, if any
+func ServeObject(dev *mtp.Device, storageId uint32, fullPath string, w http.ResponseWriter, r *http.Request) error {
+	op := &OperationInfo{Type: StreamObjectOp, StorageId: storageId, Sources: []string{fullPath}}
+
+	err := runMiddlewares(dev, op, func() error {
+		lock := streamLock(dev)
+
+		lock.Lock()
+		fi, err := GetObjectFromPath(dev, storageId, fullPath)
+		lock.Unlock()
+
+		if err != nil {
+			return err
+		}
+
+		if fi.IsDir {
+			return InvalidPathError{error: fmt.Errorf("invalid path: %s. The object is a directory", fullPath)}
+		}
+
+		if contentType := objectContentType(fi.Name); contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+
+		release := markObjectInUse(dev, fi.ObjectId)
+		defer release()
+
+		// the errors of the reads which follow the headers can't be reported to the client anymore
+		http.ServeContent(w, r, fi.Name, fi.ModTime, NewObjectReader(dev, fi))
+
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), httpStatus(err))
+	}
+
+	return err
+}
+
+// Run the read-only operations in [fn] (eg: [Walk], [GetObjectFromPath]) between the chunks of the files which are being
+// read using [ObjectReader] or [ServeObject] on the other goroutines, eg: to keep browsing the device while a video is streamed
+// the mtp session doesn't allow overlapping transactions, [fn] waits for the chunk in flight and the readers wait for [fn]
+// so keep [fn] short. the listings which are in the cache don't touch the device at all, see [Prefetch]
+// note: the downloads read each file in a single transaction unless [SetInterleaveFrequency] is set
+func Interleave(dev *mtp.Device, fn func() error) error {
+	lock := streamLock(dev)
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	return fn()
+}
+
+// returns a reader of the file [fi] which reads the device using the partial reads, starting at any offset
+// the reader implements [io.ReadSeeker], eg: to be passed to [http.ServeContent], and [io.ReaderAt] for the mounts
+// the file is read [TransferChunkSize] bytes at a time and the last few blocks are kept in memory, so the many small reads
+// of the players cost a single request per block
+func NewObjectReader(dev *mtp.Device, fi *FileInfo) *ObjectReader {
+	return &ObjectReader{dev: dev, objectId: fi.ObjectId, size: fi.Size}
+}
+
+// returns a reader which streams the file [objectId] of the storage [storageId], eg: to pipe it into a hasher,
+// an http response or an archive without saving it to the disk first
+// the file is read in chunks using the partial reads, the reader implements [io.Seeker] too when the device supports them
+// (see [ObjectReader]). otherwise the whole file is read in a single transaction as the reader consumes it,
+// closing such a reader early waits for the device to finish sending the file
+// the other operations of the device should not run while a reader is open, unless they are run through [Interleave]
+// the file can't be deleted, renamed or moved until the reader is closed, see [IsObjectInUse]
+// the single transaction reader runs through the middlewares and holds up [Shutdown] until it is closed
+func NewFileReader(dev *mtp.Device, storageId uint32, objectId uint32) (io.ReadCloser, error) {
+	fi, err := GetObjectFromObjectId(dev, objectId, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if fi.IsDir {
+		return nil, InvalidPathError{error: fmt.Errorf("invalid object: %d. The object is a directory", objectId)}
+	}
+
+	if fi.Info.StorageID != storageId {
+		return nil, InvalidPathError{error: fmt.Errorf("invalid object: %d. The object is not in the storage %d", objectId, storageId)}
+	}
+
+	if supportsOperation(dev, mtp.OC_GetPartialObject) {
+		r := NewObjectReader(dev, fi)
+		r.release = markObjectInUse(dev, fi.ObjectId)
+
+		return r, nil
+	}
+
+	end, err := beginOperation(dev)
+	if err != nil {
+		return nil, err
+	}
+
+	r := newPipedObjectReader(dev, storageId, fi)
+	r.release = markObjectInUse(dev, fi.ObjectId)
+	r.end = end
+
+	return r, nil
+}
+
+// stream the file [fi] through a pipe, for the devices which don't support the partial reads
+// the caller registers the reader with [beginOperation], see [pipedObjectReader.end]
+func newPipedObjectReader(dev *mtp.Device, storageId uint32, fi *FileInfo) *pipedObjectReader {
+	pr, pw := io.Pipe()
+	r := &pipedObjectReader{pr: pr, done: make(chan struct{})}
+
+	op := &OperationInfo{Type: StreamObjectOp, StorageId: storageId, Sources: []string{fi.FullPath}, Size: fi.Size}
+
+	go func() {
+		defer close(r.done)
+
+		r.err = runNestedMiddlewares(dev, op, func() error {
+			lock := streamLock(dev)
+			lock.Lock()
+			defer lock.Unlock()
+
+			err := withTransferTimeout(dev, func() error {
+				return dev.GetObject(fi.ObjectId, &drainingWriter{w: pw}, mtp.EmptyProgressFunc)
+			})
+			if err != nil {
+				recordTransferError(dev)
+
+				return FileTransferError{error: err}
+			}
+
+			return nil
+		})
+
+		_ = pw.CloseWithError(r.err)
+	}()
+
+	return r
+}
+
+// returns a writer which uploads a new file named [filename] into the directory [parentId], eg: to store an http download
+// or the entries of an archive on the device without a temporary local file
+// [size] is the exact number of bytes which will be written, mtp needs it before the transfer starts
+// an existing file with the same name is overwritten. the upload is done once [ObjectWriter.Close] returns
+// the other operations of the device should not run while the writer is open
+func NewFileWriter(dev *mtp.Device, storageId uint32, parentId uint32, filename string, size int64) (*ObjectWriter, error) {
+	if err := checkStorageWritable(dev, storageId, false); err != nil {
+		return nil, err
+	}
+
+	if filename == "" || strings.ContainsAny(filename, "/\\") {
+		return nil, InvalidPathError{error: fmt.Errorf("invalid filename: %s", filename)}
+	}
+
+	if size < 0 {
+		return nil, InvalidPathError{error: fmt.Errorf("invalid size of %s: %d", filename, size)}
+	}
+
+	pr, pw := io.Pipe()
+	w := &ObjectWriter{pw: pw, size: size, done: make(chan struct{})}
+
+	op := &OperationInfo{Type: WriteFileOp, Mutating: true, StorageId: storageId, Size: size}
+
+	go func() {
+		defer close(w.done)
+
+		err := runMiddlewares(dev, op, func() error {
+			startTime := time.Now()
+
+			objectId, err := sendObject(dev, storageId, parentId, filename, mtp.OFC_Undefined, time.Now(), pr, size, true,
+				func(total, sent int64, objectId uint32, err error) error {
+					if err != nil {
+						return err
+					}
+
+					return touchOperation(dev)
+				})
+			if err != nil {
+				recordTransferError(dev)
+
+				return err
+			}
+
+			recordTransferredBytes(dev, Upload, size, time.Since(startTime))
+			recordTransferredFile(dev, Upload)
+
+			w.objectId = objectId
+
+			return nil
+		})
+
+		w.err = err
+
+		// unblock the writes if the upload stopped early
+		if err != nil {
+			_ = pr.CloseWithError(err)
+		} else {
+			_ = pr.Close()
+		}
+	}()
+
+	return w, nil
+}
+
+// content type of the file by its extension, the common media formats of the phones are known regardless of the mime
+// tables of the system. returns an empty string for the other files
+func objectContentType(filename string) string {
+	return streamContentTypes[strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), "."))]
+}
+
+// http status code of the error of [ServeObject]
+func httpStatus(err error) int {
+	switch err.(type) {
+	case InvalidPathError, FileNotFoundError:
+		return http.StatusNotFound
+
+	case DeviceShutdownError, OperationCancelledError:
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusInternalServerError
+}
+
+func streamLock(dev *mtp.Device) *sync.Mutex {
+	deviceStreamLocks.Lock()
+	defer deviceStreamLocks.Unlock()
+
+	l, ok := deviceStreamLocks.m[dev]
+	if !ok {
+		l = &sync.Mutex{}
+		deviceStreamLocks.m[dev] = l
+	}
+
+	return l
+}
+
+func disposeStreamLock(dev *mtp.Device) {
+	deviceStreamLocks.Lock()
+	defer deviceStreamLocks.Unlock()
+
+	delete(deviceStreamLocks.m, dev)
+}
+
+func (r *ObjectReader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n, err := r.readAt(p, r.offset)
+	r.offset += int64(n)
+
+	return n, err
+}
+
+// read len(p) bytes starting at [off] without moving the offset of [Read], it implements [io.ReaderAt]
+func (r *ObjectReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset: %d", off)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var total int
+	for total < len(p) {
+		n, err := r.readAt(p[total:], off+int64(total))
+		total += n
+
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+func (r *ObjectReader) Seek(offset int64, whence int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, fmt.Errorf("invalid whence: %d", whence)
+	}
+
+	if offset < 0 {
+		return 0, fmt.Errorf("negative offset: %d", offset)
+	}
+
+	r.offset = offset
+
+	return offset, nil
+}
+
+// copy the bytes starting at [off] out of the block holding them
+// the caller should hold the lock
+func (r *ObjectReader) readAt(p []byte, off int64) (int, error) {
+	if len(p) < 1 {
+		return 0, nil
+	}
+
+	if off >= r.size {
+		return 0, io.EOF
+	}
+
+	cache := r.blocks()
+	index := off / cache.blockSize
+
+	block, err := r.block(index)
+	if err != nil {
+		return 0, err
+	}
+
+	start := off - index*cache.blockSize
+	if start >= int64(len(block)) {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	return copy(p, block[start:]), nil
+}
+
+// fetch the block [index] of the file, it is read from the device unless it is cached
+// the caller should hold the lock
+func (r *ObjectReader) block(index int64) ([]byte, error) {
+	cache := r.blocks()
+
+	if block, ok := cache.get(index); ok {
+		return block, nil
+	}
+
+	offset := index * cache.blockSize
+	size := cache.blockSize
+	if rest := r.size - offset; size > rest {
+		size = rest
+	}
+
+	var b bytes.Buffer
+	b.Grow(int(size))
+
+	lock := streamLock(r.dev)
+	lock.Lock()
+	err := readPartialObject(r.dev, r.objectId, &b, offset, size)
+	lock.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if b.Len() < 1 {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	cache.put(index, b.Bytes())
+
+	return b.Bytes(), nil
+}
+
+// the caller should hold the lock
+func (r *ObjectReader) blocks() *objectBlockCache {
+	if r.cache == nil {
+		r.cache = newObjectBlockCache(TransferChunkSize(), objectReaderCacheBlocks)
+	}
+
+	return r.cache
+}
+
+// drop the cached blocks, the reader does not hold the device
+func (r *ObjectReader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cache = nil
+
+	if r.release != nil {
+		r.release()
+	}
+
+	return nil
+}
+
+func (r *pipedObjectReader) Read(p []byte) (int, error) {
+	return r.pr.Read(p)
+}
+
+// stop reading, the rest of the file is still received from the device and thrown away as the transaction can't be cut short
+// returns once the device is free again
+func (r *pipedObjectReader) Close() error {
+	_ = r.pr.Close()
+	<-r.done
+
+	if r.release != nil {
+		r.release()
+	}
+
+	if r.end != nil {
+		r.end(r.err)
+		r.end = nil
+	}
+
+	return nil
+}
+
+func (dw *drainingWriter) Write(p []byte) (int, error) {
+	if !dw.draining {
+		n, err := dw.w.Write(p)
+		if err != io.ErrClosedPipe {
+			return n, err
+		}
+
+		dw.draining = true
+	}
+
+	return len(p), nil
+}
+
+func (w *ObjectWriter) Write(p []byte) (int, error) {
+	if w.written+int64(len(p)) > w.size {
+		return 0, TooLargeError{error: fmt.Errorf("the data is larger than the size of the file: %d", w.size)}
+	}
+
+	n, err := w.pw.Write(p)
+	w.written += int64(n)
+
+	if err == io.ErrClosedPipe {
+		<-w.done
+
+		// the upload was left out, eg: in the simulation mode. the data is taken as written so that [Close] succeeds
+		if w.err == nil {
+			w.written += int64(len(p) - n)
+
+			return len(p), nil
+		}
+
+		return n, w.err
+	}
+
+	return n, err
+}
+
+// finish the upload and wait for the device to store the file
+// returns an error if fewer bytes than the size of the file were written or if the upload failed
+func (w *ObjectWriter) Close() error {
+	if w.written < w.size {
+		_ = w.pw.CloseWithError(io.ErrUnexpectedEOF)
+	} else {
+		_ = w.pw.Close()
+	}
+
+	<-w.done
+
+	if w.err == nil && w.written < w.size {
+		return SendObjectError{error: fmt.Errorf("only %d of %d bytes were written", w.written, w.size)}
+	}
+
+	return w.err
+}
+
+// objectId of the new file, available once [Close] returns without an error
+func (w *ObjectWriter) ObjectId() uint32 {
+	return w.objectId
+}
@@ -0,0 +1,266 @@
+package mtpx
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	. "github.com/smartystreets/goconvey/convey"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServeObject(t *testing.T) {
+	dev, err := Initialize(Init{})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	storages, err := FetchStorages(dev)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	sid := storages[0].Sid
+
+	Convey("Testing ServeObject | range request", t, func() {
+		expected, err := ioutil.ReadFile(getTestMocksAsset("mock_dir1/a.txt"))
+		So(err, ShouldBeNil)
+
+		req := httptest.NewRequest(http.MethodGet, "/a.txt", nil)
+		req.Header.Set("Range", "bytes=1-3")
+		rec := httptest.NewRecorder()
+
+		err = ServeObject(dev, sid, "/mtp-test-files/mock_dir1/a.txt", rec, req)
+		So(err, ShouldBeNil)
+		So(rec.Code, ShouldEqual, http.StatusPartialContent)
+		So(rec.Header().Get("Content-Range"), ShouldEqual, fmt.Sprintf("bytes 1-3/%d", len(expected)))
+		So(rec.Body.String(), ShouldEqual, string(expected[1:4]))
+	})
+
+	Convey("Testing ServeObject | invalid path", t, func() {
+		rec := httptest.NewRecorder()
+
+		err := ServeObject(dev, sid, "/mtp-test-files/fake-file.mp4", rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+		So(rec.Code, ShouldEqual, http.StatusNotFound)
+	})
+
+	Convey("Testing NewFileReader", t, func() {
+		expected, err := ioutil.ReadFile(getTestMocksAsset("mock_dir1/a.txt"))
+		So(err, ShouldBeNil)
+
+		fi, err := GetObjectFromPath(dev, sid, "/mtp-test-files/mock_dir1/a.txt")
+		So(err, ShouldBeNil)
+
+		r, err := NewFileReader(dev, sid, fi.ObjectId)
+		So(err, ShouldBeNil)
+
+		data, err := ioutil.ReadAll(r)
+		So(err, ShouldBeNil)
+		So(data, ShouldResemble, expected)
+		So(r.Close(), ShouldBeNil)
+
+		dir, err := GetObjectFromPath(dev, sid, "/mtp-test-files/mock_dir1")
+		So(err, ShouldBeNil)
+
+		_, err = NewFileReader(dev, sid, dir.ObjectId)
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+	})
+
+	Convey("Testing NewFileWriter", t, func() {
+		parentId, err := MakeDirectory(dev, sid, "/mtp-test-files/temp_dir/test_NewFileWriter")
+		So(err, ShouldBeNil)
+
+		data := []byte("streamed to the device")
+
+		w, err := NewFileWriter(dev, sid, parentId, "streamed.txt", int64(len(data)))
+		So(err, ShouldBeNil)
+
+		_, err = io.Copy(w, bytes.NewReader(data))
+		So(err, ShouldBeNil)
+		So(w.Close(), ShouldBeNil)
+		So(w.ObjectId(), ShouldBeGreaterThan, 0)
+
+		result, err := ReadFileToBytes(dev, w.ObjectId(), 1024)
+		So(err, ShouldBeNil)
+		So(result, ShouldResemble, data)
+
+		_, err = NewFileWriter(dev, sid, parentId, "a/b.txt", 1)
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+
+		err = DeleteFile(dev, sid, []FileProp{{0, "/mtp-test-files/temp_dir/test_NewFileWriter"}})
+		So(err, ShouldBeNil)
+	})
+
+	Dispose(dev)
+}
+
+func TestObjectReader(t *testing.T) {
+	Convey("Test seeking | ObjectReader", t, func() {
+		r := NewObjectReader(nil, &FileInfo{ObjectId: 1, Size: 100})
+
+		offset, err := r.Seek(10, io.SeekStart)
+		So(err, ShouldBeNil)
+		So(offset, ShouldEqual, 10)
+
+		offset, err = r.Seek(5, io.SeekCurrent)
+		So(err, ShouldBeNil)
+		So(offset, ShouldEqual, 15)
+
+		offset, err = r.Seek(-20, io.SeekEnd)
+		So(err, ShouldBeNil)
+		So(offset, ShouldEqual, 80)
+
+		_, err = r.Seek(-1, io.SeekStart)
+		So(err, ShouldNotBeNil)
+
+		// the device is not read past the end of the file
+		_, err = r.Seek(0, io.SeekEnd)
+		So(err, ShouldBeNil)
+
+		n, err := r.Read(make([]byte, 8))
+		So(n, ShouldEqual, 0)
+		So(err, ShouldEqual, io.EOF)
+	})
+
+	Convey("Test reading from the cached blocks | ObjectReader", t, func() {
+		r := &ObjectReader{size: 10}
+		r.blocks().put(0, []byte("0123456789"))
+
+		p := make([]byte, 4)
+		n, err := r.Read(p)
+		So(err, ShouldBeNil)
+		So(string(p[:n]), ShouldEqual, "0123")
+
+		_, err = r.Seek(8, io.SeekStart)
+		So(err, ShouldBeNil)
+
+		n, err = r.Read(p)
+		So(err, ShouldBeNil)
+		So(string(p[:n]), ShouldEqual, "89")
+
+		// the offset of [Read] is left alone
+		n, err = r.ReadAt(p, 2)
+		So(err, ShouldBeNil)
+		So(string(p[:n]), ShouldEqual, "2345")
+
+		n, err = r.ReadAt(p, 7)
+		So(err, ShouldEqual, io.EOF)
+		So(string(p[:n]), ShouldEqual, "789")
+
+		_, err = r.Read(p)
+		So(err, ShouldEqual, io.EOF)
+	})
+
+	Convey("Test waiting for the chunk in flight | Interleave", t, func() {
+		lock := streamLock(nil)
+		defer disposeStreamLock(nil)
+
+		lock.Lock()
+		released := make(chan struct{})
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			close(released)
+			lock.Unlock()
+		}()
+
+		var ranAfterRelease bool
+		err := Interleave(nil, func() error {
+			select {
+			case <-released:
+				ranAfterRelease = true
+			default:
+			}
+
+			return nil
+		})
+		So(err, ShouldBeNil)
+		So(ranAfterRelease, ShouldBeTrue)
+	})
+
+	Convey("Test throwing away the rest of the file once the reader is closed | drainingWriter", t, func() {
+		pr, pw := io.Pipe()
+		dw := &drainingWriter{w: pw}
+
+		written := make(chan error, 1)
+		go func() {
+			if _, err := dw.Write([]byte("abc")); err != nil {
+				written <- err
+
+				return
+			}
+
+			_, err := dw.Write([]byte("def"))
+			written <- err
+		}()
+
+		p := make([]byte, 3)
+		_, err := io.ReadFull(pr, p)
+		So(err, ShouldBeNil)
+		So(string(p), ShouldEqual, "abc")
+
+		So(pr.Close(), ShouldBeNil)
+		So(<-written, ShouldBeNil)
+		So(dw.draining, ShouldBeTrue)
+	})
+
+	Convey("Test the size of the written data | ObjectWriter", t, func() {
+		pr, pw := io.Pipe()
+		w := &ObjectWriter{pw: pw, size: 4, done: make(chan struct{})}
+
+		received := make(chan []byte, 1)
+		go func() {
+			defer close(w.done)
+
+			data, err := ioutil.ReadAll(pr)
+			w.err = err
+			received <- data
+		}()
+
+		_, err := w.Write([]byte("abcdef"))
+		So(err, ShouldHaveSameTypeAs, TooLargeError{})
+
+		n, err := w.Write([]byte("abc"))
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 3)
+
+		// fewer bytes than the size of the file
+		So(w.Close(), ShouldNotBeNil)
+		So(string(<-received), ShouldEqual, "abc")
+	})
+
+	Convey("Test the upload which is left out | ObjectWriter", t, func() {
+		pr, pw := io.Pipe()
+		w := &ObjectWriter{pw: pw, size: 4, done: make(chan struct{})}
+
+		// the simulation mode closes the pipe without reading it
+		_ = pr.Close()
+		close(w.done)
+
+		n, err := w.Write([]byte("ab"))
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 2)
+
+		_, err = w.Write([]byte("cd"))
+		So(err, ShouldBeNil)
+
+		So(w.Close(), ShouldBeNil)
+	})
+
+	Convey("Test the content types | objectContentType", t, func() {
+		So(objectContentType("VID_20210101.MP4"), ShouldEqual, "video/mp4")
+		So(objectContentType("song.flac"), ShouldEqual, "audio/flac")
+		So(objectContentType("notes.txt"), ShouldEqual, "")
+	})
+
+	Convey("Test the http status of the errors | httpStatus", t, func() {
+		So(httpStatus(InvalidPathError{}), ShouldEqual, http.StatusNotFound)
+		So(httpStatus(DeviceShutdownError{}), ShouldEqual, http.StatusServiceUnavailable)
+		So(httpStatus(mtp.RCError(mtp.RC_GeneralError)), ShouldEqual, http.StatusInternalServerError)
+	})
+}
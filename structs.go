@@ -1,8 +1,14 @@
 package mtpx
 
 import (
+	"container/list"
+	"context"
 	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"io"
+	"net"
+	"net/http"
 	"os"
+	"sync"
 	"time"
 )
 
@@ -10,11 +16,319 @@ type allowedSecondExtMap map[string]string
 
 type Init struct {
 	DebugMode bool
+
+	// the device to open, see [ListDevices]
+	// if nil then the only connected device is opened
+	Device *DeviceDescriptor
+
+	// cache the directory listings of the device in memory
+	// the cached listings are invalidated by the mutating operations of this package,
+	// changes made on the device itself are not picked up until the listing is invalidated
+	EnableCache bool
+
+	// tunables of the metadata cache
+	CacheConfig CacheConfig
+
+	// keep a copy of the downloaded files on the local disk, the repeated downloads of the unchanged files are served from it
+	// the files are identified by their persistent uid, size and modification date. the devices which don't report
+	// the persistent uids are downloaded as usual
+	EnableContentCache bool
+
+	// tunables of the content cache
+	ContentCache ContentCacheConfig
+
+	// keep retrying for [BusyTimeout] while the device is held by another application
+	// if the value is 0 then a [DeviceBusyError] is returned right away
+	BusyTimeout time.Duration
+
+	// hooks run around the operations of the device, see [AddMiddleware]
+	Middlewares []Middleware
+
+	// the mutating operations only report what they would do and leave the device untouched
+	// useful to test the automation scripts against a real device
+	Simulate bool
+
+	// receives the simulated operations
+	// if nil then the simulated operations are logged
+	SimulateCb SimulateCb
+
+	// a bulk transfer which makes no progress for [TransferStallTimeout] is cancelled and the session with the device is reopened
+	// the downloads then resume from the last byte received, when the device supports the partial reads
+	// only the object transfers use it as the usb timeout, the rest of the requests keep [devTimeout]
+	// if the value is 0 then [devTimeout] is used
+	TransferStallTimeout time.Duration
+
+	// maximum number of bytes written to the device during the session, see [SetUploadQuota]
+	// if the value is 0 then the uploads are not limited
+	UploadQuota int64
+
+	// quirks and layout of the device, see [DeviceProfile]
+	// if nil then the profile is picked by the usb ids of the device
+	DeviceProfile *DeviceProfile
+
+	// receives the changes in the free space of the storages, see [SetStorageSpaceCb]
+	StorageSpaceCb StorageSpaceCb
+
+	// called when the device is unplugged or stops responding, see [OnDisconnect]
+	OnDisconnect DisconnectCb
+
+	// periodic callbacks and stall detection of the long-running operations, see [SetHeartbeat]
+	Heartbeat HeartbeatConfig
+
+	// record the protocol transactions of the session, see [StartTranscript]
+	// the transcript is written only if [TranscriptConfig.Path] or [TranscriptConfig.Writer] is set
+	Transcript TranscriptConfig
+}
+
+// a connected MTP device, see [ListDevices]
+type DeviceDescriptor struct {
+	VendorId  uint16
+	ProductId uint16
+
+	Serial       string
+	Manufacturer string
+	Product      string
+
+	// name to show to the users, eg: "Google Pixel 4a"
+	FriendlyName string
+}
+
+type DeviceEvent struct {
+	Type DeviceEventType
+
+	// for [DeviceDisconnected] events this is the last known information of the device
+	Device DeviceDescriptor
+}
+
+type DeviceEventCb func(event DeviceEvent)
+
+type MtpEvent struct {
+	Type MtpEventType
+
+	// MTP event code which the event stands for, eg: [mtp.EC_ObjectAdded]
+	Code uint16
+
+	StorageId uint32
+
+	// 0 for the storage events
+	ObjectId uint32
+
+	// for [EventObjectAdded] events this is the information of the new object, nil if it could not be fetched
+	// for [EventObjectRemoved] events this is the last known information of the object, nil if it was never reported
+	FileInfo *FileInfo
+}
+
+type MtpEventCb func(ev MtpEvent)
+
+type TranscriptConfig struct {
+	// file to write the transcript to, it is truncated if it exists
+	Path string
+
+	// writer to write the transcript to, used when [Path] is empty
+	Writer io.Writer
+
+	// replace the file paths and the serial number of the device with placeholders and drop the decoded payloads
+	// so that the transcript can be attached to a public bug report
+	Redact bool
+
+	// turn on the debug mode of the device while the transcript is running so that the transactions are recorded,
+	// go-mtpfs then logs them through the standard logger too. it is turned back off by [StopTranscript]
+	// not needed if the device was initialized with [Init.DebugMode]
+	EnableDebugMode bool
+}
+
+type HeartbeatConfig struct {
+	// called every [Interval] while an operation is running
+	Cb HeartbeatCb
+
+	// if the value is 0 then [defaultHeartbeatInterval] is used
+	Interval time.Duration
+
+	// an operation which makes no progress for [StallTimeout] is reported as stalled
+	// and aborted with an [OperationStalledError] as soon as it gets the control back (eg: when the device request times out)
+	// the time spent in the walk callbacks is not counted, and the session of an operation which failed after stalling is reset
+	// if the value is 0 then the operations are never considered stalled
+	StallTimeout time.Duration
+}
+
+type CacheConfig struct {
+	// cached listings older than [TTL] are fetched again from the device
+	// if the value is 0 then the listings never expire. the changes made on the device side are only seen through the
+	// TTL, [SubscribeEvents] picks up the added and the removed objects but not the renames
+	TTL time.Duration
+
+	// maximum number of directory listings to keep in the cache
+	// the least recently used listing is evicted when the limit is reached
+	// if the value is 0 then [defaultCacheMaxEntries] is used
+	MaxEntries int
+}
+
+type ContentCacheConfig struct {
+	// directory to keep the cached files in
+	// if empty then [DefaultContentCachePath] is used
+	Directory string
+
+	// maximum size of the cached files of a device (in bytes)
+	// the least recently used files are evicted when the limit is reached
+	// if the value is 0 then [defaultContentCacheMaxSize] is used
+	MaxSize int64
+}
+
+type ContentCacheStats struct {
+	// downloads served from the cache
+	Hits int64
+
+	// downloads which had to be fetched from the device
+	Misses int64
+
+	// files added to the cache
+	Stored int64
+
+	// files removed to make room for the new ones
+	Evictions int64
+
+	// bytes which were not read from the device thanks to the cache
+	BytesSaved int64
+
+	// total number of cached files of the device
+	Entries int
+
+	// total size of the cached files of the device (in bytes)
+	Size int64
+}
+
+type ExportOptions struct {
+	// if empty then [ExportCsv] is used
+	Format ExportFormat
+
+	// leave out the row with the names of the columns
+	NoHeader bool
+
+	// files matching the disallowed files list are left out, see [SetDisallowedFilesPolicy]
+	SkipDisallowedFiles bool
+
+	// hidden files (unix style) are left out
+	SkipHiddenFiles bool
+}
+
+// a directory in the tree written by [ExportTree]
+type ExportNode struct {
+	Name     string
+	FullPath string
+	ObjectId uint32
+
+	// total size of the files in the whole subtree (in bytes)
+	Size int64
+
+	// total number of files in the whole subtree
+	TotalFiles int64
+
+	Children []*ExportNode `json:",omitempty"`
+}
+
+type CleanCachesPolicy struct {
+	// drop the cached listings and the cached files which were not used for [MaxAge]
+	// if the value is 0 then they are not dropped by their age
+	MaxAge time.Duration
+
+	// maximum size of the cached files of a device (in bytes)
+	// if the value is 0 then the cached files are not dropped by their size
+	MaxSize int64
+
+	// drop the devices which were not seen for [DeviceMaxAge]
+	// if the value is 0 then the devices are kept
+	DeviceMaxAge time.Duration
+
+	// if empty then [DefaultContentCachePath] is used
+	ContentCacheDirectory string
+
+	// if empty then [DefaultDeviceStorePath] is used
+	DeviceStorePath string
+}
+
+type CleanCachesReport struct {
+	// listings dropped from the metadata caches of the connected devices
+	RemovedListings int64
+
+	// files dropped from the content cache
+	RemovedFiles int64
+
+	// bytes freed on the local disk
+	RemovedBytes int64
+
+	// serial numbers of the devices dropped from the device store
+	RemovedDevices []string
+
+	// devices whose content cache was dropped as a whole
+	RemovedDeviceCaches int
+}
+
+type CacheDrift struct {
+	StorageId uint32
+	ObjectId  uint32
+
+	// what is different between the cached and the live object
+	Reason string
+
+	// cached information of the object
+	Cached *FileInfo
+
+	// live information of the object. nil if the object no longer exists
+	Live *FileInfo
+}
+
+type CacheDriftReport struct {
+	// total number of objects compared against the device
+	Checked int
+
+	// objects which are out of date in the cache
+	Drifts []CacheDrift
+}
+
+type CacheStats struct {
+	// lookups served from the cache
+	Hits int64
+
+	// lookups which had to be fetched from the device
+	Misses int64
+
+	// listings removed to make room for the new ones or because they were expired
+	Evictions int64
+
+	// total number of cached directory listings
+	Entries int
+
+	// total number of cached objects across all the listings
+	Objects int
+
+	// approximate memory used by the cached objects (in bytes)
+	MemoryUsage int64
 }
 
 type StorageData struct {
 	Sid  uint32
 	Info mtp.StorageInfo
+
+	// name of the storage, eg: "Internal shared storage", "SD card"
+	Description string
+
+	VolumeLabel string
+
+	FreeBytes  uint64
+	TotalBytes uint64
+
+	// name of the filesystem type as reported by the device, eg: "GenericHierarchical"
+	FilesystemType string
+
+	// the storage can be removed from the device (eg: an SD card)
+	Removable bool
+
+	// objects can't be created or modified on the storage (eg: a locked SD card)
+	ReadOnly bool
+
+	// objects can be deleted from the storage
+	// note: a few read-only storages allow deleting the objects
+	AllowsDeletion bool
 }
 
 type FileInfo struct {
@@ -28,11 +342,57 @@ type FileInfo struct {
 	ParentId   uint32
 	ObjectId   uint32
 
+	// kind of the file derived from its format and extension, see [fileCategory]. empty for the directories
+	Category FileCategory
+
+	// protection status of the object as reported by the device (see mtp.PS_*)
+	ProtectionStatus uint16
+
+	// the object is read-only on the device and won't be deleted or overwritten unless forced
+	WriteProtected bool
+
+	// persistent unique identifier of the object (hex), it survives the renames and the reconnects unlike [ObjectId]
+	// note: the value will be empty unless [SetPersistentUids] is enabled and the device supports it
+	PersistentUid string
+
+	// the ObjectInfo dataset of the object. the listings built out of the MTP property lists leave the thumbnail,
+	// pixel and keywords fields empty, see [fileInfoFromProps]
 	Info *mtp.ObjectInfo
 }
 
 type WalkCb func(objectId uint32, fi *FileInfo, err error) error
 
+// position of a walk, see [WalkWithCheckpoint]
+type WalkCheckpoint struct {
+	StorageId uint32
+
+	// directory being walked
+	FullPath string
+
+	// directories whose whole subtree was walked
+	// the directories nested inside a completed directory are left out
+	Completed []string
+
+	// the walk is over, resuming from the checkpoint visits nothing
+	Done bool
+
+	UpdatedAt time.Time
+}
+
+type WalkCheckpointOptions struct {
+	// continue the walk from the checkpoint. if nil then the walk starts over
+	Resume *WalkCheckpoint
+
+	// called with the latest checkpoint at most once per [Interval], right after a directory is completed, and once the walk is over
+	// the checkpoint is a copy which can be kept, eg: using [SaveWalkCheckpoint]. returning an error stops the walk
+	Cb WalkCheckpointCb
+
+	// if the value is 0 then a checkpoint is taken after every completed directory
+	Interval time.Duration
+}
+
+type WalkCheckpointCb func(cp *WalkCheckpoint) error
+
 type TransferSizeInfo struct {
 	// total size to transfer
 	// note: the value will be 0 if pre-processing was not allowed
@@ -57,6 +417,10 @@ type ProgressInfo struct {
 	// transfer rate (in MB/s)
 	Speed float64
 
+	// estimated time left for the transfer session, based on the average rate since [StartTime]
+	// note: the value will be 0 if pre-processing was not allowed
+	ETA time.Duration
+
 	// total files to transfer
 	// note: the value will be 0 if pre-processing was not allowed
 	TotalFiles int64
@@ -78,6 +442,112 @@ type ProgressInfo struct {
 	BulkFileSize *TransferSizeInfo
 
 	Status TransferStatus
+
+	// statistics of the device session
+	// note: the value is only available when the [Status] is [Completed]
+	SessionStats *TransferStats
+
+	// transferred, skipped and failed files by their extension and category
+	// the counts are updated as the files are processed and are final once the [Status] is [Completed]
+	Breakdown *TransferBreakdown
+
+	// results of the files processed so far, see [UploadFilesWithSummary] and [DownloadFilesWithSummary]
+	Summary *OperationSummary
+}
+
+// results of a bulk transfer, file by file
+type OperationSummary struct {
+	mu sync.Mutex
+
+	Type OperationType
+
+	StorageId uint32
+
+	StartTime time.Time
+
+	// zero until the operation is over
+	EndTime time.Time
+
+	// files in the order in which they were processed
+	Files []*FileResult
+
+	Transferred int64
+	Skipped     int64
+	Failed      int64
+
+	// error which the operation stopped at, nil if it completed
+	Err error
+
+	// machine-readable code of [Err], eg: "QuotaExceeded" for a [QuotaExceededError]
+	ErrorCode string
+
+	// options of the operation, reused by [RetryFailed]
+	options transferOptions
+}
+
+// options of a bulk transfer, kept on its summary so that [RetryFailed] runs the failed files the same way
+type transferOptions struct {
+	preprocessFiles   bool
+	localPreprocessCb LocalPreprocessCb
+	mtpPreprocessCb   MtpPreprocessCb
+	progressCb        ProgressCb
+
+	// disallowed files policy at the start of the transfer, see [SetDisallowedFilesPolicy]
+	disallowedFiles DisallowedFilesPolicy
+
+	// record the files which fail and move on to the next file instead of stopping the transfer
+	continueOnError bool
+}
+
+type FileResult struct {
+	// local path of an uploaded file or device path of a downloaded file
+	Source string
+
+	// where the file was copied to. empty for the skipped uploads
+	Destination string
+
+	// objectId of the file on the device. 0 if an upload failed or was skipped
+	ObjectId uint32
+
+	Size int64
+
+	Status FileResultStatus
+
+	// nil unless the file failed
+	Err error
+
+	// machine-readable code of [Err]
+	ErrorCode string
+
+	// time spent on the file
+	Duration time.Duration
+
+	// chunks of the file retried after a failure
+	Retries int64
+
+	// the download was served from the content cache, see [Init.EnableContentCache]
+	Cached bool
+}
+
+type TransferBreakdown struct {
+	mu sync.Mutex
+
+	// keyed by the lowercase extension without the dot, "" for the files without an extension
+	ByExtension map[string]*TransferCounts
+
+	ByCategory map[FileCategory]*TransferCounts
+}
+
+type TransferCounts struct {
+	Transferred     int64
+	TransferredSize int64
+
+	// files left out by the disallowed files policy, see [SetDisallowedFilesPolicy]
+	Skipped     int64
+	SkippedSize int64
+
+	Failed     int64
+	FailedSize int64
 }
 
 type SizeProgressCb func(total, sent int64, objectId uint32, err error) error
@@ -90,14 +560,31 @@ type LocalPreprocessCb func(fi *os.FileInfo, fullPath string, err error) error
 
 type MtpPreprocessCb func(fi *FileInfo, err error) error
 
+// return true to select the object
+type FileFilterCb func(fi *FileInfo) bool
+
+type DeleteProgressCb func(fi *FileInfo, totalDeleted int64, err error) error
+
 type FileProp struct {
 	ObjectId uint32
 	FullPath string
 }
 
+type processUploadFilesProps struct {
+	sourceFilePath, destinationFileParentPath, destinationFilePath string
+	fileParentId                                                   uint32
+	bulkFilesSent, bulkSizeSent, totalFiles, totalSize             int64
+
+	// the error belongs to the file alone and was recorded in the summary, the transfer can move on to the next file
+	fileFailed bool
+}
+
 type processDownloadFilesProps struct {
 	destinationFileParentPath, destinationFilePath, sourceParentPath string
 	bulkFilesSent, bulkSizeSent, totalFiles, totalSize               int64
+
+	// the error belongs to the file alone and was recorded in the summary, the transfer can move on to the next file
+	fileFailed bool
 }
 
 type downloadFilesObjectCache map[string]downloadFilesObjectCacheContainer
@@ -107,7 +594,749 @@ type downloadFilesObjectCacheContainer struct {
 	destinationFileParentPath, destinationFilePath, sourceParentPath string
 }
 
+type DeleteOptions struct {
+	// return a [FileNotFoundError] if an object does not exist
+	// and a [DeleteRefusedError] if the device refuses to delete an object (eg: write-protected object)
+	// if false then the missing objects are skipped silently
+	Strict bool
+
+	// clear the protection status of write-protected objects before deleting them
+	// if false then a [FileProtectedError] is returned for the write-protected objects
+	// note: not every device allows changing the protection status
+	Force bool
+}
+
+// values of the properties of an object keyed by the property code (see mtp.OPC_*), see [GetPropertiesBulk]
+// the integers are decoded into the Go integer of the same size, the 128 bit integers into [2]uint64 (low, high),
+// the dates into time.Time, the other strings into string and the arrays into []interface{}
+type ObjectProps map[uint16]interface{}
+
+// changes applied by [UpdateObjectInfo], the nil fields are left unchanged
+type ObjectInfoChanges struct {
+	// new name of the object
+	Name *string
+
+	// new modification date of the object
+	ModTime *time.Time
+
+	// set or clear the MTP Hidden property
+	Hidden *bool
+
+	// make the object read-only or writable, see [FileInfo.WriteProtected]
+	WriteProtected *bool
+}
+
+type timeValue struct {
+	Value time.Time
+}
+
+type uint16Value struct {
+	Value uint16
+}
+
+type uint32Value struct {
+	Value uint32
+}
+
+type uint128Value struct {
+	Lo uint64
+	Hi uint64
+}
+
 type FileExistsContainer struct {
 	Exists   bool
 	FileInfo *FileInfo
 }
+
+type DeviceRecord struct {
+	// serial number of the device as reported by the MTP DeviceInfo
+	Serial string
+
+	// user defined alias for the device
+	Alias string
+
+	Manufacturer string
+	Model        string
+
+	// storage descriptions seen the last time the device was connected
+	Storages []string
+
+	// most recent time the device was remembered
+	LastConnected time.Time
+
+	// state of the incremental imports keyed by the storage and the folder they are made from, see [FetchImportWatermark]
+	Imports map[string]ImportWatermark `json:",omitempty"`
+}
+
+type ImportWatermark struct {
+	// storage of the device the files are imported from
+	StorageId uint32
+
+	// folder of the storage the files are imported from, eg: "/DCIM/Camera"
+	Folder string
+
+	// modification date of the newest file imported so far
+	LastImportTime time.Time
+
+	// [FileInfo.PersistentUid] of the newest file imported so far, empty if the persistent uids are off (see [SetPersistentUids])
+	LastSeenObjectUID string
+}
+
+type deviceStoreContainer struct {
+	Devices map[string]DeviceRecord
+}
+
+type WatchEvent struct {
+	Type     WatchEventType
+	ObjectId uint32
+
+	// for [ObjectRemoved] events this is the last known information of the object
+	FileInfo *FileInfo
+}
+
+type WatchCb func(events []WatchEvent, err error) error
+
+type WatchOptions struct {
+	// watch the whole nested tree
+	Recursive bool
+
+	// time between two snapshots
+	// if the value is 0 then [defaultWatchInterval] is used
+	Interval time.Duration
+
+	// events of an object are held back until the object stays unchanged for [DebounceWindow]
+	// the events received within the window are consolidated into a single event
+	// note: the window is checked once every [Interval]. if the value is 0 then the events are delivered right away
+	DebounceWindow time.Duration
+}
+
+type watchSnapshot map[uint32]*FileInfo
+
+type pendingWatchEvent struct {
+	event    WatchEvent
+	lastSeen time.Time
+}
+
+type objectCacheKey struct {
+	storageId, parentId uint32
+}
+
+type objectCache struct {
+	sync.Mutex
+
+	config CacheConfig
+
+	// children of the directories keyed by storageId and parentId
+	// the values are the elements of [lru] holding an [*objectCacheEntry]
+	listings map[objectCacheKey]*list.Element
+
+	// most recently used listing is at the front
+	lru *list.List
+
+	stats CacheStats
+}
+
+type cachedFileInfo struct {
+	key      objectCacheKey
+	fileInfo FileInfo
+}
+
+type objectCacheEntry struct {
+	key      objectCacheKey
+	listing  []FileInfo
+	storedAt time.Time
+
+	// positions of the objects in [listing] keyed by the [filenameKey] of their names
+	// so that the paths are resolved without going through the whole listing
+	names map[string][]int
+
+	// approximate memory used by the [listing] (in bytes)
+	size int64
+}
+
+// counts the bytes written to the underlying writer
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+// writes a downloaded file leaving the blocks which are all zeroes as holes, so that the device images and the
+// preallocated media files don't take up the disk space for their empty parts
+// the filesystems which don't support the sparse files fill the holes with zeroes. call [finish] once the file is written
+type sparseWriter struct {
+	f *os.File
+
+	// logical size of the file written so far, including the trailing hole
+	offset int64
+
+	// the file offset lags behind [offset] while a hole is pending
+	pendingHole bool
+}
+
+// splits the stream of a file into chunks of [TransferChunkSize] bytes for the hashers, see [HashFiles]
+type hashChunkWriter struct {
+	dev    *mtp.Device
+	chunks chan<- *[]byte
+	buf    *[]byte
+	n      int
+}
+
+// reads a file of the device using the partial reads, see [NewObjectReader]
+type ObjectReader struct {
+	dev      *mtp.Device
+	objectId uint32
+	size     int64
+	offset   int64
+
+	// the reads and the seeks may come from several goroutines, eg: a mount
+	mu sync.Mutex
+
+	// blocks of the file read from the device, the players and the mounts ask for small pieces at a time
+	cache *objectBlockCache
+
+	// drops the in-use mark of the object, set for the readers of [NewFileReader]
+	release func()
+}
+
+// an open file of the device, see [OpenFile]
+// the writes are held in a local buffer and the whole file is sent to the device by [Flush] or [Close],
+// as the objects can't be modified in place
+type File struct {
+	dev       *mtp.Device
+	storageId uint32
+	parentId  uint32
+	name      string
+	fullPath  string
+
+	writable   bool
+	appendOnly bool
+
+	mu sync.Mutex
+
+	// information of the file on the device, nil until the file is created
+	fi *FileInfo
+
+	// reads the unmodified file using the partial reads
+	reader *ObjectReader
+
+	// content of the file once it was written to (or read on a device without the partial reads), nil otherwise
+	buf *writeBuffer
+
+	// drops the in-use mark of the object [fi], see [IsObjectInUse]
+	release func()
+
+	// the buffer holds changes which are not on the device yet
+	dirty bool
+
+	offset int64
+	closed bool
+}
+
+// write buffer of a [File], it is kept in memory up to [spillSize] bytes and moved to a local temp file beyond it
+type writeBuffer struct {
+	spillSize int64
+	size      int64
+
+	// content of the buffer until it is spilled, its length is [size]
+	mem []byte
+
+	// temp file holding the content once the buffer is spilled
+	f *os.File
+}
+
+// context of an operation running under [runWithContext]
+type operationContext struct {
+	ctx context.Context
+
+	// the metadata cache is not read while the operation runs, see [WithoutCache]
+	noCache bool
+}
+
+// key of the [WithoutCache] flag in the contexts
+type noCacheContextKey struct{}
+
+// streams a file of a device which doesn't support the partial reads, see [NewFileReader]
+// the file is read in a single transaction which runs until the whole file is sent by the device
+
+type pipedObjectReader struct {
+	pr   *io.PipeReader
+	done chan struct{}
+
+	// drops the in-use mark of the object
+	release func()
+
+	// ends the operation registered by [NewFileReader], see [beginOperation]
+	end func(err error)
+
+	// error of the transaction, set once [done] is closed
+	err error
+}
+
+// writes to [w] until the reading end of the pipe is closed, the rest is thrown away
+type drainingWriter struct {
+	w        io.Writer
+	draining bool
+}
+
+// writes a new file to the device as it is being written to, see [NewFileWriter]
+type ObjectWriter struct {
+	pw *io.PipeWriter
+
+	// size of the file, given beforehand as mtp needs it before the transfer starts
+	size    int64
+	written int64
+
+	// closed once the upload is over, [objectId] and [err] are set by then
+	done     chan struct{}
+	objectId uint32
+	err      error
+}
+
+type MediaServerConfig struct {
+	// name of the server shown by the players
+	// if empty then the manufacturer and the model of the device are used
+	FriendlyName string
+
+	// storage whose folders are served
+	// if the value is 0 then the first storage of the device is used
+	StorageId uint32
+
+	// folders listed at the top level of the server
+	// if empty then [DefaultMediaFolders] is used. the folders which don't exist on the device are left out
+	Folders []string
+
+	// address of the http server, eg: ":8200"
+	// if empty then a random port is used
+	Addr string
+
+	// time between the announcements of the server on the network
+	// if the value is 0 then [defaultSsdpNotifyInterval] is used
+	NotifyInterval time.Duration
+}
+
+// DLNA media server in front of a device, see [StartMediaServer]
+type MediaServer struct {
+	dev       *mtp.Device
+	storageId uint32
+	config    MediaServerConfig
+
+	// unique device name of the server, eg: "uuid:..."
+	udn string
+
+	listener net.Listener
+	server   *http.Server
+	ssdpConn *net.UDPConn
+
+	mu sync.Mutex
+
+	// folders listed at the top level
+	folders []*FileInfo
+
+	// device paths of the objects handed out to the players, keyed by their objectIds
+	paths map[uint32]string
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+type HashOptions struct {
+	// if empty then [HashSha256] is used
+	Algorithm HashAlgorithm
+
+	// number of the goroutines which hash the files
+	// if the value is 0 then the number of CPUs is used
+	Workers int
+}
+
+type FileHash struct {
+	FileInfo *FileInfo
+
+	Algorithm HashAlgorithm
+
+	// hex encoded digest of the contents of the file
+	Sum string
+
+	// the file could not be read, [Sum] is empty
+	Err error
+}
+
+type HashCb func(fh *FileHash) error
+
+type FsckOptions struct {
+	// move the orphan objects to [lostAndFoundPath]
+	// if false then the storage is only scanned and nothing is changed
+	Repair bool
+}
+
+type FsckIssue struct {
+	Type     FsckIssueType
+	ObjectId uint32
+	ParentId uint32
+	Name     string
+	Detail   string
+}
+
+type FsckReport struct {
+	// total number of objects scanned
+	Checked int
+
+	Issues []FsckIssue
+
+	// objectIds of the orphans which were moved to [lostAndFoundPath]
+	Repaired []uint32
+}
+
+// minimal object information required by [FsckStorage]
+type fsckObject struct {
+	objectId, parentId uint32
+	name               string
+}
+
+// writes into a fixed size buffer without allocating
+type bufferWriter struct {
+	buf []byte
+	n   int
+}
+
+// how the uploads and the copies of a device deal with the existing files, see [SetConflictPolicy]
+type conflictSettings struct {
+	policy ConflictPolicy
+
+	// template of the keep-both names, the default is used if empty
+	template string
+}
+
+// adapts the chunk size of the partial transfers to the observed throughput
+type chunkSizer struct {
+	// current chunk size
+	size int64
+
+	// throughput of the last chunk (bytes per second)
+	lastRate float64
+
+	// consecutive failed chunks
+	failures int
+}
+
+// statistics of all the transfers made since the device was initialized
+type TransferStats struct {
+	// time when the statistics were started or reset
+	StartTime time.Time
+
+	// total bytes received from the device
+	BytesIn int64
+
+	// total bytes sent to the device
+	BytesOut int64
+
+	FilesDownloaded int64
+
+	FilesUploaded int64
+
+	// time spent transferring the data
+	ActiveTime time.Duration
+
+	// average transfer rate over the [ActiveTime] (in MB/s)
+	AverageSpeed float64
+
+	// highest transfer rate observed (in MB/s)
+	PeakSpeed float64
+
+	// total chunks retried after a failure
+	Retries int64
+
+	// total failed transfers
+	Errors int64
+
+	// total stalled transfers which the session with the device was reopened for
+	StallRecoveries int64
+}
+
+// describes an operation passed through the middlewares
+type OperationInfo struct {
+	Type OperationType
+
+	// the operation changes the contents of the device
+	Mutating bool
+
+	StorageId uint32
+
+	// objects on the device which the operation works on
+	FileProps []FileProp
+
+	// local sources of an upload or device sources of a download
+	Sources []string
+
+	// destination directory of an upload or a download
+	Destination string
+
+	// new filename of a rename
+	NewFileName string
+
+	// size of the data, if known beforehand
+	Size int64
+}
+
+// wraps an operation
+// call [next] to run the operation (and the remaining middlewares), return an error without calling [next] to block it
+type Middleware func(op *OperationInfo, next func() error) error
+
+// record of a mutating operation, see [NewAuditMiddleware]
+type AuditRecord struct {
+	Time time.Time
+
+	Operation OperationType
+
+	StorageId uint32
+
+	FileProps   []FileProp `json:",omitempty"`
+	Sources     []string   `json:",omitempty"`
+	Destination string     `json:",omitempty"`
+	NewFileName string     `json:",omitempty"`
+	Size        int64      `json:",omitempty"`
+
+	// true if the operation completed without an error
+	Success bool
+
+	// error returned by the operation
+	Error string `json:",omitempty"`
+
+	Duration time.Duration
+}
+
+type AuditCb func(record *AuditRecord) error
+
+// describes what a mutating operation would have done, see [Init.Simulate]
+type SimulatedOperation struct {
+	Operation *OperationInfo
+
+	// existing objects on the device which the operation would touch
+	// the objects which do not exist yet (eg: the directory of a [MakeDirectory]) are not listed
+	Objects []*FileInfo
+
+	// total files which would be affected (nested files of a directory included)
+	TotalFiles int64
+
+	// total size of the affected files (in bytes)
+	TotalSize int64
+}
+
+type SimulateCb func(so *SimulatedOperation) error
+
+type Heartbeat struct {
+	// the outermost running operation
+	Operation *OperationInfo
+
+	StartTime time.Time
+
+	// time elapsed since the operation last made progress (eg: an object was listed, a chunk was transferred)
+	Idle time.Duration
+
+	// the operation made no progress for [HeartbeatConfig.StallTimeout]
+	Stalled bool
+}
+
+type HeartbeatCb func(hb *Heartbeat)
+
+type StorageSpaceChange struct {
+	StorageId uint32
+
+	// free space of the storage before and after the change (in bytes)
+	OldFreeSpace uint64
+	NewFreeSpace uint64
+
+	// total capacity of the storage (in bytes)
+	Capacity uint64
+}
+
+type StorageSpaceCb func(change *StorageSpaceChange)
+
+// [err] is the error of the operation which found the device gone
+type DisconnectCb func(err error)
+
+// known quirks and layout of a family of devices (eg: GPS watches, voice recorders, e-readers)
+// the profile is picked by the usb ids of the device when it is initialized, see [RegisterDeviceProfile]
+type DeviceProfile struct {
+	Name string
+
+	// usb vendor id of the devices
+	VendorId uint16
+
+	// usb product ids of the devices. if empty then the profile covers all the devices of the vendor
+	ProductIds []uint16
+
+	// reported by [FetchStorages] for the storages which don't describe themselves
+	StorageName string
+
+	// operations which the devices can't carry out; they are refused with an [UnsupportedOperationError]
+	UnsupportedOps []OperationType
+
+	// see [Tracks]
+	Tracks ProfileContent
+
+	// see [Recordings]
+	Recordings ProfileContent
+
+	// see [Books]
+	Books ProfileContent
+}
+
+type SendBookOptions struct {
+	// storage to send the book to. if 0 then the first writable storage of the device is used
+	StorageId uint32
+
+	// directory on the device to send the book to
+	// if empty then the books directory of the device profile is used, or [defaultBooksPath] if the device has no profile
+	Destination string
+
+	// replace another file of the same name in the destination directory
+	// the books of the same title and size are duplicates and are never replaced
+	Overwrite bool
+
+	// optional, receives the progress of the transfer
+	ProgressCb SizeProgressCb
+}
+
+type InstallSoundOptions struct {
+	// storage to install the sound on. if 0 then the first writable storage of the device is used
+	StorageId uint32
+
+	// name of the sound file on the device. if empty then the name of the local file is used
+	FileName string
+
+	// title shown by the sound pickers of the device
+	Title string
+
+	// cut the sound down before it is sent. only the PCM WAV files can be trimmed
+	Trim *SoundTrim
+}
+
+type SoundTrim struct {
+	Start time.Duration
+
+	// if the value is 0 then the sound is kept until its end
+	End time.Duration
+}
+
+// where a kind of content is kept on the device
+type ProfileContent struct {
+	// directories which are searched recursively
+	Paths []string
+
+	// extensions of the files, matched case-insensitively. if empty then all the files are listed
+	Extensions []string
+}
+
+// a file or directory which is copied by a [CopyPlan]
+type CopyPlanItem struct {
+	// full path of the source; a local path for the uploads, a device path for the downloads
+	Source string
+
+	// full path where the item is copied to
+	Destination string
+
+	IsDir   bool
+	Size    int64
+	ModTime time.Time
+
+	// the destination already exists and will be overwritten
+	Conflict bool
+
+	// size of the existing destination file. set for the conflicts only
+	ExistingSize int64
+
+	// source object on the device. set for the downloads only
+	Object *FileInfo
+
+	// items which are copied or skipped as a unit, eg: a RAW file with its JPEG and XMP sidecar
+	// set to the [Source] of the primary item for all the items of the group, empty if the item is not grouped
+	Group string
+
+	GroupKind CopyPlanGroupKind
+
+	// the item is the primary one of its group, eg: the RAW file of a pair or the cover frame of a burst
+	Primary bool
+}
+
+// describes a recursive copy before anything is transferred, see [PlanUpload] and [PlanDownload]
+// the plan can be inspected and narrowed down using [CopyPlan.Filter] before it is handed to [ExecuteCopyPlan]
+type CopyPlan struct {
+	Direction   TransferDirection
+	StorageId   uint32
+	Sources     []string
+	Destination string
+
+	// files and directories in the order in which they are copied
+	Items []*CopyPlanItem
+
+	TotalFiles       int64
+	TotalDirectories int64
+
+	// total size of the files
+	TotalSize int64
+
+	// items whose destination already exists
+	Conflicts []*CopyPlanItem
+
+	// bytes needed at the destination; the files which are overwritten give back their space
+	RequiredSpace int64
+
+	// free space at the destination, see [FreeSpaceKnown]
+	FreeSpace uint64
+
+	// the free space of the destination could be determined, a [FreeSpace] of 0 is a full destination then
+	FreeSpaceKnown bool
+
+	// the destination doesn't have enough free space for [RequiredSpace]
+	InsufficientSpace bool
+}
+
+// decides what happens to the junk files (eg: .DS_Store, Thumbs.db) during the uploads and downloads
+// see [SetDisallowedFilesPolicy]
+type DisallowedFilesPolicy struct {
+	// names of the disallowed files, matched exactly
+	Names []string
+
+	Action DisallowedFilesAction
+
+	// receives the files skipped by [DisallowedFilesWarn]
+	// if nil then the skipped files are logged
+	WarnCb DisallowedFileCb
+}
+
+type DisallowedFileCb func(fullPath string)
+
+type DiagnosticsOptions struct {
+	// storage to run the benchmark on. if 0 then the first writable storage is used
+	StorageId uint32
+
+	// size of the temporary object which is written and read back
+	// if the value is 0 then [defaultBenchmarkSize] is used, if it is negative then the benchmark is skipped
+	BenchmarkSize int64
+}
+
+type DiagnosticsReport struct {
+	// negotiated speed of the usb link
+	Speed UsbSpeed
+
+	// signalling rate of [Speed] (in MB/s), the transfers never get close to it
+	LinkSpeed float64
+
+	// the device is connected at a usb 1.x speed, usually a charge-only cable, a hub or a port which can't do better
+	LinkLimited bool
+
+	// maximum packet sizes of the bulk endpoints of the mtp interface
+	MaxPacketSizeIn  int
+	MaxPacketSizeOut int
+
+	// size of the benchmark object, 0 if the benchmark was skipped
+	BenchmarkSize int64
+
+	WriteDuration time.Duration
+	ReadDuration  time.Duration
+
+	// transfer rates of the benchmark (in MB/s)
+	WriteSpeed float64
+	ReadSpeed  float64
+}
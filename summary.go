@@ -0,0 +1,113 @@
+package mtpx
+
+import (
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"strings"
+	"time"
+)
+
+func newOperationSummary(opType OperationType, storageId uint32, opts transferOptions) *OperationSummary {
+	return &OperationSummary{Type: opType, StorageId: storageId, StartTime: time.Now(), options: opts}
+}
+
+// add the result of a file to the summary
+func (s *OperationSummary) add(r *FileResult) {
+	if s == nil {
+		return
+	}
+
+	if r.Err != nil {
+		r.ErrorCode = errorCode(r.Err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Files = append(s.Files, r)
+
+	switch r.Status {
+	case FileTransferred:
+		s.Transferred += 1
+	case FileSkipped:
+		s.Skipped += 1
+	case FileFailed:
+		s.Failed += 1
+	}
+}
+
+// close the summary with the outcome of the operation
+func (s *OperationSummary) finish(err error) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.EndTime = time.Now()
+	s.Err = err
+	s.ErrorCode = ""
+	if err != nil {
+		s.ErrorCode = errorCode(err)
+	}
+	s.mu.Unlock()
+}
+
+// results of the files which failed
+func (s *OperationSummary) Failures() []*FileResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var failures []*FileResult
+	for _, r := range s.Files {
+		if r.Status == FileFailed {
+			failures = append(failures, r)
+		}
+	}
+
+	return failures
+}
+
+// sources of the files which failed, to be passed back to the operation to retry them
+func (s *OperationSummary) FailedSources() []string {
+	var sources []string
+	for _, r := range s.Failures() {
+		sources = append(sources, r.Source)
+	}
+
+	return sources
+}
+
+// machine-readable code of the error, derived from its type. eg: "QuotaExceeded" for a [QuotaExceededError]
+// the errors which are not typed by this package are reported as "Unknown"
+func errorCode(err error) string {
+	name := fmt.Sprintf("%T", err)
+	if !strings.HasPrefix(name, "mtpx.") {
+		return "Unknown"
+	}
+
+	return strings.TrimSuffix(strings.TrimPrefix(name, "mtpx."), "Error")
+}
+
+// record the outcome of a file of a bulk transfer in the breakdown and the summary of the transfer
+func (p *ProgressInfo) recordFile(r *FileResult, name string, category FileCategory) {
+	var outcome transferOutcome
+	switch r.Status {
+	case FileSkipped:
+		outcome = transferOutcomeSkipped
+	case FileFailed:
+		outcome = transferOutcomeFailed
+	default:
+		outcome = transferOutcomeTransferred
+	}
+
+	p.Breakdown.record(name, category, r.Size, outcome)
+	p.Summary.add(r)
+}
+
+// total chunks retried on the device so far, used to work out the retries of a single file
+func transferRetries(dev *mtp.Device) int64 {
+	deviceTransferStats.Lock()
+	defer deviceTransferStats.Unlock()
+
+	return getTransferStats(dev).Retries
+}
@@ -0,0 +1,39 @@
+package mtpx
+
+import (
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestOperationSummary(t *testing.T) {
+	Convey("Test OperationSummary", t, func() {
+		pInfo := &ProgressInfo{Breakdown: newTransferBreakdown(), Summary: newOperationSummary(UploadFilesOp, 1, transferOptions{})}
+
+		pInfo.recordFile(&FileResult{Source: "/tmp/a.jpg", Size: 10, Status: FileTransferred}, "a.jpg", CategoryImage)
+		pInfo.recordFile(&FileResult{Source: "/tmp/.DS_Store", Size: 1, Status: FileSkipped}, ".DS_Store", CategoryOther)
+		pInfo.recordFile(&FileResult{
+			Source: "/tmp/b.jpg", Size: 20, Status: FileFailed,
+			Err: QuotaExceededError{error: fmt.Errorf("quota exceeded")},
+		}, "b.jpg", CategoryImage)
+
+		s := pInfo.Summary
+		So(s.Transferred, ShouldEqual, 1)
+		So(s.Skipped, ShouldEqual, 1)
+		So(s.Failed, ShouldEqual, 1)
+		So(s.Files[2].ErrorCode, ShouldEqual, "QuotaExceeded")
+		So(s.FailedSources(), ShouldResemble, []string{"/tmp/b.jpg"})
+
+		So(pInfo.Breakdown.ByCategory[CategoryImage].Failed, ShouldEqual, 1)
+
+		s.finish(InvalidPathError{error: fmt.Errorf("path not found")})
+
+		So(s.ErrorCode, ShouldEqual, "InvalidPath")
+		So(s.EndTime.IsZero(), ShouldBeFalse)
+	})
+
+	Convey("Test errorCode", t, func() {
+		So(errorCode(FileNotFoundError{error: fmt.Errorf("missing")}), ShouldEqual, "FileNotFound")
+		So(errorCode(fmt.Errorf("plain")), ShouldEqual, "Unknown")
+	})
+}
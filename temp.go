@@ -0,0 +1,129 @@
+package mtpx
+
+import (
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/ganeshrvel/go-mtpx/devicepath"
+	"io"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// sequence of the temp object names created by this process
+var tempObjectSeq uint64
+
+// Create a temporary object in the temp directory of the storage ([tempDirectoryPath])
+// meant for the staging of the uploads which are moved into place once they are complete, and for the benchmarks
+// the name is made unique by replacing the last "*" in [pattern] with a random string, or by appending it if [pattern] has no "*"
+// the temp objects are deleted by [CleanupTemp], the ones left behind by a crashed run are deleted when the device is initialized
+// again once they are older than [staleTempObjectAge]
+// return:
+// [objectId]: objectId of the temp object
+// [fullPath]: full path of the temp object
+func CreateTempObject(dev *mtp.Device, storageId uint32, pattern string, r io.Reader, size int64) (objectId uint32, fullPath string, err error) {
+	fullPath = devicepath.Join(tempDirectoryPath, tempObjectName(pattern))
+
+	op := &OperationInfo{Type: WriteFileOp, Mutating: true, StorageId: storageId, FileProps: []FileProp{{0, fullPath}}, Size: size}
+
+	err = runMiddlewares(dev, op, func() error {
+		objectId, err = createTempObject(dev, storageId, fullPath, r, size)
+
+		return err
+	})
+
+	return objectId, fullPath, err
+}
+
+// helper function for [CreateTempObject]
+func createTempObject(dev *mtp.Device, storageId uint32, fullPath string, r io.Reader, size int64) (objectId uint32, err error) {
+	if err := checkStorageWritable(dev, storageId, false); err != nil {
+		return 0, err
+	}
+
+	parentId, err := makeNestedDirectory(dev, storageId, tempDirectoryPath)
+	if err != nil {
+		return 0, err
+	}
+
+	_, name := devicepath.Split(fullPath)
+
+	startTime := time.Now()
+
+	objectId, err = sendObject(dev, storageId, parentId, name, mtp.OFC_Undefined, time.Now(), r, size, true,
+		func(total, sent int64, objectId uint32, err error) error {
+			return err
+		})
+	if err != nil {
+		recordTransferError(dev)
+
+		return objectId, err
+	}
+
+	recordTransferredBytes(dev, Upload, size, time.Since(startTime))
+	recordTransferredFile(dev, Upload)
+
+	return objectId, nil
+}
+
+// Delete the temp directory of the storage along with all the temp objects in it
+func CleanupTemp(dev *mtp.Device, storageId uint32) error {
+	return DeleteFileWithOptions(dev, storageId, []FileProp{{0, tempDirectoryPath}}, DeleteOptions{Force: true})
+}
+
+// delete the temp objects of the writable storages which are older than [staleTempObjectAge]
+// the errors are ignored, a storage which can't be cleaned up now will be on the next connect
+func cleanupStaleTempObjects(dev *mtp.Device) {
+	storages, err := FetchStorages(dev)
+	if err != nil {
+		return
+	}
+
+	before := time.Now().Add(-staleTempObjectAge)
+
+	for _, s := range storages {
+		if s.ReadOnly {
+			continue
+		}
+
+		dir, err := GetObjectFromPath(dev, s.Sid, tempDirectoryPath)
+		if err != nil || !dir.IsDir {
+			continue
+		}
+
+		children, err := listDirectory(dev, s.Sid, dir.ObjectId, tempDirectoryPath)
+		if err != nil {
+			continue
+		}
+
+		if stale := staleTempObjects(children, before); len(stale) > 0 {
+			_ = DeleteFileWithOptions(dev, s.Sid, stale, DeleteOptions{Force: true})
+		}
+	}
+}
+
+// the temp objects in [children] which were last modified before [before]
+// the objects without a modification date are kept, their age is unknown
+func staleTempObjects(children []*FileInfo, before time.Time) []FileProp {
+	var stale []FileProp
+	for _, fi := range children {
+		if fi.ModTime.IsZero() || !fi.ModTime.Before(before) {
+			continue
+		}
+
+		stale = append(stale, FileProp{ObjectId: fi.ObjectId})
+	}
+
+	return stale
+}
+
+// unique name for a temp object, see [CreateTempObject]
+func tempObjectName(pattern string) string {
+	random := fmt.Sprintf("%x%x", time.Now().UnixNano(), atomic.AddUint64(&tempObjectSeq, 1))
+
+	if i := strings.LastIndex(pattern, "*"); i > -1 {
+		return pattern[:i] + random + pattern[i+1:]
+	}
+
+	return pattern + random
+}
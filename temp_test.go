@@ -0,0 +1,36 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTempObjectName(t *testing.T) {
+	Convey("Test tempObjectName", t, func() {
+		name := tempObjectName("upload-*.part")
+		So(name, ShouldStartWith, "upload-")
+		So(name, ShouldEndWith, ".part")
+		So(strings.Contains(name, "*"), ShouldBeFalse)
+
+		So(tempObjectName("stage-"), ShouldStartWith, "stage-")
+		So(tempObjectName("a*"), ShouldNotEqual, tempObjectName("a*"))
+	})
+}
+
+func TestStaleTempObjects(t *testing.T) {
+	Convey("Test staleTempObjects", t, func() {
+		now := time.Now()
+		children := []*FileInfo{
+			{ObjectId: 1, ModTime: now.Add(-2 * staleTempObjectAge)},
+			{ObjectId: 2, ModTime: now},
+			{ObjectId: 3},
+		}
+
+		stale := staleTempObjects(children, now.Add(-staleTempObjectAge))
+		So(stale, ShouldResemble, []FileProp{{ObjectId: 1}})
+
+		So(staleTempObjects(nil, now), ShouldBeEmpty)
+	})
+}
@@ -0,0 +1,342 @@
+package mtpx
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var deviceTranscripts = struct {
+	sync.Mutex
+	m map[*mtp.Device]*transcript
+
+	// output of the standard logger before the transcripts took it over
+	logOutput io.Writer
+}{m: map[*mtp.Device]*transcript{}}
+
+type transcript struct {
+	mu sync.Mutex
+
+	w      io.Writer
+	closer io.Closer
+	redact bool
+
+	startTime time.Time
+
+	// the request waiting for its response, used to time the transactions
+	pendingStart time.Time
+
+	// the transcript turned on the debug mode of the device, see [TranscriptConfig.EnableDebugMode]
+	enabledDebug bool
+
+	// debug flag of the device before the transcript was started, restored by [StopTranscript]
+	prevMTPDebug bool
+}
+
+// start recording the protocol transactions of the device: the operation codes, their parameters, the response codes and their timings
+// the operations of this package are recorded too so that the transactions can be told apart
+// the data phases are reported by their size and the decoded payloads are truncated, see [transcriptPayloadLimit]
+// use [config.Redact] to attach the transcript to a public bug report
+// the transactions are only reported while the device is in the debug mode, see [config.EnableDebugMode] and [Init.DebugMode]
+// note: go-mtpfs reports the transactions through the standard logger without telling the devices apart, the output of the
+// standard logger is teed into the transcripts until the last one is stopped. the lines still reach the previous output
+// and the transactions of the other devices in the debug mode end up in the transcript too
+func StartTranscript(dev *mtp.Device, config TranscriptConfig) error {
+	w := config.Writer
+	var closer io.Closer
+
+	if config.Path != "" {
+		f, err := os.Create(config.Path)
+		if err != nil {
+			return LocalFileError{error: err}
+		}
+
+		w, closer = f, f
+	}
+
+	if w == nil {
+		return LocalFileError{error: fmt.Errorf("the transcript has no destination")}
+	}
+
+	_ = StopTranscript(dev)
+
+	t := &transcript{w: w, closer: closer, redact: config.Redact, startTime: time.Now(),
+		enabledDebug: config.EnableDebugMode, prevMTPDebug: dev.MTPDebug}
+	t.writeHeader(dev)
+
+	attachTranscript(dev, t)
+
+	if config.EnableDebugMode {
+		dev.MTPDebug = true
+	}
+
+	return nil
+}
+
+// stop recording the protocol transactions of the device and close the transcript file
+func StopTranscript(dev *mtp.Device) error {
+	t := detachTranscript(dev)
+	if t == nil {
+		return nil
+	}
+
+	if t.enabledDebug {
+		dev.MTPDebug = t.prevMTPDebug
+	}
+
+	t.printf("# transcript stopped\n")
+
+	if t.closer != nil {
+		if err := t.closer.Close(); err != nil {
+			return LocalFileError{error: err}
+		}
+	}
+
+	return nil
+}
+
+// register the transcript and tee the output of the standard logger into it
+func attachTranscript(dev *mtp.Device, t *transcript) {
+	deviceTranscripts.Lock()
+	defer deviceTranscripts.Unlock()
+
+	if len(deviceTranscripts.m) < 1 {
+		deviceTranscripts.logOutput = log.Writer()
+		log.SetOutput(&transcriptLogWriter{out: deviceTranscripts.logOutput})
+	}
+
+	deviceTranscripts.m[dev] = t
+}
+
+// unregister the transcript of the device and hand the standard logger back once no transcripts are left
+func detachTranscript(dev *mtp.Device) *transcript {
+	deviceTranscripts.Lock()
+	defer deviceTranscripts.Unlock()
+
+	t, ok := deviceTranscripts.m[dev]
+	if !ok {
+		return nil
+	}
+
+	delete(deviceTranscripts.m, dev)
+
+	if len(deviceTranscripts.m) < 1 {
+		// leave the logger alone if the application replaced it in the meantime
+		if _, ok := log.Writer().(*transcriptLogWriter); ok {
+			log.SetOutput(deviceTranscripts.logOutput)
+		}
+
+		deviceTranscripts.logOutput = nil
+	}
+
+	return t
+}
+
+func getTranscript(dev *mtp.Device) *transcript {
+	deviceTranscripts.Lock()
+	defer deviceTranscripts.Unlock()
+
+	return deviceTranscripts.m[dev]
+}
+
+// record the start of an operation of this package
+// returns a function which records its end
+func transcribeOperation(dev *mtp.Device, op *OperationInfo) (done func(err error)) {
+	t := getTranscript(dev)
+	if t == nil {
+		return func(error) {}
+	}
+
+	sources := make([]string, len(op.Sources))
+	for i, s := range op.Sources {
+		sources[i] = t.path(s)
+	}
+
+	t.printf("%s op %s storageId: %d, sources: %v, destination: %s\n",
+		t.elapsed(time.Now()), op.Type, op.StorageId, sources, t.path(op.Destination))
+
+	startTime := time.Now()
+
+	return func(err error) {
+		now := time.Now()
+		took := now.Sub(startTime).Round(time.Millisecond)
+
+		if err == nil {
+			t.printf("%s op %s done in %v\n", t.elapsed(now), op.Type, took)
+
+			return
+		}
+
+		// the error messages carry the file paths
+		if t.redact {
+			t.printf("%s op %s failed in %v: %s\n", t.elapsed(now), op.Type, took, errorCode(err))
+		} else {
+			t.printf("%s op %s failed in %v: %s: %v\n", t.elapsed(now), op.Type, took, errorCode(err), err)
+		}
+	}
+}
+
+// describe the device at the top of the transcript
+func (t *transcript) writeHeader(dev *mtp.Device) {
+	t.printf("# mtpx transcript started at %s\n", t.startTime.Format(time.RFC3339))
+
+	if usbInfo, err := dev.GetUsbInfo(); err == nil {
+		t.printf("# usb: %04x:%04x\n", usbInfo.IdVendor, usbInfo.IdProduct)
+	}
+
+	info, err := FetchDeviceInfo(dev)
+	if err != nil {
+		t.printf("# device info: %v\n", err)
+
+		return
+	}
+
+	serial := info.SerialNumber
+	if t.redact {
+		serial = "<redacted>"
+	}
+
+	t.printf("# device: %s %s, version: %s, serial: %s, mtp extension: %s\n",
+		info.Manufacturer, info.Model, info.DeviceVersion, serial, info.MTPExtension)
+}
+
+// record a protocol line logged by go-mtpfs
+func (t *transcript) record(line string, now time.Time) {
+	kind, msg := parseProtocolLine(line)
+
+	switch kind {
+	case "request":
+		t.mu.Lock()
+		t.pendingStart = now
+		t.mu.Unlock()
+
+		t.printf("%s > %s\n", t.elapsed(now), msg)
+	case "response":
+		t.mu.Lock()
+		took := now.Sub(t.pendingStart)
+		t.mu.Unlock()
+
+		t.printf("%s < %s (%v)\n", t.elapsed(now), msg, took.Round(time.Microsecond))
+	case "data":
+		t.printf("%s   data: %s\n", t.elapsed(now), dataSize(msg))
+	case "payload":
+		if t.redact {
+			t.printf("%s   payload: <elided>\n", t.elapsed(now))
+		} else {
+			t.printf("%s   payload: %s\n", t.elapsed(now), truncate(msg, transcriptPayloadLimit))
+		}
+	case "error", "fatal":
+		t.printf("%s ! %s\n", t.elapsed(now), msg)
+	}
+}
+
+func (t *transcript) printf(format string, a ...interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, _ = fmt.Fprintf(t.w, format, a...)
+}
+
+// time since the start of the transcript, eg: "+1.204s"
+func (t *transcript) elapsed(now time.Time) string {
+	return fmt.Sprintf("+%.3fs", now.Sub(t.startTime).Seconds())
+}
+
+// the path, or a placeholder which stays the same for the same path if the transcript is redacted
+func (t *transcript) path(p string) string {
+	if !t.redact || p == "" {
+		return p
+	}
+
+	sum := sha1.Sum([]byte(p))
+
+	return fmt.Sprintf("<path:%x>", sum[:4])
+}
+
+// prefixes of the lines which go-mtpfs logs for the transactions, see [mtp.Device.MTPDebug]
+var protocolLinePrefixes = []struct {
+	prefix string
+	kind   string
+}{
+	{"MTP request ", "request"},
+	{"MTP response ", "response"},
+	{"MTP data ", "data"},
+	{"MTP discarding unexpected data ", "error"},
+	{"MTP sendreq failed: ", "error"},
+	{"MTP decoded ", "payload"},
+	{"MTP encoded ", "payload"},
+	{"fatal error ", "fatal"},
+}
+
+// split a line of the logger into the kind of the protocol event and its message
+// the kind is empty if the line is not a part of the protocol
+func parseProtocolLine(line string) (kind, msg string) {
+	line = strings.TrimRight(line, "\n")
+
+	for _, p := range protocolLinePrefixes {
+		// skip the date and the time which the logger prefixes the lines with
+		if i := strings.Index(line, p.prefix); i > -1 {
+			if p.kind == "error" || p.kind == "fatal" {
+				return p.kind, line[i:]
+			}
+
+			return p.kind, line[i+len(p.prefix):]
+		}
+	}
+
+	return "", ""
+}
+
+// "0x1c bytes" to "28 bytes"
+func dataSize(msg string) string {
+	fields := strings.Fields(msg)
+	if len(fields) < 1 {
+		return msg
+	}
+
+	size, err := strconv.ParseInt(fields[0], 0, 64)
+	if err != nil {
+		return msg
+	}
+
+	return fmt.Sprintf("%d bytes", size)
+}
+
+func truncate(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+
+	return fmt.Sprintf("%s... (%d bytes truncated)", s[:limit], len(s)-limit)
+}
+
+// output of the standard logger while the transcripts are running
+// the protocol lines are copied to the transcripts and every line is passed on to the original output
+type transcriptLogWriter struct {
+	out io.Writer
+}
+
+func (w *transcriptLogWriter) Write(p []byte) (int, error) {
+	now := time.Now()
+	line := string(p)
+
+	deviceTranscripts.Lock()
+	transcripts := make([]*transcript, 0, len(deviceTranscripts.m))
+	for _, t := range deviceTranscripts.m {
+		transcripts = append(transcripts, t)
+	}
+	deviceTranscripts.Unlock()
+
+	for _, t := range transcripts {
+		t.record(line, now)
+	}
+
+	return w.out.Write(p)
+}
@@ -0,0 +1,64 @@
+package mtpx
+
+import (
+	"bytes"
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"log"
+	"testing"
+	"time"
+)
+
+func TestTranscript(t *testing.T) {
+	Convey("Test parseProtocolLine", t, func() {
+		kind, msg := parseProtocolLine("2021/01/01 10:00:00 MTP request GetObjectHandles [65537 0 4294967295]\n")
+		So(kind, ShouldEqual, "request")
+		So(msg, ShouldEqual, "GetObjectHandles [65537 0 4294967295]")
+
+		kind, msg = parseProtocolLine("MTP response OK []")
+		So(kind, ShouldEqual, "response")
+		So(msg, ShouldEqual, "OK []")
+
+		kind, _ = parseProtocolLine("[disallowed] skipped: /tmp/.DS_Store")
+		So(kind, ShouldEqual, "")
+
+		So(dataSize("0x1c bytes"), ShouldEqual, "28 bytes")
+		So(truncate("abcdef", 3), ShouldEqual, "abc... (3 bytes truncated)")
+	})
+
+	Convey("Test transcript", t, func() {
+		var logOutput, buf bytes.Buffer
+
+		prevOutput := log.Writer()
+		log.SetOutput(&logOutput)
+		defer log.SetOutput(prevOutput)
+
+		// the transcripts are keyed by the device, a nil device is good enough here
+		tr := &transcript{w: &buf, redact: true, startTime: time.Now()}
+		attachTranscript(nil, tr)
+
+		done := transcribeOperation(nil, &OperationInfo{Type: UploadFilesOp, StorageId: 1, Sources: []string{"/tmp/secret.jpg"}, Destination: "/DCIM"})
+		log.Printf("MTP request SendObjectInfo [65537 0]")
+		log.Printf("MTP encoded &mtp.ObjectInfo{Filename:\"secret.jpg\"}")
+		log.Printf("MTP response OK [65537 0 42]")
+		log.Printf("unrelated")
+		done(QuotaExceededError{error: fmt.Errorf("quota exceeded: /tmp/secret.jpg")})
+
+		So(detachTranscript(nil), ShouldEqual, tr)
+		So(getTranscript(nil), ShouldBeNil)
+		So(log.Writer(), ShouldEqual, &logOutput)
+
+		out := buf.String()
+		So(out, ShouldContainSubstring, "> SendObjectInfo [65537 0]")
+		So(out, ShouldContainSubstring, "< OK [65537 0 42]")
+		So(out, ShouldContainSubstring, "payload: <elided>")
+		So(out, ShouldContainSubstring, "failed in")
+		So(out, ShouldContainSubstring, ": QuotaExceeded")
+		So(out, ShouldNotContainSubstring, "secret")
+		So(out, ShouldNotContainSubstring, "unrelated")
+
+		// the lines still reach the previous output of the logger
+		So(logOutput.String(), ShouldContainSubstring, "unrelated")
+		So(logOutput.String(), ShouldContainSubstring, "SendObjectInfo")
+	})
+}
@@ -0,0 +1,57 @@
+package mtpx
+
+import (
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"sync/atomic"
+)
+
+var persistentUids atomic.Value
+
+// whether the persistent unique identifiers of the objects are fetched into [FileInfo.PersistentUid]
+func PersistentUids() bool {
+	if enabled, ok := persistentUids.Load().(bool); ok {
+		return enabled
+	}
+
+	return false
+}
+
+// set whether the persistent unique identifiers of the objects are fetched into [FileInfo.PersistentUid]
+// unlike the objectIds, the identifiers survive the renames, the moves and the reconnects, the UI trees and the sync
+// databases can key on them. it costs an extra request for every object, hence off by default
+// the listings which are already cached are not updated, see [Init.EnableCache]
+func SetPersistentUids(enabled bool) {
+	persistentUids.Store(enabled)
+}
+
+// fetch the persistent unique identifier of the object as a hex string
+// returns an empty string if the device doesn't support it
+func persistentUid(dev *mtp.Device, objectId uint32) string {
+	var uid uint128Value
+	if err := dev.GetObjectPropValue(objectId, mtp.OPC_PersistantUniqueObjectIdentifier, &uid); err != nil {
+		return ""
+	}
+
+	return formatPersistentUid(uid.Hi, uid.Lo)
+}
+
+// the persistent unique identifier out of the property list of the object, see [decodePropValue]
+// returns false if the list doesn't hold it
+func persistentUidFromProps(props ObjectProps) (string, bool) {
+	uid, ok := props[mtp.OPC_PersistantUniqueObjectIdentifier].([2]uint64)
+	if !ok {
+		return "", false
+	}
+
+	return formatPersistentUid(uid[1], uid[0]), true
+}
+
+// the identifier as a hex string, empty for the devices which report 0
+func formatPersistentUid(hi, lo uint64) string {
+	if hi == 0 && lo == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%016x%016x", hi, lo)
+}
@@ -0,0 +1,48 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"log"
+	"testing"
+)
+
+func TestSetPersistentUids(t *testing.T) {
+	Convey("Test SetPersistentUids", t, func() {
+		So(PersistentUids(), ShouldBeFalse)
+
+		SetPersistentUids(true)
+		So(PersistentUids(), ShouldBeTrue)
+
+		SetPersistentUids(false)
+		So(PersistentUids(), ShouldBeFalse)
+	})
+}
+
+func TestPersistentUid(t *testing.T) {
+	dev, err := Initialize(Init{})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	storages, err := FetchStorages(dev)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	sid := storages[0].Sid
+
+	Convey("Testing the persistent uids | GetObjectFromPath", t, func() {
+		fi, err := GetObjectFromPath(dev, sid, "/mtp-test-files/mock_dir1/a.txt")
+		So(err, ShouldBeNil)
+		So(fi.PersistentUid, ShouldBeEmpty)
+
+		SetPersistentUids(true)
+		defer SetPersistentUids(false)
+
+		fi, err = GetObjectFromPath(dev, sid, "/mtp-test-files/mock_dir1/a.txt")
+		So(err, ShouldBeNil)
+		So(fi.PersistentUid, ShouldEqual, persistentUid(dev, fi.ObjectId))
+	})
+
+	Dispose(dev)
+}
@@ -2,6 +2,7 @@ package mtpx
 
 import (
 	"fmt"
+	"github.com/ganeshrvel/go-mtpx/devicepath"
 	. "github.com/smartystreets/goconvey/convey"
 	"log"
 	"math/rand"
@@ -115,7 +116,7 @@ func TestUploadFiles(t *testing.T) {
 		sources := []string{uploadFile1}
 
 		randFName := fmt.Sprintf("%x", rand.Int31())
-		destination := getFullPath("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
+		destination := devicepath.Join("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
 
 		dirList := []string{"/mock_dir1/1/a.txt", "/mock_dir1/2/b.txt", "/mock_dir1/3/2/b.txt", "/mock_dir1/3/b.txt", "/mock_dir1/a.txt"}
 
@@ -239,7 +240,7 @@ func TestUploadFiles(t *testing.T) {
 		sources := []string{uploadFile1, uploadFile2}
 
 		randFName := fmt.Sprintf("%x", rand.Int31())
-		destination := getFullPath("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
+		destination := devicepath.Join("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
 
 		dirList := []string{
 			"/mock_dir1/1/a.txt", "/mock_dir1/2/b.txt", "/mock_dir1/3/2/b.txt", "/mock_dir1/3/b.txt", "/mock_dir1/a.txt",
@@ -376,7 +377,7 @@ func TestUploadFiles(t *testing.T) {
 		sources := []string{uploadFile1, uploadFile2}
 
 		randFName := fmt.Sprintf("%x", rand.Int31())
-		destination := getFullPath("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
+		destination := devicepath.Join("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
 
 		dirList := []string{
 			"/mock_dir1/1/a.txt", "/mock_dir1/2/b.txt", "/mock_dir1/3/2/b.txt", "/mock_dir1/3/b.txt", "/mock_dir1/a.txt",
@@ -499,7 +500,7 @@ func TestUploadFiles(t *testing.T) {
 		sources := []string{uploadFile1}
 
 		randFName := fmt.Sprintf("%x", rand.Int31())
-		destination := getFullPath("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
+		destination := devicepath.Join("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
 
 		dirList := []string{"/a.txt"}
 
@@ -593,7 +594,7 @@ func TestUploadFiles(t *testing.T) {
 		sources := []string{uploadFile1, uploadFile2}
 
 		randFName := fmt.Sprintf("%x", rand.Int31())
-		destination := getFullPath("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
+		destination := devicepath.Join("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
 
 		dirList := []string{"/a.txt", "/b.txt"}
 
@@ -686,7 +687,7 @@ func TestUploadFiles(t *testing.T) {
 		sources := []string{uploadFile1, uploadFile2}
 
 		randFName := fmt.Sprintf("%x", rand.Int31())
-		destination := getFullPath("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
+		destination := devicepath.Join("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
 
 		dirList := []string{"/a.txt", "/a.txt"}
 
@@ -783,7 +784,7 @@ func TestUploadFiles(t *testing.T) {
 		sources := []string{uploadFile1, uploadFile2}
 
 		randFName := fmt.Sprintf("%x", rand.Int31())
-		_destination = getFullPath("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
+		_destination = devicepath.Join("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
 
 		dirList := []string{
 			"/mock_dir1/1/a.txt", "/mock_dir1/2/b.txt", "/mock_dir1/3/2/b.txt", "/mock_dir1/3/b.txt", "/mock_dir1/a.txt", "a.txt",
@@ -973,7 +974,7 @@ func TestUploadFiles(t *testing.T) {
 		uploadFile1 := getTestMocksAsset("4mb_txt_file")
 		sources := []string{uploadFile1}
 		randFName := fmt.Sprintf("%x", rand.Int31())
-		destination := getFullPath("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
+		destination := devicepath.Join("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
 
 		dirList := []string{
 			"4mb_txt_file",
@@ -1100,7 +1101,7 @@ func TestUploadFiles(t *testing.T) {
 		uploadFile2 := getTestMocksAsset("4mb_txt_file_2")
 		sources := []string{uploadFile1, uploadFile2}
 		randFName := fmt.Sprintf("%x", rand.Int31())
-		destination := getFullPath("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
+		destination := devicepath.Join("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
 
 		dirList := []string{
 			"4mb_txt_file",
@@ -1235,7 +1236,7 @@ func TestUploadFiles(t *testing.T) {
 		uploadFile4 := getTestMocksAsset("mock_dir1")
 		sources := []string{uploadFile1, uploadFile2, uploadFile3, uploadFile4}
 		randFName := fmt.Sprintf("%x", rand.Int31())
-		destination := getFullPath("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
+		destination := devicepath.Join("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
 
 		dirList := []string{
 			"4mb_txt_file",
@@ -1384,7 +1385,7 @@ func TestUploadFiles(t *testing.T) {
 		sources := []string{uploadFile1}
 
 		randFName := fmt.Sprintf("%x", rand.Int31())
-		destination := getFullPath("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
+		destination := devicepath.Join("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
 
 		dirList := []string{"/mock_dir1/1/a.txt", "/mock_dir1/2/b.txt", "/mock_dir1/3/2/b.txt", "/mock_dir1/3/b.txt", "/mock_dir1/a.txt"}
 
@@ -1525,7 +1526,7 @@ func TestUploadFiles(t *testing.T) {
 		uploadFile4 := getTestMocksAsset("mock_dir1")
 		sources := []string{uploadFile1, uploadFile2, uploadFile3, uploadFile4}
 		randFName := fmt.Sprintf("%x", rand.Int31())
-		destination := getFullPath("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
+		destination := devicepath.Join("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
 
 		dirList := []string{
 			"4mb_txt_file",
@@ -1684,7 +1685,7 @@ func TestUploadFiles(t *testing.T) {
 		sources := []string{uploadFile1, uploadFile2}
 
 		randFName := fmt.Sprintf("%x", rand.Int31())
-		destination := getFullPath("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
+		destination := devicepath.Join("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
 
 		var prevLatestSentTime int64
 		var prevFilesSent int64
@@ -1768,7 +1769,7 @@ func TestUploadFiles(t *testing.T) {
 		sources := []string{uploadFile1}
 
 		randFName := fmt.Sprintf("%x", rand.Int31())
-		destination := getFullPath("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
+		destination := devicepath.Join("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
 
 		var prevLatestSentTime int64
 		var prevFilesSent int64
@@ -1847,7 +1848,7 @@ func TestUploadFiles(t *testing.T) {
 		sources := []string{uploadFile1}
 
 		randFName := fmt.Sprintf("%x", rand.Int31())
-		destination := getFullPath("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
+		destination := devicepath.Join("/mtp-test-files/temp_dir/test_UploadFiles", randFName)
 
 		var prevLatestSentTime int64
 		var prevFilesSent int64
@@ -1917,5 +1918,93 @@ func TestUploadFiles(t *testing.T) {
 		_, err = GetObjectFromPath(dev, sid, destination)
 		So(err, ShouldBeError)
 	})
+
+	Convey("Re-upload into an existing tree | UploadFiles", t, func() {
+		sources := []string{getTestMocksAsset("mock_dir1")}
+		destination := "/mtp-test-files/temp_dir/test_UploadFiles_existing_tree"
+
+		progressCb := func(fi *ProgressInfo, err error) error {
+			return err
+		}
+
+		_, totalFiles, totalSize, err := UploadFiles(dev, sid, sources, destination, false, nil, progressCb)
+		So(err, ShouldBeNil)
+
+		var paths []string
+		_, _, _, err = Walk(dev, sid, destination, true, false, false, func(objectId uint32, fi *FileInfo, err error) error {
+			paths = append(paths, fi.FullPath)
+
+			return err
+		})
+		So(err, ShouldBeNil)
+
+		// the existing directories are reused and the files are overwritten
+		_, totalFiles2, totalSize2, err := UploadFiles(dev, sid, sources, destination, false, nil, progressCb)
+		So(err, ShouldBeNil)
+		So(totalFiles2, ShouldEqual, totalFiles)
+		So(totalSize2, ShouldEqual, totalSize)
+
+		var paths2 []string
+		_, _, _, err = Walk(dev, sid, destination, true, false, false, func(objectId uint32, fi *FileInfo, err error) error {
+			paths2 = append(paths2, fi.FullPath)
+
+			return err
+		})
+		So(err, ShouldBeNil)
+		So(paths2, ShouldResemble, paths)
+
+		err = DeleteFile(dev, sid, []FileProp{{0, destination}})
+		So(err, ShouldBeNil)
+	})
+
+	Dispose(dev)
+}
+
+func TestUploadFile(t *testing.T) {
+	dev, err := Initialize(Init{})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	storages, err := FetchStorages(dev)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	sid := storages[0].Sid
+
+	Convey("General | UploadFile", t, func() {
+		source := getTestMocksAsset("mock_dir1/a.txt")
+		destination := "/mtp-test-files/temp_dir/test_UploadFile"
+
+		stat, err := os.Stat(source)
+		So(err, ShouldBeNil)
+
+		fi, err := UploadFile(dev, sid, source, destination)
+		So(err, ShouldBeNil)
+		So(fi.Name, ShouldEqual, "a.txt")
+		So(fi.FullPath, ShouldEqual, "/mtp-test-files/temp_dir/test_UploadFile/a.txt")
+		So(fi.Size, ShouldEqual, stat.Size())
+		So(fi.ModTime.Unix(), ShouldEqual, stat.ModTime().Unix())
+
+		// the existing file is overwritten
+		fi2, err := UploadFile(dev, sid, source, destination)
+		So(err, ShouldBeNil)
+		So(fi2.ObjectId, ShouldNotEqual, fi.ObjectId)
+
+		err = DeleteFile(dev, sid, []FileProp{{0, destination}})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Directory source | UploadFile | should throw an error", t, func() {
+		_, err := UploadFile(dev, sid, getTestMocksAsset("mock_dir1"), "/mtp-test-files/temp_dir/test_UploadFile")
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+	})
+
+	Convey("Invalid source | UploadFile | should throw an error", t, func() {
+		_, err := UploadFile(dev, sid, getTestMocksAsset("fake_file.txt"), "/mtp-test-files/temp_dir/test_UploadFile")
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+	})
+
 	Dispose(dev)
 }
@@ -2,6 +2,7 @@ package mtpx
 
 import (
 	"fmt"
+	"github.com/ganeshrvel/go-mtpx/devicepath"
 	"log"
 	"math"
 	"os"
@@ -9,6 +10,7 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+	"unsafe"
 )
 
 func extension(filename string, isDir bool) string {
@@ -29,7 +31,7 @@ func extension(filename string, isDir bool) string {
 
 	if length > 2 {
 		exts := f[length-2:]
-		if _, ok := allowedSecondExtensions[exts[0]]; ok {
+		if _, ok := getAllowedSecondExtensions()[exts[0]]; ok {
 			return strings.Join(exts, ".")
 		}
 	}
@@ -43,24 +45,6 @@ func extension(filename string, isDir bool) string {
 	return extension
 }
 
-func getFullPath(parentPath, filename string) string {
-	pathSep := PathSep
-
-	_fullPath := fmt.Sprintf("%s%s%s", parentPath, pathSep, filename)
-
-	return fixSlash(_fullPath)
-}
-
-func fixSlash(absFilepath string) string {
-	_absFilepath := absFilepath
-
-	if !strings.HasPrefix(_absFilepath, PathSep) {
-		_absFilepath = fmt.Sprintf("%s%s", PathSep, _absFilepath)
-	}
-
-	return path.Clean(_absFilepath)
-}
-
 func indexExists(arr interface{}, index int) bool {
 	switch value := arr.(type) {
 	case *[]string:
@@ -77,6 +61,7 @@ func indexExists(arr interface{}, index int) bool {
 }
 
 // Get Parent path of a list of directories and files
+// Deprecated: use devicepath.CommonParent for the device paths
 func GetParentPath(sep byte, paths ...string) string {
 	// Handle special cases.
 	switch len(paths) {
@@ -161,12 +146,6 @@ func isSymlinkLocal(fi os.FileInfo) bool {
 	return fi.Mode()&os.ModeSymlink != 0
 }
 
-func isDisallowedFiles(filename string) bool {
-	contains, _ := StringContains(disallowedFiles, filename)
-
-	return contains
-}
-
 func existsLocal(filename string) bool {
 	_, err := os.Stat(filename)
 
@@ -213,13 +192,80 @@ func subpathExists(path, searchPath string) bool {
 	return path != "" && strings.HasPrefix(searchPath, path)
 }
 
-func mapSourcePathToDestinationPath(
+// map the local [sourcePath] (a descendant of [sourceParentPath]) onto the device directory [destinationPath]
+func mapLocalPathToDevicePath(
+	sourcePath, sourceParentPath, destinationPath string,
+) (destinationParentPath, destinationFilePath string, err error) {
+	relativePath, err := filepath.Rel(sourceParentPath, sourcePath)
+	if err != nil {
+		return "", "", InvalidPathError{error: err}
+	}
+
+	fullPath := devicepath.Join(destinationPath, filepath.ToSlash(relativePath))
+
+	if !devicepath.IsSubpath(destinationPath, fullPath) {
+		return "", "", InvalidPathError{error: fmt.Errorf("source path escapes the destination directory: %s", sourcePath)}
+	}
+
+	destinationParentPath, _ = devicepath.Split(fullPath)
+
+	return destinationParentPath, fullPath, nil
+}
+
+// map the device [sourcePath] (a descendant of [sourceParentPath]) onto the local directory [destinationPath].
+// '..' components in the source (eg: a crafted device filename) must not let the result escape [destinationPath]
+func mapDevicePathToLocalPath(
 	sourcePath, sourceParentPath, destinationPath string,
-) (destinationParentPath, destinationFilePath string) {
+) (destinationParentPath, destinationFilePath string, err error) {
 	trimmedSourcePath := strings.TrimPrefix(sourcePath, sourceParentPath)
-	fullPath := getFullPath(destinationPath, trimmedSourcePath)
+	fullPath := filepath.Join(destinationPath, filepath.FromSlash(trimmedSourcePath))
+
+	relativePath, err := filepath.Rel(filepath.Clean(destinationPath), fullPath)
+	if err != nil || relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+		return "", "", InvalidPathError{error: fmt.Errorf("source path escapes the destination directory: %s", sourcePath)}
+	}
+
+	return filepath.Dir(fullPath), fullPath, nil
+}
+
+// clean up the [fullPaths] for [WalkMultiple] keeping their order
+// the duplicates are dropped; if [recursive] is true then the paths nested inside another path are dropped too
+// the paths are compared case-insensitively, like the device resolves them
+func walkRoots(fullPaths []string, recursive bool) []string {
+	var roots []string
+
+	for i, fullPath := range fullPaths {
+		_fullPath := devicepath.Clean(fullPath)
+		_lowerFullPath := strings.ToLower(_fullPath)
+		covered := false
+
+		for j, other := range fullPaths {
+			_other := strings.ToLower(devicepath.Clean(other))
 
-	return filepath.Dir(fullPath), fullPath
+			// keep the first one of the duplicates
+			if _other == _lowerFullPath {
+				if j < i {
+					covered = true
+
+					break
+				}
+
+				continue
+			}
+
+			if recursive && devicepath.IsSubpath(_other, _lowerFullPath) {
+				covered = true
+
+				break
+			}
+		}
+
+		if !covered {
+			roots = append(roots, _fullPath)
+		}
+	}
+
+	return roots
 }
 
 func SanitizeDosName(name string) string {
@@ -248,6 +294,41 @@ func transferRate(size int64, lastSentTime time.Time) float64 {
 	return math.Round(rate*100) / 100
 }
 
+// estimated time left to transfer [total] bytes at the average rate since [startTime]
+// returns 0 if the total is unknown or nothing was sent yet
+func estimateRemainingTime(startTime time.Time, sent, total int64) time.Duration {
+	if total <= 0 || sent <= 0 || sent >= total {
+		return 0
+	}
+
+	elapsed := time.Since(startTime)
+
+	return time.Duration(float64(elapsed) / float64(sent) * float64(total-sent))
+}
+
+// check if all the bytes of [block] are zero
+func isZeroBlock(block []byte) bool {
+	for _, b := range block {
+		if b != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
 func isHiddenFile(filename string) bool {
 	return len(filename) > 0 && filename[0:1] == "."
 }
+
+// approximate memory used by a [FileInfo] (in bytes)
+func fileInfoMemoryUsage(fi *FileInfo) int64 {
+	size := int64(unsafe.Sizeof(*fi)) +
+		int64(len(fi.Name)+len(fi.FullPath)+len(fi.ParentPath)+len(fi.Extension)+len(fi.PersistentUid))
+
+	if fi.Info != nil {
+		size += int64(unsafe.Sizeof(*fi.Info)) + int64(len(fi.Info.Filename)+len(fi.Info.Keywords))
+	}
+
+	return size
+}
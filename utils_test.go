@@ -2,7 +2,9 @@ package mtpx
 
 import (
 	. "github.com/smartystreets/goconvey/convey"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestUtils(t *testing.T) {
@@ -10,55 +12,59 @@ func TestUtils(t *testing.T) {
 	//	t.Skip("skipping 'TestUtils' testing in short mode")
 	//}
 
-	Convey("Test fixSlash", t, func() {
-		filenameList := []string{"", ".", "/./", "././", "/../", "/", "//", "/abc", "//bcd", "/cde/", "/def//", "efg/", "fgh", "ghi/124", "hij/124/", "/ijk/124/"}
-		dirList := []string{"/", "/", "/", "/", "/", "/", "/", "/abc", "/bcd", "/cde", "/def", "/efg", "/fgh", "/ghi/124", "/hij/124", "/ijk/124"}
+	Convey("Test mapDevicePathToLocalPath", t, func() {
+		parentPath, filePath, err := mapDevicePathToLocalPath("/mtp/abc/def.txt", "/mtp", filepath.FromSlash("/local/dl"))
 
-		for i, f := range filenameList {
-			dir := fixSlash(f)
+		So(err, ShouldBeNil)
+		So(parentPath, ShouldEqual, filepath.FromSlash("/local/dl/abc"))
+		So(filePath, ShouldEqual, filepath.FromSlash("/local/dl/abc/def.txt"))
 
-			So(dir, ShouldEqual, dirList[i])
-		}
+		_, _, err = mapDevicePathToLocalPath("/mtp/../../etc/passwd", "/mtp", filepath.FromSlash("/local/dl"))
+
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+
+		_, _, err = mapDevicePathToLocalPath("/mtp/abc/../../../dl2/x", "/mtp", filepath.FromSlash("/local/dl"))
+
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+
+		_, filePath, err = mapDevicePathToLocalPath("/mtp/abc/../def.txt", "/mtp", filepath.FromSlash("/local/dl"))
+
+		So(err, ShouldBeNil)
+		So(filePath, ShouldEqual, filepath.FromSlash("/local/dl/def.txt"))
 	})
 
-	Convey("Test getFullPath", t, func() {
-		type s struct {
-			parentPath, filename, fullPath string
-		}
+	Convey("Test mapLocalPathToDevicePath", t, func() {
+		parentPath, filePath, err := mapLocalPathToDevicePath(
+			filepath.Join("local", "abc", "def.txt"), "local", "/DCIM",
+		)
 
-		sl := []s{
-			s{
-				parentPath: "/",
-				filename:   "abc",
-				fullPath:   "/abc",
-			},
-			s{
-				parentPath: "//",
-				filename:   "bcd",
-				fullPath:   "/bcd",
-			},
-			s{
-				parentPath: "/",
-				filename:   "cde/",
-				fullPath:   "/cde",
-			},
-			s{
-				parentPath: "/def",
-				filename:   "abc/",
-				fullPath:   "/def/abc",
-			},
-			s{
-				parentPath: "/efg/",
-				filename:   "abc/",
-				fullPath:   "/efg/abc",
-			},
-		}
+		So(err, ShouldBeNil)
+		So(parentPath, ShouldEqual, "/DCIM/abc")
+		So(filePath, ShouldEqual, "/DCIM/abc/def.txt")
 
-		for i, f := range sl {
-			fullPath := getFullPath(f.parentPath, f.filename)
+		_, filePath, err = mapLocalPathToDevicePath(filepath.Join("local", "abc"), "local", "/")
 
-			So(fullPath, ShouldEqual, sl[i].fullPath)
-		}
+		So(err, ShouldBeNil)
+		So(filePath, ShouldEqual, "/abc")
+	})
+
+	Convey("Test walkRoots", t, func() {
+		fullPaths := []string{"/DCIM", "/Pictures/", "/dcim/Camera", "/Download", "/pictures", "/Download/a"}
+
+		So(walkRoots(fullPaths, true), ShouldResemble, []string{"/DCIM", "/Pictures", "/Download"})
+		So(walkRoots(fullPaths, false), ShouldResemble, []string{"/DCIM", "/Pictures", "/dcim/Camera", "/Download", "/Download/a"})
+		So(walkRoots([]string{"/DCIM", "/"}, true), ShouldResemble, []string{"/"})
+	})
+
+	Convey("Test estimateRemainingTime", t, func() {
+		startTime := time.Now().Add(-10 * time.Second)
+
+		eta := estimateRemainingTime(startTime, 25, 100)
+		So(eta, ShouldBeBetween, 29*time.Second, 31*time.Second)
+
+		So(estimateRemainingTime(startTime, 0, 100), ShouldEqual, 0)
+		So(estimateRemainingTime(startTime, 25, 0), ShouldEqual, 0)
+		So(estimateRemainingTime(startTime, 100, 100), ShouldEqual, 0)
 	})
 
 	Convey("Test extension", t, func() {
@@ -644,5 +644,68 @@ func TestWalk(t *testing.T) {
 		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
 	})
 
+	Convey("Testing recursive=true | WalkConcurrently", t, func() {
+		var walked []uint32
+		_, totalFiles1, totalDirectories1, err := Walk(dev, sid, "/mtp-test-files", true, true, false,
+			func(objectId uint32, fi *FileInfo, err error) error {
+				walked = append(walked, objectId)
+
+				return nil
+			})
+		So(err, ShouldBeNil)
+
+		var walkedConcurrently []uint32
+		_, totalFiles2, totalDirectories2, err := WalkConcurrently(dev, sid, "/mtp-test-files", true, true, false, 3,
+			func(objectId uint32, fi *FileInfo, err error) error {
+				So(err, ShouldBeNil)
+				So(fi.FullPath, ShouldContainSubstring, "/mtp-test-files/")
+
+				walkedConcurrently = append(walkedConcurrently, objectId)
+
+				return nil
+			})
+
+		So(err, ShouldBeNil)
+		So(totalFiles2, ShouldEqual, totalFiles1)
+		So(totalDirectories2, ShouldEqual, totalDirectories1)
+		So(walkedConcurrently, ShouldResemble, walked)
+	})
+
+	Convey("Testing callback error | WalkConcurrently | It should throw an error", t, func() {
+		_, _, _, err := WalkConcurrently(dev, sid, "/mtp-test-files", true, true, false, 0,
+			func(objectId uint32, fi *FileInfo, err error) error {
+				return InvalidPathError{error: fmt.Errorf("some error occured")}
+			})
+
+		So(err, ShouldBeError)
+		So(err, ShouldHaveSameTypeAs, InvalidPathError{})
+	})
+
+	Convey("Testing overlapping paths | WalkMultiple", t, func() {
+		var walked []uint32
+		_, totalFiles1, totalDirectories1, err := Walk(dev, sid, "/mtp-test-files", true, true, false,
+			func(objectId uint32, fi *FileInfo, err error) error {
+				walked = append(walked, objectId)
+
+				return nil
+			})
+		So(err, ShouldBeNil)
+
+		var walkedMultiple []uint32
+		totalFiles2, totalDirectories2, err := WalkMultiple(dev, sid, []string{"/mtp-test-files", "/mtp-test-files/mock_dir1", "/mtp-test-files/"}, true, true, false,
+			func(objectId uint32, fi *FileInfo, err error) error {
+				So(err, ShouldBeNil)
+
+				walkedMultiple = append(walkedMultiple, objectId)
+
+				return nil
+			})
+
+		So(err, ShouldBeNil)
+		So(totalFiles2, ShouldEqual, totalFiles1)
+		So(totalDirectories2, ShouldEqual, totalDirectories1)
+		So(walkedMultiple, ShouldResemble, walked)
+	})
+
 	Dispose(dev)
 }
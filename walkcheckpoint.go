@@ -0,0 +1,245 @@
+package mtpx
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/ganeshrvel/go-mtpx/devicepath"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// walk state of [WalkWithCheckpoint]
+type checkpointWalker struct {
+	dev                 *mtp.Device
+	storageId           uint32
+	skipDisallowedFiles bool
+	skipHiddenFiles     bool
+	opts                WalkCheckpointOptions
+	cb                  WalkCb
+
+	checkpoint     *WalkCheckpoint
+	completed      map[string]bool
+	lastCheckpoint time.Time
+}
+
+// Walk the whole tree of a directory while periodically taking a checkpoint of its position
+// meant for indexing the very large devices: an interrupted walk is resumed using [opts.Resume] from the last completed directory instead of starting over
+// the directories which were completed are skipped along with their whole subtree,
+// the objects of the directories which were not completed are passed to [cb] again
+// the objects are visited in the same order as [Walk], see [Walk] for the rest of the parameters
+// return:
+// [totalFiles]: total number of files visited by this run
+// [totalDirectories]: total number of directories visited by this run
+func WalkWithCheckpoint(dev *mtp.Device, storageId uint32, fullPath string, skipDisallowedFiles, skipHiddenFiles bool,
+	opts WalkCheckpointOptions, cb WalkCb) (totalFiles, totalDirectories int64, err error) {
+	_fullPath := devicepath.Clean(fullPath)
+
+	checkpoint := &WalkCheckpoint{StorageId: storageId, FullPath: _fullPath}
+	if opts.Resume != nil {
+		if opts.Resume.StorageId != storageId || devicepath.Clean(opts.Resume.FullPath) != _fullPath {
+			return 0, 0, InvalidPathError{
+				error: fmt.Errorf("the checkpoint belongs to the walk of %s on the storage %d", opts.Resume.FullPath, opts.Resume.StorageId),
+			}
+		}
+
+		if opts.Resume.Done {
+			return 0, 0, nil
+		}
+
+		checkpoint.Completed = append(checkpoint.Completed, opts.Resume.Completed...)
+	}
+
+	release, err := beginOperation(dev)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() { release(err) }()
+
+	stop := monitorOperation(dev, &OperationInfo{Type: WalkOp, StorageId: storageId, Sources: []string{_fullPath}})
+	defer func() { stop(err) }()
+
+	fi, err := GetObjectFromPath(dev, storageId, _fullPath)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if !fi.IsDir {
+		return 0, 0, InvalidPathError{error: fmt.Errorf("invalid path: %s. The object is not a directory", _fullPath)}
+	}
+
+	w := &checkpointWalker{
+		dev:                 dev,
+		storageId:           storageId,
+		skipDisallowedFiles: skipDisallowedFiles,
+		skipHiddenFiles:     skipHiddenFiles,
+		opts:                opts,
+		cb:                  cb,
+		checkpoint:          checkpoint,
+		completed:           map[string]bool{},
+		lastCheckpoint:      time.Now(),
+	}
+
+	for _, p := range checkpoint.Completed {
+		w.completed[p] = true
+	}
+
+	totalFiles, totalDirectories, err = w.walk(FileProp{fi.ObjectId, _fullPath})
+	if err != nil {
+		return totalFiles, totalDirectories, err
+	}
+
+	checkpoint.Done = true
+	checkpoint.Completed = nil
+
+	return totalFiles, totalDirectories, w.emit()
+}
+
+// walk the objects of the directory and descend into the directories which were not completed yet
+func (w *checkpointWalker) walk(fileProp FileProp) (totalFiles, totalDirectories int64, err error) {
+	children, err := listDirectory(w.dev, w.storageId, fileProp.ObjectId, fileProp.FullPath)
+	if err != nil {
+		return totalFiles, totalDirectories, err
+	}
+
+	for _, fi := range sortWalkObjects(children, WalkDirsFirst()) {
+		if err := touchOperation(w.dev); err != nil {
+			return totalFiles, totalDirectories, err
+		}
+
+		if w.skipHiddenFiles && isHiddenFile(fi.Name) {
+			continue
+		}
+
+		if w.skipDisallowedFiles && isDisallowedFiles(fi.Name) {
+			continue
+		}
+
+		if fi.IsDir && w.completed[fi.FullPath] {
+			continue
+		}
+
+		if fi.IsDir {
+			totalDirectories += 1
+		} else {
+			totalFiles += 1
+		}
+
+		if err := runWalkCb(w.dev, w.cb, fi); err != nil {
+			return totalFiles, totalDirectories, err
+		}
+
+		if !fi.IsDir {
+			continue
+		}
+
+		_totalFiles, _totalDirectories, err := w.walk(FileProp{fi.ObjectId, fi.FullPath})
+		totalFiles += _totalFiles
+		totalDirectories += _totalDirectories
+
+		if err != nil {
+			return totalFiles, totalDirectories, err
+		}
+
+		if err := w.complete(fi.FullPath); err != nil {
+			return totalFiles, totalDirectories, err
+		}
+	}
+
+	return totalFiles, totalDirectories, nil
+}
+
+// mark the directory as completed and take a checkpoint if it is due
+func (w *checkpointWalker) complete(fullPath string) error {
+	w.checkpoint.Completed = compactCompleted(w.checkpoint.Completed, fullPath)
+	w.completed[fullPath] = true
+
+	if time.Since(w.lastCheckpoint) < w.opts.Interval {
+		return nil
+	}
+
+	return w.emit()
+}
+
+// pass a copy of the checkpoint to the callback
+func (w *checkpointWalker) emit() error {
+	w.lastCheckpoint = time.Now()
+
+	if w.opts.Cb == nil {
+		return nil
+	}
+
+	cp := *w.checkpoint
+	cp.Completed = append([]string(nil), w.checkpoint.Completed...)
+	cp.UpdatedAt = w.lastCheckpoint
+
+	return w.opts.Cb(&cp)
+}
+
+// add [fullPath] to the completed directories and drop the ones nested inside it
+func compactCompleted(completed []string, fullPath string) []string {
+	prefix := fullPath + devicepath.Separator
+	if fullPath == devicepath.Separator {
+		prefix = fullPath
+	}
+
+	_completed := completed[:0]
+	for _, p := range completed {
+		if !strings.HasPrefix(p, prefix) {
+			_completed = append(_completed, p)
+		}
+	}
+
+	_completed = append(_completed, fullPath)
+	sort.Strings(_completed)
+
+	return _completed
+}
+
+// write the checkpoint of a walk to a local file, see [WalkWithCheckpoint]
+func SaveWalkCheckpoint(filename string, cp *WalkCheckpoint) error {
+	if err := makeLocalDirectory(filepath.Dir(filename)); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return LocalFileError{error: err}
+	}
+
+	// write to a temporary file first so that a crash won't leave a truncated checkpoint behind
+	tmpPath := fmt.Sprintf("%s.tmp", filename)
+	if err := ioutil.WriteFile(tmpPath, data, newLocalFileMode); err != nil {
+		return LocalFileError{error: err}
+	}
+
+	if err := os.Rename(tmpPath, filename); err != nil {
+		return LocalFileError{error: err}
+	}
+
+	return nil
+}
+
+// read the checkpoint of a walk written by [SaveWalkCheckpoint]
+// returns nil if the file does not exist so that the result can be passed to [WalkCheckpointOptions.Resume] as it is
+func LoadWalkCheckpoint(filename string) (*WalkCheckpoint, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, LocalFileError{error: err}
+	}
+
+	var cp WalkCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, LocalFileError{error: fmt.Errorf("invalid walk checkpoint %s: %v", filename, err)}
+	}
+
+	return &cp, nil
+}
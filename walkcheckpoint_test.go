@@ -0,0 +1,101 @@
+package mtpx
+
+import (
+	"errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWalkWithCheckpoint(t *testing.T) {
+	dev, err := Initialize(Init{})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	storages, err := FetchStorages(dev)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	sid := storages[0].Sid
+
+	Convey("Testing WalkWithCheckpoint", t, func() {
+		fullPath := "/mtp-test-files"
+		walkCb := func(objectId uint32, fi *FileInfo, err error) error { return err }
+
+		totalFiles, totalDirectories, err := WalkWithCheckpoint(dev, sid, fullPath, true, false, WalkCheckpointOptions{}, walkCb)
+		So(err, ShouldBeNil)
+
+		// stop the walk at the first checkpoint
+		errStop := errors.New("stop")
+		var checkpoint *WalkCheckpoint
+
+		_, _, err = WalkWithCheckpoint(dev, sid, fullPath, true, false, WalkCheckpointOptions{
+			Cb: func(cp *WalkCheckpoint) error {
+				checkpoint = cp
+
+				return errStop
+			},
+		}, walkCb)
+		So(err, ShouldEqual, errStop)
+		So(checkpoint, ShouldNotBeNil)
+		So(checkpoint.Done, ShouldBeFalse)
+		So(checkpoint.Completed, ShouldHaveLength, 1)
+
+		completed := checkpoint.Completed[0]
+
+		resumedFiles, resumedDirectories, err := WalkWithCheckpoint(dev, sid, fullPath, true, false, WalkCheckpointOptions{Resume: checkpoint},
+			func(objectId uint32, fi *FileInfo, err error) error {
+				So(fi.FullPath, ShouldNotEqual, completed)
+				So(strings.HasPrefix(fi.FullPath, completed+"/"), ShouldBeFalse)
+
+				return err
+			})
+		So(err, ShouldBeNil)
+		So(resumedFiles+resumedDirectories, ShouldBeLessThan, totalFiles+totalDirectories)
+	})
+
+	Dispose(dev)
+}
+
+func TestWalkCheckpoint(t *testing.T) {
+	Convey("Test compactCompleted", t, func() {
+		completed := compactCompleted(nil, "/DCIM/Camera")
+		completed = compactCompleted(completed, "/DCIM/.thumbnails")
+		completed = compactCompleted(completed, "/DCIMX")
+		So(completed, ShouldResemble, []string{"/DCIM/.thumbnails", "/DCIM/Camera", "/DCIMX"})
+
+		completed = compactCompleted(completed, "/DCIM")
+		So(completed, ShouldResemble, []string{"/DCIM", "/DCIMX"})
+
+		So(compactCompleted(completed, "/"), ShouldResemble, []string{"/"})
+	})
+
+	Convey("Test SaveWalkCheckpoint and LoadWalkCheckpoint", t, func() {
+		dir, err := ioutil.TempDir("", "mtpx-checkpoint")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		filename := filepath.Join(dir, "nested", "walk.json")
+
+		cp, err := LoadWalkCheckpoint(filename)
+		So(err, ShouldBeNil)
+		So(cp, ShouldBeNil)
+
+		err = SaveWalkCheckpoint(filename, &WalkCheckpoint{StorageId: 1, FullPath: "/", Completed: []string{"/DCIM"}})
+		So(err, ShouldBeNil)
+
+		cp, err = LoadWalkCheckpoint(filename)
+		So(err, ShouldBeNil)
+		So(cp.StorageId, ShouldEqual, 1)
+		So(cp.Completed, ShouldResemble, []string{"/DCIM"})
+
+		_, err = os.Stat(filename + ".tmp")
+		So(os.IsNotExist(err), ShouldBeTrue)
+	})
+}
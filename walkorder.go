@@ -0,0 +1,54 @@
+package mtpx
+
+import (
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+var walkDirsFirst atomic.Value
+
+// whether [Walk] visits the directories before the files of a directory
+func WalkDirsFirst() bool {
+	if dirsFirst, ok := walkDirsFirst.Load().(bool); ok {
+		return dirsFirst
+	}
+
+	return false
+}
+
+// set whether [Walk] visits the directories before the files of a directory
+// the objects are always visited in the order of their names, see [sortWalkObjects]
+// the default is false (the directories and files are interleaved)
+func SetWalkDirsFirst(dirsFirst bool) {
+	walkDirsFirst.Store(dirsFirst)
+}
+
+// sort the objects of a directory in the order in which they are walked
+// the device returns the object handles in an arbitrary order which varies between the devices and the sessions,
+// so the objects are sorted case-insensitively by name; ties are broken by the raw name and then by the [ObjectId]
+// a copy is sorted so that the cached listings are left untouched
+func sortWalkObjects(children []*FileInfo, dirsFirst bool) []*FileInfo {
+	sorted := make([]*FileInfo, len(children))
+	copy(sorted, children)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+
+		if dirsFirst && a.IsDir != b.IsDir {
+			return a.IsDir
+		}
+
+		if la, lb := strings.ToLower(a.Name), strings.ToLower(b.Name); la != lb {
+			return la < lb
+		}
+
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+
+		return a.ObjectId < b.ObjectId
+	})
+
+	return sorted
+}
@@ -0,0 +1,42 @@
+package mtpx
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestSortWalkObjects(t *testing.T) {
+	children := []*FileInfo{
+		{ObjectId: 5, Name: "b.txt"},
+		{ObjectId: 4, Name: "Zeta", IsDir: true},
+		{ObjectId: 3, Name: "a.txt"},
+		{ObjectId: 2, Name: "B.txt"},
+		{ObjectId: 1, Name: "alpha", IsDir: true},
+	}
+
+	names := func(l []*FileInfo) []string {
+		var n []string
+		for _, fi := range l {
+			n = append(n, fi.Name)
+		}
+
+		return n
+	}
+
+	Convey("Test sortWalkObjects", t, func() {
+		So(names(sortWalkObjects(children, false)), ShouldResemble, []string{"a.txt", "alpha", "B.txt", "b.txt", "Zeta"})
+		So(names(sortWalkObjects(children, true)), ShouldResemble, []string{"alpha", "Zeta", "a.txt", "B.txt", "b.txt"})
+
+		// the input is left untouched
+		So(children[0].Name, ShouldEqual, "b.txt")
+	})
+
+	Convey("Test SetWalkDirsFirst", t, func() {
+		So(WalkDirsFirst(), ShouldBeFalse)
+
+		SetWalkDirsFirst(true)
+		So(WalkDirsFirst(), ShouldBeTrue)
+
+		SetWalkDirsFirst(false)
+	})
+}
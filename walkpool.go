@@ -0,0 +1,200 @@
+package mtpx
+
+import (
+	"fmt"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"sync"
+)
+
+type walkListing struct {
+	// the objects to deliver, in the order of [proccessWalk]
+	children []*FileInfo
+	err      error
+}
+
+type concurrentWalker struct {
+	dev                                                        *mtp.Device
+	storageId                                                  uint32
+	recursive, skipDisallowedFiles, skipHiddenFiles, dirsFirst bool
+	cb                                                         WalkCb
+
+	// the listings in the order in which [walk] consumes them, filled ahead of time by [prefetch]
+	listings chan walkListing
+
+	// closed once the walk returns, the pending listings are abandoned
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// List the contents in a directory like [Walk], fetching the listings of the subdirectories ahead of time
+// up to [lookahead] listings are fetched ahead of the objects delivered to [cb]. if [lookahead] is less than 1 then
+// [defaultWalkLookahead] is used
+// the objects are delivered to [cb] one at a time and in the same order as [Walk]
+// the listings are fetched while [cb] runs, so [cb] must only use the device through [Interleave] until the walk returns
+// return:
+// [objectId]: objectId of the file/diectory
+// [totalFiles]: total number of files
+// [totalDirectories]: total number of directories
+func WalkConcurrently(dev *mtp.Device, storageId uint32, fullPath string, recursive, skipDisallowedFiles,
+	skipHiddenFiles bool, lookahead int, cb WalkCb) (objectId uint32, totalFiles, totalDirectories int64, err error) {
+	release, err := beginOperation(dev)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer func() { release(err) }()
+
+	stop := monitorOperation(dev, &OperationInfo{Type: WalkOp, StorageId: storageId, Sources: []string{fullPath}})
+	defer func() { stop(err) }()
+
+	fi, err := GetObjectFromPath(dev, storageId, fullPath)
+	if err != nil {
+		return 0, totalFiles, totalDirectories, err
+	}
+
+	// if the object file name matches the disallowed files list then return an error
+	if skipDisallowedFiles && isDisallowedFiles(fi.Name) {
+		return 0, totalFiles, totalDirectories, InvalidPathError{error: fmt.Errorf("disallowed file %v", fi.Name)}
+	}
+
+	// there is nothing to fetch ahead of time for a file
+	if !fi.IsDir {
+		return walkNested(dev, storageId, fullPath, recursive, skipDisallowedFiles, skipHiddenFiles, cb)
+	}
+
+	if lookahead < 1 {
+		lookahead = defaultWalkLookahead
+	}
+
+	w := &concurrentWalker{
+		dev:                 dev,
+		storageId:           storageId,
+		recursive:           recursive,
+		skipDisallowedFiles: skipDisallowedFiles,
+		skipHiddenFiles:     skipHiddenFiles,
+		dirsFirst:           WalkDirsFirst(),
+		cb:                  cb,
+		listings:            make(chan walkListing, lookahead),
+		done:                make(chan struct{}),
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		w.prefetch(FileProp{fi.ObjectId, fullPath})
+	}()
+
+	totalFiles, totalDirectories, err = w.walk()
+
+	// wait for the in-flight listing so that the device is free once the walk returns
+	close(w.done)
+	w.wg.Wait()
+
+	if err != nil {
+		return 0, totalFiles, totalDirectories, err
+	}
+
+	return fi.ObjectId, totalFiles, totalDirectories, nil
+}
+
+// fetch the listing of the directory [fileProp] and then the listings of its subdirectories, depth first, which is the
+// order in which [walk] consumes them
+// the fetching pauses while [listings] is full and stops once the walk returns
+// returns false if the walk has returned or a listing failed
+func (w *concurrentWalker) prefetch(fileProp FileProp) bool {
+	select {
+	case <-w.done:
+		return false
+	default:
+	}
+
+	// the walk shares the device with the operations run through [Interleave] and the streams
+	lock := streamLock(w.dev)
+
+	lock.Lock()
+	children, err := listDirectory(w.dev, w.storageId, fileProp.ObjectId, fileProp.FullPath)
+	lock.Unlock()
+
+	l := walkListing{err: err}
+	if err == nil {
+		l.children = w.filter(children)
+	}
+
+	select {
+	case w.listings <- l:
+	case <-w.done:
+		return false
+	}
+
+	if err != nil {
+		return false
+	}
+
+	if !w.recursive {
+		return true
+	}
+
+	for _, fi := range l.children {
+		if fi.IsDir && !w.prefetch(FileProp{fi.ObjectId, fi.FullPath}) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// the objects of a listing which are delivered to [cb], in the order of [proccessWalk]
+func (w *concurrentWalker) filter(children []*FileInfo) []*FileInfo {
+	var filtered []*FileInfo
+
+	for _, fi := range sortWalkObjects(children, w.dirsFirst) {
+		// skip the object if it's a hidden file
+		if w.skipHiddenFiles && isHiddenFile(fi.Name) {
+			continue
+		}
+
+		// if the object file name matches the disallowed files list then ignore it
+		if w.skipDisallowedFiles && isDisallowedFiles(fi.Name) {
+			continue
+		}
+
+		filtered = append(filtered, fi)
+	}
+
+	return filtered
+}
+
+// deliver the objects of the next listing to [cb], walking into the subdirectories as they are reached
+func (w *concurrentWalker) walk() (totalFiles, totalDirectories int64, err error) {
+	l := <-w.listings
+	if l.err != nil {
+		return totalFiles, totalDirectories, l.err
+	}
+
+	for _, fi := range l.children {
+		if fi.IsDir {
+			totalDirectories += 1
+		} else {
+			totalFiles += 1
+		}
+
+		err = runWalkCb(w.dev, w.cb, fi)
+		if err != nil {
+			return totalFiles, totalDirectories, err
+		}
+
+		if !w.recursive || !fi.IsDir {
+			continue
+		}
+
+		_totalFiles, _totalDirectories, err := w.walk()
+		if err != nil {
+			return totalFiles, totalDirectories, err
+		}
+
+		totalFiles += _totalFiles
+		totalDirectories += _totalDirectories
+	}
+
+	return totalFiles, totalDirectories, nil
+}
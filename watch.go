@@ -0,0 +1,224 @@
+package mtpx
+
+import (
+	"context"
+	"github.com/ganeshrvel/go-mtpfs/mtp"
+	"github.com/ganeshrvel/go-mtpx/devicepath"
+	"sort"
+	"time"
+)
+
+// Watch the changes inside a directory by taking periodic snapshots of the subtree and comparing them
+// This works on every device, including the ones which do not emit ObjectAdded/ObjectRemoved events
+// all the changes detected between two snapshots are coalesced and delivered as a single batch to [cb]
+// an object which was modified several times between two snapshots will produce a single event
+// use [opts.DebounceWindow] to hold back the events of busy objects (eg: camera bursts, temp files) until they settle
+// errors while taking a snapshot are passed on to [cb]; returning an error from [cb] stops the watch
+// if the metadata cache is enabled then the cached listings affected by the changes are invalidated
+// Watch blocks until [ctx] is done or [cb] returns an error
+func Watch(ctx context.Context, dev *mtp.Device, storageId uint32, fullPath string, opts WatchOptions, cb WatchCb) error {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	prev, err := takeWatchSnapshot(dev, storageId, fullPath, opts.Recursive)
+	if err != nil {
+		return err
+	}
+
+	pending := map[uint32]*pendingWatchEvent{}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// deliver whatever is held back before leaving
+			if events := flushWatchEvents(pending, 0, time.Now()); len(events) > 0 {
+				return cb(events, nil)
+			}
+
+			return nil
+
+		case <-ticker.C:
+			next, err := takeWatchSnapshot(dev, storageId, fullPath, opts.Recursive)
+			if err != nil {
+				if err := cb(nil, err); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			now := time.Now()
+
+			changes := diffWatchSnapshots(prev, next)
+
+			// keep the metadata cache in line with the device
+			if c := getDeviceCache(dev); c != nil {
+				c.invalidateEvents(storageId, changes, prev)
+			}
+
+			if len(changes) > 0 {
+				refreshStorageSpace(dev, storageId)
+			}
+
+			coalesceWatchEvents(pending, changes, now)
+			prev = next
+
+			events := flushWatchEvents(pending, opts.DebounceWindow, now)
+			if len(events) < 1 {
+				continue
+			}
+
+			if err := cb(events, nil); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// merge the [events] into the [pending] events of the respective objects
+func coalesceWatchEvents(pending map[uint32]*pendingWatchEvent, events []WatchEvent, now time.Time) {
+	for _, e := range events {
+		p, ok := pending[e.ObjectId]
+		if !ok {
+			pending[e.ObjectId] = &pendingWatchEvent{event: e, lastSeen: now}
+
+			continue
+		}
+
+		p.lastSeen = now
+		p.event.FileInfo = e.FileInfo
+
+		switch {
+		// an object which was added and removed within the window never existed for the consumer
+		case p.event.Type == ObjectAdded && e.Type == ObjectRemoved:
+			delete(pending, e.ObjectId)
+
+		// an object which was added and then changed is still a new object
+		case p.event.Type == ObjectAdded && e.Type == ObjectChanged:
+
+		// an object which was removed and came back with the same objectId has changed
+		case p.event.Type == ObjectRemoved && e.Type == ObjectAdded:
+			p.event.Type = ObjectChanged
+
+		default:
+			p.event.Type = e.Type
+		}
+	}
+}
+
+// remove and return the [pending] events which did not change for [window]
+// the events are sorted by the [FullPath] of the objects
+func flushWatchEvents(pending map[uint32]*pendingWatchEvent, window time.Duration, now time.Time) []WatchEvent {
+	var events []WatchEvent
+
+	for objectId, p := range pending {
+		if now.Sub(p.lastSeen) < window {
+			continue
+		}
+
+		events = append(events, p.event)
+		delete(pending, objectId)
+	}
+
+	sortWatchEvents(events)
+
+	return events
+}
+
+// fetch the current state of the watched subtree
+// the objects are always fetched from the device, bypassing the metadata cache
+func takeWatchSnapshot(dev *mtp.Device, storageId uint32, fullPath string, recursive bool) (watchSnapshot, error) {
+	fi, err := GetObjectFromPath(dev, storageId, fullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := watchSnapshot{}
+
+	if !fi.IsDir {
+		parentPath, _ := devicepath.Split(fi.FullPath)
+		_fi, err := GetObjectFromObjectId(dev, fi.ObjectId, parentPath)
+		if err != nil {
+			return nil, err
+		}
+
+		snapshot[_fi.ObjectId] = _fi
+
+		return snapshot, nil
+	}
+
+	if err := snapshotDirectory(dev, storageId, fi.ObjectId, fi.FullPath, recursive, snapshot); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// helper function to add the objects inside a directory to the [snapshot]
+func snapshotDirectory(dev *mtp.Device, storageId, objectId uint32, fullPath string, recursive bool, snapshot watchSnapshot) error {
+	children, err := fetchDirectory(dev, storageId, objectId, fullPath)
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range children {
+		snapshot[fi.ObjectId] = fi
+
+		if !recursive || !fi.IsDir {
+			continue
+		}
+
+		if err := snapshotDirectory(dev, storageId, fi.ObjectId, fi.FullPath, recursive, snapshot); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// compare two snapshots and list the added, removed and changed objects
+// the events are sorted by the [FullPath] of the objects
+func diffWatchSnapshots(prev, next watchSnapshot) []WatchEvent {
+	var events []WatchEvent
+
+	for objectId, fi := range next {
+		prevFi, ok := prev[objectId]
+		if !ok {
+			events = append(events, WatchEvent{Type: ObjectAdded, ObjectId: objectId, FileInfo: fi})
+
+			continue
+		}
+
+		if isWatchObjectChanged(prevFi, fi) {
+			events = append(events, WatchEvent{Type: ObjectChanged, ObjectId: objectId, FileInfo: fi})
+		}
+	}
+
+	for objectId, fi := range prev {
+		if _, ok := next[objectId]; !ok {
+			events = append(events, WatchEvent{Type: ObjectRemoved, ObjectId: objectId, FileInfo: fi})
+		}
+	}
+
+	sortWatchEvents(events)
+
+	return events
+}
+
+func sortWatchEvents(events []WatchEvent) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].FileInfo.FullPath < events[j].FileInfo.FullPath
+	})
+}
+
+func isWatchObjectChanged(prev, next *FileInfo) bool {
+	return prev.Name != next.Name ||
+		prev.Size != next.Size ||
+		prev.ParentId != next.ParentId ||
+		!prev.ModTime.Equal(next.ModTime)
+}
@@ -0,0 +1,131 @@
+package mtpx
+
+import (
+	"context"
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"log"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	dev, err := Initialize(Init{})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	storages, err := FetchStorages(dev)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	sid := storages[0].Sid
+
+	Convey("Detect a new directory | Watch", t, func() {
+		// test the directory '/mtp-test-files/temp_dir/test-Watch'
+		watchDir := "/mtp-test-files/temp_dir/test-Watch"
+		_, err := MakeDirectory(dev, sid, watchDir)
+		So(err, ShouldBeNil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		newDir := fmt.Sprintf("%s/%x", watchDir, rand.Int31())
+		go func() {
+			time.Sleep(500 * time.Millisecond)
+			_, _ = MakeDirectory(dev, sid, newDir)
+		}()
+
+		var added []WatchEvent
+		err = Watch(ctx, dev, sid, watchDir, WatchOptions{Interval: 1 * time.Second}, func(events []WatchEvent, err error) error {
+			So(err, ShouldBeNil)
+
+			for _, e := range events {
+				if e.Type == ObjectAdded {
+					added = append(added, e)
+				}
+			}
+
+			cancel()
+
+			return nil
+		})
+
+		So(err, ShouldBeNil)
+		So(len(added), ShouldEqual, 1)
+		So(added[0].FileInfo.FullPath, ShouldEqual, newDir)
+	})
+
+	Dispose(dev)
+}
+
+func TestDiffWatchSnapshots(t *testing.T) {
+	Convey("Test diffWatchSnapshots", t, func() {
+		modTime := time.Now()
+
+		prev := watchSnapshot{
+			1: &FileInfo{ObjectId: 1, Name: "a.txt", FullPath: "/a.txt", Size: 10, ModTime: modTime},
+			2: &FileInfo{ObjectId: 2, Name: "b.txt", FullPath: "/b.txt", Size: 10, ModTime: modTime},
+			3: &FileInfo{ObjectId: 3, Name: "c.txt", FullPath: "/c.txt", Size: 10, ModTime: modTime},
+		}
+		next := watchSnapshot{
+			1: &FileInfo{ObjectId: 1, Name: "a.txt", FullPath: "/a.txt", Size: 10, ModTime: modTime},
+			2: &FileInfo{ObjectId: 2, Name: "b.txt", FullPath: "/b.txt", Size: 20, ModTime: modTime},
+			4: &FileInfo{ObjectId: 4, Name: "d.txt", FullPath: "/d.txt", Size: 10, ModTime: modTime},
+		}
+
+		events := diffWatchSnapshots(prev, next)
+
+		So(len(events), ShouldEqual, 3)
+		So(events[0].Type, ShouldEqual, ObjectChanged)
+		So(events[0].ObjectId, ShouldEqual, 2)
+		So(events[1].Type, ShouldEqual, ObjectRemoved)
+		So(events[1].ObjectId, ShouldEqual, 3)
+		So(events[2].Type, ShouldEqual, ObjectAdded)
+		So(events[2].ObjectId, ShouldEqual, 4)
+
+		So(len(diffWatchSnapshots(next, next)), ShouldEqual, 0)
+	})
+}
+
+func TestCoalesceWatchEvents(t *testing.T) {
+	Convey("Test coalesceWatchEvents | flushWatchEvents", t, func() {
+		now := time.Now()
+		pending := map[uint32]*pendingWatchEvent{}
+
+		fi1 := &FileInfo{ObjectId: 1, FullPath: "/a.txt", Size: 10}
+		fi1Changed := &FileInfo{ObjectId: 1, FullPath: "/a.txt", Size: 20}
+		fi2 := &FileInfo{ObjectId: 2, FullPath: "/b.txt"}
+		fi3 := &FileInfo{ObjectId: 3, FullPath: "/c.txt"}
+
+		coalesceWatchEvents(pending, []WatchEvent{
+			{Type: ObjectAdded, ObjectId: 1, FileInfo: fi1},
+			{Type: ObjectAdded, ObjectId: 2, FileInfo: fi2},
+			{Type: ObjectChanged, ObjectId: 3, FileInfo: fi3},
+		}, now)
+
+		coalesceWatchEvents(pending, []WatchEvent{
+			{Type: ObjectChanged, ObjectId: 1, FileInfo: fi1Changed},
+			{Type: ObjectRemoved, ObjectId: 2, FileInfo: fi2},
+		}, now.Add(time.Second))
+
+		// nothing has settled yet
+		events := flushWatchEvents(pending, 5*time.Second, now.Add(2*time.Second))
+		So(len(events), ShouldEqual, 0)
+
+		events = flushWatchEvents(pending, 5*time.Second, now.Add(10*time.Second))
+		// added + removed is dropped
+		So(len(events), ShouldEqual, 2)
+
+		// added + changed is delivered as added with the latest information
+		So(events[0].Type, ShouldEqual, ObjectAdded)
+		So(events[0].FileInfo.Size, ShouldEqual, 20)
+
+		So(events[1].Type, ShouldEqual, ObjectChanged)
+		So(events[1].ObjectId, ShouldEqual, 3)
+
+		So(len(pending), ShouldEqual, 0)
+	})
+}